@@ -0,0 +1,286 @@
+package statesync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AsyncHandler runs the work of an AsyncRequest and returns its response
+type AsyncHandler func(ctx context.Context, req AsyncRequest) (response any, err error)
+
+// AsyncRunnerConfig configures an AsyncRunner
+type AsyncRunnerConfig[T any] struct {
+	// Requests lists the requests stored in the state (only pending ones are run)
+	Requests func(state T) []AsyncRequest
+
+	// Apply writes a finished (completed or failed) request back into the state
+	Apply func(state *T, req AsyncRequest)
+
+	Concurrency  int           // Max requests running at once (default 4)
+	Timeout      time.Duration // Per attempt (default 30s)
+	MaxAttempts  int           // Attempts before the request fails (default 3)
+	Backoff      time.Duration // Delay before the first retry, doubled per retry (default 100ms)
+	MaxBackoff   time.Duration // Upper bound of the retry delay (default 10s)
+	PollInterval time.Duration // How often Run scans the state (default 100ms)
+
+	// Recorder records finished requests as external inputs (optional, see ExternalInjector.WithRecorder)
+	Recorder InputRecorder
+
+	// Playback switches the runner to re-simulation: handlers are not called, pending
+	// requests are finished with their recorded "async:<type>" results instead (matched by ID),
+	// once the session's Seq reaches the seq of the diff the result was recorded with.
+	// Requests without a recorded result stay pending.
+	Playback *InputPlayback
+}
+
+// AsyncRunner runs pending AsyncRequests stored in a session's state.
+// Handlers are registered per AsyncRequest.Type; results are written back with
+// Complete/Fail through an ExternalInjector (source "async:<type>"), so they are
+// recorded like any other external input. The runner never ticks: the session
+// owner's next Tick broadcasts and records them like any other state change.
+type AsyncRunner[T Trackable, A any, ID comparable] struct {
+	session *TrackedSession[T, A, ID]
+	cfg     AsyncRunnerConfig[T]
+
+	// mu also serializes scanning the state (Poll) with writing results back (finish),
+	// so a request that just finished is never seen as pending and not running
+	mu       sync.Mutex
+	handlers map[string]AsyncHandler
+	running  map[string]bool // Request IDs currently running
+	sem      chan struct{}
+	wg       sync.WaitGroup
+}
+
+// errPermanent marks errors that should not be retried
+type errPermanent struct{ err error }
+
+func (e *errPermanent) Error() string { return e.err.Error() }
+func (e *errPermanent) Unwrap() error { return e.err }
+
+// Permanent wraps a handler error so the request fails without further retries
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &errPermanent{err: err}
+}
+
+// NewAsyncRunner creates a runner for a session. Requests and Apply are required.
+func NewAsyncRunner[T Trackable, A any, ID comparable](session *TrackedSession[T, A, ID], cfg AsyncRunnerConfig[T]) *AsyncRunner[T, A, ID] {
+	if cfg.Requests == nil || cfg.Apply == nil {
+		panic("statesync: AsyncRunnerConfig requires Requests and Apply")
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = 100 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 10 * time.Second
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 100 * time.Millisecond
+	}
+	return &AsyncRunner[T, A, ID]{
+		session:  session,
+		cfg:      cfg,
+		handlers: make(map[string]AsyncHandler),
+		running:  make(map[string]bool),
+		sem:      make(chan struct{}, cfg.Concurrency),
+	}
+}
+
+// Handle registers the handler for a request type
+func (r *AsyncRunner[T, A, ID]) Handle(reqType string, h AsyncHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[reqType] = h
+}
+
+// Poll scans the state once and starts all pending requests that have a handler
+// and are not already running. Returns the number of started requests.
+// In re-simulation (see AsyncRunnerConfig.Playback) recorded results are applied
+// synchronously instead, and the number of finished requests is returned. Playback
+// stops at the first recorded result that can't be applied and returns its error.
+func (r *AsyncRunner[T, A, ID]) Poll(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pending []AsyncRequest
+	r.session.State().ReadBase(func(state T) {
+		for _, req := range r.cfg.Requests(state) {
+			if req.Status == AsyncPending {
+				pending = append(pending, req)
+			}
+		}
+	})
+
+	if r.cfg.Playback != nil {
+		return r.playback(pending)
+	}
+
+	started := 0
+	for _, req := range pending {
+		h, ok := r.handlers[req.Type]
+		if !ok || r.running[req.ID] {
+			continue
+		}
+		r.running[req.ID] = true
+		r.wg.Add(1)
+		started++
+		go r.run(ctx, h, req)
+	}
+	return started, nil
+}
+
+// Run polls the state until ctx is cancelled, then waits for running requests.
+// Requests interrupted by cancellation stay pending and are picked up again later.
+// In re-simulation Run returns the first playback error (see Poll).
+func (r *AsyncRunner[T, A, ID]) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Poll(ctx); err != nil {
+			r.Wait()
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			r.Wait()
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Wait blocks until all running requests have finished
+func (r *AsyncRunner[T, A, ID]) Wait() {
+	r.wg.Wait()
+}
+
+// run executes a request with retries and writes the result back
+func (r *AsyncRunner[T, A, ID]) run(ctx context.Context, h AsyncHandler, req AsyncRequest) {
+	defer r.wg.Done()
+	result, ok := r.execute(ctx, h, req)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ok {
+		// A result that can't be written back leaves the request pending, to be run again
+		_ = r.finish(result)
+	}
+	delete(r.running, req.ID)
+}
+
+// execute runs the handler (bounded by Concurrency). Returns false if ctx was cancelled.
+func (r *AsyncRunner[T, A, ID]) execute(ctx context.Context, h AsyncHandler, req AsyncRequest) (AsyncRequest, bool) {
+	select {
+	case r.sem <- struct{}{}:
+		defer func() { <-r.sem }()
+	case <-ctx.Done():
+		return req, false
+	}
+
+	response, err := r.attempt(ctx, h, req)
+	if ctx.Err() != nil {
+		return req, false // Stopped: leave the request pending
+	}
+
+	result := req
+	if err != nil {
+		result.Fail(err)
+	} else if cerr := result.Complete(response); cerr != nil {
+		result.Fail(fmt.Errorf("marshal response: %w", cerr))
+	}
+	return result, true
+}
+
+// finish applies a finished request through the injector of its type, which records it
+// as an external input, without ticking (caller must hold r.mu)
+func (r *AsyncRunner[T, A, ID]) finish(result AsyncRequest) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("marshal result: %w", err)
+	}
+	return r.injector(result.Type).applyRaw(data, r.applyResult)
+}
+
+// playback finishes pending requests with their recorded results (caller must hold r.mu).
+// Returns the number of finished requests.
+func (r *AsyncRunner[T, A, ID]) playback(pending []AsyncRequest) (int, error) {
+	finished := 0
+	seq := r.session.Seq()
+	for _, req := range pending {
+		data, ok := r.cfg.Playback.take("async:"+req.Type, seq, func(payload json.RawMessage) bool {
+			var recorded struct {
+				ID string `json:"id"`
+			}
+			return json.Unmarshal(payload, &recorded) == nil && recorded.ID == req.ID
+		})
+		if !ok {
+			continue
+		}
+		if err := r.injector(req.Type).applyRaw(data, r.applyResult); err != nil {
+			return finished, fmt.Errorf("async request %s: %w", req.ID, err)
+		}
+		finished++
+	}
+	return finished, nil
+}
+
+// injector returns the injector that applies and records the results of a request type
+func (r *AsyncRunner[T, A, ID]) injector(reqType string) *ExternalInjector[T, A, ID] {
+	return NewExternalInjector(r.session, "async:"+reqType).WithRecorder(r.cfg.Recorder)
+}
+
+// applyResult decodes a serialized result and writes it into the state with Apply
+func (r *AsyncRunner[T, A, ID]) applyResult(state *T, payload json.RawMessage) error {
+	var result AsyncRequest
+	if err := json.Unmarshal(payload, &result); err != nil {
+		return fmt.Errorf("unmarshal result: %w", err)
+	}
+	r.cfg.Apply(state, result)
+	return nil
+}
+
+// attempt calls the handler up to MaxAttempts times with exponential backoff
+func (r *AsyncRunner[T, A, ID]) attempt(ctx context.Context, h AsyncHandler, req AsyncRequest) (any, error) {
+	backoff := r.cfg.Backoff
+	var err error
+	for i := 0; i < r.cfg.MaxAttempts; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if backoff *= 2; backoff > r.cfg.MaxBackoff {
+				backoff = r.cfg.MaxBackoff
+			}
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+		var response any
+		response, err = h(attemptCtx, req)
+		cancel()
+		if err == nil {
+			return response, nil
+		}
+		var perm *errPermanent
+		if errors.As(err, &perm) || ctx.Err() != nil {
+			return nil, err
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,363 @@
+package statesync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type asyncTestState struct {
+	changes  *ChangeSet
+	Finished int64
+	Requests []AsyncRequest // Not synced
+}
+
+func (s *asyncTestState) Changes() *ChangeSet { return s.changes }
+func (s *asyncTestState) ClearChanges()       { s.changes.Clear() }
+func (s *asyncTestState) MarkAllDirty()       { s.changes.MarkAll(0) }
+func (s *asyncTestState) Schema() *Schema {
+	return &Schema{
+		ID:     101,
+		Name:   "AsyncTestState",
+		Fields: []FieldMeta{{Index: 0, Name: "Finished", Type: TypeInt64}},
+	}
+}
+
+func (s *asyncTestState) GetFieldValue(index uint8) interface{} {
+	if index == 0 {
+		return s.Finished
+	}
+	return nil
+}
+
+func newAsyncTestRunner(t *testing.T, cfg AsyncRunnerConfig[*asyncTestState], reqs ...string) (*TrackedSession[*asyncTestState, any, string], *AsyncRunner[*asyncTestState, any, string]) {
+	t.Helper()
+	state := &asyncTestState{changes: NewChangeSet()}
+	for _, id := range reqs {
+		req, err := NewAsyncRequest(id, "echo", id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state.Requests = append(state.Requests, *req)
+	}
+	session := NewTrackedSession[*asyncTestState, any, string](NewTrackedState[*asyncTestState, any](state, nil))
+
+	cfg.Requests = func(s *asyncTestState) []AsyncRequest { return s.Requests }
+	cfg.Apply = func(s **asyncTestState, req AsyncRequest) {
+		for i := range (*s).Requests {
+			if (*s).Requests[i].ID == req.ID {
+				(*s).Requests[i] = req
+			}
+		}
+		(*s).Finished++
+		(*s).changes.Mark(0, OpReplace)
+	}
+	return session, NewAsyncRunner(session, cfg)
+}
+
+func asyncRequestByID(session *TrackedSession[*asyncTestState, any, string], id string) AsyncRequest {
+	var result AsyncRequest
+	session.State().ReadBase(func(s *asyncTestState) {
+		for _, req := range s.Requests {
+			if req.ID == id {
+				result = req
+			}
+		}
+	})
+	return result
+}
+
+func TestAsyncRunner_CompletesAndInjects(t *testing.T) {
+	recorder := NewDiffRecorder()
+	session, runner := newAsyncTestRunner(t, AsyncRunnerConfig[*asyncTestState]{Recorder: recorder}, "a", "b")
+	session.SetHooks(RecordingHooks[*asyncTestState, string](recorder))
+	session.Connect("alice", nil)
+	session.Tick() // Full state
+
+	runner.Handle("echo", func(ctx context.Context, req AsyncRequest) (any, error) {
+		var s string
+		if err := req.GetRequest(&s); err != nil {
+			return nil, err
+		}
+		return s + "!", nil
+	})
+
+	if n, _ := runner.Poll(context.Background()); n != 2 {
+		t.Fatalf("expected 2 started, got %d", n)
+	}
+	runner.Wait()
+
+	for _, id := range []string{"a", "b"} {
+		req := asyncRequestByID(session, id)
+		if req.Status != AsyncCompleted {
+			t.Fatalf("%s: expected completed, got %s", id, req.Status)
+		}
+		var resp string
+		req.GetResponse(&resp)
+		if resp != id+"!" {
+			t.Errorf("%s: unexpected response %q", id, resp)
+		}
+	}
+	// The runner doesn't tick: the owner's next Tick broadcasts and records the results
+	if n := len(recorder.Records()); n != 0 {
+		t.Fatalf("expected no record before the owner's Tick, got %d", n)
+	}
+	diff := session.Tick()["alice"]
+	registry := NewSchemaRegistry()
+	registry.Register((&asyncTestState{}).Schema())
+	patch, err := NewDecoder(registry).Decode(diff)
+	if err != nil || len(patch.Changes) != 1 || patch.Changes[0].Value != int64(2) {
+		t.Fatalf("expected alice to receive Finished=2, got %+v, %v", patch, err)
+	}
+	records := recorder.Records()
+	if len(records) != 1 {
+		t.Fatalf("expected 1 recorded tick, got %d", len(records))
+	}
+	if in := records[0].Inputs; len(in) != 2 || in[0].Source != "async:echo" {
+		t.Errorf("expected 2 async inputs on the tick's record, got %+v", in)
+	}
+
+	// Finished requests are not picked up again
+	if n, _ := runner.Poll(context.Background()); n != 0 {
+		t.Errorf("expected nothing to start, got %d", n)
+	}
+}
+
+func TestAsyncRunner_RetriesAndFails(t *testing.T) {
+	session, runner := newAsyncTestRunner(t, AsyncRunnerConfig[*asyncTestState]{
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+	}, "retry", "fail")
+
+	var attempts atomic.Int32
+	runner.Handle("echo", func(ctx context.Context, req AsyncRequest) (any, error) {
+		if req.ID == "fail" {
+			return nil, errors.New("boom")
+		}
+		if attempts.Add(1) < 3 {
+			return nil, errors.New("flaky")
+		}
+		return "ok", nil
+	})
+	runner.Poll(context.Background())
+	runner.Wait()
+
+	if req := asyncRequestByID(session, "retry"); req.Status != AsyncCompleted {
+		t.Errorf("expected retry to complete, got %s (%s)", req.Status, req.Error)
+	}
+	if req := asyncRequestByID(session, "fail"); req.Status != AsyncFailed || req.Error != "boom" {
+		t.Errorf("expected fail to fail with boom, got %s (%s)", req.Status, req.Error)
+	}
+}
+
+func TestAsyncRunner_PermanentErrorNotRetried(t *testing.T) {
+	session, runner := newAsyncTestRunner(t, AsyncRunnerConfig[*asyncTestState]{Backoff: time.Millisecond}, "a")
+
+	var attempts atomic.Int32
+	runner.Handle("echo", func(ctx context.Context, req AsyncRequest) (any, error) {
+		attempts.Add(1)
+		return nil, Permanent(errors.New("bad request"))
+	})
+	runner.Poll(context.Background())
+	runner.Wait()
+
+	if attempts.Load() != 1 {
+		t.Errorf("expected 1 attempt, got %d", attempts.Load())
+	}
+	if req := asyncRequestByID(session, "a"); req.Status != AsyncFailed || req.Error != "bad request" {
+		t.Errorf("unexpected request %s (%s)", req.Status, req.Error)
+	}
+}
+
+func TestAsyncRunner_Timeout(t *testing.T) {
+	session, runner := newAsyncTestRunner(t, AsyncRunnerConfig[*asyncTestState]{
+		Timeout:     10 * time.Millisecond,
+		MaxAttempts: 1,
+	}, "slow")
+
+	runner.Handle("echo", func(ctx context.Context, req AsyncRequest) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	runner.Poll(context.Background())
+	runner.Wait()
+
+	if req := asyncRequestByID(session, "slow"); req.Status != AsyncFailed || req.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("unexpected request %s (%s)", req.Status, req.Error)
+	}
+}
+
+func TestAsyncRunner_Concurrency(t *testing.T) {
+	_, runner := newAsyncTestRunner(t, AsyncRunnerConfig[*asyncTestState]{Concurrency: 2}, "a", "b", "c", "d", "e")
+
+	var current, peak atomic.Int32
+	runner.Handle("echo", func(ctx context.Context, req AsyncRequest) (any, error) {
+		n := current.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		current.Add(-1)
+		return nil, nil
+	})
+	runner.Poll(context.Background())
+	runner.Wait()
+
+	if peak.Load() > 2 {
+		t.Errorf("expected at most 2 concurrent requests, got %d", peak.Load())
+	}
+}
+
+func TestAsyncRunner_UnknownTypeStaysPending(t *testing.T) {
+	session, runner := newAsyncTestRunner(t, AsyncRunnerConfig[*asyncTestState]{}, "a")
+
+	if n, _ := runner.Poll(context.Background()); n != 0 {
+		t.Fatalf("expected nothing to start, got %d", n)
+	}
+	if req := asyncRequestByID(session, "a"); req.Status != AsyncPending {
+		t.Errorf("expected pending, got %s", req.Status)
+	}
+}
+
+func TestAsyncRunner_RunStopLeavesPending(t *testing.T) {
+	session, runner := newAsyncTestRunner(t, AsyncRunnerConfig[*asyncTestState]{PollInterval: time.Millisecond}, "a")
+
+	started := make(chan struct{})
+	runner.Handle("echo", func(ctx context.Context, req AsyncRequest) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(done)
+	}()
+	<-started
+	cancel()
+	<-done
+
+	if req := asyncRequestByID(session, "a"); req.Status != AsyncPending {
+		t.Errorf("expected cancelled request to stay pending, got %s", req.Status)
+	}
+}
+
+func TestAsyncRunner_ConcurrentPollRunsOnce(t *testing.T) {
+	ids := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	session, runner := newAsyncTestRunner(t, AsyncRunnerConfig[*asyncTestState]{Concurrency: 8}, ids...)
+
+	var calls atomic.Int32
+	runner.Handle("echo", func(ctx context.Context, req AsyncRequest) (any, error) {
+		calls.Add(1)
+		return nil, nil
+	})
+
+	// Polls racing with finishing requests must never start a request twice
+	for i := 0; i < 200; i++ {
+		runner.Poll(context.Background())
+	}
+	runner.Wait()
+	runner.Poll(context.Background())
+	runner.Wait()
+
+	if n := calls.Load(); n != int32(len(ids)) {
+		t.Errorf("expected %d handler calls, got %d", len(ids), n)
+	}
+	session.State().ReadBase(func(s *asyncTestState) {
+		if s.Finished != int64(len(ids)) {
+			t.Errorf("expected %d finished, got %d", len(ids), s.Finished)
+		}
+	})
+}
+
+func TestAsyncRunner_Playback(t *testing.T) {
+	recorder := NewDiffRecorder()
+	session, runner := newAsyncTestRunner(t, AsyncRunnerConfig[*asyncTestState]{Recorder: recorder}, "a", "b")
+	session.SetHooks(RecordingHooks[*asyncTestState, string](recorder))
+	runner.Handle("echo", func(ctx context.Context, req AsyncRequest) (any, error) {
+		if req.ID == "b" {
+			return nil, Permanent(errors.New("rejected"))
+		}
+		return "live " + req.ID, nil
+	})
+	runner.Poll(context.Background())
+	runner.Wait()
+	session.Tick()
+
+	// Re-simulation feeds the recorded results instead of calling the handler
+	resim, replay := newAsyncTestRunner(t, AsyncRunnerConfig[*asyncTestState]{
+		Playback: NewInputPlayback(recorder.Records()),
+	}, "b", "a", "c")
+	replay.Handle("echo", func(ctx context.Context, req AsyncRequest) (any, error) {
+		t.Errorf("handler called in playback for %s", req.ID)
+		return nil, nil
+	})
+	if n, err := replay.Poll(context.Background()); err != nil || n != 2 {
+		t.Fatalf("expected 2 finished from the recording, got %d (%v)", n, err)
+	}
+
+	var resp string
+	if req := asyncRequestByID(resim, "a"); req.Status != AsyncCompleted || req.GetResponse(&resp) != nil || resp != "live a" {
+		t.Errorf("expected a to complete with the recorded response, got %s %q", req.Status, resp)
+	}
+	if req := asyncRequestByID(resim, "b"); req.Status != AsyncFailed || req.Error != "rejected" {
+		t.Errorf("expected b to fail as recorded, got %s (%s)", req.Status, req.Error)
+	}
+	if req := asyncRequestByID(resim, "c"); req.Status != AsyncPending {
+		t.Errorf("expected c without a recorded result to stay pending, got %s", req.Status)
+	}
+}
+
+func TestAsyncRunner_PlaybackStopsOnBadResult(t *testing.T) {
+	records := []DiffRecord{{Seq: 1, Inputs: []ExternalInput{
+		{Source: "async:echo", Payload: json.RawMessage(`{"id":"a","status":5}`)},
+		{Source: "async:echo", Payload: json.RawMessage(`{"id":"b","status":"completed"}`)},
+	}}}
+	session, replay := newAsyncTestRunner(t, AsyncRunnerConfig[*asyncTestState]{
+		Playback: NewInputPlayback(records),
+	}, "a", "b")
+
+	n, err := replay.Poll(context.Background())
+	if err == nil || n != 0 {
+		t.Fatalf("expected playback to stop at the bad result, got %d finished, %v", n, err)
+	}
+	if req := asyncRequestByID(session, "a"); req.Status != AsyncPending {
+		t.Errorf("expected a to stay pending, got %s", req.Status)
+	}
+	if req := asyncRequestByID(session, "b"); req.Status != AsyncPending {
+		t.Errorf("expected b not to be applied after the error, got %s", req.Status)
+	}
+
+	// The bad result was consumed, the next Poll continues with b
+	if n, err := replay.Poll(context.Background()); err != nil || n != 1 {
+		t.Errorf("expected b to finish on the next Poll, got %d, %v", n, err)
+	}
+}
+
+func TestAsyncRunner_PlaybackWaitsForRecordedSeq(t *testing.T) {
+	records := []DiffRecord{
+		{Seq: 1, Inputs: []ExternalInput{{Source: "async:echo", Payload: json.RawMessage(`{"id":"a","status":"completed"}`)}}},
+		{Seq: 3, Inputs: []ExternalInput{{Source: "async:echo", Payload: json.RawMessage(`{"id":"b","status":"completed"}`)}}},
+	}
+	session, replay := newAsyncTestRunner(t, AsyncRunnerConfig[*asyncTestState]{
+		Playback: NewInputPlayback(records),
+	}, "a", "b")
+
+	for _, want := range []int{1, 0, 1} {
+		if n, err := replay.Poll(context.Background()); err != nil || n != want {
+			t.Fatalf("seq %d: expected %d finished, got %d (%v)", session.Seq(), want, n, err)
+		}
+		session.Tick()
+	}
+	if req := asyncRequestByID(session, "b"); req.Status != AsyncCompleted {
+		t.Errorf("expected b to complete at its recorded seq, got %s", req.Status)
+	}
+}
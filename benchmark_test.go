@@ -1,6 +1,7 @@
 package statesync
 
 import (
+	"strconv"
 	"sync"
 	"testing"
 )
@@ -285,27 +286,37 @@ func BenchmarkChangeSetCloneForFilter(b *testing.B) {
 }
 
 func BenchmarkBroadcast_10Players_NoFilter(b *testing.B) {
-	benchBroadcast(b, 10, false)
+	benchBroadcast(b, 10, false, false)
 }
 
 func BenchmarkBroadcast_50Players_NoFilter(b *testing.B) {
-	benchBroadcast(b, 50, false)
+	benchBroadcast(b, 50, false, false)
 }
 
+// Filtered clients get only their dirty fields encoded unless visibility diffs are enabled
 func BenchmarkBroadcast_10Players_WithFilter(b *testing.B) {
-	benchBroadcast(b, 10, true)
+	benchBroadcast(b, 10, true, false)
 }
 
 func BenchmarkBroadcast_50Players_WithFilter(b *testing.B) {
-	benchBroadcast(b, 50, true)
+	benchBroadcast(b, 50, true, false)
 }
 
-func benchBroadcast(b *testing.B, nClients int, withFilter bool) {
+func BenchmarkBroadcast_10Players_VisibilityDiffs(b *testing.B) {
+	benchBroadcast(b, 10, true, true)
+}
+
+func BenchmarkBroadcast_50Players_VisibilityDiffs(b *testing.B) {
+	benchBroadcast(b, 50, true, true)
+}
+
+func benchBroadcast(b *testing.B, nClients int, withFilter, visibilityDiffs bool) {
 	s := NewBenchState()
 	populateBenchState(s, 10, 5)
 
 	ts := NewTrackedState[*BenchState, any](s, nil)
 	session := NewTrackedSession[*BenchState, any, string](ts)
+	session.SetVisibilityDiffs(visibilityDiffs)
 
 	var filter FilterFunc[*BenchState]
 	if withFilter {
@@ -351,3 +362,42 @@ func BenchmarkEncoderPool_Parallel(b *testing.B) {
 		}
 	})
 }
+
+// UpdateAs runs a client's update on a clone of a state with 50 units and 50 bank entries
+func BenchmarkTrackedState_UpdateAs(b *testing.B) {
+	benchUpdateAs(b, "u0", func(s *aclState) {
+		s.Units[0].Name = "renamed"
+		s.Units[0].changes.Mark(1, OpReplace)
+	})
+}
+
+func BenchmarkTrackedState_UpdateAsDenied(b *testing.B) {
+	benchUpdateAs(b, "u0", func(s *aclState) {
+		s.Round++
+		s.changes.Mark(0, OpReplace)
+	})
+}
+
+func BenchmarkTrackedState_UpdateAsServer(b *testing.B) {
+	benchUpdateAs(b, "", func(s *aclState) {
+		s.Round++
+		s.changes.Mark(0, OpReplace)
+	})
+}
+
+func benchUpdateAs(b *testing.B, clientID string, fn func(s *aclState)) {
+	state := &aclState{changes: NewChangeSet(), Bank: make(map[string]*aclUnit)}
+	for i := 0; i < 50; i++ {
+		id := "u" + strconv.Itoa(i)
+		state.Units = append(state.Units, &aclUnit{changes: NewChangeSet(), ID: id, Name: id})
+		state.Bank[id] = &aclUnit{changes: NewChangeSet(), ID: id, Gold: int64(i)}
+	}
+	ts := NewTrackedState[*aclState, any](state, nil)
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ts.UpdateAs(clientID, func(s **aclState) { fn(*s) })
+		ts.Commit()
+	}
+}
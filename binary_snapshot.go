@@ -0,0 +1,417 @@
+package statesync
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Binary snapshot protocol constants
+const (
+	// MsgSnapshot is a binary snapshot: a header followed by a MsgFullState message.
+	// Format: [MsgSnapshot][version:uint8][schemaID:uint16][fingerprint:uint64][savedAt:int64 unix nanos]
+	//         [effectsLen:varint][effects:JSON][extraLen:varint][extra:JSON]
+	//         [schemaVersion:varint][schemaLen:varint][schema:MsgSchema] (version 2)
+	//         [MsgFullState message]
+	MsgSnapshot uint8 = 0x05
+
+	// BinarySnapshotVersion is the current binary snapshot header version.
+	// Version 2 stores the migration version and a descriptor of the saved schema.
+	BinarySnapshotVersion uint8 = 2
+)
+
+// Binary snapshot errors
+var (
+	ErrInvalidSnapshot = errors.New("invalid snapshot")
+	ErrSchemaMismatch  = errors.New("snapshot schema fingerprint mismatch")
+)
+
+// SnapshotHeader is the metadata stored in front of a binary snapshot's state
+type SnapshotHeader struct {
+	Version     uint8
+	SchemaID    uint16
+	Fingerprint uint64
+	SavedAt     time.Time
+	Effects     []EffectMeta
+	Extra       json.RawMessage
+
+	SchemaVersion int    // Migration version of the state (0 before version 2)
+	Schema        []byte // MsgSchema descriptor of the saved schema (nil before version 2)
+}
+
+// DecodedSnapshot is a decoded binary snapshot
+type DecodedSnapshot struct {
+	Header SnapshotHeader
+	State  *DecodedPatch // Full state (all fields as OpReplace)
+}
+
+// Fingerprint returns a hash of the schema layout (field names, types and nested schemas).
+// Binary snapshots store it to detect schema changes.
+func (s *Schema) Fingerprint() uint64 {
+	h := fnv.New64a()
+	s.writeFingerprint(h)
+	return h.Sum64()
+}
+
+// writeFingerprint writes the schema layout to a hash
+func (s *Schema) writeFingerprint(h interface{ Write([]byte) (int, error) }) {
+	for i := range s.Fields {
+		f := &s.Fields[i]
+		h.Write([]byte(f.Name))
+		h.Write([]byte{0, byte(f.Type), byte(f.ElemType)})
+		h.Write([]byte(f.KeyField))
+		h.Write([]byte{0})
+		if f.ChildSchema != nil {
+			h.Write([]byte{'{'})
+			f.ChildSchema.writeFingerprint(h)
+			h.Write([]byte{'}'})
+		}
+	}
+}
+
+// EncodeBinarySnapshot encodes the base state with the schema encoder (EncodeAll),
+// preceded by a header with schema ID, fingerprint, effects, extra data, the
+// migration version (see TrackedConfig.Migrations) and a descriptor of the schema.
+func EncodeBinarySnapshot[T Trackable, A any](state *TrackedState[T, A], effects []EffectMeta, extra any) ([]byte, error) {
+	var effectsJSON, extraJSON []byte
+	var err error
+	if len(effects) > 0 {
+		if effectsJSON, err = json.Marshal(effects); err != nil {
+			return nil, fmt.Errorf("marshal effects: %w", err)
+		}
+	}
+	if extra != nil {
+		if extraJSON, err = json.Marshal(extra); err != nil {
+			return nil, fmt.Errorf("marshal extra: %w", err)
+		}
+	}
+
+	var schema *Schema
+	var full []byte
+	state.ReadBase(func(base T) {
+		schema = base.Schema()
+		full = state.poolEncodeAll(base)
+	})
+
+	descriptor := DescribeSchemas(schema).Encode()
+
+	buf := make([]byte, 0, 1+1+2+8+8+len(effectsJSON)+len(extraJSON)+len(descriptor)+30+len(full))
+	buf = append(buf, MsgSnapshot, BinarySnapshotVersion)
+	buf = binary.LittleEndian.AppendUint16(buf, schema.ID)
+	buf = binary.LittleEndian.AppendUint64(buf, schema.Fingerprint())
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(time.Now().UnixNano()))
+	buf = binary.AppendUvarint(buf, uint64(len(effectsJSON)))
+	buf = append(buf, effectsJSON...)
+	buf = binary.AppendUvarint(buf, uint64(len(extraJSON)))
+	buf = append(buf, extraJSON...)
+	buf = binary.AppendUvarint(buf, uint64(state.migrations.CurrentVersion(schema.Name)))
+	buf = binary.AppendUvarint(buf, uint64(len(descriptor)))
+	buf = append(buf, descriptor...)
+	buf = append(buf, full...)
+	return buf, nil
+}
+
+// DecodeSnapshot decodes a binary snapshot (header and full state)
+func (d *Decoder) DecodeSnapshot(data []byte) (*DecodedSnapshot, error) {
+	header, state, err := splitBinarySnapshot(data)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := d.Decode(state)
+	if err != nil {
+		return nil, err
+	}
+	if patch.SchemaID != header.SchemaID {
+		return nil, fmt.Errorf("%w: header schema %d, state schema %d", ErrInvalidSnapshot, header.SchemaID, patch.SchemaID)
+	}
+	if schema := d.registry.Get(header.SchemaID); schema != nil && schema.Fingerprint() != header.Fingerprint {
+		return nil, fmt.Errorf("%w: schema %q", ErrSchemaMismatch, schema.Name)
+	}
+	return &DecodedSnapshot{Header: header, State: patch}, nil
+}
+
+// splitBinarySnapshot parses the header and returns the embedded MsgFullState message
+func splitBinarySnapshot(data []byte) (SnapshotHeader, []byte, error) {
+	var h SnapshotHeader
+	if len(data) < 20 || data[0] != MsgSnapshot {
+		return h, nil, ErrInvalidSnapshot
+	}
+	h.Version = data[1]
+	if h.Version > BinarySnapshotVersion {
+		return h, nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, h.Version)
+	}
+	h.SchemaID = binary.LittleEndian.Uint16(data[2:])
+	h.Fingerprint = binary.LittleEndian.Uint64(data[4:])
+	h.SavedAt = time.Unix(0, int64(binary.LittleEndian.Uint64(data[12:])))
+	pos := 20
+
+	section := func() ([]byte, error) {
+		n, m := binary.Uvarint(data[pos:])
+		if m <= 0 || n > uint64(len(data)-pos-m) {
+			return nil, ErrInvalidSnapshot
+		}
+		pos += m
+		b := data[pos : pos+int(n)]
+		pos += int(n)
+		return b, nil
+	}
+
+	effects, err := section()
+	if err != nil {
+		return h, nil, err
+	}
+	if len(effects) > 0 {
+		if err := json.Unmarshal(effects, &h.Effects); err != nil {
+			return h, nil, fmt.Errorf("%w: effects: %v", ErrInvalidSnapshot, err)
+		}
+	}
+	extra, err := section()
+	if err != nil {
+		return h, nil, err
+	}
+	if len(extra) > 0 {
+		h.Extra = append(json.RawMessage(nil), extra...)
+	}
+	if h.Version >= 2 {
+		v, m := binary.Uvarint(data[pos:])
+		if m <= 0 || v > math.MaxInt32 {
+			return h, nil, ErrInvalidSnapshot
+		}
+		pos += m
+		h.SchemaVersion = int(v)
+		if h.Schema, err = section(); err != nil {
+			return h, nil, err
+		}
+	}
+	return h, data[pos:], nil
+}
+
+// decodeBinarySnapshot decodes a binary snapshot into a Snapshot of T (see StateFromFields).
+// Snapshots of another schema are decoded with the schema stored in them. If migrate is
+// set, older snapshots are upgraded with migrations (see migrateFields); otherwise the
+// fields are matched to T by name.
+func decodeBinarySnapshot[T any](data []byte, migrations *MigrationRegistry, migrate bool) (*Snapshot[T], error) {
+	schema := schemaOf[T]()
+	if schema == nil {
+		return nil, fmt.Errorf("%w: cannot determine schema of %T", ErrInvalidSnapshot, *new(T))
+	}
+	header, full, err := splitBinarySnapshot(data)
+	if err != nil {
+		return nil, err
+	}
+
+	saved := schema
+	if header.Fingerprint != schema.Fingerprint() {
+		if saved, err = header.savedSchema(); err != nil {
+			return nil, fmt.Errorf("%w: schema %q: %v", ErrSchemaMismatch, schema.Name, err)
+		}
+	}
+	registry := NewSchemaRegistry()
+	registry.Register(saved)
+	patch, err := NewDecoder(registry).Decode(full)
+	if err != nil {
+		return nil, err
+	}
+	if patch.SchemaID != header.SchemaID {
+		return nil, fmt.Errorf("%w: header schema %d, state schema %d", ErrInvalidSnapshot, header.SchemaID, patch.SchemaID)
+	}
+	fields := make(map[string]interface{}, len(patch.Changes))
+	if err := ApplyPatch(fields, patch, saved); err != nil {
+		return nil, err
+	}
+
+	snap := &Snapshot[T]{
+		Version: max(header.SchemaVersion, SnapshotVersion),
+		Effects: header.Effects,
+		SavedAt: header.SavedAt,
+		Extra:   header.Extra,
+	}
+	current := migrations.CurrentVersion(schema.Name)
+	if !migrate || (saved == schema && snap.Version == current) {
+		snap.State, err = StateFromFields[T](fields)
+		return snap, err
+	}
+	if saved != schema && snap.Version == current {
+		return nil, fmt.Errorf("%w: %s v%d was saved with a different schema", ErrMigrationMissing, schema.Name, snap.Version)
+	}
+	if snap.Version, err = migrateFields(&snap.State, schema.Name, snap.Version, saved, fields, migrations); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// savedSchema builds the schema stored in a version 2 header
+func (h *SnapshotHeader) savedSchema() (*Schema, error) {
+	if len(h.Schema) == 0 {
+		return nil, errors.New("snapshot has no schema descriptor")
+	}
+	desc, err := DecodeSchemaDescriptor(h.Schema)
+	if err != nil {
+		return nil, err
+	}
+	schemas, err := desc.Build()
+	if err != nil {
+		return nil, err
+	}
+	if len(schemas) == 0 || schemas[0].ID != h.SchemaID || schemas[0].Fingerprint() != h.Fingerprint {
+		return nil, errors.New("schema descriptor does not match the header")
+	}
+	return schemas[0], nil
+}
+
+// migrateFields converts decoded fields of the saved schema to the JSON object of
+// a JSON snapshot (see jsonSnapshotObject), runs the migrations from version and
+// unmarshals the result into state. Returns the migrated version.
+func migrateFields[T any](state *T, name string, version int, saved *Schema, fields map[string]interface{}, migrations *MigrationRegistry) (int, error) {
+	raw, err := json.Marshal(jsonSnapshotObject(fields, saved))
+	if err != nil {
+		return version, fmt.Errorf("marshal state: %w", err)
+	}
+	var obj map[string]any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber() // Like migrateSnapshot
+	if err := dec.Decode(&obj); err != nil {
+		return version, fmt.Errorf("unmarshal state: %w", err)
+	}
+	if version, err = migrations.Migrate(name, version, obj); err != nil {
+		return version, err
+	}
+	if raw, err = json.Marshal(obj); err != nil {
+		return version, fmt.Errorf("marshal state: %w", err)
+	}
+	if err := json.Unmarshal(raw, state); err != nil {
+		return version, fmt.Errorf("unmarshal state: %w", err)
+	}
+	return version, nil
+}
+
+// jsonSnapshotObject renames decoded fields (see ApplyPatch) to the JSON field names of
+// generated types, recursively, so migrations see the same keys as in JSON snapshots.
+// Bytes fields holding JSON are kept as raw JSON.
+func jsonSnapshotObject(fields map[string]interface{}, schema *Schema) map[string]interface{} {
+	obj := make(map[string]interface{}, len(fields))
+	for i := range schema.Fields {
+		f := &schema.Fields[i]
+		if v, ok := fields[f.Name]; ok {
+			obj[jsonFieldName(f.Name)] = jsonSnapshotValue(v, f)
+		}
+	}
+	return obj
+}
+
+// jsonSnapshotValue converts a decoded field value (see jsonSnapshotObject)
+func jsonSnapshotValue(v interface{}, f *FieldMeta) interface{} {
+	switch c := v.(type) {
+	case []interface{}:
+		if f.Type == TypeArray {
+			elems := make([]interface{}, len(c))
+			for i, elem := range c {
+				elems[i] = jsonSnapshotElem(elem, f.ElemType, f.ChildSchema)
+			}
+			return elems
+		}
+	case map[string]interface{}:
+		if f.Type == TypeMap {
+			elems := make(map[string]interface{}, len(c))
+			for key, elem := range c {
+				elems[key] = jsonSnapshotElem(elem, f.ElemType, f.ChildSchema)
+			}
+			return elems
+		}
+	}
+	return jsonSnapshotElem(v, f.Type, f.ChildSchema)
+}
+
+// jsonSnapshotElem converts a struct or bytes value (other values are returned unchanged)
+func jsonSnapshotElem(v interface{}, typ FieldType, child *Schema) interface{} {
+	switch typ {
+	case TypeStruct:
+		if m, ok := v.(map[string]interface{}); ok && child != nil {
+			return jsonSnapshotObject(m, child)
+		}
+	case TypeBytes:
+		if b, ok := v.([]byte); ok && json.Valid(b) {
+			return json.RawMessage(b)
+		}
+	}
+	return v
+}
+
+// jsonFieldName returns the JSON name schemagen gives a field:
+// the first letter is lowercased and a trailing "ID" becomes "Id"
+func jsonFieldName(name string) string {
+	if len(name) <= 1 {
+		return strings.ToLower(name)
+	}
+	if strings.HasSuffix(name, "ID") {
+		if len(name) == 2 {
+			return "id"
+		}
+		name = name[:len(name)-2] + "Id"
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// StateFromFields builds a T from decoded fields (see ApplyPatch) through its generated
+// SetFieldValue (see FieldSetter). T must be a pointer to a FieldSetter; generated
+// types are reset to their defaults first, so fields missing from the map keep them.
+func StateFromFields[T any](fields map[string]interface{}) (T, error) {
+	state, ok := newState[T]()
+	if !ok {
+		return state, fmt.Errorf("%w: %T is not a pointer type", ErrInvalidSnapshot, state)
+	}
+	setter, ok := any(state).(FieldSetter)
+	if !ok {
+		return state, fmt.Errorf("%w: %T does not implement FieldSetter", ErrInvalidSnapshot, state)
+	}
+	tr, ok := any(state).(Trackable)
+	if !ok || tr.Schema() == nil {
+		return state, fmt.Errorf("%w: cannot determine schema of %T", ErrInvalidSnapshot, state)
+	}
+	if err := SetFields(setter, tr.Schema(), fields); err != nil {
+		return state, err
+	}
+	return state, nil
+}
+
+// newState allocates a T if it is a pointer type, reset to its defaults if it is a
+// generated type. ok is false if T is not a pointer type.
+func newState[T any]() (state T, ok bool) {
+	t := reflect.TypeOf(state)
+	if t == nil || t.Kind() != reflect.Pointer {
+		return state, false
+	}
+	state = reflect.New(t.Elem()).Interface().(T)
+	if r, isGenerated := any(state).(defaultsResetter); isGenerated {
+		r.ResetToDefaults()
+	}
+	return state, true
+}
+
+// schemaOf returns the schema of T (nil if T is not Trackable), allocating a value if T is a pointer type
+func schemaOf[T any]() *Schema {
+	var v any = *new(T)
+	if state, ok := newState[T](); ok {
+		v = state
+	}
+	if tr, ok := v.(Trackable); ok {
+		return tr.Schema()
+	}
+	return nil
+}
+
+// SaveBinarySnapshot encodes state and effects as a binary snapshot and stores it under key
+func SaveBinarySnapshot[T Trackable, A any](store SnapshotStore, key string, state *TrackedState[T, A], effects []EffectMeta, extra any) error {
+	data, err := EncodeBinarySnapshot(state, effects, extra)
+	if err != nil {
+		return err
+	}
+	return store.Put(key, data)
+}
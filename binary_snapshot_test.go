@@ -0,0 +1,233 @@
+package statesync
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestBinarySnapshot_RoundTrip(t *testing.T) {
+	reg := newPoisonRegistry(t)
+	ts := NewTrackedState[*simpleTestState, string](&simpleTestState{changes: NewChangeSet(), Score: math.MaxInt64 - 1, Name: "big"}, nil)
+	ts.AddEffect(&poisonEffect{id: "p1", damage: 5}, "alice")
+
+	metas, err := reg.Metas(ts)
+	if err != nil {
+		t.Fatalf("Metas: %v", err)
+	}
+	data, err := EncodeBinarySnapshot(ts, metas, map[string]int{"round": 3})
+	if err != nil {
+		t.Fatalf("EncodeBinarySnapshot: %v", err)
+	}
+	if data[0] != MsgSnapshot {
+		t.Fatalf("first byte = %#x, want MsgSnapshot", data[0])
+	}
+
+	store := NewMemoryStore()
+	store.Put("bin", data)
+	snap, err := LoadSnapshot[*simpleTestState](store, "bin")
+	if err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	// int64 values keep full precision (JSON float64 would round them)
+	if snap.State.Score != math.MaxInt64-1 || snap.State.Name != "big" {
+		t.Errorf("state = %d %q, want %d big", snap.State.Score, snap.State.Name, int64(math.MaxInt64-1))
+	}
+	if string(snap.Extra) != `{"round":3}` {
+		t.Errorf("Extra = %s", snap.Extra)
+	}
+
+	result := reg.RestoreSnapshot(snap, initSimpleTestState, nil)
+	if len(result.EffectErrors) != 0 {
+		t.Fatalf("EffectErrors: %v", result.EffectErrors)
+	}
+	if got := result.State.Get().Score; got != math.MaxInt64-6 {
+		t.Errorf("Score with effect = %d, want %d", got, int64(math.MaxInt64-6))
+	}
+	if a := result.State.GetEffect("p1").Activator(); a != "alice" {
+		t.Errorf("activator = %q, want alice", a)
+	}
+}
+
+func TestBinarySnapshot_Decoder(t *testing.T) {
+	ts := NewTrackedState[*simpleTestState, any](&simpleTestState{changes: NewChangeSet(), Score: 42, Name: "x"}, nil)
+	data, err := EncodeBinarySnapshot(ts, nil, nil)
+	if err != nil {
+		t.Fatalf("EncodeBinarySnapshot: %v", err)
+	}
+
+	schema := (&simpleTestState{}).Schema()
+	registry := NewSchemaRegistry()
+	registry.Register(schema)
+	dec := NewDecoder(registry)
+
+	snap, err := dec.DecodeSnapshot(data)
+	if err != nil {
+		t.Fatalf("DecodeSnapshot: %v", err)
+	}
+	if snap.Header.Version != BinarySnapshotVersion || snap.Header.SchemaID != 1 || snap.Header.Fingerprint != schema.Fingerprint() {
+		t.Errorf("unexpected header: %+v", snap.Header)
+	}
+	if snap.Header.SavedAt.IsZero() {
+		t.Error("SavedAt should be set")
+	}
+
+	// Decode returns the embedded full state, so replays can read snapshots directly
+	patch, err := dec.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	state := make(map[string]interface{})
+	if err := ApplyPatch(state, patch, schema); err != nil {
+		t.Fatalf("ApplyPatch: %v", err)
+	}
+	if state["Score"] != int64(42) || state["Name"] != "x" {
+		t.Errorf("decoded state = %v", state)
+	}
+}
+
+func TestBinarySnapshot_SchemaMismatch(t *testing.T) {
+	ts := NewTrackedState[*simpleTestState, any](&simpleTestState{changes: NewChangeSet()}, nil)
+	data, _ := EncodeBinarySnapshot(ts, nil, nil)
+
+	changed := (&simpleTestState{}).Schema()
+	changed.Fields[1].Name = "Title"
+	registry := NewSchemaRegistry()
+	registry.Register(changed)
+
+	if _, err := NewDecoder(registry).DecodeSnapshot(data); !errors.Is(err, ErrSchemaMismatch) {
+		t.Errorf("expected ErrSchemaMismatch, got %v", err)
+	}
+	if _, err := NewDecoder(registry).DecodeSnapshot(data[:10]); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Errorf("expected ErrInvalidSnapshot for truncated data, got %v", err)
+	}
+}
+
+func TestBinarySnapshot_AutoSnapshot(t *testing.T) {
+	session := NewTrackedSession[*simpleTestState, any, string](NewTrackedState[*simpleTestState, any](&simpleTestState{changes: NewChangeSet(), Score: 7}, nil))
+	store := NewDirStore(filepath.Join(t.TempDir(), "snaps"), 0)
+	if err := session.EnableAutoSnapshot(AutoSnapshotConfig[*simpleTestState, any]{Store: store, Binary: true}); err != nil {
+		t.Fatalf("EnableAutoSnapshot: %v", err)
+	}
+	key, err := session.SnapshotNow()
+	if err != nil {
+		t.Fatalf("SnapshotNow: %v", err)
+	}
+	data, _ := store.Get(key)
+	if len(data) == 0 || data[0] != MsgSnapshot {
+		t.Fatal("expected a binary snapshot")
+	}
+	snap, err := LoadSnapshot[*simpleTestState](store, key)
+	if err != nil || snap.State.Score != 7 {
+		t.Errorf("LoadSnapshot = %+v, %v", snap, err)
+	}
+}
+
+// floatTestState has float fields and a nested struct, for StateFromFields
+type floatTestState struct {
+	changes *ChangeSet
+	Speed   float64
+	Scale   float32
+	Team    *floatTestTeam
+	Ratios  []float64
+}
+
+type floatTestTeam struct {
+	changes *ChangeSet
+	Name    string
+	Power   int
+}
+
+func (s *floatTestState) Changes() *ChangeSet { return s.changes }
+func (s *floatTestState) ClearChanges()       { s.changes.Clear() }
+func (s *floatTestState) MarkAllDirty()       { s.changes.MarkAll(3) }
+func (s *floatTestState) Schema() *Schema {
+	return NewSchemaBuilder("FloatTestState").WithID(9).
+		Float64("Speed").Float32("Scale").Struct("Team", (&floatTestTeam{}).Schema()).
+		Array("Ratios", TypeFloat64, nil).Build()
+}
+func (s *floatTestState) GetFieldValue(index uint8) interface{} {
+	switch index {
+	case 0:
+		return s.Speed
+	case 1:
+		return s.Scale
+	case 2:
+		return s.Team
+	case 3:
+		return s.Ratios
+	}
+	return nil
+}
+func (s *floatTestState) SetFieldValue(index uint16, value interface{}) (err error) {
+	switch index {
+	case 0:
+		s.Speed, err = DecodedValue[float64](value)
+	case 1:
+		s.Scale, err = DecodedValue[float32](value)
+	case 2:
+		var team floatTestTeam
+		team, err = DecodedStruct[floatTestTeam](value)
+		s.Team = &team
+	case 3:
+		s.Ratios, err = DecodedSlice(value, DecodedValue[float64])
+	}
+	return err
+}
+
+func (t *floatTestTeam) Changes() *ChangeSet { return t.changes }
+func (t *floatTestTeam) ClearChanges()       { t.changes.Clear() }
+func (t *floatTestTeam) MarkAllDirty()       { t.changes.MarkAll(1) }
+func (t *floatTestTeam) Schema() *Schema {
+	return NewSchemaBuilder("FloatTestTeam").WithID(10).String("Name").Int64("Power").Build()
+}
+func (t *floatTestTeam) GetFieldValue(index uint8) interface{} {
+	switch index {
+	case 0:
+		return t.Name
+	case 1:
+		return int64(t.Power)
+	}
+	return nil
+}
+func (t *floatTestTeam) SetFieldValue(index uint16, value interface{}) (err error) {
+	switch index {
+	case 0:
+		t.Name, err = DecodedValue[string](value)
+	case 1:
+		t.Power, err = DecodedValue[int](value)
+	}
+	return err
+}
+
+func TestBinarySnapshot_NonFiniteFloats(t *testing.T) {
+	state := &floatTestState{
+		changes: NewChangeSet(),
+		Speed:   math.Inf(1),
+		Scale:   float32(math.NaN()),
+		Team:    &floatTestTeam{changes: NewChangeSet(), Name: "red", Power: 3},
+		Ratios:  []float64{math.Inf(-1), 0.5},
+	}
+	data, err := EncodeBinarySnapshot(NewTrackedState[*floatTestState, any](state, nil), nil, nil)
+	if err != nil {
+		t.Fatalf("EncodeBinarySnapshot: %v", err)
+	}
+	store := NewMemoryStore()
+	store.Put("floats", data)
+
+	snap, err := LoadSnapshot[*floatTestState](store, "floats")
+	if err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	got := snap.State
+	if !math.IsInf(got.Speed, 1) || !math.IsNaN(float64(got.Scale)) {
+		t.Errorf("floats = %v %v, want +Inf NaN", got.Speed, got.Scale)
+	}
+	if got.Team == nil || got.Team.Name != "red" || got.Team.Power != 3 {
+		t.Errorf("Team = %+v, want red 3", got.Team)
+	}
+	if len(got.Ratios) != 2 || !math.IsInf(got.Ratios[0], -1) || got.Ratios[1] != 0.5 {
+		t.Errorf("Ratios = %v", got.Ratios)
+	}
+}
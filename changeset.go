@@ -2,6 +2,7 @@ package statesync
 
 import (
 	"math/bits"
+	"sort"
 	"sync"
 )
 
@@ -44,21 +45,24 @@ type FieldChange struct {
 type ChangeSet struct {
 	mu sync.RWMutex
 
-	// Bitset for tracking which fields changed (supports up to 256 fields)
+	// Bitset for tracking which of the first 256 fields changed
 	// Each bit represents a field index
 	dirty [4]uint64
 
 	// Field operations (indexed by field number, only valid if dirty bit is set)
 	ops [256]FieldChange
 
+	// Changed fields from index 256 on (only used by schemas with more than 256 fields)
+	wide map[uint16]FieldChange
+
 	// For nested objects: field index -> child ChangeSet
-	children map[uint8]*ChangeSet
+	children map[uint16]*ChangeSet
 
 	// For arrays: field index -> array changes
-	arrays map[uint8]*ArrayChangeSet
+	arrays map[uint16]*ArrayChangeSet
 
 	// For maps: field index -> map changes
-	maps map[uint8]*MapChangeSet
+	maps map[uint16]*MapChangeSet
 }
 
 // ArrayChangeSet tracks changes to array elements
@@ -97,26 +101,56 @@ func NewChangeSet() *ChangeSet {
 
 // Mark marks a field as changed
 func (cs *ChangeSet) Mark(fieldIndex uint8, op Operation) {
+	cs.MarkWide(uint16(fieldIndex), op)
+}
+
+// MarkWide is Mark for any field index, including those from 256 on (see Schema.Wide)
+func (cs *ChangeSet) MarkWide(fieldIndex uint16, op Operation) {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
-	// Set bit in dirty bitset
-	cs.dirty[fieldIndex/64] |= 1 << (fieldIndex % 64)
-	cs.ops[fieldIndex] = FieldChange{Op: op}
+	cs.setLocked(fieldIndex, FieldChange{Op: op})
 }
 
 // MarkWithIndex marks an array field change with index info
 func (cs *ChangeSet) MarkWithIndex(fieldIndex uint8, op Operation, oldIdx, newIdx int) {
+	cs.MarkWideWithIndex(uint16(fieldIndex), op, oldIdx, newIdx)
+}
+
+// MarkWideWithIndex is MarkWithIndex for any field index
+func (cs *ChangeSet) MarkWideWithIndex(fieldIndex uint16, op Operation, oldIdx, newIdx int) {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
+	cs.setLocked(fieldIndex, FieldChange{Op: op, OldIndex: oldIdx, NewIndex: newIdx})
+}
+
+// setLocked records a field change (caller must hold lock)
+func (cs *ChangeSet) setLocked(fieldIndex uint16, change FieldChange) {
+	if fieldIndex >= MaxCompactFields {
+		if cs.wide == nil {
+			cs.wide = make(map[uint16]FieldChange)
+		}
+		cs.wide[fieldIndex] = change
+		return
+	}
+	// Set bit in dirty bitset
 	cs.dirty[fieldIndex/64] |= 1 << (fieldIndex % 64)
-	cs.ops[fieldIndex] = FieldChange{Op: op, OldIndex: oldIdx, NewIndex: newIdx}
+	cs.ops[fieldIndex] = change
 }
 
 // GetFieldChange returns the change for a field, or OpNone if unchanged
 func (cs *ChangeSet) GetFieldChange(fieldIndex uint8) FieldChange {
+	return cs.GetWideFieldChange(uint16(fieldIndex))
+}
+
+// GetWideFieldChange is GetFieldChange for any field index
+func (cs *ChangeSet) GetWideFieldChange(fieldIndex uint16) FieldChange {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	if cs.isDirty(fieldIndex) {
+	if fieldIndex >= MaxCompactFields {
+		if change, ok := cs.wide[fieldIndex]; ok {
+			return change
+		}
+	} else if cs.isDirty(fieldIndex) {
 		return cs.ops[fieldIndex]
 	}
 	return FieldChange{Op: OpNone}
@@ -124,6 +158,11 @@ func (cs *ChangeSet) GetFieldChange(fieldIndex uint8) FieldChange {
 
 // IsFieldDirty returns true if the field has been changed (fast bitset check)
 func (cs *ChangeSet) IsFieldDirty(fieldIndex uint8) bool {
+	return cs.IsWideFieldDirty(uint16(fieldIndex))
+}
+
+// IsWideFieldDirty is IsFieldDirty for any field index
+func (cs *ChangeSet) IsWideFieldDirty(fieldIndex uint16) bool {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 	return cs.isDirty(fieldIndex)
@@ -133,7 +172,7 @@ func (cs *ChangeSet) IsFieldDirty(fieldIndex uint8) bool {
 func (cs *ChangeSet) HasChanges() bool {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	if cs.dirty[0] != 0 || cs.dirty[1] != 0 || cs.dirty[2] != 0 || cs.dirty[3] != 0 {
+	if cs.dirty[0] != 0 || cs.dirty[1] != 0 || cs.dirty[2] != 0 || cs.dirty[3] != 0 || len(cs.wide) > 0 {
 		return true
 	}
 	for _, child := range cs.children {
@@ -163,6 +202,7 @@ func (cs *ChangeSet) Clear() {
 	cs.dirty[1] = 0
 	cs.dirty[2] = 0
 	cs.dirty[3] = 0
+	clear(cs.wide)
 	// Recursively clear nested objects (don't delete from map — cached pointers stay valid)
 	for _, child := range cs.children {
 		child.Clear()
@@ -184,10 +224,16 @@ func (cs *ChangeSet) CloneForFilter() *ChangeSet {
 		dirty: cs.dirty,
 		ops:   cs.ops,
 	}
+	if len(cs.wide) > 0 {
+		clone.wide = make(map[uint16]FieldChange, len(cs.wide))
+		for idx, change := range cs.wide {
+			clone.wide[idx] = change
+		}
+	}
 
 	// Deep-copy map change sets
 	if len(cs.maps) > 0 {
-		clone.maps = make(map[uint8]*MapChangeSet, len(cs.maps))
+		clone.maps = make(map[uint16]*MapChangeSet, len(cs.maps))
 		for idx, mcs := range cs.maps {
 			if mcs == nil {
 				continue
@@ -204,7 +250,7 @@ func (cs *ChangeSet) CloneForFilter() *ChangeSet {
 
 	// Deep-copy array change sets
 	if len(cs.arrays) > 0 {
-		clone.arrays = make(map[uint8]*ArrayChangeSet, len(cs.arrays))
+		clone.arrays = make(map[uint16]*ArrayChangeSet, len(cs.arrays))
 		for idx, acs := range cs.arrays {
 			if acs == nil {
 				continue
@@ -229,23 +275,50 @@ func (cs *ChangeSet) CloneForFilter() *ChangeSet {
 	return clone
 }
 
+// Clone returns a deep copy of the ChangeSet, including nested, array and map change sets
+// (see Cloner). Element values recorded by array and map changes are shared.
+func (cs *ChangeSet) Clone() *ChangeSet {
+	if cs == nil {
+		return nil
+	}
+	clone := cs.CloneForFilter()
+
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if len(cs.children) > 0 {
+		clone.children = make(map[uint16]*ChangeSet, len(cs.children))
+		for idx, child := range cs.children {
+			clone.children[idx] = child.Clone()
+		}
+	}
+	return clone
+}
+
 // MarkAll marks all fields up to maxIndex as changed (for full sync)
 func (cs *ChangeSet) MarkAll(maxIndex uint8) {
+	cs.MarkAllWide(uint16(maxIndex))
+}
+
+// MarkAllWide is MarkAll for schemas with more than 256 fields
+func (cs *ChangeSet) MarkAllWide(maxIndex uint16) {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
 	for i := 0; i <= int(maxIndex); i++ {
-		idx := uint8(i)
-		cs.dirty[idx/64] |= 1 << (idx % 64)
-		cs.ops[idx] = FieldChange{Op: OpReplace}
+		cs.setLocked(uint16(i), FieldChange{Op: OpReplace})
 	}
 }
 
 // GetOrCreateChild returns the ChangeSet for a nested object, creating if needed
 func (cs *ChangeSet) GetOrCreateChild(fieldIndex uint8) *ChangeSet {
+	return cs.GetOrCreateWideChild(uint16(fieldIndex))
+}
+
+// GetOrCreateWideChild is GetOrCreateChild for any field index
+func (cs *ChangeSet) GetOrCreateWideChild(fieldIndex uint16) *ChangeSet {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
 	if cs.children == nil {
-		cs.children = make(map[uint8]*ChangeSet)
+		cs.children = make(map[uint16]*ChangeSet)
 	}
 	if child, ok := cs.children[fieldIndex]; ok {
 		return child
@@ -257,6 +330,11 @@ func (cs *ChangeSet) GetOrCreateChild(fieldIndex uint8) *ChangeSet {
 
 // GetChild returns the ChangeSet for a nested object, or nil if none
 func (cs *ChangeSet) GetChild(fieldIndex uint8) *ChangeSet {
+	return cs.GetWideChild(uint16(fieldIndex))
+}
+
+// GetWideChild is GetChild for any field index
+func (cs *ChangeSet) GetWideChild(fieldIndex uint16) *ChangeSet {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 	return cs.children[fieldIndex]
@@ -264,10 +342,15 @@ func (cs *ChangeSet) GetChild(fieldIndex uint8) *ChangeSet {
 
 // GetOrCreateArray returns the ArrayChangeSet for an array field
 func (cs *ChangeSet) GetOrCreateArray(fieldIndex uint8) *ArrayChangeSet {
+	return cs.GetOrCreateWideArray(uint16(fieldIndex))
+}
+
+// GetOrCreateWideArray is GetOrCreateArray for any field index
+func (cs *ChangeSet) GetOrCreateWideArray(fieldIndex uint16) *ArrayChangeSet {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
 	if cs.arrays == nil {
-		cs.arrays = make(map[uint8]*ArrayChangeSet)
+		cs.arrays = make(map[uint16]*ArrayChangeSet)
 	}
 	if arr, ok := cs.arrays[fieldIndex]; ok {
 		return arr
@@ -279,6 +362,11 @@ func (cs *ChangeSet) GetOrCreateArray(fieldIndex uint8) *ArrayChangeSet {
 
 // GetArray returns the ArrayChangeSet for an array field, or nil
 func (cs *ChangeSet) GetArray(fieldIndex uint8) *ArrayChangeSet {
+	return cs.GetWideArray(uint16(fieldIndex))
+}
+
+// GetWideArray is GetArray for any field index
+func (cs *ChangeSet) GetWideArray(fieldIndex uint16) *ArrayChangeSet {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 	return cs.arrays[fieldIndex]
@@ -286,10 +374,15 @@ func (cs *ChangeSet) GetArray(fieldIndex uint8) *ArrayChangeSet {
 
 // GetOrCreateMap returns the MapChangeSet for a map field
 func (cs *ChangeSet) GetOrCreateMap(fieldIndex uint8) *MapChangeSet {
+	return cs.GetOrCreateWideMap(uint16(fieldIndex))
+}
+
+// GetOrCreateWideMap is GetOrCreateMap for any field index
+func (cs *ChangeSet) GetOrCreateWideMap(fieldIndex uint16) *MapChangeSet {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
 	if cs.maps == nil {
-		cs.maps = make(map[uint8]*MapChangeSet)
+		cs.maps = make(map[uint16]*MapChangeSet)
 	}
 	if m, ok := cs.maps[fieldIndex]; ok {
 		return m
@@ -301,45 +394,76 @@ func (cs *ChangeSet) GetOrCreateMap(fieldIndex uint8) *MapChangeSet {
 
 // GetMap returns the MapChangeSet for a map field, or nil
 func (cs *ChangeSet) GetMap(fieldIndex uint8) *MapChangeSet {
+	return cs.GetWideMap(uint16(fieldIndex))
+}
+
+// GetWideMap is GetMap for any field index
+func (cs *ChangeSet) GetWideMap(fieldIndex uint16) *MapChangeSet {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 	return cs.maps[fieldIndex]
 }
 
-// ChangedFields returns all changed field indices in sorted order
+// ChangedFields returns all changed field indices in sorted order.
+// Fields from index 256 on are only returned by ChangedWideFields.
 func (cs *ChangeSet) ChangedFields() []uint8 {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	wide := cs.changedFieldsLocked()
+	if wide == nil {
+		return nil
+	}
+	fields := make([]uint8, 0, len(wide))
+	for _, idx := range wide {
+		if idx < MaxCompactFields {
+			fields = append(fields, uint8(idx))
+		}
+	}
+	return fields
+}
+
+// ChangedWideFields is ChangedFields for any field index
+func (cs *ChangeSet) ChangedWideFields() []uint16 {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 	return cs.changedFieldsLocked()
 }
 
 // changedFieldsLocked returns changed fields without locking (caller must hold lock)
-func (cs *ChangeSet) changedFieldsLocked() []uint8 {
+func (cs *ChangeSet) changedFieldsLocked() []uint16 {
 	// Count dirty bits for pre-allocation
-	count := popcount(cs.dirty[0]) + popcount(cs.dirty[1]) + popcount(cs.dirty[2]) + popcount(cs.dirty[3])
+	count := popcount(cs.dirty[0]) + popcount(cs.dirty[1]) + popcount(cs.dirty[2]) + popcount(cs.dirty[3]) + len(cs.wide)
 	if count == 0 && len(cs.children) == 0 && len(cs.arrays) == 0 && len(cs.maps) == 0 {
 		return nil
 	}
 
 	// Use stack-allocated buffer for small field counts (typical: <64 fields)
-	var buf [64]uint8
-	var result []uint8
+	var buf [64]uint16
+	var result []uint16
 	if count <= 64 {
 		result = buf[:0]
 	} else {
-		result = make([]uint8, 0, count)
+		result = make([]uint16, 0, count)
 	}
 
 	// Extract set bits from bitset (already in sorted order)
 	for i := 0; i < 4; i++ {
 		w := cs.dirty[i]
-		base := uint8(i * 64)
+		base := uint16(i * 64)
 		for w != 0 {
 			tz := trailingZeros64(w)
-			result = append(result, base+uint8(tz))
+			result = append(result, base+uint16(tz))
 			w &= w - 1
 		}
 	}
+	if len(cs.wide) > 0 {
+		start := len(result)
+		for idx := range cs.wide {
+			result = append(result, idx)
+		}
+		wide := result[start:]
+		sort.Slice(wide, func(i, j int) bool { return wide[i] < wide[j] })
+	}
 
 	// Add any children/arrays/maps whose parent dirty bit is not set
 	for idx := range cs.children {
@@ -362,7 +486,11 @@ func (cs *ChangeSet) changedFieldsLocked() []uint8 {
 }
 
 // isDirty checks if a field's dirty bit is set (caller must hold lock)
-func (cs *ChangeSet) isDirty(idx uint8) bool {
+func (cs *ChangeSet) isDirty(idx uint16) bool {
+	if idx >= MaxCompactFields {
+		_, ok := cs.wide[idx]
+		return ok
+	}
 	return cs.dirty[idx/64]&(1<<(idx%64)) != 0
 }
 
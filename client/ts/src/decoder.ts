@@ -9,6 +9,19 @@
 export const MsgFullState = 0x01;
 export const MsgPatch = 0x02;
 export const MsgPatchBatch = 0x03;
+export const MsgRootRemove = 0x04;
+export const MsgSnapshot = 0x05;
+export const MsgSchema = 0x06;
+export const MsgEvent = 0x10;
+export const MsgEventBatch = 0x11;
+export const MsgReliableEvents = 0x12;
+
+// Flag on the MsgFullState/MsgPatch type byte of schemas with more than 256 fields:
+// the field count and field indices are varints instead of bytes
+export const MsgFlagWideIndex = 0x80;
+
+// Newest binary snapshot header version (must match Go BinarySnapshotVersion)
+export const SnapshotVersion = 2;
 
 // Operation types
 export enum Operation {
@@ -51,6 +64,8 @@ export interface FieldMeta {
   elemType?: FieldType;
   childSchema?: Schema;
   keyField?: string;
+  views?: string[]; // Projections that can see the field (empty = everyone)
+  write?: string; // Write permission ('server', 'owner' or '' for anyone)
 }
 
 // Schema definition
@@ -58,8 +73,36 @@ export interface Schema {
   id: number;
   name: string;
   fields: FieldMeta[];
+  ownerField?: string;
+}
+
+// Schema descriptor (MsgSchema, or the JSON form of Go's SchemaDescriptor).
+// child is the position+1 of the nested schema in schemas (0 = none).
+export interface SchemaDescriptor {
+  version: number;
+  schemas: Array<{
+    id: number;
+    name: string;
+    ownerField?: string;
+    fields: Array<{
+      index: number;
+      name: string;
+      type: FieldType;
+      elemType?: FieldType;
+      child?: number;
+      keyField?: string;
+      views?: string[];
+      write?: string;
+    }>;
+  }>;
 }
 
+// Supported schema descriptor version (must match Go SchemaDescriptorVersion)
+export const SchemaDescriptorVersion = 1;
+
+// Schema descriptor version with varint field indices (schemas with more than 256 fields)
+export const SchemaDescriptorVersionWide = 2;
+
 // Decoded change
 export interface DecodedChange {
   fieldIndex: number;
@@ -88,9 +131,18 @@ export interface DecodedPatch {
   schemaId: number;
   schemaName?: string;
   isFullState: boolean;
+  isRemoved?: boolean; // Root schema was deactivated (MsgRootRemove)
+  isSchema?: boolean; // Schemas were registered from a descriptor (MsgSchema)
   changes: DecodedChange[];
 }
 
+// Decoded event (seq is set for reliable events)
+export interface DecodedEvent {
+  type: string;
+  payload: Uint8Array;
+  seq?: number;
+}
+
 /**
  * Schema registry for looking up schemas by ID
  */
@@ -110,6 +162,68 @@ export class SchemaRegistry {
   getByName(name: string): Schema | undefined {
     return this.byName.get(name);
   }
+
+  /**
+   * Register the schemas of a descriptor (a MsgSchema message or its JSON form).
+   * Nested schemas without an ID are only reachable through their parent.
+   */
+  loadDescriptor(descriptor: ArrayBuffer | Uint8Array | SchemaDescriptor): Schema[] {
+    const desc =
+      descriptor instanceof Uint8Array || descriptor instanceof ArrayBuffer
+        ? new Decoder(this).decodeSchemaDescriptor(descriptor)
+        : descriptor;
+    const schemas = schemasFromDescriptor(desc);
+    for (const schema of schemas) {
+      if (schema.id !== 0) this.register(schema);
+    }
+    return schemas;
+  }
+
+  /**
+   * Create a registry from a schema descriptor sent by the server
+   */
+  static fromDescriptor(descriptor: ArrayBuffer | Uint8Array | SchemaDescriptor): SchemaRegistry {
+    const registry = new SchemaRegistry();
+    registry.loadDescriptor(descriptor);
+    return registry;
+  }
+}
+
+/**
+ * Build the schemas of a descriptor (nested schemas are linked by reference)
+ */
+export function schemasFromDescriptor(desc: SchemaDescriptor): Schema[] {
+  if (desc.version !== SchemaDescriptorVersion && desc.version !== SchemaDescriptorVersionWide) {
+    throw new Error(`Unsupported schema descriptor version: ${desc.version}`);
+  }
+  const schemas: Schema[] = desc.schemas.map((s) => ({
+    id: s.id,
+    name: s.name,
+    fields: [],
+    ownerField: s.ownerField || undefined,
+  }));
+  desc.schemas.forEach((s, i) => {
+    schemas[i].fields = s.fields.map((f, j) => {
+      if (f.index !== j) {
+        throw new Error(`Invalid schema descriptor: field ${s.name}.${f.name} has index ${f.index} at position ${j}`);
+      }
+      const child = f.child ?? 0;
+      if (child < 0 || child > schemas.length) {
+        throw new Error(`Invalid schema descriptor: field ${s.name}.${f.name} refers to schema ${child}`);
+      }
+      return {
+        index: f.index,
+        name: f.name,
+        type: f.type,
+        elemType: f.elemType || undefined,
+        childSchema: child > 0 ? schemas[child - 1] : undefined,
+        keyField: f.keyField || undefined,
+        views: f.views && f.views.length > 0 ? f.views : undefined,
+        write: f.write || undefined,
+      };
+    });
+  });
+  return schemas;
 }
 
 /**
@@ -137,25 +251,172 @@ export class Decoder {
     this.pos = 0;
 
     const msgType = this.readByte();
+    const wide = msgType === (MsgFullState | MsgFlagWideIndex) || msgType === (MsgPatch | MsgFlagWideIndex);
 
-    switch (msgType) {
+    switch (wide ? msgType & ~MsgFlagWideIndex : msgType) {
       case MsgFullState:
-        return this.decodeFullState();
+        return this.decodeFullState(wide);
       case MsgPatch:
-        return this.decodePatch();
+        return this.decodePatch(wide);
+      case MsgRootRemove: {
+        const schemaId = this.readUint16();
+        return {
+          schemaId,
+          schemaName: this.registry.get(schemaId)?.name,
+          isFullState: false,
+          isRemoved: true,
+          changes: [],
+        };
+      }
+      case MsgSnapshot: {
+        // Binary snapshot: skip the header and decode the embedded full state
+        const version = this.readByte();
+        if (version > SnapshotVersion) {
+          throw new Error(`Unsupported snapshot version: ${version}`);
+        }
+        const schemaId = this.readUint16();
+        this.pos += 8 + 8; // fingerprint, savedAt
+        // Read lengths first: `this.pos += this.readVarUint()` would drop the varint bytes
+        const effectsLen = this.readVarUint();
+        this.pos += effectsLen;
+        const extraLen = this.readVarUint();
+        this.pos += extraLen;
+        if (version >= 2) {
+          this.readVarUint(); // schemaVersion
+          const schemaLen = this.readVarUint();
+          const end = this.pos + schemaLen;
+          // The embedded descriptor makes the snapshot self-describing
+          if (schemaLen > 0 && !this.registry.get(schemaId)) {
+            this.registry.loadDescriptor(this.readSchemaDescriptor());
+          }
+          this.pos = end;
+        }
+        const stateType = this.readByte();
+        if ((stateType & ~MsgFlagWideIndex) !== MsgFullState) {
+          throw new Error('Invalid snapshot');
+        }
+        return this.decodeFullState((stateType & MsgFlagWideIndex) !== 0);
+      }
+      case MsgSchema: {
+        // Schema descriptor: register the schemas for the messages that follow
+        this.pos = 0;
+        this.registry.loadDescriptor(this.readSchemaDescriptor());
+        return { schemaId: 0, isFullState: false, isSchema: true, changes: [] };
+      }
       default:
         throw new Error(`Invalid message type: ${msgType}`);
     }
   }
 
-  private decodeFullState(): DecodedPatch {
+  /**
+   * Decode a schema descriptor message (MsgSchema) without registering it
+   */
+  decodeSchemaDescriptor(data: ArrayBuffer | Uint8Array): SchemaDescriptor {
+    if (data instanceof Uint8Array) {
+      this.buffer = new DataView(data.buffer, data.byteOffset, data.byteLength);
+    } else {
+      this.buffer = new DataView(data);
+    }
+    this.pos = 0;
+    return this.readSchemaDescriptor();
+  }
+
+  private readSchemaDescriptor(): SchemaDescriptor {
+    if (this.readByte() !== MsgSchema) {
+      throw new Error('Invalid schema descriptor');
+    }
+    const version = this.readByte();
+    if (version !== SchemaDescriptorVersion && version !== SchemaDescriptorVersionWide) {
+      throw new Error(`Unsupported schema descriptor version: ${version}`);
+    }
+    const desc: SchemaDescriptor = { version, schemas: [] };
+    const count = this.readVarUint();
+    for (let i = 0; i < count; i++) {
+      const id = this.readUint16();
+      const name = this.readString();
+      const ownerField = this.readString();
+      const fields: SchemaDescriptor['schemas'][number]['fields'] = [];
+      const fieldCount = this.readVarUint();
+      for (let j = 0; j < fieldCount; j++) {
+        const index = this.readFieldIndex(version === SchemaDescriptorVersionWide);
+        const fieldName = this.readString();
+        const type = this.readByte() as FieldType;
+        const elemType = this.readByte() as FieldType;
+        const child = this.readVarUint();
+        const keyField = this.readString();
+        const views: string[] = [];
+        const viewCount = this.readVarUint();
+        for (let k = 0; k < viewCount; k++) {
+          views.push(this.readString());
+        }
+        const write = this.readString();
+        fields.push({ index, name: fieldName, type, elemType, child, keyField, views, write });
+      }
+      desc.schemas.push({ id, name, ownerField, fields });
+    }
+    return desc;
+  }
+
+  /**
+   * Decode a message that may be a patch batch (multi-root sessions).
+   * Non-batch messages are decoded as a single patch.
+   */
+  decodeBatch(data: ArrayBuffer | Uint8Array): DecodedPatch[] {
+    const bytes = data instanceof Uint8Array ? data : new Uint8Array(data);
+    if (bytes.length === 0 || bytes[0] !== MsgPatchBatch) {
+      return [this.decode(bytes)];
+    }
+
+    this.buffer = new DataView(bytes.buffer, bytes.byteOffset, bytes.byteLength);
+    this.pos = 1;
+    const count = this.readVarUint();
+    const messages: Uint8Array[] = [];
+    for (let i = 0; i < count; i++) {
+      const length = this.readVarUint();
+      if (this.pos + length > bytes.byteLength) {
+        throw new Error('Buffer underflow');
+      }
+      messages.push(bytes.subarray(this.pos, this.pos + length));
+      this.pos += length;
+    }
+    return messages.map((msg) => this.decode(msg));
+  }
+
+  /**
+   * Decode an event message (MsgEvent, MsgEventBatch or MsgReliableEvents)
+   */
+  decodeEvents(data: ArrayBuffer | Uint8Array): DecodedEvent[] {
+    const bytes = data instanceof Uint8Array ? data : new Uint8Array(data);
+    this.buffer = new DataView(bytes.buffer, bytes.byteOffset, bytes.byteLength);
+    this.pos = 0;
+
+    const msgType = this.readByte();
+    switch (msgType) {
+      case MsgEvent:
+        return [{ type: this.readString(), payload: this.readBytes() }];
+      case MsgEventBatch:
+      case MsgReliableEvents: {
+        const count = this.readVarUint();
+        const events: DecodedEvent[] = [];
+        for (let i = 0; i < count; i++) {
+          const seq = msgType === MsgReliableEvents ? this.readVarUint() : undefined;
+          events.push({ type: this.readString(), payload: this.readBytes(), seq });
+        }
+        return events;
+      }
+      default:
+        throw new Error(`Invalid event message type: ${msgType}`);
+    }
+  }
+
+  private decodeFullState(wide: boolean): DecodedPatch {
     const schemaId = this.readUint16();
     const schema = this.registry.get(schemaId);
     if (!schema) {
       throw new Error(`Unknown schema ID: ${schemaId}`);
     }
 
-    const fieldCount = this.readByte();
+    const fieldCount = this.readFieldIndex(wide);
     const changes: DecodedChange[] = [];
 
     for (let i = 0; i < fieldCount; i++) {
@@ -179,7 +440,7 @@ export class Decoder {
     };
   }
 
-  private decodePatch(): DecodedPatch {
+  private decodePatch(wide: boolean): DecodedPatch {
     const schemaId = this.readUint16();
     const schema = this.registry.get(schemaId);
     if (!schema) {
@@ -190,7 +451,7 @@ export class Decoder {
     const changes: DecodedChange[] = [];
 
     for (let i = 0; i < changeCount; i++) {
-      const fieldIndex = this.readByte();
+      const fieldIndex = this.readFieldIndex(wide);
       const field = schema.fields[fieldIndex];
       if (!field) continue;
 
@@ -389,6 +650,11 @@ export class Decoder {
 
   // Primitive read methods
 
+  // Field index or count: a varint in wide messages (see MsgFlagWideIndex)
+  private readFieldIndex(wide: boolean): number {
+    return wide ? this.readVarUint() : this.readByte();
+  }
+
   private readByte(): number {
     if (this.pos >= this.buffer.byteLength) {
       throw new Error('Buffer underflow');
@@ -551,7 +817,16 @@ export class SyncState<T extends Record<string, any>> {
    * Apply a binary patch/full state message
    */
   apply(data: ArrayBuffer | Uint8Array): DecodedChange[] {
-    const patch = this.decoder.decode(data);
+    return this.applyPatch(this.decoder.decode(data));
+  }
+
+  /**
+   * Apply an already decoded patch/full state message
+   */
+  applyPatch(patch: DecodedPatch): DecodedChange[] {
+    if (patch.isSchema) {
+      return []; // Schemas were registered with the decoder
+    }
 
     if (patch.isFullState) {
       // Full state replace
@@ -647,6 +922,194 @@ export class SyncState<T extends Record<string, any>> {
   }
 }
 
+/**
+ * Container for multi-root sessions: keeps one state per root schema and
+ * routes each message in a batch to it by schema ID.
+ */
+export class MultiSyncState {
+  private states: Map<number, SyncState<any>> = new Map();
+  private registry: SchemaRegistry;
+  private decoder: Decoder;
+  private listeners: Set<(schemaName: string, state: Record<string, any> | null) => void> = new Set();
+
+  constructor(registry: SchemaRegistry) {
+    this.registry = registry;
+    this.decoder = new Decoder(registry);
+  }
+
+  /**
+   * Get the state of a root schema (null if not active)
+   */
+  get<T extends Record<string, any>>(schemaName: string): T | null {
+    const schema = this.registry.getByName(schemaName);
+    if (!schema) return null;
+    const state = this.states.get(schema.id);
+    return state ? (state.get() as T) : null;
+  }
+
+  /**
+   * Names of all roots currently synced
+   */
+  activeRoots(): string[] {
+    const names: string[] = [];
+    this.states.forEach((_, id) => {
+      const schema = this.registry.get(id);
+      if (schema) names.push(schema.name);
+    });
+    return names;
+  }
+
+  /**
+   * Apply a (possibly batched) message from the server
+   */
+  apply(data: ArrayBuffer | Uint8Array): void {
+    for (const patch of this.decoder.decodeBatch(data)) {
+      if (patch.isSchema) continue; // Schemas were registered with the decoder
+
+      const name = patch.schemaName ?? String(patch.schemaId);
+
+      if (patch.isRemoved) {
+        this.states.delete(patch.schemaId);
+        this.listeners.forEach((fn) => fn(name, null));
+        continue;
+      }
+
+      let state = this.states.get(patch.schemaId);
+      if (!state) {
+        const schema = this.registry.get(patch.schemaId)!;
+        state = new SyncState<any>(schema, this.registry);
+        this.states.set(patch.schemaId, state);
+      }
+      state.applyPatch(patch);
+      this.listeners.forEach((fn) => fn(name, state!.get()));
+    }
+  }
+
+  /**
+   * Subscribe to root state changes (state is null when a root is removed)
+   */
+  onChange(fn: (schemaName: string, state: Record<string, any> | null) => void): () => void {
+    this.listeners.add(fn);
+    return () => this.listeners.delete(fn);
+  }
+}
+
+/**
+ * Receives events and drops reliable events that were already seen
+ * (the server replays unacknowledged events after a reconnect).
+ * Send lastSeq to the server periodically so it can prune acknowledged events.
+ */
+export class EventReceiver {
+  private decoder: Decoder;
+  private seq = 0;
+  private listeners: Set<(event: DecodedEvent) => void> = new Set();
+
+  constructor(registry: SchemaRegistry = new SchemaRegistry()) {
+    this.decoder = new Decoder(registry);
+  }
+
+  /**
+   * Highest reliable event sequence received (0 if none)
+   */
+  get lastSeq(): number {
+    return this.seq;
+  }
+
+  /**
+   * Apply an event message from the server and return the new events
+   */
+  apply(data: ArrayBuffer | Uint8Array): DecodedEvent[] {
+    const fresh: DecodedEvent[] = [];
+    for (const event of this.decoder.decodeEvents(data)) {
+      if (event.seq !== undefined) {
+        if (event.seq <= this.seq) continue;
+        this.seq = event.seq;
+      }
+      fresh.push(event);
+      this.listeners.forEach((fn) => fn(event));
+    }
+    return fresh;
+  }
+
+  /**
+   * Subscribe to events
+   */
+  onEvent(fn: (event: DecodedEvent) => void): () => void {
+    this.listeners.add(fn);
+    return () => this.listeners.delete(fn);
+  }
+}
+
+/**
+ * Reads event payloads written by the Go EventPayloadEncoder
+ * (little-endian numbers, varint length-prefixed strings and bytes)
+ */
+export class EventPayloadReader {
+  private view: DataView;
+  private pos = 0;
+
+  constructor(payload: Uint8Array) {
+    this.view = new DataView(payload.buffer, payload.byteOffset, payload.byteLength);
+  }
+
+  readString(): string {
+    return new TextDecoder().decode(this.readBytes());
+  }
+
+  readInt32(): number {
+    return this.view.getInt32(this.take(4), true);
+  }
+
+  readUint32(): number {
+    return this.view.getUint32(this.take(4), true);
+  }
+
+  readInt64(): number {
+    return Number(this.view.getBigInt64(this.take(8), true));
+  }
+
+  readUint64(): number {
+    return Number(this.view.getBigUint64(this.take(8), true));
+  }
+
+  readFloat32(): number {
+    return this.view.getFloat32(this.take(4), true);
+  }
+
+  readFloat64(): number {
+    return this.view.getFloat64(this.take(8), true);
+  }
+
+  readBool(): boolean {
+    return this.view.getUint8(this.take(1)) !== 0;
+  }
+
+  readBytes(): Uint8Array {
+    let length = 0;
+    let shift = 0;
+    while (true) {
+      const b = this.view.getUint8(this.take(1));
+      length |= (b & 0x7f) << shift;
+      if ((b & 0x80) === 0) break;
+      shift += 7;
+      if (shift >= 32) {
+        throw new Error('VarUint overflow');
+      }
+    }
+    const start = this.take(length >>> 0);
+    return new Uint8Array(this.view.buffer, this.view.byteOffset + start, length).slice();
+  }
+
+  private take(n: number): number {
+    if (this.pos + n > this.view.byteLength) {
+      throw new Error('Buffer underflow');
+    }
+    const start = this.pos;
+    this.pos += n;
+    return start;
+  }
+}
+
 /**
  * Helper to create a schema from a simple definition
  */
@@ -674,9 +1137,21 @@ export default {
   Decoder,
   SchemaRegistry,
   SyncState,
+  MultiSyncState,
+  EventReceiver,
+  EventPayloadReader,
   defineSchema,
   FieldType,
   Operation,
   MsgFullState,
   MsgPatch,
+  MsgRootRemove,
+  MsgSnapshot,
+  MsgSchema,
+  MsgFlagWideIndex,
+  SnapshotVersion,
+  MsgEvent,
+  MsgEventBatch,
+  MsgReliableEvents,
+  schemasFromDescriptor,
 };
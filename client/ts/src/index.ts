@@ -2,8 +2,10 @@ export {
   // Types
   type FieldMeta,
   type Schema,
+  type SchemaDescriptor,
   type DecodedChange,
   type DecodedPatch,
+  type DecodedEvent,
   type ArrayChange,
   type MapChange,
 
@@ -15,12 +17,26 @@ export {
   MsgFullState,
   MsgPatch,
   MsgPatchBatch,
+  MsgRootRemove,
+  MsgSnapshot,
+  MsgSchema,
+  MsgFlagWideIndex,
+  SnapshotVersion,
+  SchemaDescriptorVersion,
+  SchemaDescriptorVersionWide,
+  MsgEvent,
+  MsgEventBatch,
+  MsgReliableEvents,
 
   // Classes
   Decoder,
   SchemaRegistry,
   SyncState,
+  MultiSyncState,
+  EventReceiver,
+  EventPayloadReader,
 
   // Helpers
   defineSchema,
+  schemasFromDescriptor,
 } from './decoder';
@@ -0,0 +1,50 @@
+import { describe, expect, it } from 'vitest';
+import { Decoder, FieldType, SchemaRegistry, defineSchema } from '../src/decoder';
+
+function hex(s: string): Uint8Array {
+  const out = new Uint8Array(s.length / 2);
+  for (let i = 0; i < out.length; i++) {
+    out[i] = parseInt(s.slice(i * 2, i * 2 + 2), 16);
+  }
+  return out;
+}
+
+// EncodeBinarySnapshot (header version 2) of a TestState{Score: 42, Name: "x"}
+// with extra {"round":3}, produced by the Go package
+const goSnapshotV2 = hex(
+  '05020100ff2d3acfd0b1559ed8891f4529aadf18000b7b22726f756e64223a337d012a06010101000954657374537461746500' +
+    '02000553636f726504000000000001044e616d650b0000000000010100022a000000000000000178'
+);
+
+describe('Decoder snapshots', () => {
+  it('decodes a Go-encoded v2 snapshot', () => {
+    const registry = new SchemaRegistry();
+    registry.register(
+      defineSchema(1, 'TestState', [
+        { name: 'Score', type: FieldType.Int64 },
+        { name: 'Name', type: FieldType.String },
+      ])
+    );
+
+    const patch = new Decoder(registry).decode(goSnapshotV2);
+    expect(patch.isFullState).toBe(true);
+    expect(patch.schemaName).toBe('TestState');
+    expect(patch.changes.map((c) => [c.fieldName, c.value])).toEqual([
+      ['Score', 42n],
+      ['Name', 'x'],
+    ]);
+  });
+
+  it('registers the embedded schema of unknown snapshots', () => {
+    const registry = new SchemaRegistry();
+    const patch = new Decoder(registry).decode(goSnapshotV2);
+    expect(registry.getByName('TestState')?.id).toBe(1);
+    expect(patch.changes.map((c) => c.value)).toEqual([42n, 'x']);
+  });
+
+  it('rejects newer snapshot versions', () => {
+    const data = goSnapshotV2.slice();
+    data[1] = 3;
+    expect(() => new Decoder(new SchemaRegistry()).decode(data)).toThrow('Unsupported snapshot version: 3');
+  });
+});
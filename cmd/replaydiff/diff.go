@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/mxkacsa/statesync"
+)
+
+// frame is a recorded diff or keyframe
+type frame struct {
+	statesync.DiffRecord
+	keyframe bool
+}
+
+// loadRecording reads a replay file or a JSON record array (MarshalRecords)
+func loadRecording(path string) ([]frame, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rr, err := statesync.NewReplayReader(bytes.NewReader(data), int64(len(data)))
+	if err == nil {
+		var frames []frame
+		err := rr.Frames(0, func(f statesync.ReplayFrame) bool {
+			frames = append(frames, frame{DiffRecord: f.DiffRecord, keyframe: f.Keyframe})
+			return true
+		})
+		return frames, err
+	}
+	if !errors.Is(err, statesync.ErrInvalidReplay) {
+		return nil, err
+	}
+
+	records, err := statesync.UnmarshalRecords(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s is neither a replay file nor a JSON record array: %w", path, err)
+	}
+	frames := make([]frame, len(records))
+	for i, r := range records {
+		frames[i] = frame{DiffRecord: r}
+	}
+	sort.SliceStable(frames, func(i, j int) bool { return frames[i].Seq < frames[j].Seq })
+	return frames, nil
+}
+
+// fieldDiff is a difference at a field path
+type fieldDiff struct {
+	Path string
+	A, B interface{}
+}
+
+// divergence is the first seq where the recordings differ
+type divergence struct {
+	Seq    uint64
+	Tick   uint64
+	Diffs  []fieldDiff // Field-level differences (nil without a schema)
+	Reason string      // Set when the recordings are compared by bytes
+}
+
+// replayState is the decoded state of one recording (root schema name -> fields)
+type replayState struct {
+	registry *statesync.SchemaRegistry
+	decoder  *statesync.Decoder
+	roots    map[string]map[string]interface{}
+}
+
+func newReplayState(registry *statesync.SchemaRegistry) *replayState {
+	return &replayState{
+		registry: registry,
+		decoder:  statesync.NewDecoder(registry),
+		roots:    make(map[string]map[string]interface{}),
+	}
+}
+
+// apply decodes a diff, keyframe or patch batch and applies it
+func (s *replayState) apply(f frame) error {
+	if len(f.Data) == 0 {
+		return nil // Only external inputs
+	}
+	patches, err := s.decoder.DecodeBatch(f.Data)
+	if err != nil {
+		return fmt.Errorf("seq %d: %w", f.Seq, err)
+	}
+	for _, patch := range patches {
+		schema := s.registry.Get(patch.SchemaID)
+		if schema == nil {
+			return fmt.Errorf("seq %d: unknown schema %d", f.Seq, patch.SchemaID)
+		}
+		if patch.Removed {
+			delete(s.roots, schema.Name)
+			continue
+		}
+		root := s.roots[schema.Name]
+		if root == nil {
+			root = make(map[string]interface{})
+			s.roots[schema.Name] = root
+		}
+		if err := statesync.ApplyPatch(root, patch, schema); err != nil {
+			return fmt.Errorf("seq %d: %w", f.Seq, err)
+		}
+	}
+	return nil
+}
+
+// compareRecordings walks both recordings in seq order and returns the first divergence.
+// Without a registry the encoded diffs are compared byte by byte.
+func compareRecordings(a, b []frame, registry *statesync.SchemaRegistry) (*divergence, error) {
+	var stateA, stateB *replayState
+	if registry != nil {
+		stateA, stateB = newReplayState(registry), newReplayState(registry)
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		seq := nextSeq(a, i, b, j)
+		var tick uint64
+		var diffsA, diffsB [][]byte
+		for ; i < len(a) && a[i].Seq == seq; i++ {
+			tick = a[i].Tick
+			if stateA != nil {
+				if err := stateA.apply(a[i]); err != nil {
+					return nil, fmt.Errorf("recording A: %w", err)
+				}
+			} else if !a[i].keyframe {
+				diffsA = append(diffsA, a[i].Data)
+			}
+		}
+		for ; j < len(b) && b[j].Seq == seq; j++ {
+			tick = b[j].Tick
+			if stateB != nil {
+				if err := stateB.apply(b[j]); err != nil {
+					return nil, fmt.Errorf("recording B: %w", err)
+				}
+			} else if !b[j].keyframe {
+				diffsB = append(diffsB, b[j].Data)
+			}
+		}
+
+		if registry == nil {
+			if !reflect.DeepEqual(diffsA, diffsB) {
+				return &divergence{Seq: seq, Tick: tick, Reason: "encoded diffs differ"}, nil
+			}
+			continue
+		}
+		var diffs []fieldDiff
+		diffValue("", toAny(stateA.roots), toAny(stateB.roots), &diffs)
+		if len(diffs) > 0 {
+			return &divergence{Seq: seq, Tick: tick, Diffs: diffs}, nil
+		}
+	}
+	return nil, nil
+}
+
+// nextSeq returns the lowest seq of the next frames of both recordings
+func nextSeq(a []frame, i int, b []frame, j int) uint64 {
+	switch {
+	case i >= len(a):
+		return b[j].Seq
+	case j >= len(b):
+		return a[i].Seq
+	case a[i].Seq < b[j].Seq:
+		return a[i].Seq
+	default:
+		return b[j].Seq
+	}
+}
+
+func toAny(roots map[string]map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(roots))
+	for k, v := range roots {
+		m[k] = v
+	}
+	return m
+}
+
+// diffValue appends the field paths where a and b differ
+func diffValue(path string, a, b interface{}, out *[]fieldDiff) {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			*out = append(*out, fieldDiff{Path: path, A: a, B: b})
+			return
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, ok := av[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			diffValue(p, av[k], bv[k], out)
+		}
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok {
+			*out = append(*out, fieldDiff{Path: path, A: a, B: b})
+			return
+		}
+		for i := 0; i < len(av) || i < len(bv); i++ {
+			p := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case i >= len(bv):
+				*out = append(*out, fieldDiff{Path: p, A: av[i]})
+			case i >= len(av):
+				*out = append(*out, fieldDiff{Path: p, B: bv[i]})
+			default:
+				diffValue(p, av[i], bv[i], out)
+			}
+		}
+	default:
+		if !reflect.DeepEqual(a, b) {
+			*out = append(*out, fieldDiff{Path: path, A: a, B: b})
+		}
+	}
+}
+
+// eventsAround returns the frames with events or external inputs within seq +/- context
+func eventsAround(frames []frame, seq, context uint64) []frame {
+	var result []frame
+	for _, f := range frames {
+		if len(f.Events)+len(f.Inputs) == 0 || f.Seq+context < seq || f.Seq > seq+context {
+			continue
+		}
+		result = append(result, f)
+	}
+	return result
+}
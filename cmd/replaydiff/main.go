@@ -0,0 +1,106 @@
+// replaydiff compares two recordings (e.g. production vs. a re-simulation) and reports
+// the first seq where the decoded states diverge.
+//
+// Usage:
+//
+//	replaydiff -schema=schema.json prod.replay resim.replay
+//
+// Recordings are replay files (statesync.CreateReplayFile) or JSON record arrays
+// (statesync.MarshalRecords). The schema is the output of schemagen -json; without it
+// the encoded diffs are compared byte by byte.
+//
+// Exit status is 0 if the recordings match, 1 if they diverge and 2 on errors.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mxkacsa/statesync"
+)
+
+var (
+	schemaFlag  = flag.String("schema", "", "schema JSON from schemagen -json (optional)")
+	contextFlag = flag.Uint64("context", 3, "show events within this many seqs of the divergence")
+	maxFlag     = flag.Int("max", 20, "maximum number of field differences to print (0 = all)")
+)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "replaydiff: expected two recordings")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	diverged, err := run(os.Stdout, flag.Arg(0), flag.Arg(1), *schemaFlag, *contextFlag, *maxFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "replaydiff: %v\n", err)
+		os.Exit(2)
+	}
+	if diverged {
+		os.Exit(1)
+	}
+}
+
+// run compares two recordings and writes a report. Returns true if they diverge.
+func run(w io.Writer, pathA, pathB, schemaPath string, context uint64, max int) (bool, error) {
+	var registry *statesync.SchemaRegistry
+	if schemaPath != "" {
+		var err error
+		if registry, err = loadSchemas(schemaPath); err != nil {
+			return false, err
+		}
+	}
+	a, err := loadRecording(pathA)
+	if err != nil {
+		return false, err
+	}
+	b, err := loadRecording(pathB)
+	if err != nil {
+		return false, err
+	}
+
+	div, err := compareRecordings(a, b, registry)
+	if err != nil {
+		return false, err
+	}
+	if div == nil {
+		fmt.Fprintf(w, "recordings match (%d and %d frames)\n", len(a), len(b))
+		return false, nil
+	}
+
+	fmt.Fprintf(w, "first divergence at seq %d (tick %d)\n", div.Seq, div.Tick)
+	if div.Reason != "" {
+		fmt.Fprintf(w, "  %s\n", div.Reason)
+	}
+	for i, d := range div.Diffs {
+		if max > 0 && i == max {
+			fmt.Fprintf(w, "  ... %d more\n", len(div.Diffs)-max)
+			break
+		}
+		fmt.Fprintf(w, "  %s: A=%v B=%v\n", d.Path, d.A, d.B)
+	}
+
+	for _, rec := range []struct {
+		name   string
+		frames []frame
+	}{{"A", a}, {"B", b}} {
+		events := eventsAround(rec.frames, div.Seq, context)
+		if len(events) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "events in %s around seq %d:\n", rec.name, div.Seq)
+		for _, f := range events {
+			for _, e := range f.Events {
+				fmt.Fprintf(w, "  seq %d tick %d [%s] %s (%d bytes)\n", f.Seq, f.Tick, f.Source, e.Type, len(e.Payload))
+			}
+			for _, in := range f.Inputs {
+				fmt.Fprintf(w, "  seq %d tick %d input [%s] %s\n", f.Seq, f.Tick, in.Source, in.Payload)
+			}
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mxkacsa/statesync"
+)
+
+type diffTestState struct {
+	changes *statesync.ChangeSet
+	Score   int64
+	Tags    []string
+}
+
+var diffTestSchema = statesync.NewSchemaBuilder("DiffTestState").
+	WithID(1).
+	Int64("Score").
+	Array("Tags", statesync.TypeString, nil).
+	Build()
+
+func (s *diffTestState) Changes() *statesync.ChangeSet { return s.changes }
+func (s *diffTestState) ClearChanges()                 { s.changes.Clear() }
+func (s *diffTestState) MarkAllDirty()                 { s.changes.MarkAll(1) }
+func (s *diffTestState) Schema() *statesync.Schema     { return diffTestSchema }
+func (s *diffTestState) GetFieldValue(index uint8) interface{} {
+	if index == 0 {
+		return s.Score
+	}
+	return s.Tags
+}
+
+// Fields are listed out of sync order: the sync index decides the wire index
+const diffTestSchemaJSON = `{"types": [{"name": "DiffTestState", "id": 1, "fields": [
+	{"name": "Tags", "type": "[]string", "syncIndex": 1},
+	{"name": "Secret", "type": "string", "noSync": true, "syncIndex": -1},
+	{"name": "Score", "type": "int64", "syncIndex": 0}
+]}]}`
+
+// writeRecording records 5 ticks (Score = seq) and returns the JSON file path.
+// At divergeAt the score is off by one and a tag is added.
+func writeRecording(t *testing.T, name string, divergeAt int64) string {
+	t.Helper()
+	state := &diffTestState{changes: statesync.NewChangeSet()}
+	session := statesync.NewTrackedSession[*diffTestState, any, string](statesync.NewTrackedState[*diffTestState, any](state, nil))
+	recorder := statesync.NewDiffRecorder()
+	session.SetHooks(statesync.RecordingHooks[*diffTestState, string](recorder))
+
+	for i := int64(1); i <= 5; i++ {
+		state.Score = i
+		state.changes.Mark(0, statesync.OpReplace)
+		if i == divergeAt {
+			state.Score++
+			state.Tags = append(state.Tags, "bug")
+			state.changes.Mark(1, statesync.OpReplace)
+		}
+		session.Tick()
+	}
+
+	records := recorder.Records()
+	if divergeAt > 0 {
+		records[divergeAt-1].Events = []statesync.Event{{Type: "Desync", Payload: []byte{1, 2}}}
+	}
+	data, err := statesync.MarshalRecords(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	os.WriteFile(path, data, 0644)
+	return path
+}
+
+func TestReplayDiff(t *testing.T) {
+	schemaPath := filepath.Join(t.TempDir(), "schema.json")
+	os.WriteFile(schemaPath, []byte(diffTestSchemaJSON), 0644)
+
+	prod := writeRecording(t, "prod.json", 0)
+	resim := writeRecording(t, "resim.json", 3)
+
+	var out bytes.Buffer
+	diverged, err := run(&out, prod, prod, schemaPath, 3, 20)
+	if err != nil || diverged {
+		t.Fatalf("identical recordings: diverged=%v err=%v\n%s", diverged, err, out.String())
+	}
+
+	out.Reset()
+	diverged, err = run(&out, prod, resim, schemaPath, 3, 20)
+	if err != nil || !diverged {
+		t.Fatalf("expected divergence, got diverged=%v err=%v", diverged, err)
+	}
+	report := out.String()
+	for _, want := range []string{
+		"first divergence at seq 3",
+		"DiffTestState.Score: A=3 B=4",
+		"DiffTestState.Tags: A=<nil> B=[bug]",
+		"seq 3 tick 0 [server] Desync (2 bytes)",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+
+	// Without a schema the encoded diffs are compared
+	out.Reset()
+	if diverged, _ := run(&out, prod, resim, "", 3, 20); !diverged || !strings.Contains(out.String(), "seq 3") {
+		t.Errorf("byte comparison should diverge at seq 3:\n%s", out.String())
+	}
+}
+
+func TestLoadSchemas_SyncIndexGap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	os.WriteFile(path, []byte(`{"types": [{"name": "S", "id": 1, "fields": [
+		{"name": "A", "type": "int64", "syncIndex": 0},
+		{"name": "B", "type": "int64", "syncIndex": 2}
+	]}]}`), 0644)
+	if _, err := loadSchemas(path); err == nil || !strings.Contains(err.Error(), "S.B: sync index 2, expected 1") {
+		t.Errorf("expected a sync index error, got %v", err)
+	}
+}
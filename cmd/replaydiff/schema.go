@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mxkacsa/statesync"
+)
+
+// schemaFile is the subset of the schemagen -json output needed to decode recordings
+type schemaFile struct {
+	Types []struct {
+		Name   string        `json:"name"`
+		ID     int           `json:"id"`
+		Fields []schemaField `json:"fields"`
+	} `json:"types"`
+}
+
+// schemaField is a field of a schemagen -json type
+type schemaField struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Key       string `json:"key"`
+	NoSync    bool   `json:"noSync"`
+	SyncIndex *int   `json:"syncIndex"` // nil in files without it: synced fields in order
+}
+
+// loadSchemas reads a schemagen -json file into a schema registry
+func loadSchemas(path string) (*statesync.SchemaRegistry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file schemaFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	// Create all schemas first so fields can reference types declared later
+	byName := make(map[string]*statesync.Schema)
+	for _, t := range file.Types {
+		byName[t.Name] = statesync.NewSchema(uint16(t.ID), t.Name)
+	}
+
+	registry := statesync.NewSchemaRegistry()
+	for _, t := range file.Types {
+		schema := byName[t.Name]
+		fields, err := syncedFields(t.Name, t.Fields)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for i, f := range fields {
+			field := statesync.FieldMeta{
+				Index:    uint16(i),
+				Name:     f.Name,
+				KeyField: f.Key,
+			}
+			switch elem, kind := elemType(f.Type); kind {
+			case statesync.TypeArray, statesync.TypeMap:
+				field.Type = kind
+				field.ElemType = fieldType(elem)
+				field.ChildSchema = byName[elem]
+			default:
+				field.Type = fieldType(f.Type)
+				if field.Type == statesync.TypeStruct {
+					field.ChildSchema = byName[strings.TrimPrefix(f.Type, "*")]
+				}
+			}
+			schema.AddField(field)
+		}
+		if t.ID > 0 {
+			registry.Register(schema)
+		}
+	}
+	return registry, nil
+}
+
+// syncedFields returns the synced fields of a type ordered by their sync index,
+// which must cover 0..n-1 like the indices schemagen assigns
+func syncedFields(typeName string, fields []schemaField) ([]schemaField, error) {
+	var synced []schemaField
+	for _, f := range fields {
+		if f.NoSync || (f.SyncIndex != nil && *f.SyncIndex < 0) {
+			continue
+		}
+		if f.SyncIndex == nil {
+			f.SyncIndex = new(int)
+			*f.SyncIndex = len(synced)
+		}
+		synced = append(synced, f)
+	}
+	sort.SliceStable(synced, func(i, j int) bool { return *synced[i].SyncIndex < *synced[j].SyncIndex })
+	for i, f := range synced {
+		if *f.SyncIndex != i {
+			return nil, fmt.Errorf("%s.%s: sync index %d, expected %d", typeName, f.Name, *f.SyncIndex, i)
+		}
+	}
+	return synced, nil
+}
+
+// elemType returns the element type and container kind of an array or map type
+func elemType(typ string) (string, statesync.FieldType) {
+	typ = strings.TrimPrefix(typ, "*")
+	if strings.HasPrefix(typ, "[]") && typ != "[]byte" {
+		return typ[2:], statesync.TypeArray
+	}
+	if strings.HasPrefix(typ, "map[") {
+		if i := strings.Index(typ, "]"); i > 0 {
+			return typ[i+1:], statesync.TypeMap
+		}
+	}
+	return "", statesync.TypeInvalid
+}
+
+// fieldType maps a schema type name to its wire type (matches schemagen)
+func fieldType(typ string) statesync.FieldType {
+	switch strings.TrimPrefix(typ, "*") {
+	case "int8":
+		return statesync.TypeInt8
+	case "int16":
+		return statesync.TypeInt16
+	case "int32":
+		return statesync.TypeInt32
+	case "int64", "int":
+		return statesync.TypeInt64
+	case "uint8":
+		return statesync.TypeUint8
+	case "uint16":
+		return statesync.TypeUint16
+	case "uint32":
+		return statesync.TypeUint32
+	case "uint64", "uint":
+		return statesync.TypeUint64
+	case "float32":
+		return statesync.TypeFloat32
+	case "float64":
+		return statesync.TypeFloat64
+	case "string", "uuid":
+		return statesync.TypeString
+	case "bool":
+		return statesync.TypeBool
+	case "bytes", "[]byte":
+		return statesync.TypeBytes
+	default:
+		return statesync.TypeStruct
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Compatibility is the impact of a schema change on clients built with the old schema
+type Compatibility string
+
+const (
+	CompatSafe     Compatibility = "safe"
+	CompatBreaking Compatibility = "breaking"
+)
+
+// CompatIssue is a single difference found by CheckCompat
+type CompatIssue struct {
+	Level   Compatibility
+	Type    string // Type name (new name if the type was renamed)
+	Field   string // Field name ("" for type-level changes)
+	Message string
+}
+
+func (i CompatIssue) String() string {
+	where := i.Type
+	if i.Field != "" {
+		where += "." + i.Field
+	}
+	return fmt.Sprintf("%-9s %s: %s", strings.ToUpper(string(i.Level)), where, i.Message)
+}
+
+// CompatReport lists the differences between two schema versions
+type CompatReport struct {
+	Issues []CompatIssue
+}
+
+// Breaking reports whether any change breaks old clients
+func (r *CompatReport) Breaking() bool {
+	for _, i := range r.Issues {
+		if i.Level == CompatBreaking {
+			return true
+		}
+	}
+	return false
+}
+
+// Write prints the issues (breaking first) and a summary line
+func (r *CompatReport) Write(w io.Writer) {
+	issues := append([]CompatIssue(nil), r.Issues...)
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Level == CompatBreaking && issues[j].Level != CompatBreaking
+	})
+	breaking := 0
+	for _, i := range issues {
+		if i.Level == CompatBreaking {
+			breaking++
+		}
+		fmt.Fprintln(w, i)
+	}
+	fmt.Fprintf(w, "%d breaking, %d safe change(s)\n", breaking, len(issues)-breaking)
+}
+
+func (r *CompatReport) add(level Compatibility, typ, field, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, CompatIssue{Level: level, Type: typ, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// CheckCompat compares two schema versions on the wire: can clients built with oldSchema
+// decode what a server built with newSchema sends? Types are matched by @id, then by name;
+// fields by sync index. Fields are decoded positionally, so added fields, reused indices,
+// type and @key changes break old clients, as do removed fields of nested types.
+// View, write permission, default and @noSync changes only affect the server.
+func CheckCompat(oldSchema, newSchema *SchemaFile) *CompatReport {
+	r := &CompatReport{}
+
+	// @id collisions in the new schema
+	byID := make(map[int][]string)
+	for _, t := range newSchema.Types {
+		if t.ID > 0 {
+			byID[t.ID] = append(byID[t.ID], t.Name)
+		}
+	}
+	for _, t := range newSchema.Types {
+		if names := byID[t.ID]; len(names) > 1 && names[0] == t.Name {
+			r.add(CompatBreaking, t.Name, "", "@id(%d) is used by %s", t.ID, strings.Join(names, ", "))
+		}
+	}
+
+	newByName := make(map[string]*TypeDef)
+	newByID := make(map[int]*TypeDef)
+	for _, t := range newSchema.Types {
+		newByName[t.Name] = t
+		if t.ID > 0 && newByID[t.ID] == nil {
+			newByID[t.ID] = t
+		}
+	}
+
+	// Match types and map old type names to new ones
+	matched := make(map[*TypeDef]*TypeDef) // old -> new
+	renamed := make(map[string]string)     // old name -> new name
+	used := make(map[*TypeDef]bool)
+	for _, old := range oldSchema.Types {
+		t := newByName[old.Name]
+		if old.ID > 0 {
+			if byID := newByID[old.ID]; byID != nil && byID.Name != old.Name {
+				if t != nil {
+					r.add(CompatBreaking, byID.Name, "", "@id(%d) is reused (it was %s, which now has @id(%d))", old.ID, old.Name, t.ID)
+					used[byID] = true
+					continue
+				}
+				t = byID
+				r.add(CompatSafe, t.Name, "", "renamed from %s (same @id)", old.Name)
+			} else if t != nil && t.ID != old.ID {
+				r.add(CompatBreaking, t.Name, "", "@id changed from %d to %d", old.ID, t.ID)
+			}
+		}
+		if t == nil {
+			r.add(CompatSafe, old.Name, "", "type removed")
+			continue
+		}
+		matched[old] = t
+		renamed[old.Name] = t.Name
+		used[t] = true
+	}
+	for _, t := range newSchema.Types {
+		if !used[t] {
+			r.add(CompatSafe, t.Name, "", "type added")
+		}
+	}
+
+	// Types old clients decode inside other types (positionally, without a field count)
+	nested := make(map[string]bool)
+	for _, t := range oldSchema.Types {
+		for _, f := range t.Fields {
+			if name := structTypeOf(f.Type); name != "" && !f.NoSync {
+				nested[name] = true
+			}
+		}
+	}
+
+	for _, old := range oldSchema.Types {
+		if t := matched[old]; t != nil {
+			compareFields(r, old, t, renamed, nested[old.Name])
+		}
+	}
+	return r
+}
+
+// compareFields compares the synced fields of two versions of a type by sync index
+func compareFields(r *CompatReport, old, t *TypeDef, renamed map[string]string, nested bool) {
+	oldFields, newFields := syncedFields(old), syncedFields(t)
+	for i := 0; i < len(oldFields) || i < len(newFields); i++ {
+		switch {
+		case i >= len(newFields):
+			f := oldFields[i]
+			if nested {
+				r.add(CompatBreaking, t.Name, f.Name, "removed (index %d) from a nested type: old clients misread the data after it", i)
+			} else {
+				r.add(CompatSafe, t.Name, f.Name, "removed (index %d): old clients keep its last value", i)
+			}
+		case i >= len(oldFields):
+			f := newFields[i]
+			r.add(CompatBreaking, t.Name, f.Name, "added at index %d: old clients can't decode it", i)
+		default:
+			compareField(r, t, i, oldFields[i], newFields[i], renamed)
+		}
+	}
+}
+
+// compareField compares the fields at the same sync index of two versions of a type
+func compareField(r *CompatReport, t *TypeDef, index int, o, n *FieldDef, renamed map[string]string) {
+	if oldWire, newWire := wireType(o.Type, renamed), wireType(n.Type, nil); oldWire != newWire {
+		r.add(CompatBreaking, t.Name, n.Name, "index %d changed type from %s to %s", index, oldWire, newWire)
+		return
+	}
+	if o.Name != n.Name {
+		r.add(CompatBreaking, t.Name, n.Name, "index %d is reused (it was %s): old clients apply it to the old field", index, o.Name)
+	}
+	switch {
+	case o.Key == n.Key:
+	case o.Key == "":
+		r.add(CompatBreaking, t.Name, n.Name, "@key(%s) added", n.Key)
+	case n.Key == "":
+		r.add(CompatBreaking, t.Name, n.Name, "@key(%s) removed", o.Key)
+	default:
+		r.add(CompatBreaking, t.Name, n.Name, "@key changed from %s to %s", o.Key, n.Key)
+	}
+	if strings.Join(o.Views, ",") != strings.Join(n.Views, ",") {
+		r.add(CompatSafe, t.Name, n.Name, "views changed from [%s] to [%s]", strings.Join(o.Views, ","), strings.Join(n.Views, ","))
+	}
+	if o.Write != n.Write {
+		r.add(CompatSafe, t.Name, n.Name, "write permission changed from %q to %q", o.Write, n.Write)
+	}
+}
+
+// syncedFields returns the fields of a type in sync index order (without @noSync fields)
+func syncedFields(t *TypeDef) []*FieldDef {
+	var fields []*FieldDef
+	for _, f := range t.Fields {
+		if !f.NoSync {
+			fields = append(fields, f)
+		}
+	}
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].SyncIndex < fields[j].SyncIndex })
+	return fields
+}
+
+// wireType describes how a field type is encoded (old struct type names are mapped
+// through renamed). Map keys are always sent as strings.
+func wireType(typ string, renamed map[string]string) string {
+	pt := ParseType(typ)
+	switch {
+	case pt.IsArray:
+		return "[]" + wireType(pt.ElemType, renamed)
+	case pt.IsMap:
+		return "map[string]" + wireType(pt.ElemType, renamed)
+	}
+	enum := FieldTypeEnum(typ)
+	if enum != "TypeStruct" {
+		return strings.ToLower(strings.TrimPrefix(enum, "Type"))
+	}
+	if name, ok := renamed[pt.BaseType]; ok {
+		return name
+	}
+	return pt.BaseType
+}
+
+// runCheckCompat compares the schema files in args (old, new) and prints the report to w
+// (errors to errw). Returns the exit code: 0 if compatible, 1 on breaking changes, 2 on errors.
+func runCheckCompat(args []string, w, errw io.Writer) int {
+	if len(args) != 2 {
+		fmt.Fprintln(errw, "schemagen: -check-compat requires two files: old.schema new.schema")
+		return 2
+	}
+	old, err := parseFile(args[0])
+	if err != nil {
+		fmt.Fprintf(errw, "schemagen: %v\n", err)
+		return 2
+	}
+	schema, err := parseFile(args[1])
+	if err != nil {
+		fmt.Fprintf(errw, "schemagen: %v\n", err)
+		return 2
+	}
+
+	report := CheckCompat(old, schema)
+	report.Write(w)
+	if report.Breaking() {
+		return 1
+	}
+	return 0
+}
@@ -186,6 +186,81 @@ func jsonGoType(f *FieldDef) string {
 	return GoType(f.Type)
 }
 
+// decodedValueExpr returns the expression converting a decoded value (named value)
+// to the Go type of a field, for the generated SetFieldValue
+func decodedValueExpr(t string) string {
+	pt := ParseType(t)
+	switch {
+	case pt.IsArray:
+		return "statesync.DecodedSlice(value, " + decodedElemFunc(pt.ElemType) + ")"
+	case pt.IsMap:
+		return "statesync.DecodedMap(value, " + decodedElemFunc(pt.ElemType) + ")"
+	default:
+		return decodedElemFunc(t) + "(value)"
+	}
+}
+
+// decodedElemFunc returns the function converting a decoded value to a non-container type
+func decodedElemFunc(t string) string {
+	if IsPrimitive(t) || ParseType(t).IsPointer {
+		return "statesync.DecodedValue[" + GoType(t) + "]"
+	}
+	return "statesync.DecodedStruct[" + GoType(t) + "]"
+}
+
+// wideChangeSetMethods maps ChangeSet methods to their variants for field indices past 255
+var wideChangeSetMethods = map[string]string{
+	"Mark":             "MarkWide",
+	"MarkAll":          "MarkAllWide",
+	"IsFieldDirty":     "IsWideFieldDirty",
+	"GetOrCreateArray": "GetOrCreateWideArray",
+	"GetOrCreateMap":   "GetOrCreateWideMap",
+}
+
+// changeSetMethod returns the ChangeSet method to call for a field index: ChangeSet
+// methods take uint8 indices, indices past 255 need the Wide variant
+func changeSetMethod(name string, index int) string {
+	if index >= 256 {
+		return wideChangeSetMethods[name]
+	}
+	return name
+}
+
+// cloneExpr returns the expression deep-copying the value v of a field type, for the generated Clone
+func cloneExpr(t, v string) string {
+	pt := ParseType(t)
+	switch {
+	case t == "bytes":
+		return "statesync.CloneSlice(" + v + ", nil)"
+	case pt.IsArray:
+		return "statesync.CloneSlice(" + v + ", " + cloneElemFunc(pt.ElemType) + ")"
+	case pt.IsMap:
+		return "statesync.CloneMap(" + v + ", " + cloneElemFunc(pt.ElemType) + ")"
+	case IsPrimitive(t) || t == "byte":
+		return v
+	case pt.IsPointer && IsPrimitive(t[1:]):
+		return cloneElemFunc(t) + "(" + v + ")"
+	case pt.IsPointer:
+		return v + ".Clone()"
+	}
+	return "*" + v + ".Clone()"
+}
+
+// cloneElemFunc returns the function deep-copying an array element or map value
+// ("nil" if it can be copied as is)
+func cloneElemFunc(t string) string {
+	pt := ParseType(t)
+	switch {
+	case t != "bytes" && (IsPrimitive(t) || t == "byte"):
+		return "nil"
+	case pt.IsPointer && !IsPrimitive(t[1:]):
+		return "(" + GoType(t) + ").Clone"
+	case pt.IsPointer:
+		return "func(p " + GoType(t) + ") " + GoType(t) + " {\n\t\tif p == nil {\n\t\t\treturn nil\n\t\t}\n\t\tv := *p\n\t\treturn &v\n\t}"
+	}
+	return "func(v " + GoType(t) + ") " + GoType(t) + " { return " + cloneExpr(t, "v") + " }"
+}
+
 // mapValueIsStruct reports whether a field is a map whose value type is a
 // non-primitive, non-pointer struct — the shape that triggers the
 // pointer-receiver MarshalJSON bug described on jsonGoType.
@@ -203,6 +278,26 @@ func mapValueIsStruct(f *FieldDef) bool {
 	return true
 }
 
+// eventWriteExpr returns the EventPayloadEncoder call that writes an event field
+func eventWriteExpr(f *FieldDef) string {
+	method, wireType := eventPayloadType(f.Type)
+	value := "e." + f.Name
+	if GoType(f.Type) != wireType {
+		value = wireType + "(" + value + ")"
+	}
+	return "Write" + method + "(" + value + ")"
+}
+
+// eventReadExpr returns the EventPayloadDecoder call that reads an event field
+func eventReadExpr(f *FieldDef) string {
+	method, wireType := eventPayloadType(f.Type)
+	expr := "d.Read" + method + "()"
+	if goT := GoType(f.Type); goT != wireType {
+		expr = goT + "(" + expr + ")"
+	}
+	return expr
+}
+
 // getRootSchemas returns only root schemas
 func getRootSchemas(schema *SchemaFile) []*TypeDef {
 	var roots []*TypeDef
@@ -230,7 +325,11 @@ func GenerateGo(schema *SchemaFile) ([]byte, error) {
 		"hasConfigDefaults": hasConfigDefaults,
 		"hasAutoGenUUID":    hasAutoGenUUID,
 		"mapValueIsStruct":  mapValueIsStruct,
+		"decodedValue":      decodedValueExpr,
+		"clone":             cloneExpr,
 		"getRootSchemas":    getRootSchemas,
+		"eventWrite":        eventWriteExpr,
+		"eventRead":         eventReadExpr,
 		"isRoot":            func(t *TypeDef) bool { return t.Role == RoleRoot },
 		"isActiveByDefault": func(t *TypeDef) bool { return t.DefaultState == "active" },
 		"hasComplexFields":  typeHasComplexFields,
@@ -254,6 +353,16 @@ func GenerateGo(schema *SchemaFile) ([]byte, error) {
 			}
 			return max
 		},
+		"csMethod": changeSetMethod,
+		// Types with sync indices past 255 implement statesync.WideTrackable
+		"isWide": func(t *TypeDef) bool {
+			for _, f := range t.Fields {
+				if !f.NoSync && f.SyncIndex >= 256 {
+					return true
+				}
+			}
+			return false
+		},
 		"toCamelCase":       toCamelCase,
 		"jsonOmitEmpty":     jsonOmitEmpty,
 		"jsonGoType":        jsonGoType,
@@ -359,12 +468,17 @@ func New{{$t.Name}}() *{{$t.Name}} {
 	return t
 }
 
-// ResetToDefaults resets all fields to their default values
+// ResetToDefaults resets all fields to their default values.
+// A zero {{$t.Name}} also gets its ChangeSet and schema.
 func (t *{{$t.Name}}) ResetToDefaults() {
 	{{- if needsMutex $t}}
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	{{- end}}
+	if t.changes == nil {
+		t.changes = statesync.NewChangeSet()
+		t.schema = {{$t.Name}}Schema()
+	}
 	{{- range $i, $f := $t.Fields}}
 	{{- $pt := parseType $f.Type}}
 	{{- if and (isRoot $t) $pt.IsMap}}
@@ -375,13 +489,16 @@ func (t *{{$t.Name}}) ResetToDefaults() {
 	t.{{lower $f.Name}} = {{goDefaultValue $f}}
 	{{- end}}
 	{{- end}}
-	{{$max := maxSyncIndex $t}}{{if ge $max 0}}t.changes.MarkAll({{$max}}){{end}}
+	{{$max := maxSyncIndex $t}}{{if ge $max 0}}t.changes.{{csMethod "MarkAll" $max}}({{$max}}){{end}}
 }
 
 // {{$t.Name}}Schema returns the schema for {{$t.Name}}
 func {{$t.Name}}Schema() *statesync.Schema {
 	return statesync.NewSchemaBuilder("{{$t.Name}}").
 		WithID({{$t.ID}}).
+		{{- if $t.OwnerField}}
+		Owner("{{$t.OwnerField}}").
+		{{- end}}
 		{{- range $i, $f := $t.Fields}}
 		{{- if isSynced $f}}
 		{{- $pt := parseType $f.Type}}
@@ -397,6 +514,12 @@ func {{$t.Name}}Schema() *statesync.Schema {
 		{{- $ft := fieldType $f.Type}}
 		{{- if eq $ft "TypeInt8"}}Int8{{else if eq $ft "TypeInt16"}}Int16{{else if eq $ft "TypeInt32"}}Int32{{else if eq $ft "TypeInt64"}}Int64{{else if eq $ft "TypeUint8"}}Uint8{{else if eq $ft "TypeUint16"}}Uint16{{else if eq $ft "TypeUint32"}}Uint32{{else if eq $ft "TypeUint64"}}Uint64{{else if eq $ft "TypeFloat32"}}Float32{{else if eq $ft "TypeFloat64"}}Float64{{else if eq $ft "TypeString"}}String{{else if eq $ft "TypeBool"}}Bool{{else if eq $ft "TypeBytes"}}Bytes{{else}}Struct{{end}}("{{$f.Name}}"{{if eq $ft "TypeStruct"}}, {{$f.Type}}Schema(){{end}}).
 		{{- end}}
+		{{- if $f.Views}}
+		Views({{range $j, $v := $f.Views}}{{if $j}}, {{end}}"{{$v}}"{{end}}).
+		{{- end}}
+		{{- if $f.Write}}
+		Write("{{$f.Write}}").
+		{{- end}}
 		{{- end}}
 		{{- end}}
 		Build()
@@ -407,9 +530,17 @@ func {{$t.Name}}Schema() *statesync.Schema {
 func (t *{{$t.Name}}) Schema() *statesync.Schema     { return t.schema }
 func (t *{{$t.Name}}) Changes() *statesync.ChangeSet { return t.changes }
 func (t *{{$t.Name}}) ClearChanges()                 { t.changes.Clear() }
-func (t *{{$t.Name}}) MarkAllDirty()                 { {{$max := maxSyncIndex $t}}{{if ge $max 0}}t.changes.MarkAll({{$max}}){{end}} }
+func (t *{{$t.Name}}) MarkAllDirty()                 { {{$max := maxSyncIndex $t}}{{if ge $max 0}}t.changes.{{csMethod "MarkAll" $max}}({{$max}}){{end}} }
+
+{{if isWide $t -}}
+func (t *{{$t.Name}}) GetFieldValue(index uint8) interface{} {
+	return t.GetWideFieldValue(uint16(index))
+}
 
+func (t *{{$t.Name}}) GetWideFieldValue(index uint16) interface{} {
+{{- else -}}
 func (t *{{$t.Name}}) GetFieldValue(index uint8) interface{} {
+{{- end}}
 	{{- if needsMutex $t}}
 	t.mu.RLock()
 	defer t.mu.RUnlock()
@@ -446,9 +577,27 @@ func (t *{{$t.Name}}) GetFieldValue(index uint8) interface{} {
 	return nil
 }
 
-{{if not (hasComplexFields $t)}}
+// SetFieldValue sets a field from a decoded value (see statesync.FieldSetter)
+func (t *{{$t.Name}}) SetFieldValue(index uint16, value interface{}) error {
+	switch index {
+	{{- range $i, $f := $t.Fields}}
+	{{- if isSynced $f}}
+	case {{$f.SyncIndex}}:
+		v, err := {{decodedValue $f.Type}}
+		if err != nil {
+			return err
+		}
+		t.Set{{$f.Name}}(v)
+	{{- end}}
+	{{- end}}
+	}
+	return nil
+}
+
+{{if not (or (hasComplexFields $t) (isWide $t))}}
 // FastEncoder implementation - zero allocation encoding
 // Generated only for types with all primitive fields (no maps/arrays/structs)
+// and at most 256 of them
 
 func (t *{{$t.Name}}) EncodeChangesTo(e *statesync.Encoder) {
 	{{- if needsMutex $t}}
@@ -461,7 +610,7 @@ func (t *{{$t.Name}}) EncodeChangesTo(e *statesync.Encoder) {
 	count := 0
 	{{- range $i, $f := $t.Fields}}
 	{{- if isSynced $f}}
-	if changes.IsFieldDirty({{$f.SyncIndex}}) { count++ }
+	if changes.{{csMethod "IsFieldDirty" $f.SyncIndex}}({{$f.SyncIndex}}) { count++ }
 	{{- end}}
 	{{- end}}
 	e.WriteChangeCount(count)
@@ -469,7 +618,7 @@ func (t *{{$t.Name}}) EncodeChangesTo(e *statesync.Encoder) {
 	// Encode each changed field directly (no interface{} boxing)
 	{{- range $i, $f := $t.Fields}}
 	{{- if isSynced $f}}
-	if changes.IsFieldDirty({{$f.SyncIndex}}) {
+	if changes.{{csMethod "IsFieldDirty" $f.SyncIndex}}({{$f.SyncIndex}}) {
 		e.WriteFieldHeader({{$f.SyncIndex}}, statesync.OpReplace)
 		e.{{encoderMethod $f.Type}}(t.{{lower $f.Name}})
 	}
@@ -533,11 +682,11 @@ func (t *{{$t.Name}}) Set{{$f.Name}}(v {{$goT}}) {
 	{{- else if and (isPrimitive $f.Type) (ne $f.Type "bytes")}}
 	if t.{{$lower}} != v {
 		t.{{$lower}} = v
-		t.changes.Mark({{$f.SyncIndex}}, statesync.OpReplace)
+		t.changes.{{csMethod "Mark" $f.SyncIndex}}({{$f.SyncIndex}}, statesync.OpReplace)
 	}
 	{{- else}}
 	t.{{$lower}} = v
-	t.changes.Mark({{$f.SyncIndex}}, statesync.OpReplace)
+	t.changes.{{csMethod "Mark" $f.SyncIndex}}({{$f.SyncIndex}}, statesync.OpReplace)
 	{{- end}}
 }
 {{end}}{{end}}
@@ -550,7 +699,7 @@ func (t *{{$t.Name}}) Set{{$f.Name}}(v {{$goT}}) {
 	defer t.mu.Unlock()
 	{{- end}}
 	t.{{$lower}} = v
-	t.changes.Mark({{$f.SyncIndex}}, statesync.OpReplace)
+	t.changes.{{csMethod "Mark" $f.SyncIndex}}({{$f.SyncIndex}}, statesync.OpReplace)
 }
 
 // Append{{$f.Name}} adds an element to the slice
@@ -560,7 +709,7 @@ func (t *{{$t.Name}}) Append{{$f.Name}}(v {{$pt.ElemType}}) {
 	defer t.mu.Unlock()
 	{{- end}}
 	t.{{$lower}} = append(t.{{$lower}}, v)
-	arr := t.changes.GetOrCreateArray({{$f.SyncIndex}})
+	arr := t.changes.{{csMethod "GetOrCreateArray" $f.SyncIndex}}({{$f.SyncIndex}})
 	arr.MarkAdd(len(t.{{$lower}})-1, v)
 }
 
@@ -572,7 +721,7 @@ func (t *{{$t.Name}}) Remove{{$f.Name}}At(index int) {
 	{{- end}}
 	if index >= 0 && index < len(t.{{$lower}}) {
 		t.{{$lower}} = append(t.{{$lower}}[:index], t.{{$lower}}[index+1:]...)
-		arr := t.changes.GetOrCreateArray({{$f.SyncIndex}})
+		arr := t.changes.{{csMethod "GetOrCreateArray" $f.SyncIndex}}({{$f.SyncIndex}})
 		arr.MarkRemove(index)
 	}
 }
@@ -585,7 +734,7 @@ func (t *{{$t.Name}}) Update{{$f.Name}}At(index int, v {{$pt.ElemType}}) {
 	{{- end}}
 	if index >= 0 && index < len(t.{{$lower}}) {
 		t.{{$lower}}[index] = v
-		arr := t.changes.GetOrCreateArray({{$f.SyncIndex}})
+		arr := t.changes.{{csMethod "GetOrCreateArray" $f.SyncIndex}}({{$f.SyncIndex}})
 		arr.MarkReplace(index, v)
 	}
 }
@@ -621,7 +770,7 @@ func (t *{{$t.Name}}) Set{{$f.Name}}(v {{$goT}}) {
 	defer t.mu.Unlock()
 	{{- end}}
 	t.{{$lower}} = v
-	t.changes.Mark({{$f.SyncIndex}}, statesync.OpReplace)
+	t.changes.{{csMethod "Mark" $f.SyncIndex}}({{$f.SyncIndex}}, statesync.OpReplace)
 }
 
 // Set{{$f.Name}}Key sets a map key
@@ -635,7 +784,7 @@ func (t *{{$t.Name}}) Set{{$f.Name}}Key(key {{$pt.KeyType}}, v {{$pt.ElemType}})
 	}
 	_, existed := t.{{$lower}}[key]
 	t.{{$lower}}[key] = v
-	m := t.changes.GetOrCreateMap({{$f.SyncIndex}})
+	m := t.changes.{{csMethod "GetOrCreateMap" $f.SyncIndex}}({{$f.SyncIndex}})
 {{- if not (isPrimitive $pt.ElemType)}}
 	vp := v
 	if existed {
@@ -661,7 +810,7 @@ func (t *{{$t.Name}}) Delete{{$f.Name}}Key(key {{$pt.KeyType}}) {
 	if t.{{$lower}} != nil {
 		if _, ok := t.{{$lower}}[key]; ok {
 			delete(t.{{$lower}}, key)
-			m := t.changes.GetOrCreateMap({{$f.SyncIndex}})
+			m := t.changes.{{csMethod "GetOrCreateMap" $f.SyncIndex}}({{$f.SyncIndex}})
 			m.MarkRemove(key)
 		}
 	}
@@ -704,7 +853,7 @@ func (t *{{$t.Name}}) Modify{{$f.Name}}Key(key {{$pt.KeyType}}, fn func(*{{$pt.E
 	}
 	t.{{$lower}}[key] = v
 	{{- if isSynced $f}}
-	m := t.changes.GetOrCreateMap({{$f.SyncIndex}})
+	m := t.changes.{{csMethod "GetOrCreateMap" $f.SyncIndex}}({{$f.SyncIndex}})
 	vp := v
 	m.MarkReplace(key, &vp)
 	{{- end}}
@@ -753,8 +902,54 @@ func (s *{{$t.Name}}) ShallowClone() *{{$t.Name}} {
 
 	return clone
 }
+
+// WithFieldsFrom returns a shallow copy of {{$t.Name}} sharing its ChangeSet, with the
+// given fields taken from src (see statesync.FieldCopier).
+func (s *{{$t.Name}}) WithFieldsFrom(src *{{$t.Name}}, fields []uint16) *{{$t.Name}} {
+	s.mu.RLock()
+	clone := &{{$t.Name}}{
+		changes: s.changes,
+		schema:  s.schema,
+		{{- range $i, $f := $t.Fields}}
+		{{lower $f.Name}}: s.{{lower $f.Name}},
+		{{- end}}
+	}
+	s.mu.RUnlock()
+
+	src.mu.RLock()
+	defer src.mu.RUnlock()
+	for _, idx := range fields {
+		switch idx {
+		{{- range $i, $f := $t.Fields}}
+		{{- if isSynced $f}}
+		case {{$f.SyncIndex}}:
+			clone.{{lower $f.Name}} = src.{{lower $f.Name}}
+		{{- end}}
+		{{- end}}
+		}
+	}
+	return clone
+}
 {{end}}
 
+// Clone returns a deep copy of {{$t.Name}}, including its ChangeSet (see statesync.Cloner)
+func (s *{{$t.Name}}) Clone() *{{$t.Name}} {
+	if s == nil {
+		return nil
+	}
+	{{- if needsMutex $t}}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	{{- end}}
+	return &{{$t.Name}}{
+		changes: s.changes.Clone(),
+		schema:  s.schema,
+		{{- range $t.Fields}}
+		{{lower .Name}}: {{clone .Type (print "s." (lower .Name))}},
+		{{- end}}
+	}
+}
+
 // ---- JSON serialization ----
 
 type {{lowerFirst $t.Name}}JSON struct {
@@ -836,6 +1031,72 @@ func (t *{{$t.Name}}) UnmarshalJSON(data []byte) error {
 
 {{end}}
 
+{{- if .Events}}
+
+// ==================================================
+// Events - typed payloads with binary encoding
+// ==================================================
+{{range $e := .Events}}
+// {{$e.Name}}EventType is the event type identifier of {{$e.Name}}Event
+const {{$e.Name}}EventType = "{{$e.Name}}"
+
+// {{$e.Name}}Event is the payload of the {{$e.Name}} event
+type {{$e.Name}}Event struct {
+	{{- range $e.Fields}}
+	{{.Name}} {{goType .Type}}
+	{{- end}}
+}
+
+// Encode encodes the payload with the binary event payload encoder
+func (e {{$e.Name}}Event) Encode() []byte {
+	enc := statesync.NewEventPayloadEncoder()
+	{{- range $e.Fields}}
+	enc.{{eventWrite .}}
+	{{- end}}
+	return enc.Bytes()
+}
+
+// Event returns the encoded {{$e.Name}} event
+func (e {{$e.Name}}Event) Event() statesync.Event {
+	return statesync.Event{Type: {{$e.Name}}EventType, Payload: e.Encode()}
+}
+
+// Decode{{$e.Name}}Event decodes a {{$e.Name}} event payload
+func Decode{{$e.Name}}Event(payload []byte) ({{$e.Name}}Event, error) {
+	var e {{$e.Name}}Event
+	{{- if $e.Fields}}
+	d := statesync.NewEventPayloadDecoder(payload)
+	{{- range $e.Fields}}
+	e.{{.Name}} = {{eventRead .}}
+	{{- end}}
+	return e, d.Err()
+	{{- else}}
+	return e, nil
+	{{- end}}
+}
+
+// Emit{{$e.Name}} sends the {{$e.Name}} event to all clients
+func Emit{{$e.Name}}[ID comparable](emitter statesync.EventEmitter[ID], e {{$e.Name}}Event) error {
+	return emitter.EmitRaw(e.Event())
+}
+
+// Emit{{$e.Name}}To sends the {{$e.Name}} event to a specific client
+func Emit{{$e.Name}}To[ID comparable](emitter statesync.EventEmitter[ID], clientID ID, e {{$e.Name}}Event) error {
+	return emitter.EmitRawTo(clientID, e.Event())
+}
+
+// Emit{{$e.Name}}Except sends the {{$e.Name}} event to all clients except one
+func Emit{{$e.Name}}Except[ID comparable](emitter statesync.EventEmitter[ID], exceptID ID, e {{$e.Name}}Event) error {
+	return emitter.EmitRawExcept(exceptID, e.Event())
+}
+
+// Emit{{$e.Name}}ToMany sends the {{$e.Name}} event to multiple specific clients
+func Emit{{$e.Name}}ToMany[ID comparable](emitter statesync.EventEmitter[ID], clientIDs []ID, e {{$e.Name}}Event) error {
+	return emitter.EmitRawToMany(clientIDs, e.Event())
+}
+{{end}}
+{{- end}}
+
 // ==================================================
 // Schema Registry - manages root schemas activation
 // ==================================================
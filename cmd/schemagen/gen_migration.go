@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+)
+
+// FieldChangeKind describes how a field changed between two schema versions
+type FieldChangeKind string
+
+const (
+	FieldAdded   FieldChangeKind = "added"
+	FieldRemoved FieldChangeKind = "removed"
+	FieldRenamed FieldChangeKind = "renamed" // Guessed: a removed and an added field with the same type
+	FieldRetyped FieldChangeKind = "retyped"
+)
+
+// FieldChange is a single field difference
+type FieldChange struct {
+	Kind    FieldChangeKind
+	Name    string // New name (old name for removed fields)
+	OldName string // Renamed fields only
+	OldType string
+	NewType string
+}
+
+// TypeDiff lists the field changes of a type between two schema versions
+type TypeDiff struct {
+	Name    string
+	OldName string // Set if the type was renamed (matched by @id)
+	Changes []FieldChange
+	Nested  []*FieldDef // Fields whose struct type (or element type) changed
+}
+
+// DiffSchemas compares two schema files and returns the types whose snapshot JSON changed.
+// Types are matched by name, then by @id. Renames are guessed by pairing removed and
+// added fields of the same type, preferring the same sync index.
+func DiffSchemas(oldSchema, newSchema *SchemaFile) []*TypeDiff {
+	oldByName := make(map[string]*TypeDef)
+	oldByID := make(map[int]*TypeDef)
+	for _, t := range oldSchema.Types {
+		oldByName[t.Name] = t
+		if t.ID > 0 {
+			oldByID[t.ID] = t
+		}
+	}
+
+	diffs := make(map[string]*TypeDiff)
+	for _, t := range newSchema.Types {
+		old := oldByName[t.Name]
+		if old == nil && t.ID > 0 {
+			old = oldByID[t.ID]
+		}
+		if old == nil {
+			continue // New type: no old snapshots
+		}
+		d := &TypeDiff{Name: t.Name, Changes: diffFields(old, t)}
+		if old.Name != t.Name {
+			d.OldName = old.Name
+		}
+		diffs[t.Name] = d
+	}
+
+	// Propagate nested changes until nothing changes
+	changed := func(name string) bool {
+		d := diffs[name]
+		return d != nil && (len(d.Changes) > 0 || len(d.Nested) > 0)
+	}
+	for progress := true; progress; {
+		progress = false
+		for _, t := range newSchema.Types {
+			d := diffs[t.Name]
+			if d == nil {
+				continue
+			}
+			for _, f := range t.Fields {
+				if containsField(d.Nested, f) || !changed(structTypeOf(f.Type)) {
+					continue
+				}
+				d.Nested = append(d.Nested, f)
+				progress = true
+			}
+		}
+	}
+
+	var result []*TypeDiff
+	for _, t := range newSchema.Types {
+		if changed(t.Name) {
+			result = append(result, diffs[t.Name])
+		}
+	}
+	return result
+}
+
+// diffFields compares the fields of two versions of a type
+func diffFields(oldType, newType *TypeDef) []FieldChange {
+	oldFields := make(map[string]*FieldDef)
+	for _, f := range oldType.Fields {
+		oldFields[f.Name] = f
+	}
+	newFields := make(map[string]*FieldDef)
+	for _, f := range newType.Fields {
+		newFields[f.Name] = f
+	}
+
+	var changes []FieldChange
+	var removed, added []*FieldDef
+	for _, f := range oldType.Fields {
+		if nf, ok := newFields[f.Name]; !ok {
+			removed = append(removed, f)
+		} else if nf.Type != f.Type {
+			changes = append(changes, FieldChange{Kind: FieldRetyped, Name: f.Name, OldType: f.Type, NewType: nf.Type})
+		}
+	}
+	for _, f := range newType.Fields {
+		if _, ok := oldFields[f.Name]; !ok {
+			added = append(added, f)
+		}
+	}
+
+	// Pair removed/added fields of the same type as renames
+	renamed := make(map[*FieldDef]*FieldDef) // removed -> added
+	used := make(map[*FieldDef]bool)
+	for _, sameIndex := range []bool{true, false} {
+		for _, r := range removed {
+			if renamed[r] != nil {
+				continue
+			}
+			for _, a := range added {
+				if !used[a] && a.Type == r.Type && (!sameIndex || a.SyncIndex == r.SyncIndex) {
+					renamed[r] = a
+					used[a] = true
+					break
+				}
+			}
+		}
+	}
+
+	for _, r := range removed {
+		if a := renamed[r]; a != nil {
+			changes = append(changes, FieldChange{Kind: FieldRenamed, Name: a.Name, OldName: r.Name, OldType: r.Type, NewType: a.Type})
+		} else {
+			changes = append(changes, FieldChange{Kind: FieldRemoved, Name: r.Name, OldType: r.Type})
+		}
+	}
+	for _, a := range added {
+		if !used[a] {
+			changes = append(changes, FieldChange{Kind: FieldAdded, Name: a.Name, NewType: a.Type})
+		}
+	}
+	return changes
+}
+
+// structTypeOf returns the struct type referenced by a field type ("" for primitives)
+func structTypeOf(typ string) string {
+	pt := ParseType(typ)
+	name := pt.BaseType
+	if pt.IsArray || pt.IsMap {
+		name = ParseType(pt.ElemType).BaseType
+	}
+	if IsPrimitive(name) {
+		return ""
+	}
+	return name
+}
+
+func containsField(fields []*FieldDef, f *FieldDef) bool {
+	for _, x := range fields {
+		if x == f {
+			return true
+		}
+	}
+	return false
+}
+
+// migrationFuncName returns the name of the generated migration function of a type
+func migrationFuncName(typeName string, fromVersion int) string {
+	return fmt.Sprintf("migrate%sV%d", typeName, fromVersion)
+}
+
+// GenerateMigrations generates migration stubs for snapshots saved with oldSchema.
+// Top-level changed types (not used by another type) are registered from fromVersion
+// to fromVersion+1 by a generated RegisterMigrationsV<fromVersion> function taking a
+// *statesync.MigrationRegistry; nested types are migrated by their parents.
+// The output is meant to be reviewed and edited.
+func GenerateMigrations(oldSchema, newSchema *SchemaFile, fromVersion int) ([]byte, error) {
+	diffs := DiffSchemas(oldSchema, newSchema)
+
+	nested := make(map[string]bool)
+	for _, t := range newSchema.Types {
+		for _, f := range t.Fields {
+			if name := structTypeOf(f.Type); name != "" {
+				nested[name] = true
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by schemagen -migrate-from. Review and edit before use.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", newSchema.Package)
+	if len(diffs) == 0 {
+		buf.WriteString("// No snapshot changes between the schema versions.\n")
+		return format.Source(buf.Bytes())
+	}
+	buf.WriteString("import \"github.com/mxkacsa/statesync\"\n\n")
+
+	var roots []string
+	for _, d := range diffs {
+		if !nested[d.Name] {
+			roots = append(roots, d.Name)
+		}
+	}
+	sort.Strings(roots)
+	if len(roots) > 0 {
+		fmt.Fprintf(&buf, "// RegisterMigrationsV%d registers the migrations from version %d to %d\n", fromVersion, fromVersion, fromVersion+1)
+		fmt.Fprintf(&buf, "func RegisterMigrationsV%d(r *statesync.MigrationRegistry) {\n", fromVersion)
+		for _, name := range roots {
+			fmt.Fprintf(&buf, "\tr.Register(%q, %d, %s)\n", name, fromVersion, migrationFuncName(name, fromVersion))
+		}
+		buf.WriteString("}\n")
+	}
+
+	for _, d := range diffs {
+		fn := migrationFuncName(d.Name, fromVersion)
+		fmt.Fprintf(&buf, "\n// %s upgrades %s snapshot data from version %d to %d\n", fn, d.Name, fromVersion, fromVersion+1)
+		fmt.Fprintf(&buf, "func %s(state map[string]any) error {\n", fn)
+		if d.OldName != "" {
+			fmt.Fprintf(&buf, "\t// Type renamed: %s -> %s\n", d.OldName, d.Name)
+		}
+		for _, c := range d.Changes {
+			switch c.Kind {
+			case FieldRenamed:
+				fmt.Fprintf(&buf, "\t// Renamed: %s -> %s (guessed from the matching type %s)\n", c.OldName, c.Name, c.NewType)
+				fmt.Fprintf(&buf, "\tstatesync.RenameField(state, %q, %q)\n", toCamelCase(c.OldName), toCamelCase(c.Name))
+			case FieldRemoved:
+				fmt.Fprintf(&buf, "\t// Removed: %s %s\n", c.Name, c.OldType)
+				fmt.Fprintf(&buf, "\tdelete(state, %q)\n", toCamelCase(c.Name))
+			case FieldAdded:
+				fmt.Fprintf(&buf, "\t// Added: %s %s (zero value unless set here)\n", c.Name, c.NewType)
+			case FieldRetyped:
+				fmt.Fprintf(&buf, "\t// TODO: %s changed type from %s to %s; convert state[%q] if needed\n", c.Name, c.OldType, c.NewType, toCamelCase(c.Name))
+			}
+		}
+		for _, f := range d.Nested {
+			child := migrationFuncName(structTypeOf(f.Type), fromVersion)
+			key := toCamelCase(f.Name)
+			pt := ParseType(f.Type)
+			if pt.IsArray || pt.IsMap {
+				fmt.Fprintf(&buf, "\tif err := statesync.MigrateEach(state[%q], %s); err != nil {\n\t\treturn err\n\t}\n", key, child)
+			} else {
+				fmt.Fprintf(&buf, "\tif obj, ok := state[%q].(map[string]any); ok {\n\t\tif err := %s(obj); err != nil {\n\t\t\treturn err\n\t\t}\n\t}\n", key, child)
+			}
+		}
+		buf.WriteString("\treturn nil\n}\n")
+	}
+
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format error: %w\n%s", err, buf.String())
+	}
+	return formatted, nil
+}
@@ -79,13 +79,17 @@ func tsZeroValue(t string) string {
 // GenerateTS generates TypeScript code from a schema file
 func GenerateTS(schema *SchemaFile) ([]byte, error) {
 	tmpl, err := template.New("ts").Funcs(template.FuncMap{
-		"tsType":            TSType,
-		"tsFieldType":       TSFieldType,
-		"parseType":         ParseType,
-		"isPrimitive":       IsPrimitive,
-		"lower":             strings.ToLower,
-		"tsDefaultValue":    tsDefaultValue,
-		"tsZeroValue":       tsZeroValue,
+		"tsType":         TSType,
+		"tsFieldType":    TSFieldType,
+		"parseType":      ParseType,
+		"isPrimitive":    IsPrimitive,
+		"lower":          strings.ToLower,
+		"tsDefaultValue": tsDefaultValue,
+		"tsZeroValue":    tsZeroValue,
+		"tsEventRead": func(f *FieldDef) string {
+			method, _ := eventPayloadType(f.Type)
+			return "r.read" + method + "()"
+		},
 		"isRoot":            func(t *TypeDef) bool { return t.Role == RoleRoot },
 		"isActiveByDefault": func(t *TypeDef) bool { return t.DefaultState == "active" },
 		"hasRootSchemas": func(schema *SchemaFile) bool {
@@ -111,7 +115,7 @@ func GenerateTS(schema *SchemaFile) ([]byte, error) {
 
 const tsTemplate = `// Code generated by schemagen. DO NOT EDIT.
 
-import { Schema, SchemaRegistry, SyncState, defineSchema, FieldType, Decoder } from '@statediff/client';
+import { Schema, SchemaRegistry, SyncState, defineSchema, FieldType, Decoder{{if .Events}}, EventReceiver, EventPayloadReader{{end}} } from '@statediff/client';
 
 {{range .Types}}
 // {{.Name}} interface
@@ -190,6 +194,55 @@ export type {{.Name}}View = '{{.Name}}';
 export type ViewType = {{range $i, $v := .Views}}{{if $i}} | {{end}}'{{$v.Name}}'{{end}};
 {{end}}
 
+{{if .Events}}
+// ==================================================
+// Events - typed payload decoders and listeners
+// ==================================================
+{{range $e := .Events}}
+// {{$e.Name}} event payload
+export interface {{$e.Name}}Event {
+{{- range $e.Fields}}
+  {{.Name}}: {{tsType .Type}};
+{{- end}}
+}
+
+export function decode{{$e.Name}}Event({{if not $e.Fields}}_{{end}}payload: Uint8Array): {{$e.Name}}Event {
+{{- if $e.Fields}}
+  const r = new EventPayloadReader(payload);
+  return {
+{{- range $e.Fields}}
+    {{.Name}}: {{tsEventRead .}},
+{{- end}}
+  };
+{{- else}}
+  return {};
+{{- end}}
+}
+{{end}}
+// Payload types by event name
+export interface EventTypes {
+{{- range .Events}}
+  {{.Name}}: {{.Name}}Event;
+{{- end}}
+}
+
+const eventDecoders: { [K in keyof EventTypes]: (payload: Uint8Array) => EventTypes[K] } = {
+{{- range .Events}}
+  {{.Name}}: decode{{.Name}}Event,
+{{- end}}
+};
+
+// Subscribe to a typed event on an EventReceiver
+export function onEvent<K extends keyof EventTypes>(
+  receiver: EventReceiver,
+  type: K,
+  fn: (event: EventTypes[K]) => void
+): () => void {
+  return receiver.onEvent((event) => {
+    if (event.type === type) fn(eventDecoders[type](event.payload));
+  });
+}
+{{end}}
 // Re-export types for convenience
 export { FieldType, Operation, Decoder, SchemaRegistry, SyncState } from '@statediff/client';
 
@@ -16,17 +16,27 @@
 //	    Secret   string    @view(admin)
 //	}
 //
-//	@id(2)
+//	@id(2) @owner(ID)
 //	type Player {
 //	    ID     string
 //	    Name   string
-//	    Score  int64
-//	    Hand   []int32  @view(owner)
+//	    Score  int64    @write(server)
+//	    Hand   []int32  @view(owner) @write(owner)
 //	}
 //
 //	view all {}
 //	view admin { includes: all }
 //	view owner { includes: all }
+//
+//	event CardPlayed { PlayerID string; CardID int32 }
+//
+// Snapshot migration stubs (field renames/removals since an older schema):
+//
+//	schemagen -input=game.schema -migrate-from=game_v1.schema -from-version=1 -migrations=migrations_v1.go
+//
+// Wire compatibility check for CI (exit code 1 on breaking changes, 2 on errors):
+//
+//	schemagen -check-compat game_v1.schema game.schema
 package main
 
 import (
@@ -48,11 +58,21 @@ var (
 	tsOutput   = flag.String("ts", "", "TypeScript output file (optional)")
 	jsOutput   = flag.String("js", "", "JavaScript schema output file (optional)")
 	jsonOutput = flag.String("json", "", "JSON schema output file (optional)")
+
+	migrateFrom  = flag.String("migrate-from", "", "older .schema file to generate snapshot migrations from (optional)")
+	migrationOut = flag.String("migrations", "", "migration stubs output file (requires -migrate-from)")
+	fromVersion  = flag.Int("from-version", 1, "snapshot version of the older schema")
+
+	checkCompat = flag.Bool("check-compat", false, "compare two .schema files (old new) and report breaking changes")
 )
 
 func main() {
 	flag.Parse()
 
+	if *checkCompat {
+		os.Exit(runCheckCompat(flag.Args(), os.Stdout, os.Stderr))
+	}
+
 	if *inputFile == "" {
 		fmt.Fprintln(os.Stderr, "schemagen: -input flag is required")
 		flag.Usage()
@@ -135,12 +155,49 @@ func main() {
 		fmt.Printf("Generated: %s\n", *jsonOutput)
 	}
 
-	if *goOutput == "" && *tsOutput == "" && *jsOutput == "" && *jsonOutput == "" {
-		fmt.Fprintln(os.Stderr, "schemagen: no output specified, use -go, -ts, -js, or -json")
+	// Generate snapshot migration stubs
+	if *migrationOut != "" {
+		if *migrateFrom == "" {
+			fmt.Fprintln(os.Stderr, "schemagen: -migrations requires -migrate-from")
+			os.Exit(1)
+		}
+		old, err := parseFile(*migrateFrom)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "schemagen: %v\n", err)
+			os.Exit(1)
+		}
+		code, err := GenerateMigrations(old, schema, *fromVersion)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "schemagen: migration generation error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(*migrationOut, code, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "schemagen: cannot write migrations output: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Generated: %s\n", *migrationOut)
+	}
+
+	if *goOutput == "" && *tsOutput == "" && *jsOutput == "" && *jsonOutput == "" && *migrationOut == "" {
+		fmt.Fprintln(os.Stderr, "schemagen: no output specified, use -go, -ts, -js, -json or -migrations")
 		os.Exit(1)
 	}
 }
 
+// parseFile parses a .schema file
+func parseFile(path string) (*SchemaFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open %s: %w", path, err)
+	}
+	defer f.Close()
+	schema, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse error in %s: %w", path, err)
+	}
+	return schema, nil
+}
+
 // GenerateJSON outputs the parsed schema as JSON (for debugging)
 func GenerateJSON(schema *SchemaFile) ([]byte, error) {
 	return jsonMarshalIndent(schema)
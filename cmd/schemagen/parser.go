@@ -71,6 +71,15 @@ func (p *Parser) parse() (*SchemaFile, error) {
 			continue
 		}
 
+		if strings.HasPrefix(line, "event ") {
+			eventDef, err := p.parseEvent(line)
+			if err != nil {
+				return nil, err
+			}
+			p.file.Events = append(p.file.Events, eventDef)
+			continue
+		}
+
 		if strings.HasPrefix(line, "view ") {
 			viewDef, err := p.parseView(line)
 			if err != nil {
@@ -93,6 +102,7 @@ type TypeAnnotations struct {
 	ID           int
 	Role         SchemaRole
 	DefaultState string // "active" or "inactive"
+	OwnerField   string // @owner(Field)
 }
 
 func (p *Parser) parseTypeAnnotations(line string) (*TypeAnnotations, error) {
@@ -119,6 +129,18 @@ func (p *Parser) parseTypeAnnotations(line string) (*TypeAnnotations, error) {
 		ann.ID = id
 	}
 
+	// Parse @owner(Field)
+	if idx := strings.Index(line, "@owner("); idx != -1 {
+		end := strings.Index(line[idx:], ")") + idx
+		if end <= idx {
+			return nil, fmt.Errorf("invalid @owner syntax")
+		}
+		ann.OwnerField = strings.TrimSpace(line[idx+7 : end])
+		if ann.OwnerField == "" {
+			return nil, fmt.Errorf("@owner requires a field name")
+		}
+	}
+
 	// Parse @helper
 	if strings.Contains(line, "@helper") {
 		ann.Role = RoleHelper
@@ -157,6 +179,7 @@ func (p *Parser) parseTypeWithAnnotations(ann *TypeAnnotations) (*TypeDef, error
 		// Apply annotations
 		typeDef.Role = ann.Role
 		typeDef.DefaultState = ann.DefaultState
+		typeDef.OwnerField = ann.OwnerField
 		return typeDef, nil
 	}
 	return nil, p.errorf("unexpected end of file after type annotations")
@@ -233,7 +256,7 @@ func (p *Parser) parseTypeFromLine(line string, id int) (*TypeDef, error) {
 }
 
 func (p *Parser) parseField(line string) (*FieldDef, error) {
-	// Parse: "name  type  @key(ID) @view(owner) @default(value) @auto(uuid)"
+	// Parse: "name  type  @key(ID) @view(owner) @write(owner) @default(value) @auto(uuid)"
 	// Split by whitespace first
 	parts := strings.Fields(line)
 	if len(parts) < 2 {
@@ -274,6 +297,20 @@ func (p *Parser) parseField(line string) (*FieldDef, error) {
 			continue
 		}
 
+		if strings.HasPrefix(ann, "@write(") {
+			end := strings.Index(ann, ")")
+			if end == -1 {
+				return nil, p.errorf("invalid @write annotation: %s", ann)
+			}
+			switch perm := WritePermission(strings.TrimSpace(ann[7:end])); perm {
+			case WriteServer, WriteOwner:
+				field.Write = perm
+			default:
+				return nil, p.errorf("invalid @write permission %q (expected server or owner)", perm)
+			}
+			continue
+		}
+
 		if strings.HasPrefix(ann, "@default(") {
 			end := strings.LastIndex(ann, ")")
 			if end == -1 {
@@ -341,6 +378,78 @@ func (p *Parser) parseField(line string) (*FieldDef, error) {
 	return field, nil
 }
 
+func (p *Parser) parseEvent(line string) (*EventDef, error) {
+	// Parse: "event Name { A string; B int32 }" or a multi-line body
+	rest := strings.TrimSpace(strings.TrimPrefix(line, "event "))
+
+	nameEnd := strings.IndexAny(rest, " {")
+	if nameEnd == -1 {
+		return nil, p.errorf("invalid event definition: %s", line)
+	}
+	name := rest[:nameEnd]
+	if !isValidIdent(name) {
+		return nil, p.errorf("invalid event name: %s", name)
+	}
+
+	event := &EventDef{
+		Name:   name,
+		Fields: make([]*FieldDef, 0),
+	}
+
+	open := strings.Index(rest, "{")
+	if open == -1 {
+		return nil, p.errorf("expected '{' after event %s", name)
+	}
+	body := rest[open+1:]
+	closed := strings.Contains(body, "}")
+	if closed {
+		body = body[:strings.Index(body, "}")]
+	}
+	if err := p.parseEventFields(event, body); err != nil {
+		return nil, err
+	}
+
+	for !closed && p.scanner.Scan() {
+		p.line++
+		fieldLine := strings.TrimSpace(p.scanner.Text())
+		if fieldLine == "" || strings.HasPrefix(fieldLine, "//") {
+			continue
+		}
+		if idx := strings.Index(fieldLine, "}"); idx != -1 {
+			fieldLine = fieldLine[:idx]
+			closed = true
+		}
+		if err := p.parseEventFields(event, fieldLine); err != nil {
+			return nil, err
+		}
+	}
+	if !closed {
+		return nil, p.errorf("unexpected end of file in event %s", name)
+	}
+
+	return event, nil
+}
+
+// parseEventFields parses ";"-separated event fields
+func (p *Parser) parseEventFields(event *EventDef, body string) error {
+	for _, part := range strings.Split(body, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field, err := p.parseField(part)
+		if err != nil {
+			return err
+		}
+		if method, _ := eventPayloadType(field.Type); method == "" {
+			return p.errorf("event %s: field %s has unsupported type %s (events support primitive types only)", event.Name, field.Name, field.Type)
+		}
+		field.SyncIndex = len(event.Fields)
+		event.Fields = append(event.Fields, field)
+	}
+	return nil
+}
+
 func (p *Parser) parseView(line string) (*ViewDef, error) {
 	// Parse: "view name { includes: other }" or "view name {}"
 	rest := strings.TrimPrefix(line, "view ")
@@ -2,9 +2,10 @@ package main
 
 // SchemaFile represents a parsed .schema file
 type SchemaFile struct {
-	Package string     `json:"package"`
-	Types   []*TypeDef `json:"types"`
-	Views   []*ViewDef `json:"views,omitempty"`
+	Package string      `json:"package"`
+	Types   []*TypeDef  `json:"types"`
+	Views   []*ViewDef  `json:"views,omitempty"`
+	Events  []*EventDef `json:"events,omitempty"`
 }
 
 // SchemaRole indicates whether a type is a root (activatable) or helper type
@@ -22,7 +23,7 @@ type TypeDef struct {
 	Fields       []*FieldDef `json:"fields"`
 	Role         SchemaRole  `json:"role,omitempty"`         // helper or root (default: helper)
 	DefaultState string      `json:"defaultState,omitempty"` // For root: "active" or "inactive" (default: inactive)
-	OwnerField   string      `json:"ownerField,omitempty"`   // Field that holds the owner player ID (for @write(owner) checks)
+	OwnerField   string      `json:"ownerField,omitempty"`   // Field that holds the owner player ID (@owner, for @view(owner) and @write(owner))
 }
 
 // IsRoot returns true if this is an activatable root schema
@@ -82,6 +83,12 @@ type ViewDef struct {
 	Includes []string `json:"includes,omitempty"` // Views this view includes
 }
 
+// EventDef represents an event declaration (binary payload, primitive fields only)
+type EventDef struct {
+	Name   string      `json:"name"`
+	Fields []*FieldDef `json:"fields"`
+}
+
 // ParsedType holds parsed type information
 type ParsedType struct {
 	IsArray   bool
@@ -148,6 +155,32 @@ func IsPrimitive(t string) bool {
 	return false
 }
 
+// eventPayloadType returns the EventPayloadEncoder/Decoder method suffix and its
+// Go value type for an event field type ("" if the type can't be an event field)
+func eventPayloadType(t string) (method, wireType string) {
+	switch t {
+	case "int8", "int16", "int32":
+		return "Int32", "int32"
+	case "int", "int64":
+		return "Int64", "int64"
+	case "uint8", "uint16", "uint32":
+		return "Uint32", "uint32"
+	case "uint", "uint64":
+		return "Uint64", "uint64"
+	case "float32":
+		return "Float32", "float32"
+	case "float64":
+		return "Float64", "float64"
+	case "string", "uuid":
+		return "String", "string"
+	case "bool":
+		return "Bool", "bool"
+	case "bytes":
+		return "Bytes", "[]byte"
+	}
+	return "", ""
+}
+
 // GoType converts a schema type to Go type
 func GoType(t string) string {
 	pt := ParseType(t)
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -147,6 +151,119 @@ view owner { includes: all }
 	}
 }
 
+func TestGenerateGoProjections(t *testing.T) {
+	input := `
+package game
+
+@id(1) @owner(ID)
+type Player {
+    ID     string
+    Secret string  @view(admin)
+    Hand   []int32 @view(owner,admin)
+}
+`
+	schema, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if schema.Types[0].OwnerField != "ID" {
+		t.Errorf("expected owner field 'ID', got %q", schema.Types[0].OwnerField)
+	}
+
+	code, err := GenerateGo(schema)
+	if err != nil {
+		t.Fatalf("Go generation failed: %v", err)
+	}
+	for _, want := range []string{
+		`Owner("ID").`,
+		`String("Secret").
+		Views("admin").`,
+		`Views("owner", "admin").`,
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("generated code missing %q", want)
+		}
+	}
+}
+
+func TestGenerateGoWritePermissions(t *testing.T) {
+	input := `
+package game
+
+@id(1) @owner(ID)
+type Player {
+    ID    string  @write(server)
+    Gold  int64   @write(server)
+    Name  string  @write(owner)
+    Notes string
+}
+`
+	schema, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	fields := schema.Types[0].Fields
+	if fields[1].Write != WriteServer || fields[2].Write != WriteOwner || fields[3].Write != WriteAnyone {
+		t.Errorf("unexpected write permissions: %q %q %q", fields[1].Write, fields[2].Write, fields[3].Write)
+	}
+
+	code, err := GenerateGo(schema)
+	if err != nil {
+		t.Fatalf("Go generation failed: %v", err)
+	}
+	for _, want := range []string{
+		`Int64("Gold").
+		Write("server").`,
+		`String("Name").
+		Write("owner").`,
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("generated code missing %q", want)
+		}
+	}
+
+	if _, err := Parse(strings.NewReader("package game\n\ntype Player {\n    Gold int64 @write(admin)\n}\n")); err == nil {
+		t.Error("expected error for unknown write permission")
+	}
+}
+
+func TestGenerateGoWideType(t *testing.T) {
+	var input strings.Builder
+	input.WriteString("package game\n\n@id(1)\ntype Config {\n")
+	for i := 0; i < 300; i++ {
+		fmt.Fprintf(&input, "    F%d int32\n", i)
+	}
+	input.WriteString("}\n")
+	schema, err := Parse(strings.NewReader(input.String()))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if got := schema.Types[0].Fields[299].SyncIndex; got != 299 {
+		t.Fatalf("F299 SyncIndex = %d, want 299", got)
+	}
+
+	code, err := GenerateGo(schema)
+	if err != nil {
+		t.Fatalf("Go generation failed: %v", err)
+	}
+	for _, want := range []string{
+		"return t.GetWideFieldValue(uint16(index))",
+		"func (t *Config) GetWideFieldValue(index uint16) interface{} {",
+		"case 299:",
+		"t.changes.MarkAllWide(299)",
+		"t.changes.Mark(255, statesync.OpReplace)",
+		"t.changes.MarkWide(256, statesync.OpReplace)",
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("generated code missing %q", want)
+		}
+	}
+	// Generated FastEncoder headers are byte-sized
+	if strings.Contains(string(code), "EncodeChangesTo") {
+		t.Error("expected no FastEncoder for a type with more than 256 fields")
+	}
+}
+
 func TestParseTypeWithoutID(t *testing.T) {
 	input := `
 package game
@@ -337,6 +454,87 @@ type Player {
 	}
 }
 
+func TestGenerateGoFieldCopierAndSetter(t *testing.T) {
+	input := `
+package game
+
+@id(1) @root
+type GameState {
+    Round int32
+    Phase string
+}
+`
+	schema, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	code, err := GenerateGo(schema)
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	codeStr := string(code)
+	checks := []string{
+		"func (s *GameState) WithFieldsFrom(src *GameState, fields []uint16) *GameState {",
+		"changes: s.changes,",
+		"case 1:\n\t\t\tclone.phase = src.phase",
+		"func (t *GameState) SetFieldValue(index uint16, value interface{}) error {",
+		"v, err := statesync.DecodedValue[int32](value)",
+	}
+	for _, check := range checks {
+		if !strings.Contains(codeStr, check) {
+			t.Errorf("generated code missing: %s", check)
+		}
+	}
+}
+
+func TestGenerateGoClone(t *testing.T) {
+	input := `
+package game
+
+@id(2)
+type Player {
+    ID string
+    Hand []int32
+    Data bytes
+}
+
+@id(1) @root
+type GameState {
+    Round int32
+    Players []Player @key(ID)
+    ByName map[string]Player
+}
+`
+	schema, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	code, err := GenerateGo(schema)
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+
+	codeStr := string(code)
+	checks := []string{
+		"func (s *GameState) Clone() *GameState {",
+		"func (s *Player) Clone() *Player {",
+		"changes: s.changes.Clone(),",
+		"round:   s.round,",
+		"players: statesync.CloneSlice(s.players, func(v Player) Player { return *v.Clone() }),",
+		"byname:  statesync.CloneMap(s.byname, func(v Player) Player { return *v.Clone() }),",
+		"hand:    statesync.CloneSlice(s.hand, nil),",
+		"data:    statesync.CloneSlice(s.data, nil),",
+	}
+	for _, check := range checks {
+		if !strings.Contains(codeStr, check) {
+			t.Errorf("generated code missing: %s", check)
+		}
+	}
+}
+
 func TestGenerateGoArrayMethods(t *testing.T) {
 	input := `
 package game
@@ -1795,3 +1993,455 @@ type Item {
 	// Helper type should NOT auto-init maps (nil is fine)
 	// Player has no map fields so this is implicitly tested
 }
+
+func TestParseEvents(t *testing.T) {
+	input := `
+package game
+
+event CardPlayed { PlayerID string; CardID int32 }
+
+event RoundEnded {
+    Winner uuid
+    Score  int64; Perfect bool
+}
+
+event Ping {}
+`
+	schema, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(schema.Events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(schema.Events))
+	}
+
+	card := schema.Events[0]
+	if card.Name != "CardPlayed" || len(card.Fields) != 2 {
+		t.Fatalf("unexpected CardPlayed event: %+v", card)
+	}
+	if card.Fields[1].Name != "CardID" || card.Fields[1].Type != "int32" {
+		t.Errorf("unexpected CardID field: %+v", card.Fields[1])
+	}
+
+	round := schema.Events[1]
+	if len(round.Fields) != 3 || round.Fields[2].Name != "Perfect" {
+		t.Errorf("expected 3 RoundEnded fields, got %+v", round.Fields)
+	}
+
+	if len(schema.Events[2].Fields) != 0 {
+		t.Errorf("expected empty Ping event, got %+v", schema.Events[2].Fields)
+	}
+}
+
+func TestParseEvents_Errors(t *testing.T) {
+	tests := map[string]string{
+		"non-primitive field": "event Bad { Players []Player }",
+		"missing brace":       "event Bad",
+		"unterminated":        "event Bad {\n  A string\n",
+	}
+	for name, input := range tests {
+		if _, err := Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected parse error", name)
+		}
+	}
+}
+
+func TestGenerateGoEvents(t *testing.T) {
+	input := `
+package game
+
+event CardPlayed { PlayerID string; CardID int32; Slot uint8 }
+`
+	schema, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	code, err := GenerateGo(schema)
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	codeStr := string(code)
+
+	checks := []string{
+		`const CardPlayedEventType = "CardPlayed"`,
+		"type CardPlayedEvent struct",
+		"enc.WriteString(e.PlayerID)",
+		"enc.WriteUint32(uint32(e.Slot))",
+		"e.Slot = uint8(d.ReadUint32())",
+		"func DecodeCardPlayedEvent(payload []byte) (CardPlayedEvent, error)",
+		"func EmitCardPlayed[ID comparable](emitter statesync.EventEmitter[ID], e CardPlayedEvent) error",
+		"func EmitCardPlayedTo[ID comparable]",
+		"func EmitCardPlayedExcept[ID comparable]",
+		"return emitter.EmitRawExcept(exceptID, e.Event())",
+		"func EmitCardPlayedToMany[ID comparable]",
+		"return emitter.EmitRawToMany(clientIDs, e.Event())",
+	}
+	for _, check := range checks {
+		if !strings.Contains(codeStr, check) {
+			t.Errorf("generated code missing: %s", check)
+		}
+	}
+}
+
+func TestGenerateTSEvents(t *testing.T) {
+	input := `
+package game
+
+@id(1) @root(active)
+type GameState {
+    Round int32
+}
+
+event CardPlayed { PlayerID string; CardID int32 }
+`
+	schema, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	code, err := GenerateTS(schema)
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	codeStr := string(code)
+
+	checks := []string{
+		"EventReceiver, EventPayloadReader } from '@statediff/client'",
+		"export interface CardPlayedEvent {",
+		"CardID: r.readInt32(),",
+		"export function decodeCardPlayedEvent(payload: Uint8Array): CardPlayedEvent",
+		"CardPlayed: CardPlayedEvent;",
+		"export function onEvent<K extends keyof EventTypes>(",
+	}
+	for _, check := range checks {
+		if !strings.Contains(codeStr, check) {
+			t.Errorf("generated code missing: %s", check)
+		}
+	}
+
+	// Schemas without events don't import the event helpers
+	schema.Events = nil
+	code, _ = GenerateTS(schema)
+	if strings.Contains(string(code), "EventPayloadReader") {
+		t.Error("expected no event code without events")
+	}
+}
+
+func TestGenerateMigrations(t *testing.T) {
+	oldSchema, err := Parse(strings.NewReader(`
+package game
+
+@id(1)
+type GameState {
+    Round   int32
+    Legacy  bool
+    Players []Player
+}
+
+@id(2)
+type Player {
+    ID  string
+    Hits int64
+}
+`))
+	if err != nil {
+		t.Fatalf("parse old: %v", err)
+	}
+	newSchema, err := Parse(strings.NewReader(`
+package game
+
+@id(1)
+type GameState {
+    Round   int64
+    Players []Player
+    Winner  string
+}
+
+@id(2)
+type Player {
+    ID      string
+    Health  int64
+}
+`))
+	if err != nil {
+		t.Fatalf("parse new: %v", err)
+	}
+
+	diffs := DiffSchemas(oldSchema, newSchema)
+	if len(diffs) != 2 {
+		t.Fatalf("expected 2 changed types, got %d", len(diffs))
+	}
+
+	code, err := GenerateMigrations(oldSchema, newSchema, 2)
+	if err != nil {
+		t.Fatalf("GenerateMigrations: %v", err)
+	}
+	out := string(code)
+	for _, want := range []string{
+		`func RegisterMigrationsV2(r *statesync.MigrationRegistry) {`,
+		`r.Register("GameState", 2, migrateGameStateV2)`,
+		`delete(state, "legacy")`,
+		`// Added: Winner string`,
+		`// TODO: Round changed type from int32 to int64`,
+		`statesync.RenameField(state, "hits", "health")`,
+		`statesync.MigrateEach(state["players"], migratePlayerV2)`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `Register("Player"`) {
+		t.Error("nested types should be migrated by their parent, not registered")
+	}
+}
+
+func TestCheckCompat(t *testing.T) {
+	old := `
+package game
+
+@id(1)
+type GameState {
+    Round   int32
+    Players []Player @key(ID)
+    Secret  string
+}
+
+@id(2) @owner(ID)
+type Player {
+    ID    string
+    Score int64
+    Notes string
+}
+
+@id(3)
+type Lobby {
+    Name string
+}
+`
+	tests := []struct {
+		name     string
+		schema   string
+		breaking []string // Substrings of the expected breaking issues
+		safe     []string // Substrings of the expected safe issues
+	}{
+		{"unchanged", old, nil, nil},
+		{"server-only changes", strings.NewReplacer(
+			"Secret  string", "Secret  string @view(admin)",
+			"Score int64", "Score int64 @write(server) @default(5)",
+			"Name string", "Name string\n    Token string @noSync",
+		).Replace(old), nil, []string{"GameState.Secret: views changed", "Player.Score: write permission changed"}},
+		{"field added", strings.Replace(old, "Notes string", "Notes string\n    Gold int32", 1),
+			[]string{"Player.Gold: added at index 3"}, nil},
+		{"nested field removed", strings.Replace(old, "    Notes string\n", "", 1),
+			[]string{"Player.Notes: removed (index 2) from a nested type"}, nil},
+		{"root field removed", strings.Replace(old, "    Secret  string\n", "", 1),
+			nil, []string{"GameState.Secret: removed (index 2)"}},
+		{"index reused", strings.Replace(old, "Notes string", "Bio string", 1),
+			[]string{"Player.Bio: index 2 is reused (it was Notes)"}, nil},
+		{"type changed", strings.Replace(old, "Score int64", "Score string", 1),
+			[]string{"Player.Score: index 1 changed type from int64 to string"}, nil},
+		{"key changed", strings.Replace(old, "@key(ID)", "@key(Score)", 1),
+			[]string{"GameState.Players: @key changed from ID to Score"}, nil},
+		{"key removed", strings.Replace(old, " @key(ID)", "", 1),
+			[]string{"GameState.Players: @key(ID) removed"}, nil},
+		{"type renamed by @id", strings.NewReplacer("type Player", "type Pawn", "[]Player", "[]Pawn").Replace(old),
+			nil, []string{"Pawn: renamed from Player"}},
+		{"@id reused", strings.Replace(old, "@id(3)", "@id(4)", 1) + "\n@id(3)\ntype Chat {\n    Text string\n}\n",
+			[]string{"Chat: @id(3) is reused (it was Lobby, which now has @id(4))"}, nil},
+		{"@id collision", strings.Replace(old, "@id(3)", "@id(2)", 1),
+			[]string{"@id(2) is used by Player, Lobby"}, nil},
+		{"type removed", strings.Replace(old, "@id(3)\ntype Lobby {\n    Name string\n}\n", "", 1),
+			nil, []string{"Lobby: type removed"}},
+	}
+	for _, tt := range tests {
+		oldSchema, err := Parse(strings.NewReader(old))
+		if err != nil {
+			t.Fatalf("parse old: %v", err)
+		}
+		newSchema, err := Parse(strings.NewReader(tt.schema))
+		if err != nil {
+			t.Fatalf("%s: parse new: %v", tt.name, err)
+		}
+		report := CheckCompat(oldSchema, newSchema)
+
+		var out strings.Builder
+		report.Write(&out)
+		if report.Breaking() != (len(tt.breaking) > 0) {
+			t.Errorf("%s: Breaking() = %v\n%s", tt.name, report.Breaking(), out.String())
+		}
+		hasIssue := func(level Compatibility, want string) bool {
+			for _, i := range report.Issues {
+				if i.Level == level && strings.Contains(i.String(), want) {
+					return true
+				}
+			}
+			return false
+		}
+		for _, want := range tt.breaking {
+			if !hasIssue(CompatBreaking, want) {
+				t.Errorf("%s: missing breaking issue %q\n%s", tt.name, want, out.String())
+			}
+		}
+		for _, want := range tt.safe {
+			if !hasIssue(CompatSafe, want) {
+				t.Errorf("%s: missing safe issue %q\n%s", tt.name, want, out.String())
+			}
+		}
+		if len(tt.breaking) == 0 && len(tt.safe) == 0 && len(report.Issues) != 0 {
+			t.Errorf("%s: expected no issues\n%s", tt.name, out.String())
+		}
+	}
+}
+
+func TestRunCheckCompat(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	v1 := write("v1.schema", "package game\n\n@id(1)\ntype Game {\n    Round int32\n}\n")
+	v2 := write("v2.schema", "package game\n\n@id(1)\ntype Game {\n    Round int32 @write(server)\n}\n")
+	v3 := write("v3.schema", "package game\n\n@id(1)\ntype Game {\n    Round int64\n}\n")
+
+	var out, errOut strings.Builder
+	if code := runCheckCompat([]string{v1, v2}, &out, &errOut); code != 0 {
+		t.Errorf("expected exit code 0 for safe changes, got %d\n%s", code, out.String())
+	}
+	out.Reset()
+	if code := runCheckCompat([]string{v1, v3}, &out, &errOut); code != 1 {
+		t.Errorf("expected exit code 1 for breaking changes, got %d\n%s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "1 breaking, 0 safe change(s)") {
+		t.Errorf("unexpected summary:\n%s", out.String())
+	}
+	if errOut.Len() != 0 {
+		t.Errorf("expected no errors, got %s", errOut.String())
+	}
+
+	// Errors go to errw, leaving the report output empty
+	out.Reset()
+	if code := runCheckCompat([]string{v1}, &out, &errOut); code != 2 {
+		t.Errorf("expected exit code 2 for missing arguments, got %d", code)
+	}
+	if code := runCheckCompat([]string{v1, filepath.Join(dir, "missing.schema")}, &out, &errOut); code != 2 {
+		t.Errorf("expected exit code 2 for a missing file, got %d", code)
+	}
+	if out.Len() != 0 || strings.Count(errOut.String(), "schemagen: ") != 2 {
+		t.Errorf("expected both errors on errw, got out %q, errw %q", out.String(), errOut.String())
+	}
+}
+
+// buildGenerated compiles generated Go files as packages of a temporary module that
+// uses this checkout of statesync. Each key of pkgs is a package directory.
+func buildGenerated(t *testing.T, pkgs map[string]map[string][]byte) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("compiles generated code")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	goMod := "module gentest\n\ngo 1.24.0\n\n" +
+		"require github.com/mxkacsa/statesync v0.0.0\n\n" +
+		"replace github.com/mxkacsa/statesync => " + root + "\n"
+	files := map[string][]byte{"go.mod": []byte(goMod), "go.sum": sum}
+	for pkg, pkgFiles := range pkgs {
+		for name, data := range pkgFiles {
+			files[filepath.Join(pkg, name)] = data
+		}
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(goBin, "build", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("generated code does not compile: %v\n%s", err, out)
+	}
+}
+
+func TestGeneratedGoCompiles(t *testing.T) {
+	generate := func(t *testing.T, schema *SchemaFile) []byte {
+		t.Helper()
+		code, err := GenerateGo(schema)
+		if err != nil {
+			t.Fatalf("Go generation failed: %v", err)
+		}
+		return code
+	}
+
+	example, err := parseFile(filepath.Join("..", "..", "example", "game.schema"))
+	if err != nil {
+		t.Fatalf("parse example: %v", err)
+	}
+
+	events, err := Parse(strings.NewReader(`
+package events
+
+@id(1)
+type Table {
+    Round int32
+    Seats []string
+}
+
+event CardPlayed { PlayerID string; CardID int32; Slot uint8; Bonus float64; Hidden bool }
+event RoundEnded {}
+`))
+	if err != nil {
+		t.Fatalf("parse events: %v", err)
+	}
+
+	var input strings.Builder
+	input.WriteString("package wide\n\n@id(1)\ntype Config {\n")
+	for i := 0; i < 300; i++ {
+		fmt.Fprintf(&input, "    F%d int32\n", i)
+	}
+	input.WriteString("    Tags []string\n    Limits map[string]int64\n}\n")
+	wide, err := Parse(strings.NewReader(input.String()))
+	if err != nil {
+		t.Fatalf("parse wide: %v", err)
+	}
+
+	buildGenerated(t, map[string]map[string][]byte{
+		// The example also needs its GameConfig and a main func
+		"example": {
+			"game_gen.go":   generate(t, example),
+			"config_gen.go": mustRead(t, filepath.Join("..", "..", "example", "config_gen.go")),
+			"main.go":       []byte("package main\n\nfunc main() {}\n"),
+		},
+		"events": {"events_gen.go": generate(t, events)},
+		"wide":   {"wide_gen.go": generate(t, wide)},
+	})
+}
+
+func mustRead(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
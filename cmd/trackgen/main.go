@@ -377,6 +377,8 @@ func (g *Generator) generate(buf *bytes.Buffer, types []string) error {
 		"visibilityCheck":  visibilityCheck,
 		"needsCloneField":  needsCloneField,
 		"sub":              func(a, b int) int { return a - b },
+		"csMethod":         changeSetMethod,
+		"isWide":           func(t *TypeInfo) bool { return len(t.Fields) > 256 },
 	}).Parse(trackedTemplate)
 	if err != nil {
 		return err
@@ -524,6 +526,24 @@ func visibilityCheck(fi FieldInfo, ownerExpr string) string {
 	}
 }
 
+// wideChangeSetMethods maps ChangeSet methods to their variants for field indices past 255
+var wideChangeSetMethods = map[string]string{
+	"Mark":             "MarkWide",
+	"MarkAll":          "MarkAllWide",
+	"IsFieldDirty":     "IsWideFieldDirty",
+	"GetOrCreateArray": "GetOrCreateWideArray",
+	"GetOrCreateMap":   "GetOrCreateWideMap",
+}
+
+// changeSetMethod returns the ChangeSet method to call for a field index: ChangeSet
+// methods take uint8 indices, indices past 255 need the Wide variant
+func changeSetMethod(name string, index int) string {
+	if index >= 256 {
+		return wideChangeSetMethods[name]
+	}
+	return name
+}
+
 // needsCloneField checks if a field needs to be explicitly cloned (slices/maps)
 func needsCloneField(fi FieldInfo) bool {
 	return fi.IsSlice || fi.IsMap
@@ -601,11 +621,21 @@ func (t *Tracked{{.Name}}) ClearChanges() {
 
 // MarkAllDirty implements Trackable
 func (t *Tracked{{.Name}}) MarkAllDirty() {
-	{{if gt (len .Fields) 0}}t.changes.MarkAll({{sub (len .Fields) 1}}){{end}}
+	{{if gt (len .Fields) 0}}t.changes.{{csMethod "MarkAll" (sub (len .Fields) 1)}}({{sub (len .Fields) 1}}){{end}}
 }
 
+{{if isWide . -}}
 // GetFieldValue implements Trackable
 func (t *Tracked{{.Name}}) GetFieldValue(index uint8) interface{} {
+	return t.GetWideFieldValue(uint16(index))
+}
+
+// GetWideFieldValue implements WideTrackable
+func (t *Tracked{{.Name}}) GetWideFieldValue(index uint16) interface{} {
+{{- else -}}
+// GetFieldValue implements Trackable
+func (t *Tracked{{.Name}}) GetFieldValue(index uint8) interface{} {
+{{- end}}
 	t.mu.RLock()
 	defer t.mu.RUnlock()
 	switch index {
@@ -633,9 +663,9 @@ func (t *Tracked{{$type.Name}}) Set{{.Name}}(v {{goFieldType .}}) {
 	defer t.mu.Unlock()
 	{{if isPrimitiveField .}}if t.data.{{.Name}} != v {
 		t.data.{{.Name}} = v
-		t.changes.Mark({{.Index}}, statediff.OpReplace)
+		t.changes.{{csMethod "Mark" .Index}}({{.Index}}, statediff.OpReplace)
 	}{{else}}t.data.{{.Name}} = v
-	t.changes.Mark({{.Index}}, statediff.OpReplace)
+	t.changes.{{csMethod "Mark" .Index}}({{.Index}}, statediff.OpReplace)
 	{{end}}
 }
 {{end}}{{end}}
@@ -646,7 +676,7 @@ func (t *Tracked{{$type.Name}}) Set{{.Name}}(v {{goFieldType .}}) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.data.{{.Name}} = v
-	t.changes.Mark({{.Index}}, statediff.OpReplace)
+	t.changes.{{csMethod "Mark" .Index}}({{.Index}}, statediff.OpReplace)
 }
 
 // Append{{.Name}} adds an element to the slice
@@ -654,7 +684,7 @@ func (t *Tracked{{$type.Name}}) Append{{.Name}}(v {{.ElemType}}) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.data.{{.Name}} = append(t.data.{{.Name}}, v)
-	arr := t.changes.GetOrCreateArray({{.Index}})
+	arr := t.changes.{{csMethod "GetOrCreateArray" .Index}}({{.Index}})
 	arr.MarkAdd(len(t.data.{{.Name}})-1, v)
 }
 
@@ -664,7 +694,7 @@ func (t *Tracked{{$type.Name}}) Remove{{.Name}}At(index int) {
 	defer t.mu.Unlock()
 	if index >= 0 && index < len(t.data.{{.Name}}) {
 		t.data.{{.Name}} = append(t.data.{{.Name}}[:index], t.data.{{.Name}}[index+1:]...)
-		arr := t.changes.GetOrCreateArray({{.Index}})
+		arr := t.changes.{{csMethod "GetOrCreateArray" .Index}}({{.Index}})
 		arr.MarkRemove(index)
 	}
 }
@@ -675,7 +705,7 @@ func (t *Tracked{{$type.Name}}) Update{{.Name}}At(index int, v {{.ElemType}}) {
 	defer t.mu.Unlock()
 	if index >= 0 && index < len(t.data.{{.Name}}) {
 		t.data.{{.Name}}[index] = v
-		arr := t.changes.GetOrCreateArray({{.Index}})
+		arr := t.changes.{{csMethod "GetOrCreateArray" .Index}}({{.Index}})
 		arr.MarkReplace(index, v)
 	}
 }
@@ -687,7 +717,7 @@ func (t *Tracked{{$type.Name}}) Set{{.Name}}(v {{goFieldType .}}) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	t.data.{{.Name}} = v
-	t.changes.Mark({{.Index}}, statediff.OpReplace)
+	t.changes.{{csMethod "Mark" .Index}}({{.Index}}, statediff.OpReplace)
 }
 
 // Set{{.Name}}Key sets a map key
@@ -699,7 +729,7 @@ func (t *Tracked{{$type.Name}}) Set{{.Name}}Key(key {{.KeyType}}, v {{.ElemType}
 	}
 	_, existed := t.data.{{.Name}}[key]
 	t.data.{{.Name}}[key] = v
-	m := t.changes.GetOrCreateMap({{.Index}})
+	m := t.changes.{{csMethod "GetOrCreateMap" .Index}}({{.Index}})
 	{{if needsChildType .}}vp := v
 	if existed {
 		m.MarkReplace(key, &vp)
@@ -719,7 +749,7 @@ func (t *Tracked{{$type.Name}}) Delete{{.Name}}Key(key {{.KeyType}}) {
 	if t.data.{{.Name}} != nil {
 		if _, ok := t.data.{{.Name}}[key]; ok {
 			delete(t.data.{{.Name}}, key)
-			m := t.changes.GetOrCreateMap({{.Index}})
+			m := t.changes.{{csMethod "GetOrCreateMap" .Index}}({{.Index}})
 			m.MarkRemove(key)
 		}
 	}
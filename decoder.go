@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"reflect"
 )
 
 // Decoder errors
@@ -36,11 +37,17 @@ func NewDecoder(registry *SchemaRegistry) *Decoder {
 type DecodedPatch struct {
 	SchemaID uint16
 	Changes  []DecodedChange
+
+	// Removed is set for MsgRootRemove messages (the root was deactivated)
+	Removed bool
+
+	// Schemas is set for MsgSchema messages (the described schemas were registered)
+	Schemas bool
 }
 
 // DecodedChange represents a single field change
 type DecodedChange struct {
-	FieldIndex uint8
+	FieldIndex uint16
 	Op         Operation
 	Value      interface{}
 
@@ -81,17 +88,54 @@ func (d *Decoder) Decode(data []byte) (*DecodedPatch, error) {
 	}
 
 	switch msgType {
-	case MsgFullState:
-		return d.decodeFullState()
-	case MsgPatch:
-		return d.decodePatch()
+	case MsgFullState, MsgFullState | MsgFlagWideIndex:
+		return d.decodeFullState(msgType&MsgFlagWideIndex != 0)
+	case MsgPatch, MsgPatch | MsgFlagWideIndex:
+		return d.decodePatch(msgType&MsgFlagWideIndex != 0)
+	case MsgRootRemove:
+		schemaID, err := d.readUint16()
+		if err != nil {
+			return nil, err
+		}
+		return &DecodedPatch{SchemaID: schemaID, Removed: true}, nil
+	case MsgSnapshot:
+		// Binary snapshot: decode the embedded full state (use DecodeSnapshot for the header)
+		_, state, err := splitBinarySnapshot(data)
+		if err != nil {
+			return nil, err
+		}
+		return d.Decode(state)
+	case MsgSchema:
+		// Schema descriptor: register the schemas for the messages that follow
+		if err := d.LoadSchemas(data); err != nil {
+			return nil, err
+		}
+		return &DecodedPatch{Schemas: true}, nil
 	default:
 		return nil, ErrInvalidMessage
 	}
 }
 
-// decodeFullState decodes a full state message
-func (d *Decoder) decodeFullState() (*DecodedPatch, error) {
+// DecodeBatch decodes a message that may be a MsgPatchBatch (see MultiSession).
+// Non-batch messages are decoded as a single patch.
+func (d *Decoder) DecodeBatch(data []byte) ([]*DecodedPatch, error) {
+	msgs, err := SplitPatchBatch(data)
+	if err != nil {
+		return nil, err
+	}
+	patches := make([]*DecodedPatch, 0, len(msgs))
+	for _, msg := range msgs {
+		patch, err := d.Decode(msg)
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, patch)
+	}
+	return patches, nil
+}
+
+// decodeFullState decodes a full state message (wide: varint field count, see MsgFlagWideIndex)
+func (d *Decoder) decodeFullState(wide bool) (*DecodedPatch, error) {
 	schemaID, err := d.readUint16()
 	if err != nil {
 		return nil, err
@@ -102,19 +146,20 @@ func (d *Decoder) decodeFullState() (*DecodedPatch, error) {
 		return nil, fmt.Errorf("%w: %d", ErrUnknownSchema, schemaID)
 	}
 
-	fieldCount, err := d.readByte()
+	fieldCount, err := d.readFieldIndex(wide)
 	if err != nil {
 		return nil, err
 	}
 	// Bound check: each field needs at least 1 byte
 	remaining := len(d.buf) - d.pos
-	if int(fieldCount) > remaining {
+	if fieldCount > remaining {
 		return nil, ErrBufferTooSmall
 	}
 
 	changes := make([]DecodedChange, fieldCount)
-	for i := uint8(0); i < fieldCount; i++ {
-		field := schema.Field(i)
+	for n := 0; n < fieldCount; n++ {
+		i := uint16(n)
+		field := schema.WideField(i)
 		if field == nil {
 			return nil, fmt.Errorf("%w: %d", ErrInvalidField, i)
 		}
@@ -137,8 +182,8 @@ func (d *Decoder) decodeFullState() (*DecodedPatch, error) {
 	}, nil
 }
 
-// decodePatch decodes an incremental patch message
-func (d *Decoder) decodePatch() (*DecodedPatch, error) {
+// decodePatch decodes an incremental patch message (wide: varint field indices, see MsgFlagWideIndex)
+func (d *Decoder) decodePatch(wide bool) (*DecodedPatch, error) {
 	schemaID, err := d.readUint16()
 	if err != nil {
 		return nil, err
@@ -161,12 +206,16 @@ func (d *Decoder) decodePatch() (*DecodedPatch, error) {
 
 	changes := make([]DecodedChange, changeCount)
 	for i := uint64(0); i < changeCount; i++ {
-		fieldIndex, err := d.readByte()
+		index, err := d.readFieldIndex(wide)
 		if err != nil {
 			return nil, err
 		}
+		if index >= MaxFields {
+			return nil, fmt.Errorf("%w: %d", ErrInvalidField, index)
+		}
+		fieldIndex := uint16(index)
 
-		field := schema.Field(fieldIndex)
+		field := schema.WideField(fieldIndex)
 		if field == nil {
 			return nil, fmt.Errorf("%w: %d", ErrInvalidField, fieldIndex)
 		}
@@ -508,6 +557,22 @@ func (d *Decoder) readByte() (uint8, error) {
 	return v, nil
 }
 
+// readFieldIndex reads a field index or count (a varint in wide messages, see MsgFlagWideIndex)
+func (d *Decoder) readFieldIndex(wide bool) (int, error) {
+	if !wide {
+		v, err := d.readByte()
+		return int(v), err
+	}
+	v, err := d.readVarUint()
+	if err != nil {
+		return 0, err
+	}
+	if v > MaxFields {
+		return 0, fmt.Errorf("%w: %d", ErrInvalidField, v)
+	}
+	return int(v), nil
+}
+
 func (d *Decoder) readInt8() (int8, error) {
 	v, err := d.readByte()
 	return int8(v), err
@@ -656,7 +721,7 @@ func (d *Decoder) readVarUint() (uint64, error) {
 // ApplyPatch applies a decoded patch to a map-based state
 func ApplyPatch(state map[string]interface{}, patch *DecodedPatch, schema *Schema) error {
 	for _, change := range patch.Changes {
-		field := schema.Field(change.FieldIndex)
+		field := schema.WideField(change.FieldIndex)
 		if field == nil {
 			continue
 		}
@@ -743,3 +808,105 @@ func applyMapChanges(m map[string]interface{}, changes []DecodedMapChange) {
 		}
 	}
 }
+
+// SetFields sets the fields of a FieldSetter from decoded fields keyed by name (see ApplyPatch)
+func SetFields(t FieldSetter, schema *Schema, fields map[string]interface{}) error {
+	for i := range schema.Fields {
+		field := &schema.Fields[i]
+		value, ok := fields[field.Name]
+		if !ok {
+			continue
+		}
+		if err := t.SetFieldValue(field.Index, value); err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// DecodedValue converts a decoded primitive to V. Numbers are converted between
+// numeric types (a Go int is sent as int64, for example); nil gives the zero value.
+func DecodedValue[V any](value interface{}) (V, error) {
+	var zero V
+	if value == nil {
+		return zero, nil
+	}
+	if v, ok := value.(V); ok {
+		return v, nil
+	}
+	rv, target := reflect.ValueOf(value), reflect.TypeOf(zero)
+	if target != nil && isNumericKind(rv.Kind()) && isNumericKind(target.Kind()) {
+		return rv.Convert(target).Interface().(V), nil
+	}
+	return zero, fmt.Errorf("%w: cannot use %T as %T", ErrInvalidType, value, zero)
+}
+
+// DecodedStruct builds a V from a decoded struct through its FieldSetter.
+// Generated types are reset to their defaults first; nil gives the zero value.
+func DecodedStruct[V any, P interface {
+	*V
+	FieldSetter
+	Trackable
+}](value interface{}) (V, error) {
+	var v V
+	if value == nil {
+		return v, nil
+	}
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return v, fmt.Errorf("%w: cannot use %T as struct", ErrInvalidType, value)
+	}
+	p := P(&v)
+	if r, ok := any(p).(defaultsResetter); ok {
+		r.ResetToDefaults()
+	}
+	if err := SetFields(p, p.Schema(), fields); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+// DecodedSlice converts a decoded array, converting each element with elem
+func DecodedSlice[V any](value interface{}, elem func(interface{}) (V, error)) ([]V, error) {
+	if value == nil {
+		return nil, nil
+	}
+	arr, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot use %T as array", ErrInvalidType, value)
+	}
+	out := make([]V, len(arr))
+	for i, e := range arr {
+		v, err := elem(e)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// DecodedMap converts a decoded map, converting each value with elem
+func DecodedMap[V any](value interface{}, elem func(interface{}) (V, error)) (map[string]V, error) {
+	if value == nil {
+		return nil, nil
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot use %T as map", ErrInvalidType, value)
+	}
+	out := make(map[string]V, len(m))
+	for k, e := range m {
+		v, err := elem(e)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k, err)
+		}
+		out[k] = v
+	}
+	return out, nil
+}
+
+// isNumericKind reports whether values of kind k are integers or floats
+func isNumericKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
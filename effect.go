@@ -2,6 +2,7 @@ package statesync
 
 import (
 	"sync"
+	"time"
 )
 
 // Effect is a reversible state transformation.
@@ -40,6 +41,118 @@ type Schedulable interface {
 	CancelScheduledExpiration()
 }
 
+// ExpireHook is an optional interface for effects that need cleanup when they expire.
+// OnExpire is called exactly once when the effect is removed as expired, with write access to the base state.
+type ExpireHook[T, A any] interface {
+	OnExpire(state T, activator A) T
+}
+
+// TickDriven is an optional interface for effects whose lifetime is measured in session ticks
+// or simulated time instead of wall-clock timers (see TimedEffect).
+// Advance is called once per tick with the simulated time step; expiry is reported via Expirable.
+type TickDriven interface {
+	Expirable
+	Advance(dt time.Duration)
+}
+
+// FieldEffect is an optional interface for effects that declare which top-level fields
+// (schema field indices) they read and write. The written fields are marked changed when the
+// effect is added or removed, and whenever a field it reads changes, so the effective state's
+// ChangeSet covers the effect's output. WritesFields returning nil means "undeclared";
+// ReadsFields returning nil means the effect only depends on its activator.
+type FieldEffect interface {
+	ReadsFields() []uint16
+	WritesFields() []uint16
+}
+
+// StackBehavior decides what happens when an effect of an already active kind is added
+type StackBehavior uint8
+
+const (
+	// StackReject rejects the new effect with ErrEffectExists
+	StackReject StackBehavior = iota
+	// StackAdd adds the new effect as another stack (up to MaxStacks; the oldest stack is dropped when full)
+	StackAdd
+	// StackRefresh replaces the active effect of the kind (e.g. to refresh its duration) without calling OnActivate again
+	StackRefresh
+)
+
+// Stackable is an optional interface for effects that share a kind (e.g. "poison").
+// Effects of the same kind stack according to StackBehavior. An empty kind disables stacking.
+type Stackable interface {
+	Kind() string
+	MaxStacks() int // 0 = unlimited
+	StackBehavior() StackBehavior
+}
+
+// Prioritized is an optional interface for effects that must be applied in a fixed order.
+// Lower priorities are applied first; effects without priority have priority 0.
+// Effects with equal priority are applied in insertion order.
+type Prioritized interface {
+	Priority() int
+}
+
+// Exclusive is an optional interface for effects where only one per group may be active.
+// Adding an effect replaces the active effect of its group if that one is weaker. On equal
+// strength the active effect is kept and AddEffect returns ErrEffectWeaker, as it does for a
+// stronger one. An empty group disables exclusivity.
+type Exclusive interface {
+	ExclusiveGroup() string
+	Strength() int
+}
+
+// effectStacking returns the stacking rules of an effect (empty kind if not Stackable)
+func effectStacking(e any) (kind string, maxStacks int, behavior StackBehavior) {
+	if st, ok := e.(Stackable); ok {
+		return st.Kind(), st.MaxStacks(), st.StackBehavior()
+	}
+	return "", 0, StackReject
+}
+
+// effectPriority returns the priority of an effect (0 if not Prioritized)
+func effectPriority(e any) int {
+	if p, ok := e.(Prioritized); ok {
+		return p.Priority()
+	}
+	return 0
+}
+
+// effectFields returns the declared fields of an effect (ok is false if undeclared)
+func effectFields(e any) (reads, writes []uint16, ok bool) {
+	if fe, isField := e.(FieldEffect); isField {
+		reads, writes = fe.ReadsFields(), fe.WritesFields()
+		return reads, writes, writes != nil
+	}
+	return nil, nil, false
+}
+
+// FieldCopier is an optional interface for state types that can copy top-level fields
+// from another value of the same type. WithFieldsFrom returns a shallow copy of the
+// receiver, sharing its ChangeSet, with the given fields (schema indices) taken from src.
+// schemagen generates it for root types; TrackedConfig.CacheEffects uses it to keep the
+// cached output of effects whose inputs didn't change.
+type FieldCopier[T any] interface {
+	WithFieldsFrom(src T, fields []uint16) T
+}
+
+// rebaseState returns a copy of base with the given top-level fields taken from out.
+// ok is false if T doesn't implement FieldCopier.
+func rebaseState[T Trackable](base, out T, fields []uint16) (T, bool) {
+	fc, ok := any(base).(FieldCopier[T])
+	if !ok {
+		return base, false
+	}
+	return fc.WithFieldsFrom(out, fields), true
+}
+
+// effectGroup returns the exclusive group of an effect (empty if not Exclusive)
+func effectGroup(e any) (group string, strength int) {
+	if ex, ok := e.(Exclusive); ok {
+		return ex.ExclusiveGroup(), ex.Strength()
+	}
+	return "", 0
+}
+
 // Func creates a simple effect from a function.
 // This is the basic building block - use it directly or as a base for custom effects.
 func Func[T, A any](id string, fn func(state T, activator A) T) *FuncEffect[T, A] {
@@ -53,8 +166,54 @@ type FuncEffect[T, A any] struct {
 	id        string
 	fn        func(T, A) T
 	activator A
+
+	// Optional stacking, ordering and exclusivity (set before AddEffect)
+	kind      string
+	maxStacks int
+	behavior  StackBehavior
+	priority  int
+	group     string
+	strength  int
+	reads     []uint16
+	writes    []uint16
 }
 
+// WithStacking sets the stacking kind and behavior (see Stackable)
+func (e *FuncEffect[T, A]) WithStacking(kind string, maxStacks int, behavior StackBehavior) *FuncEffect[T, A] {
+	e.kind, e.maxStacks, e.behavior = kind, maxStacks, behavior
+	return e
+}
+
+// WithPriority sets the application order (see Prioritized)
+func (e *FuncEffect[T, A]) WithPriority(priority int) *FuncEffect[T, A] {
+	e.priority = priority
+	return e
+}
+
+// WithExclusiveGroup sets the exclusive group and strength (see Exclusive)
+func (e *FuncEffect[T, A]) WithExclusiveGroup(group string, strength int) *FuncEffect[T, A] {
+	e.group, e.strength = group, strength
+	return e
+}
+
+// WithFields declares the fields the effect reads and writes (see FieldEffect)
+func (e *FuncEffect[T, A]) WithFields(reads, writes []uint16) *FuncEffect[T, A] {
+	if writes == nil {
+		writes = []uint16{}
+	}
+	e.reads, e.writes = reads, writes
+	return e
+}
+
+func (e *FuncEffect[T, A]) Kind() string                 { return e.kind }
+func (e *FuncEffect[T, A]) MaxStacks() int               { return e.maxStacks }
+func (e *FuncEffect[T, A]) StackBehavior() StackBehavior { return e.behavior }
+func (e *FuncEffect[T, A]) Priority() int                { return e.priority }
+func (e *FuncEffect[T, A]) ExclusiveGroup() string       { return e.group }
+func (e *FuncEffect[T, A]) Strength() int                { return e.strength }
+func (e *FuncEffect[T, A]) ReadsFields() []uint16        { return e.reads }
+func (e *FuncEffect[T, A]) WritesFields() []uint16       { return e.writes }
+
 func (e *FuncEffect[T, A]) ID() string { return e.id }
 
 func (e *FuncEffect[T, A]) Apply(s T, activator A) T {
@@ -0,0 +1,204 @@
+package statesync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Effect registry errors
+var (
+	ErrUnregisteredEffect = errors.New("effect type not registered")
+	ErrEffectTypeExists   = errors.New("effect type already registered")
+)
+
+// EffectRegistry maps effect types to names and param codecs, so snapshots can
+// persist effects (including activator and remaining TimedEffect lifetime)
+// without a hand-written EffectFactory.
+type EffectRegistry[T Trackable, A any] struct {
+	mu    sync.RWMutex
+	types map[string]*effectType[T, A]
+	order []string // Registration order (first matching type wins)
+
+	encodeActivator func(A) (json.RawMessage, error)
+	decodeActivator func(json.RawMessage) (A, error)
+}
+
+// effectType is a registered effect type with type-erased codecs
+type effectType[T Trackable, A any] struct {
+	encode func(e Effect[T, A]) (params any, ok bool)
+	decode func(id string, params json.RawMessage) (Effect[T, A], error)
+}
+
+// NewEffectRegistry creates an empty registry. Activators are encoded as JSON by default.
+func NewEffectRegistry[T Trackable, A any]() *EffectRegistry[T, A] {
+	return &EffectRegistry[T, A]{
+		types: make(map[string]*effectType[T, A]),
+		encodeActivator: func(a A) (json.RawMessage, error) {
+			return json.Marshal(a)
+		},
+		decodeActivator: func(data json.RawMessage) (A, error) {
+			var a A
+			if len(data) == 0 {
+				return a, nil
+			}
+			err := json.Unmarshal(data, &a)
+			return a, err
+		},
+	}
+}
+
+// RegisterEffect registers effect type E under a name.
+// params returns the data needed to recreate an effect, build recreates it from its ID and params.
+func RegisterEffect[T Trackable, A any, E Effect[T, A], P any](r *EffectRegistry[T, A], name string, params func(E) P, build func(id string, params P) (E, error)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.types[name]; ok {
+		return fmt.Errorf("%w: %s", ErrEffectTypeExists, name)
+	}
+	r.types[name] = &effectType[T, A]{
+		encode: func(e Effect[T, A]) (any, bool) {
+			typed, ok := e.(E)
+			if !ok {
+				return nil, false
+			}
+			return params(typed), true
+		},
+		decode: func(id string, data json.RawMessage) (Effect[T, A], error) {
+			var p P
+			if len(data) > 0 {
+				if err := json.Unmarshal(data, &p); err != nil {
+					return nil, fmt.Errorf("unmarshal params: %w", err)
+				}
+			}
+			return build(id, p)
+		},
+	}
+	r.order = append(r.order, name)
+	return nil
+}
+
+// SetActivatorCodec overrides how activators are encoded (default: JSON)
+func (r *EffectRegistry[T, A]) SetActivatorCodec(encode func(A) (json.RawMessage, error), decode func(json.RawMessage) (A, error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.encodeActivator = encode
+	r.decodeActivator = decode
+}
+
+// Describe creates the metadata for an effect.
+// TimedEffects are stored as their inner effect plus the remaining lifetime;
+// hooks set with WithOnExpire are not persisted (implement ExpireHook on the inner effect instead).
+func (r *EffectRegistry[T, A]) Describe(e Effect[T, A]) (EffectMeta, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	meta := EffectMeta{ID: e.ID()}
+	inner := e
+	if timed, ok := e.(*TimedEffect[T, A]); ok {
+		lt := timed.Lifetime()
+		meta.Lifetime = &lt
+		inner = timed.Inner()
+	}
+
+	for _, name := range r.order {
+		params, ok := r.types[name].encode(inner)
+		if !ok {
+			continue
+		}
+		data, err := json.Marshal(params)
+		if err != nil {
+			return EffectMeta{}, fmt.Errorf("effect %q: marshal params: %w", meta.ID, err)
+		}
+		act, err := r.encodeActivator(e.Activator())
+		if err != nil {
+			return EffectMeta{}, fmt.Errorf("effect %q: encode activator: %w", meta.ID, err)
+		}
+		meta.Type = name
+		meta.Params = data
+		meta.Activator = act
+		return meta, nil
+	}
+	return EffectMeta{}, fmt.Errorf("%w: effect %q (%T)", ErrUnregisteredEffect, meta.ID, inner)
+}
+
+// Metas describes all active effects of a state in application order
+func (r *EffectRegistry[T, A]) Metas(state *TrackedState[T, A]) ([]EffectMeta, error) {
+	effects := state.Effects()
+	metas := make([]EffectMeta, 0, len(effects))
+	for _, e := range effects {
+		meta, err := r.Describe(e)
+		if err != nil {
+			return nil, err
+		}
+		metas = append(metas, meta)
+	}
+	return metas, nil
+}
+
+// Build recreates an effect and its activator from metadata
+func (r *EffectRegistry[T, A]) Build(meta EffectMeta) (Effect[T, A], A, error) {
+	r.mu.RLock()
+	typ, ok := r.types[meta.Type]
+	decodeActivator := r.decodeActivator
+	r.mu.RUnlock()
+
+	var activator A
+	if !ok {
+		return nil, activator, fmt.Errorf("%w: %s", ErrUnregisteredEffect, meta.Type)
+	}
+	effect, err := typ.decode(meta.ID, meta.Params)
+	if err != nil {
+		return nil, activator, err
+	}
+	activator, err = decodeActivator(meta.Activator)
+	if err != nil {
+		return nil, activator, fmt.Errorf("decode activator: %w", err)
+	}
+	if meta.Lifetime != nil {
+		effect = timedWithLifetime(effect, *meta.Lifetime)
+	}
+	return effect, activator, nil
+}
+
+// Save writes state and all active effects to a JSON file (see Save)
+func (r *EffectRegistry[T, A]) Save(path string, state *TrackedState[T, A], extra any) error {
+	metas, err := r.Metas(state)
+	if err != nil {
+		return err
+	}
+	return Save(path, state, metas, extra)
+}
+
+// Restore loads state and recreates effects with their activator and remaining lifetime.
+// OnActivate is not called again for restored effects. Effect errors are non-fatal (see Restore).
+func (r *EffectRegistry[T, A]) Restore(path string, initializer func(loaded T) T, cfg *TrackedConfig) (*RestoreResult[T, A], error) {
+	snap, err := LoadMigrated[T](path, cfg.migrationRegistry())
+	if err != nil {
+		return nil, err
+	}
+	if snap == nil {
+		return nil, nil // No saved state
+	}
+	return r.RestoreSnapshot(snap, initializer, cfg), nil
+}
+
+// RestoreSnapshot recreates state and effects from a loaded snapshot (see Restore)
+func (r *EffectRegistry[T, A]) RestoreSnapshot(snap *Snapshot[T], initializer func(loaded T) T, cfg *TrackedConfig) *RestoreResult[T, A] {
+	state := NewTrackedState[T, A](initializer(snap.State), cfg)
+	result := &RestoreResult[T, A]{State: state}
+	for _, meta := range snap.Effects {
+		effect, activator, err := r.Build(meta)
+		if err != nil {
+			result.EffectErrors = append(result.EffectErrors,
+				fmt.Errorf("effect %q (type %s): %w", meta.ID, meta.Type, err))
+			continue
+		}
+		if _, err := state.addEffect(effect, activator, false); err != nil {
+			result.EffectErrors = append(result.EffectErrors, err)
+		}
+	}
+	return result
+}
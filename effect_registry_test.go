@@ -0,0 +1,121 @@
+package statesync
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// poisonEffect is a persistable test effect that lowers Score
+type poisonEffect struct {
+	id          string
+	damage      int64
+	activator   string
+	activations *int
+}
+
+type poisonParams struct {
+	Damage int64 `json:"damage"`
+}
+
+func (e *poisonEffect) ID() string            { return e.id }
+func (e *poisonEffect) Activator() string     { return e.activator }
+func (e *poisonEffect) SetActivator(a string) { e.activator = a }
+func (e *poisonEffect) Apply(s *simpleTestState, _ string) *simpleTestState {
+	c := *s
+	c.Score -= e.damage
+	return &c
+}
+func (e *poisonEffect) OnActivate(s *simpleTestState, _ string) *simpleTestState {
+	if e.activations != nil {
+		*e.activations++
+	}
+	return s
+}
+
+func newPoisonRegistry(t *testing.T) *EffectRegistry[*simpleTestState, string] {
+	t.Helper()
+	reg := NewEffectRegistry[*simpleTestState, string]()
+	err := RegisterEffect(reg, "poison",
+		func(e *poisonEffect) poisonParams { return poisonParams{Damage: e.damage} },
+		func(id string, p poisonParams) (*poisonEffect, error) {
+			return &poisonEffect{id: id, damage: p.Damage}, nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("RegisterEffect: %v", err)
+	}
+	return reg
+}
+
+func initSimpleTestState(loaded *simpleTestState) *simpleTestState {
+	loaded.changes = NewChangeSet()
+	return loaded
+}
+
+func TestEffectRegistry_SaveRestore(t *testing.T) {
+	reg := newPoisonRegistry(t)
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	activations := 0
+	ts := NewTrackedState[*simpleTestState, string](&simpleTestState{changes: NewChangeSet(), Score: 100}, nil)
+	ts.AddEffect(&poisonEffect{id: "p1", damage: 5, activations: &activations}, "alice")
+	ts.AddEffect(Timed[*simpleTestState, string](&poisonEffect{id: "p2", damage: 10, activations: &activations}, 3), "bob")
+	ts.AdvanceEffects(0)
+
+	if err := reg.Save(path, ts, nil); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	result, err := reg.Restore(path, initSimpleTestState, nil)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if len(result.EffectErrors) != 0 {
+		t.Fatalf("EffectErrors: %v", result.EffectErrors)
+	}
+
+	restored := result.State
+	if got := restored.Get().Score; got != 85 {
+		t.Errorf("Score = %d, want 85", got)
+	}
+	if a := restored.GetEffect("p1").Activator(); a != "alice" {
+		t.Errorf("p1 activator = %q, want alice", a)
+	}
+
+	timed, ok := restored.GetEffect("p2").(*TimedEffect[*simpleTestState, string])
+	if !ok {
+		t.Fatalf("p2 should be restored as TimedEffect, got %T", restored.GetEffect("p2"))
+	}
+	if timed.RemainingTicks() != 2 {
+		t.Errorf("RemainingTicks = %d, want 2", timed.RemainingTicks())
+	}
+	if timed.Activator() != "bob" {
+		t.Errorf("p2 activator = %q, want bob", timed.Activator())
+	}
+	if activations != 2 {
+		t.Errorf("OnActivate called %d times, want 2 (not again on restore)", activations)
+	}
+}
+
+func TestEffectRegistry_Errors(t *testing.T) {
+	reg := newPoisonRegistry(t)
+
+	err := RegisterEffect(reg, "poison",
+		func(e *poisonEffect) poisonParams { return poisonParams{} },
+		func(id string, p poisonParams) (*poisonEffect, error) { return nil, nil },
+	)
+	if !errors.Is(err, ErrEffectTypeExists) {
+		t.Errorf("expected ErrEffectTypeExists, got %v", err)
+	}
+
+	ts := NewTrackedState[*simpleTestState, string](&simpleTestState{changes: NewChangeSet()}, nil)
+	ts.AddEffect(Func("anon", func(s *simpleTestState, _ string) *simpleTestState { return s }), "")
+	if _, err := reg.Metas(ts); !errors.Is(err, ErrUnregisteredEffect) {
+		t.Errorf("expected ErrUnregisteredEffect, got %v", err)
+	}
+
+	if _, _, err := reg.Build(EffectMeta{ID: "x", Type: "unknown"}); !errors.Is(err, ErrUnregisteredEffect) {
+		t.Errorf("expected ErrUnregisteredEffect from Build, got %v", err)
+	}
+}
@@ -14,6 +14,10 @@ const (
 	MsgPatch      uint8 = 0x02
 	MsgPatchBatch uint8 = 0x03
 
+	// MsgFlagWideIndex is set on the MsgFullState/MsgPatch type byte of schemas with more
+	// than 256 fields: the field count and field indices are varints instead of bytes
+	MsgFlagWideIndex uint8 = 0x80
+
 	// Array/Map encoding modes (first byte after field index)
 	ArrayModeIncremental uint8 = 0x00 // Incremental changes follow
 	ArrayModeFull        uint8 = 0x01 // Full replacement follows
@@ -31,6 +35,14 @@ type Encoder struct {
 	sortKeys    []string
 	sortInts    []int
 	sortIndices []int
+	sentOps     []syntheticOp
+	dirtyFields []bool
+	// Viewer context (nil = everything is visible) and owner of the struct being encoded
+	viewer   *Viewer
+	owner    string
+	hasOwner bool
+	// Field indices of the current message are varints (see MsgFlagWideIndex)
+	wide bool
 }
 
 // NewEncoder creates a new encoder
@@ -92,6 +104,12 @@ func (e *Encoder) grow(n int) {
 
 // Encode encodes only the changed fields of a Trackable object
 func (e *Encoder) Encode(t Trackable) []byte {
+	return e.EncodeFor(t, nil)
+}
+
+// EncodeFor encodes the changed fields visible to viewer (nil = all fields).
+// Hidden fields are never read. Returns nil if no visible field changed.
+func (e *Encoder) EncodeFor(t Trackable, viewer *Viewer) []byte {
 	e.Reset()
 
 	changes := t.Changes()
@@ -102,11 +120,24 @@ func (e *Encoder) Encode(t Trackable) []byte {
 	schema := t.Schema()
 
 	// Message type
-	e.writeByte(MsgPatch)
+	e.writeMsgType(MsgPatch, schema)
 
 	// Schema ID
 	e.writeUint16(schema.ID)
 
+	restricted := viewer != nil && isRestricted(schema)
+	if restricted {
+		e.setViewer(viewer, t, schema)
+		defer e.setViewer(nil, nil, nil)
+	}
+	if _, isOverlay := t.(*Overlay); isOverlay || restricted || e.wide {
+		// Projections, overlays and wide indices are only handled by the reflection-based path
+		if e.encodeChanges(t, schema, changes) == 0 {
+			return nil
+		}
+		return e.Bytes()
+	}
+
 	// FAST PATH: Use generated encoder if available (no interface{} boxing)
 	if fast, ok := t.(FastEncoder); ok {
 		fast.EncodeChangesTo(e)
@@ -121,46 +152,101 @@ func (e *Encoder) Encode(t Trackable) []byte {
 
 // EncodeAll encodes all fields of a Trackable object (for initial sync)
 func (e *Encoder) EncodeAll(t Trackable) []byte {
+	return e.EncodeAllFor(t, nil)
+}
+
+// EncodeAllFor encodes the full state for viewer (nil = all fields).
+// Hidden fields are never read and are encoded as zero values.
+func (e *Encoder) EncodeAllFor(t Trackable, viewer *Viewer) []byte {
 	e.Reset()
 
 	schema := t.Schema()
 
 	// Message type
-	e.writeByte(MsgFullState)
+	e.writeMsgType(MsgFullState, schema)
 
 	// Schema ID
 	e.writeUint16(schema.ID)
 
+	restricted := viewer != nil && isRestricted(schema)
+	if restricted {
+		e.setViewer(viewer, t, schema)
+		defer e.setViewer(nil, nil, nil)
+	}
+
+	// Number of fields
+	e.writeFieldIndex(len(schema.Fields))
+
 	// FAST PATH: Use generated encoder if available
-	if fast, ok := t.(FastEncoder); ok {
-		e.writeByte(uint8(len(schema.Fields)))
+	if fast, ok := t.(FastEncoder); ok && !restricted {
 		fast.EncodeAllTo(e)
 		return e.Bytes()
 	}
 
 	// SLOW PATH: Use reflection-based encoding
-	// Number of fields (max 256 — field indices are uint8)
-	e.writeByte(uint8(len(schema.Fields)))
-
-	// Encode all fields
 	for i := range schema.Fields {
 		field := &schema.Fields[i]
-		value := t.GetFieldValue(uint8(i))
+		if !e.visible(field) || overlayHidden(t, uint16(i)) {
+			e.writeZero(field)
+			continue
+		}
+		value := fieldValue(t, uint16(i))
 		e.encodeField(field, value)
 	}
 
 	return e.Bytes()
 }
 
-// encodeChanges encodes only the changed fields
-func (e *Encoder) encodeChanges(t Trackable, schema *Schema, changes *ChangeSet) {
-	changedFields := changes.ChangedFields()
+// writeMsgType writes a MsgFullState/MsgPatch type byte, flagged for wide schemas
+func (e *Encoder) writeMsgType(msgType uint8, schema *Schema) {
+	e.wide = schema.Wide()
+	if e.wide {
+		msgType |= MsgFlagWideIndex
+	}
+	e.writeByte(msgType)
+}
+
+// writeFieldIndex writes a field index or count (a varint in wide messages)
+func (e *Encoder) writeFieldIndex(index int) {
+	if e.wide {
+		e.writeVarUint(uint64(index))
+		return
+	}
+	e.writeByte(uint8(index))
+}
+
+// setViewer sets the viewer context for encoding the root t (nil to clear)
+func (e *Encoder) setViewer(viewer *Viewer, t Trackable, schema *Schema) {
+	e.viewer = viewer
+	e.owner, e.hasOwner = "", false
+	if viewer != nil {
+		e.owner, e.hasOwner = structOwner(t, schema, "", false)
+	}
+}
+
+// visible reports whether the current viewer may see a field of the struct being encoded
+func (e *Encoder) visible(field *FieldMeta) bool {
+	return e.viewer.CanSee(field, e.owner, e.hasOwner)
+}
+
+// writeZero encodes the zero value of a hidden field (keeps positional encodings aligned)
+func (e *Encoder) writeZero(field *FieldMeta) {
+	if field.Type == TypeStruct {
+		e.writeByte(0) // Null marker
+		return
+	}
+	e.encodeField(field, nil)
+}
+
+// encodeChanges encodes only the changed (and visible) fields. Returns the number of encoded fields.
+func (e *Encoder) encodeChanges(t Trackable, schema *Schema, changes *ChangeSet) int {
+	changedFields := changes.ChangedWideFields()
 
 	// Filter to valid schema fields only. An out-of-range index (e.g., from
 	// incorrect MarkAll) would cause a count/record mismatch in the decoder.
 	valid := changedFields[:0]
 	for _, idx := range changedFields {
-		if schema.Field(idx) != nil {
+		if field := schema.WideField(idx); field != nil && e.visible(field) && !overlayHidden(t, idx) {
 			valid = append(valid, idx)
 		}
 	}
@@ -168,55 +254,60 @@ func (e *Encoder) encodeChanges(t Trackable, schema *Schema, changes *ChangeSet)
 	e.writeVarUint(uint64(len(valid)))
 
 	for _, idx := range valid {
-		field := schema.Field(idx)
-
-		// Field index
-		e.writeByte(idx)
-
-		// Check if it's an array/map change or simple field change
-		if field.Type == TypeArray {
-			// If field-level op is OpReplace (e.g., SetChatMessages was called for full replacement),
-			// always use full mode even if incremental changes were also tracked afterwards.
-			fieldChange := changes.GetFieldChange(idx)
-			if fieldChange.Op != OpReplace {
-				if arrChanges := changes.GetArray(idx); arrChanges != nil && arrChanges.HasChanges() {
-					// Incremental array changes
-					e.writeByte(ArrayModeIncremental)
-					e.encodeArrayChanges(field, arrChanges, t.GetFieldValue(idx))
-					continue
-				}
+		e.encodeFieldChange(t, idx, schema.WideField(idx), changes)
+	}
+	return len(valid)
+}
+
+// encodeFieldChange encodes a changed field (field index, op/mode and value)
+func (e *Encoder) encodeFieldChange(t Trackable, idx uint16, field *FieldMeta, changes *ChangeSet) {
+	// Field index
+	e.writeFieldIndex(int(idx))
+
+	// Check if it's an array/map change or simple field change
+	if field.Type == TypeArray {
+		// If field-level op is OpReplace (e.g., SetChatMessages was called for full replacement),
+		// always use full mode even if incremental changes were also tracked afterwards.
+		fieldChange := changes.GetWideFieldChange(idx)
+		// Overridden elements can't be expressed as ops on base indices.
+		if fieldChange.Op != OpReplace && !overlayOverridden(t, idx) {
+			if arrChanges := changes.GetWideArray(idx); arrChanges != nil && arrChanges.HasChanges() {
+				// Incremental array changes
+				e.writeByte(ArrayModeIncremental)
+				e.encodeArrayChanges(field, arrChanges, fieldValue(t, idx))
+				return
 			}
-			// Full array replacement
-			e.writeByte(ArrayModeFull)
-			e.encodeArray(field, t.GetFieldValue(idx))
-			continue
 		}
-		if field.Type == TypeMap {
-			// If field-level op is OpReplace (e.g., SetCollectibles was called for full replacement),
-			// always use full mode even if incremental changes were also tracked afterwards.
-			fieldChange := changes.GetFieldChange(idx)
-			if fieldChange.Op != OpReplace {
-				if mapChanges := changes.GetMap(idx); mapChanges != nil && mapChanges.HasChanges() {
-					// Incremental map changes
-					e.writeByte(ArrayModeIncremental)
-					e.encodeMapChanges(field, mapChanges, t.GetFieldValue(idx))
-					continue
-				}
+		// Full array replacement
+		e.writeByte(ArrayModeFull)
+		e.encodeArray(field, fieldValue(t, idx))
+		return
+	}
+	if field.Type == TypeMap {
+		// If field-level op is OpReplace (e.g., SetCollectibles was called for full replacement),
+		// always use full mode even if incremental changes were also tracked afterwards.
+		fieldChange := changes.GetWideFieldChange(idx)
+		if fieldChange.Op != OpReplace && !overlayOverridden(t, idx) {
+			if mapChanges := changes.GetWideMap(idx); mapChanges != nil && mapChanges.HasChanges() {
+				// Incremental map changes
+				e.writeByte(ArrayModeIncremental)
+				e.encodeMapChanges(field, mapChanges, fieldValue(t, idx))
+				return
 			}
-			// Full map replacement
-			e.writeByte(ArrayModeFull)
-			e.encodeMap(field, t.GetFieldValue(idx))
-			continue
 		}
+		// Full map replacement
+		e.writeByte(ArrayModeFull)
+		e.encodeMap(field, fieldValue(t, idx))
+		return
+	}
 
-		// Simple field replacement (primitives, structs)
-		change := changes.GetFieldChange(idx)
-		e.writeByte(uint8(change.Op))
+	// Simple field replacement (primitives, structs)
+	change := changes.GetWideFieldChange(idx)
+	e.writeByte(uint8(change.Op))
 
-		if change.Op != OpRemove {
-			value := t.GetFieldValue(idx)
-			e.encodeField(field, value)
-		}
+	if change.Op != OpRemove {
+		value := fieldValue(t, idx)
+		e.encodeField(field, value)
 	}
 }
 
@@ -274,10 +365,20 @@ func (e *Encoder) encodeStruct(t Trackable, schema *Schema) {
 	}
 	e.writeByte(1) // Non-null marker
 
+	if e.viewer != nil {
+		owner, hasOwner := e.owner, e.hasOwner
+		e.owner, e.hasOwner = structOwner(t, schema, owner, hasOwner)
+		defer func() { e.owner, e.hasOwner = owner, hasOwner }()
+	}
+
 	// Encode all fields of nested struct
 	for i := range schema.Fields {
 		field := &schema.Fields[i]
-		value := t.GetFieldValue(uint8(i))
+		if !e.visible(field) || overlayHidden(t, uint16(i)) {
+			e.writeZero(field)
+			continue
+		}
+		value := fieldValue(t, uint16(i))
 		e.encodeField(field, value)
 	}
 }
@@ -334,6 +435,14 @@ func (e *Encoder) encodeArrayChanges(field *FieldMeta, changes *ArrayChangeSet,
 func (e *Encoder) encodeArrayElement(field *FieldMeta, elem interface{}) {
 	if field.ElemType == TypeStruct {
 		if t, ok := elem.(Trackable); ok {
+			if e.viewer != nil && field.KeyField != "" {
+				// Keyed elements are owned by their key (unless the schema has an OwnerField)
+				if key := fieldNamed(field.ChildSchema, field.KeyField); key != nil {
+					owner, hasOwner := e.owner, e.hasOwner
+					e.owner, e.hasOwner = ownerKey(fieldValue(t, key.Index)), true
+					defer func() { e.owner, e.hasOwner = owner, hasOwner }()
+				}
+			}
 			e.encodeStruct(t, field.ChildSchema)
 		} else {
 			e.writeByte(0) // Null marker for non-Trackable struct
@@ -353,7 +462,7 @@ func (e *Encoder) encodeMap(field *FieldMeta, value interface{}) {
 
 	for i, key := range keys {
 		e.writeString(key)
-		e.encodeMapValue(field, values[i])
+		e.encodeMapValue(field, key, values[i])
 	}
 }
 
@@ -382,15 +491,21 @@ func (e *Encoder) encodeMapChanges(field *FieldMeta, changes *MapChangeSet, valu
 		e.writeByte(uint8(change.Op))
 
 		if change.Op != OpRemove {
-			e.encodeMapValue(field, change.Value)
+			e.encodeMapValue(field, key, change.Value)
 		}
 	}
 }
 
 // encodeMapValue encodes a single map value
-func (e *Encoder) encodeMapValue(field *FieldMeta, value interface{}) {
+func (e *Encoder) encodeMapValue(field *FieldMeta, key string, value interface{}) {
 	if field.ElemType == TypeStruct {
 		if t, ok := value.(Trackable); ok {
+			if e.viewer != nil {
+				// Struct values are owned by their map key (unless the schema has an OwnerField)
+				owner, hasOwner := e.owner, e.hasOwner
+				e.owner, e.hasOwner = key, true
+				defer func() { e.owner, e.hasOwner = owner, hasOwner }()
+			}
 			e.encodeStruct(t, field.ChildSchema)
 		} else {
 			e.writeByte(0) // Null marker for non-Trackable struct
@@ -416,7 +531,7 @@ func (e *Encoder) writeByte(v uint8) {
 
 // WriteFieldHeader writes the field index and operation
 func (e *Encoder) WriteFieldHeader(fieldIndex uint8, op Operation) {
-	e.writeByte(fieldIndex)
+	e.writeFieldIndex(int(fieldIndex))
 	e.writeByte(uint8(op))
 }
 
@@ -689,6 +804,8 @@ func toBytes(v interface{}) []byte {
 
 func getArrayLength(v interface{}) int {
 	switch arr := v.(type) {
+	case *arrayOverlay:
+		return arr.Len()
 	case []interface{}:
 		return len(arr)
 	case []string:
@@ -732,6 +849,8 @@ func getArrayLength(v interface{}) int {
 
 func getArrayElement(v interface{}, i int) interface{} {
 	switch arr := v.(type) {
+	case *arrayOverlay:
+		return arr.At(i)
 	case []interface{}:
 		return arr[i]
 	case []string:
@@ -782,6 +901,8 @@ func getArrayElement(v interface{}, i int) interface{} {
 
 func getMapKeysValues(v interface{}) ([]string, []interface{}) {
 	switch m := v.(type) {
+	case *mapOverlay:
+		return m.keysValues()
 	case map[string]interface{}:
 		return extractMapKV(m)
 	case map[string]string:
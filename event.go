@@ -1,6 +1,7 @@
 package statesync
 
 import (
+	"bytes"
 	"encoding/binary"
 	"math"
 	"sync"
@@ -32,15 +33,94 @@ const (
 	TargetExcept
 	// TargetMany sends to multiple specific clients
 	TargetMany
+	// TargetTopic sends to clients subscribed to a topic
+	TargetTopic
 )
 
 // PendingEvent is an event waiting to be broadcast
 type PendingEvent[ID comparable] struct {
-	Event  Event
-	Target EventTarget
-	To     ID   // For TargetOne
-	Except ID   // For TargetExcept
-	ToMany []ID // For TargetMany
+	Event    Event
+	Target   EventTarget
+	To       ID     // For TargetOne
+	Except   ID     // For TargetExcept
+	ToMany   []ID   // For TargetMany
+	Topic    string // For TargetTopic
+	Reliable bool   // Sequenced, kept in history until acknowledged
+}
+
+// ReliableEvent is an event with a session-wide sequence number.
+// Sequence numbers are strictly increasing, so clients can drop duplicates
+// after a replay. A client may see gaps for events addressed to others.
+type ReliableEvent struct {
+	Seq   uint64
+	Event Event
+}
+
+// DefaultReliableEventLimit is the default number of unacknowledged reliable events a
+// session keeps per client (see TrackedSession.SetReliableEventLimit)
+const DefaultReliableEventLimit = 256
+
+// EventPolicyMode selects how an EventBuffer bounds events of one type
+type EventPolicyMode uint8
+
+const (
+	// PolicyCoalesce keeps only the last occurrence per recipient each tick
+	PolicyCoalesce EventPolicyMode = iota + 1
+	// PolicyRateLimit delivers at most Limit events per client per Ticks ticks.
+	// Extra events are dropped; reliable events are never rate limited.
+	PolicyRateLimit
+	// PolicyAggregate combines all occurrences per recipient each tick into one event
+	PolicyAggregate
+)
+
+// EventPolicy bounds the events of one type (see EventBuffer.SetPolicy)
+type EventPolicy struct {
+	Mode      EventPolicyMode
+	Limit     int                            // PolicyRateLimit: max events per client per window
+	Ticks     uint64                         // PolicyRateLimit: window length in ticks (Advance calls), > 0
+	Aggregate func(payloads [][]byte) []byte // PolicyAggregate: combines payloads in emit order
+}
+
+// CoalesceEvents returns a policy that keeps only the last occurrence per tick
+func CoalesceEvents() EventPolicy {
+	return EventPolicy{Mode: PolicyCoalesce}
+}
+
+// RateLimitEvents returns a policy that delivers at most limit events per client in each window of ticks ticks.
+// Windows are counted in ticks, so the delivered events don't depend on the wall clock.
+// Panics if ticks is 0.
+func RateLimitEvents(limit int, ticks uint64) EventPolicy {
+	if ticks == 0 {
+		panic("statesync: rate limit window must be at least one tick")
+	}
+	return EventPolicy{Mode: PolicyRateLimit, Limit: limit, Ticks: ticks}
+}
+
+// AggregateEvents returns a policy that combines all payloads of a tick into one event.
+// A nil fn uses AggregateJSONArray.
+func AggregateEvents(fn func(payloads [][]byte) []byte) EventPolicy {
+	if fn == nil {
+		fn = AggregateJSONArray
+	}
+	return EventPolicy{Mode: PolicyAggregate, Aggregate: fn}
+}
+
+// AggregateJSONArray combines JSON payloads into a JSON array (nil payloads become null)
+func AggregateJSONArray(payloads [][]byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, p := range payloads {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if len(p) == 0 {
+			buf.WriteString("null")
+		} else {
+			buf.Write(p)
+		}
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
 }
 
 // EventBuffer collects events between Tick() calls.
@@ -49,6 +129,23 @@ type EventBuffer[ID comparable] struct {
 	mu     sync.Mutex
 	events []PendingEvent[ID]
 	count  atomic.Int32 // Lock-free count for HasEvents check
+
+	// Per-event-type policies
+	policies map[string]EventPolicy
+	windows  map[rateKey[ID]]*rateWindow // Rate limit windows per type and client
+	tick     atomic.Uint64               // Number of Advance calls
+}
+
+// rateKey identifies a rate limit window
+type rateKey[ID comparable] struct {
+	eventType string
+	client    ID
+}
+
+// rateWindow counts deliveries in a fixed window of ticks
+type rateWindow struct {
+	start uint64
+	count int
 }
 
 // NewEventBuffer creates a new event buffer
@@ -58,6 +155,46 @@ func NewEventBuffer[ID comparable]() *EventBuffer[ID] {
 	}
 }
 
+// SetPolicy sets the policy for an event type, replacing any previous policy.
+// Policies are applied per recipient by ApplyPolicies (see TrackedSession.TickWithEvents).
+// Panics if a rate limit policy has a zero-tick window.
+func (eb *EventBuffer[ID]) SetPolicy(eventType string, policy EventPolicy) {
+	if policy.Mode == PolicyRateLimit && policy.Ticks == 0 {
+		panic("statesync: rate limit for " + eventType + " must span at least one tick")
+	}
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	if eb.policies == nil {
+		eb.policies = make(map[string]EventPolicy)
+	}
+	eb.policies[eventType] = policy
+	for k := range eb.windows {
+		if k.eventType == eventType {
+			delete(eb.windows, k)
+		}
+	}
+}
+
+// ClearPolicy removes the policy for an event type
+func (eb *EventBuffer[ID]) ClearPolicy(eventType string) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	delete(eb.policies, eventType)
+	for k := range eb.windows {
+		if k.eventType == eventType {
+			delete(eb.windows, k)
+		}
+	}
+}
+
+// Policy returns the policy for an event type
+func (eb *EventBuffer[ID]) Policy(eventType string) (EventPolicy, bool) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	p, ok := eb.policies[eventType]
+	return p, ok
+}
+
 // Add adds an event to the buffer
 func (eb *EventBuffer[ID]) Add(event PendingEvent[ID]) {
 	eb.mu.Lock()
@@ -66,6 +203,12 @@ func (eb *EventBuffer[ID]) Add(event PendingEvent[ID]) {
 	eb.mu.Unlock()
 }
 
+// Advance counts one tick for rate limit windows (see RateLimitEvents).
+// TrackedSession calls it on every Tick.
+func (eb *EventBuffer[ID]) Advance() {
+	eb.tick.Add(1)
+}
+
 // Drain returns all pending events and clears the buffer.
 // The returned slice is a copy safe for concurrent iteration.
 func (eb *EventBuffer[ID]) Drain() []PendingEvent[ID] {
@@ -87,6 +230,122 @@ func (eb *EventBuffer[ID]) Drain() []PendingEvent[ID] {
 	return result
 }
 
+// ApplyPolicies coalesces, aggregates and rate limits the drained events of each
+// recipient in place. Reliable events are coalesced and aggregated but never rate
+// limited. Recipients left without events are removed from the maps.
+func (eb *EventBuffer[ID]) ApplyPolicies(events map[ID][]Event, reliable map[ID][]ReliableEvent) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	if len(eb.policies) == 0 {
+		return
+	}
+
+	tick := eb.tick.Load()
+	for id, evts := range events {
+		evts = coalesceEvents(eb.policies, evts, func(e *Event) *Event { return e })
+		kept := evts[:0]
+		for _, e := range evts {
+			if eb.allowLocked(e.Type, id, tick) {
+				kept = append(kept, e)
+			}
+		}
+		if len(kept) == 0 {
+			delete(events, id)
+		} else {
+			events[id] = kept
+		}
+	}
+	for id, evts := range reliable {
+		reliable[id] = coalesceEvents(eb.policies, evts, func(e *ReliableEvent) *Event { return &e.Event })
+	}
+}
+
+// coalesceEvents coalesces and aggregates the events of one recipient by type.
+// The surviving event of each type takes the position of its last occurrence.
+func coalesceEvents[E any](policies map[string]EventPolicy, events []E, event func(*E) *Event) []E {
+	type group struct {
+		last     int
+		payloads [][]byte
+	}
+
+	var groups map[string]*group
+	var owners []*group
+	for i := range events {
+		typ := event(&events[i]).Type
+		p, ok := policies[typ]
+		if !ok || (p.Mode != PolicyCoalesce && p.Mode != PolicyAggregate) {
+			continue
+		}
+		if groups == nil {
+			groups = make(map[string]*group)
+			owners = make([]*group, len(events))
+		}
+		g := groups[typ]
+		if g == nil {
+			g = &group{}
+			groups[typ] = g
+		}
+		g.last = i
+		if p.Mode == PolicyAggregate {
+			g.payloads = append(g.payloads, event(&events[i]).Payload)
+		}
+		owners[i] = g
+	}
+	if groups == nil {
+		return events
+	}
+
+	out := events[:0]
+	for i := range events {
+		if g := owners[i]; g != nil {
+			if g.last != i {
+				continue
+			}
+			e := event(&events[i])
+			if p := policies[e.Type]; p.Mode == PolicyAggregate && p.Aggregate != nil {
+				e.Payload = p.Aggregate(g.payloads)
+			}
+		}
+		out = append(out, events[i])
+	}
+	return out
+}
+
+// allowLocked reports whether an event may be delivered to a client under its rate limit.
+// Caller must hold mu.
+func (eb *EventBuffer[ID]) allowLocked(eventType string, id ID, tick uint64) bool {
+	p, ok := eb.policies[eventType]
+	if !ok || p.Mode != PolicyRateLimit {
+		return true
+	}
+
+	key := rateKey[ID]{eventType: eventType, client: id}
+	w := eb.windows[key]
+	if w == nil || tick-w.start >= p.Ticks {
+		if eb.windows == nil {
+			eb.windows = make(map[rateKey[ID]]*rateWindow)
+		}
+		w = &rateWindow{start: tick}
+		eb.windows[key] = w
+	}
+	if w.count >= p.Limit {
+		return false
+	}
+	w.count++
+	return true
+}
+
+// forgetClient drops rate limit state for a client
+func (eb *EventBuffer[ID]) forgetClient(id ID) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	for k := range eb.windows {
+		if k.client == id {
+			delete(eb.windows, k)
+		}
+	}
+}
+
 // Count returns the number of pending events (lock-free)
 func (eb *EventBuffer[ID]) Count() int {
 	return int(eb.count.Load())
@@ -133,6 +392,12 @@ type EventEmitter[ID comparable] interface {
 
 	// EmitRawTo sends a pre-encoded event to a specific client
 	EmitRawTo(clientID ID, event Event) error
+
+	// EmitRawExcept sends a pre-encoded event to all clients except one
+	EmitRawExcept(exceptID ID, event Event) error
+
+	// EmitRawToMany sends a pre-encoded event to multiple specific clients
+	EmitRawToMany(clientIDs []ID, event Event) error
 }
 
 // Binary protocol for events
@@ -142,6 +407,9 @@ const (
 
 	// MsgEventBatch is for multiple events in one message
 	MsgEventBatch uint8 = 0x11
+
+	// MsgReliableEvents is for sequenced events that clients acknowledge
+	MsgReliableEvents uint8 = 0x12
 )
 
 // EncodeEvent encodes an event to binary format
@@ -307,6 +575,90 @@ func DecodeEventBatch(data []byte) ([]Event, error) {
 	return events, nil
 }
 
+// EncodeReliableEvents encodes sequenced events into a single message
+func EncodeReliableEvents(events []ReliableEvent) []byte {
+	if len(events) == 0 {
+		return nil
+	}
+
+	// Format: [MsgReliableEvents][count:varint]{[seq:varint][typeLen:varint][type:bytes][payloadLen:varint][payload:bytes]}
+	size := 1 + varIntSize(uint64(len(events)))
+	for _, e := range events {
+		size += varIntSize(e.Seq)
+		size += varIntSize(uint64(len(e.Event.Type))) + len(e.Event.Type)
+		size += varIntSize(uint64(len(e.Event.Payload))) + len(e.Event.Payload)
+	}
+
+	buf := make([]byte, size)
+	buf[0] = MsgReliableEvents
+	pos := 1
+	pos += putVarUint(buf[pos:], uint64(len(events)))
+
+	for _, e := range events {
+		pos += putVarUint(buf[pos:], e.Seq)
+
+		pos += putVarUint(buf[pos:], uint64(len(e.Event.Type)))
+		pos += copy(buf[pos:], e.Event.Type)
+
+		pos += putVarUint(buf[pos:], uint64(len(e.Event.Payload)))
+		pos += copy(buf[pos:], e.Event.Payload)
+	}
+
+	return buf
+}
+
+// DecodeReliableEvents decodes sequenced events from binary format
+func DecodeReliableEvents(data []byte) ([]ReliableEvent, error) {
+	if len(data) < 2 || data[0] != MsgReliableEvents {
+		return nil, ErrInvalidEventFormat
+	}
+
+	pos := 1
+	count, n := readVarUint(data[pos:])
+	if n == 0 || count > uint64(len(data)) {
+		return nil, ErrInvalidEventFormat
+	}
+	pos += n
+
+	events := make([]ReliableEvent, 0, count)
+	for i := uint64(0); i < count; i++ {
+		seq, n := readVarUint(data[pos:])
+		if n == 0 {
+			return nil, ErrInvalidEventFormat
+		}
+		pos += n
+
+		// Event type
+		typeLen, n := readVarUint(data[pos:])
+		if n == 0 {
+			return nil, ErrInvalidEventFormat
+		}
+		pos += n
+		if typeLen > uint64(len(data)-pos) {
+			return nil, ErrInvalidEventFormat
+		}
+		eventType := string(data[pos : pos+int(typeLen)])
+		pos += int(typeLen)
+
+		// Payload
+		payloadLen, n := readVarUint(data[pos:])
+		if n == 0 {
+			return nil, ErrInvalidEventFormat
+		}
+		pos += n
+		if payloadLen > uint64(len(data)-pos) {
+			return nil, ErrInvalidEventFormat
+		}
+		payload := make([]byte, payloadLen)
+		copy(payload, data[pos:pos+int(payloadLen)])
+		pos += int(payloadLen)
+
+		events = append(events, ReliableEvent{Seq: seq, Event: Event{Type: eventType, Payload: payload}})
+	}
+
+	return events, nil
+}
+
 // EventPayloadEncoder helps encode event payloads
 type EventPayloadEncoder struct {
 	buf []byte
@@ -366,6 +718,27 @@ func (e *EventPayloadEncoder) WriteInt32(v int32) {
 	e.pos += 4
 }
 
+// WriteUint32 writes a uint32 to the payload
+func (e *EventPayloadEncoder) WriteUint32(v uint32) {
+	e.grow(4)
+	binary.LittleEndian.PutUint32(e.buf[e.pos:], v)
+	e.pos += 4
+}
+
+// WriteUint64 writes a uint64 to the payload
+func (e *EventPayloadEncoder) WriteUint64(v uint64) {
+	e.grow(8)
+	binary.LittleEndian.PutUint64(e.buf[e.pos:], v)
+	e.pos += 8
+}
+
+// WriteFloat32 writes a float32 to the payload
+func (e *EventPayloadEncoder) WriteFloat32(v float32) {
+	e.grow(4)
+	binary.LittleEndian.PutUint32(e.buf[e.pos:], math.Float32bits(v))
+	e.pos += 4
+}
+
 // WriteFloat64 writes a float64 to the payload
 func (e *EventPayloadEncoder) WriteFloat64(v float64) {
 	e.grow(8)
@@ -392,6 +765,109 @@ func (e *EventPayloadEncoder) WriteBytes(b []byte) {
 	e.pos += len(b)
 }
 
+// EventPayloadDecoder reads payloads written by EventPayloadEncoder.
+// Reads past the end return zero values; check Err after decoding.
+type EventPayloadDecoder struct {
+	buf []byte
+	pos int
+	err error
+}
+
+// NewEventPayloadDecoder creates a decoder for a payload
+func NewEventPayloadDecoder(payload []byte) *EventPayloadDecoder {
+	return &EventPayloadDecoder{buf: payload}
+}
+
+// Err returns ErrInvalidEventFormat if any read ran past the payload
+func (d *EventPayloadDecoder) Err() error {
+	return d.err
+}
+
+// next returns the next n bytes, or nil if the payload is too short
+func (d *EventPayloadDecoder) next(n int) []byte {
+	if d.err != nil || n > len(d.buf)-d.pos {
+		d.err = ErrInvalidEventFormat
+		return nil
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+// nextLen reads a varint length prefix
+func (d *EventPayloadDecoder) nextLen() int {
+	if d.err != nil {
+		return 0
+	}
+	v, n := readVarUint(d.buf[d.pos:])
+	if n == 0 || v > uint64(len(d.buf)-d.pos-n) {
+		d.err = ErrInvalidEventFormat
+		return 0
+	}
+	d.pos += n
+	return int(v)
+}
+
+// ReadString reads a string from the payload
+func (d *EventPayloadDecoder) ReadString() string {
+	return string(d.next(d.nextLen()))
+}
+
+// ReadInt64 reads an int64 from the payload
+func (d *EventPayloadDecoder) ReadInt64() int64 {
+	return int64(d.ReadUint64())
+}
+
+// ReadInt32 reads an int32 from the payload
+func (d *EventPayloadDecoder) ReadInt32() int32 {
+	return int32(d.ReadUint32())
+}
+
+// ReadUint64 reads a uint64 from the payload
+func (d *EventPayloadDecoder) ReadUint64() uint64 {
+	if b := d.next(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+// ReadUint32 reads a uint32 from the payload
+func (d *EventPayloadDecoder) ReadUint32() uint32 {
+	if b := d.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+// ReadFloat32 reads a float32 from the payload
+func (d *EventPayloadDecoder) ReadFloat32() float32 {
+	return math.Float32frombits(d.ReadUint32())
+}
+
+// ReadFloat64 reads a float64 from the payload
+func (d *EventPayloadDecoder) ReadFloat64() float64 {
+	return math.Float64frombits(d.ReadUint64())
+}
+
+// ReadBool reads a bool from the payload
+func (d *EventPayloadDecoder) ReadBool() bool {
+	if b := d.next(1); b != nil {
+		return b[0] != 0
+	}
+	return false
+}
+
+// ReadBytes reads a byte slice from the payload (copied)
+func (d *EventPayloadDecoder) ReadBytes() []byte {
+	b := d.next(d.nextLen())
+	if b == nil {
+		return nil
+	}
+	out := make([]byte, len(b))
+	copy(out, b)
+	return out
+}
+
 // Errors
 var (
 	ErrInvalidEventFormat = &EventError{msg: "invalid event format"}
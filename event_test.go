@@ -1,6 +1,7 @@
 package statesync
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -256,3 +257,485 @@ func TestEventDecodeInvalid(t *testing.T) {
 		t.Error("expected error for truncated message")
 	}
 }
+
+func TestReliableEventsEncodeDecode(t *testing.T) {
+	events := []ReliableEvent{
+		{Seq: 1, Event: Event{Type: "Damage", Payload: []byte{1, 2}}},
+		{Seq: 300, Event: Event{Type: "Heal"}},
+	}
+
+	encoded := EncodeReliableEvents(events)
+	if encoded[0] != MsgReliableEvents {
+		t.Fatalf("expected MsgReliableEvents, got 0x%02x", encoded[0])
+	}
+
+	decoded, err := DecodeReliableEvents(encoded)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if len(decoded) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(decoded))
+	}
+	if decoded[0].Seq != 1 || decoded[0].Event.Type != "Damage" || string(decoded[0].Event.Payload) != string([]byte{1, 2}) {
+		t.Errorf("first event mismatch: %+v", decoded[0])
+	}
+	if decoded[1].Seq != 300 || decoded[1].Event.Type != "Heal" {
+		t.Errorf("second event mismatch: %+v", decoded[1])
+	}
+
+	if EncodeReliableEvents(nil) != nil {
+		t.Error("expected nil for empty events")
+	}
+	if _, err := DecodeReliableEvents(encoded[:len(encoded)-3]); err != ErrInvalidEventFormat {
+		t.Errorf("expected ErrInvalidEventFormat for truncated data, got %v", err)
+	}
+}
+
+func newReliableTestSession() (*TrackedSession[*simpleTestState, any, string], *simpleTestState) {
+	state := &simpleTestState{changes: NewChangeSet()}
+	session := NewTrackedSession[*simpleTestState, any, string](NewTrackedState[*simpleTestState, any](state, nil))
+	session.SetHistorySize(10)
+	return session, state
+}
+
+func TestSession_ReliableEventsReplayedUntilAcked(t *testing.T) {
+	session, _ := newReliableTestSession()
+	session.Connect("alice", nil)
+	session.Connect("bob", nil)
+
+	session.EmitReliableTo("alice", "Reward", "gold")
+	session.Emit("Sound", nil)
+	result := session.TickWithEvents()
+
+	if _, ok := result.ReliableEvents["bob"]; ok {
+		t.Error("bob should not receive alice's reliable event")
+	}
+	sent, err := DecodeReliableEvents(result.ReliableEvents["alice"])
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if len(sent) != 1 || sent[0].Seq != 1 || sent[0].Event.Type != "Reward" {
+		t.Fatalf("unexpected reliable events: %+v", sent)
+	}
+	if evts, _ := DecodeEventBatch(result.Events["alice"]); len(evts) != 1 || evts[0].Type != "Sound" {
+		t.Errorf("expected unreliable Sound event, got %+v", evts)
+	}
+
+	// Alice drops before acknowledging; events emitted while she is away are kept for her
+	session.Disconnect("alice")
+	session.EmitReliable("RoundStarted", nil)
+	if _, ok := session.TickWithEvents().ReliableEvents["alice"]; ok {
+		t.Error("disconnected clients should not be sent events")
+	}
+
+	updates, _ := session.Reconnect("alice", result.Seq, nil)
+	if len(updates) == 0 {
+		t.Fatal("expected Reconnect to replay reliable events")
+	}
+	replayed, err := DecodeReliableEvents(updates[len(updates)-1])
+	if err != nil {
+		t.Fatalf("decode replay failed: %v", err)
+	}
+	if len(replayed) != 2 || replayed[0].Seq != 1 || string(replayed[0].Event.Payload) != "gold" ||
+		replayed[1].Seq != 2 || replayed[1].Event.Type != "RoundStarted" {
+		t.Fatalf("expected Reward and RoundStarted replayed, got %+v", replayed)
+	}
+
+	session.AckEvents("alice", 2)
+	if data := session.UnackedEvents("alice"); data != nil {
+		t.Errorf("expected no unacked events after ack, got %v", data)
+	}
+	if bob, _ := DecodeReliableEvents(session.UnackedEvents("bob")); len(bob) != 1 || bob[0].Seq != 2 {
+		t.Errorf("expected bob to keep RoundStarted unacked, got %+v", bob)
+	}
+
+	// Forgotten clients no longer collect events
+	session.Disconnect("bob")
+	session.ForgetClientEvents("bob")
+	session.EmitReliable("RoundEnded", nil)
+	session.TickWithEvents()
+	if data := session.UnackedEvents("bob"); data != nil {
+		t.Errorf("expected no events for a forgotten client, got %v", data)
+	}
+}
+
+func TestSession_ReliableEventsExpireWithHistory(t *testing.T) {
+	session, state := newReliableTestSession()
+	session.SetHistorySize(2)
+	session.Connect("alice", nil)
+	session.TickWithEvents()
+
+	session.EmitReliable("Reward", nil)
+	session.TickWithEvents()
+	if events, _ := DecodeReliableEvents(session.UnackedEvents("alice")); len(events) != 1 {
+		t.Fatalf("expected Reward to be kept while in history, got %+v", events)
+	}
+	for i := 0; i < 5; i++ {
+		state.Score++
+		state.changes.Mark(0, OpReplace)
+		session.TickWithEvents()
+	}
+
+	// The history ring dropped the event's tick, and the event with it
+	if data := session.UnackedEvents("alice"); data != nil {
+		t.Fatalf("expected Reward to expire with the history, got %v", data)
+	}
+	updates, isFull := session.Reconnect("alice", 1, nil)
+	if !isFull || len(updates) != 1 {
+		t.Errorf("expected only a full state, got %d updates (full=%v)", len(updates), isFull)
+	}
+}
+
+func TestSession_ReliableEventsDroppedOnFullReconnect(t *testing.T) {
+	session, state := newReliableTestSession()
+	session.SetHistorySize(2)
+	session.Connect("alice", nil)
+	session.EmitReliable("Reward", nil)
+	session.TickWithEvents()
+	state.Score++
+	state.changes.Mark(0, OpReplace)
+	session.TickWithEvents()
+
+	// lastSeq 0 predates the history, so the client resyncs from a full state
+	updates, isFull := session.Reconnect("alice", 0, nil)
+	if !isFull || len(updates) != 1 || updates[0][0] == MsgReliableEvents {
+		t.Fatalf("expected a full state without stale events, got %d updates (full=%v)", len(updates), isFull)
+	}
+	if data := session.UnackedEvents("alice"); data != nil {
+		t.Errorf("expected events superseded by the full state to be dropped, got %v", data)
+	}
+}
+
+func TestSession_ReliableEventLimit(t *testing.T) {
+	session, _ := newReliableTestSession()
+	session.SetReliableEventLimit(3)
+	session.Connect("alice", nil)
+	for i := 0; i < 4; i++ {
+		session.EmitReliable("Ping", nil)
+		session.EmitReliable("Pong", nil)
+		session.TickWithEvents()
+	}
+
+	events, _ := DecodeReliableEvents(session.UnackedEvents("alice"))
+	if len(events) != 3 || events[0].Seq != 6 || events[2].Seq != 8 {
+		t.Fatalf("expected the 3 newest events, got %+v", events)
+	}
+}
+
+func TestSession_ReliableEventsForDepartedClients(t *testing.T) {
+	session, state := newReliableTestSession()
+	session.SetHistorySize(2)
+	session.Connect("alice", nil)
+	session.Connect("bob", nil)
+	session.TickWithEvents()
+
+	// Leave drops the queue at once
+	session.EmitReliable("Reward", nil)
+	session.TickWithEvents()
+	session.Leave("alice")
+	if data := session.UnackedEvents("alice"); data != nil {
+		t.Errorf("expected no events after Leave, got %v", data)
+	}
+
+	// A disconnected client stops collecting events once the history no longer covers it
+	session.Disconnect("bob")
+	for i := 0; i < 3; i++ {
+		state.Score++
+		state.changes.Mark(0, OpReplace)
+		session.TickWithEvents()
+	}
+	session.EmitReliable("RoundEnded", nil)
+	session.TickWithEvents()
+	if data := session.UnackedEvents("bob"); data != nil {
+		t.Errorf("expected no events for a client gone longer than the history, got %v", data)
+	}
+	if _, ok := session.eventClients["bob"]; ok {
+		t.Error("expected departed client to be forgotten")
+	}
+}
+
+func TestSession_ReliableEventOnlyTickKeepsIncrementalReconnect(t *testing.T) {
+	session, state := newReliableTestSession()
+	filter := func(s *simpleTestState) *simpleTestState { return s }
+	session.Connect("alice", filter)
+	state.Name = "lobby"
+	state.changes.Mark(1, OpReplace)
+	_, lastSeq := session.TickWithSeq()
+
+	state.Score = 5
+	state.changes.Mark(0, OpReplace)
+	session.TickWithSeq()
+
+	// Tick with a reliable event but no state change
+	session.EmitReliable("Ping", nil)
+	session.TickWithEvents()
+
+	updates, isFull := session.Reconnect("alice", lastSeq, filter)
+	if isFull {
+		t.Fatal("event-only tick should not force full state")
+	}
+	if len(updates) != 2 || updates[0][0] != MsgPatch || updates[1][0] != MsgReliableEvents {
+		t.Errorf("expected 1 incremental update and the unacked events, got %d updates", len(updates))
+	}
+}
+
+func TestEventPayloadDecoder(t *testing.T) {
+	enc := NewEventPayloadEncoder()
+	enc.WriteString("alice")
+	enc.WriteInt32(-7)
+	enc.WriteInt64(1 << 40)
+	enc.WriteUint32(42)
+	enc.WriteUint64(1 << 63)
+	enc.WriteFloat32(1.5)
+	enc.WriteFloat64(-2.25)
+	enc.WriteBool(true)
+	enc.WriteBytes([]byte{9, 8})
+
+	d := NewEventPayloadDecoder(enc.Bytes())
+	if v := d.ReadString(); v != "alice" {
+		t.Errorf("ReadString: got %q", v)
+	}
+	if v := d.ReadInt32(); v != -7 {
+		t.Errorf("ReadInt32: got %d", v)
+	}
+	if v := d.ReadInt64(); v != 1<<40 {
+		t.Errorf("ReadInt64: got %d", v)
+	}
+	if v := d.ReadUint32(); v != 42 {
+		t.Errorf("ReadUint32: got %d", v)
+	}
+	if v := d.ReadUint64(); v != 1<<63 {
+		t.Errorf("ReadUint64: got %d", v)
+	}
+	if v := d.ReadFloat32(); v != 1.5 {
+		t.Errorf("ReadFloat32: got %v", v)
+	}
+	if v := d.ReadFloat64(); v != -2.25 {
+		t.Errorf("ReadFloat64: got %v", v)
+	}
+	if !d.ReadBool() {
+		t.Error("ReadBool: expected true")
+	}
+	if v := d.ReadBytes(); len(v) != 2 || v[0] != 9 || v[1] != 8 {
+		t.Errorf("ReadBytes: got %v", v)
+	}
+	if d.Err() != nil {
+		t.Errorf("unexpected error: %v", d.Err())
+	}
+
+	// Reading past the end sets a sticky error
+	if v := d.ReadInt32(); v != 0 || d.Err() != ErrInvalidEventFormat {
+		t.Errorf("expected zero value and ErrInvalidEventFormat, got %d, %v", v, d.Err())
+	}
+}
+
+func TestSession_EmitTopic(t *testing.T) {
+	session, _ := newReliableTestSession()
+	session.Connect("alice", nil)
+	session.Connect("bob", nil)
+	session.Connect("carol", nil)
+	session.Subscribe("alice", "team:red", "chat:global")
+	session.Subscribe("bob", "team:blue", "chat:global")
+
+	session.EmitTopic("team:red", "Rally", nil)
+	session.EmitTopic("chat:global", "Chat", "hi")
+	session.EmitTopic("team:green", "Nobody", nil)
+	result := session.TickWithEvents()
+
+	expect := map[string][]string{
+		"alice": {"Rally", "Chat"},
+		"bob":   {"Chat"},
+	}
+	for id, types := range expect {
+		events, err := DecodeEventBatch(result.Events[id])
+		if err != nil {
+			t.Fatalf("%s: decode failed: %v", id, err)
+		}
+		if len(events) != len(types) {
+			t.Fatalf("%s: expected %v, got %+v", id, types, events)
+		}
+		for i, typ := range types {
+			if events[i].Type != typ {
+				t.Errorf("%s: event %d expected %s, got %s", id, i, typ, events[i].Type)
+			}
+		}
+	}
+	if _, ok := result.Events["carol"]; ok {
+		t.Error("carol has no subscriptions and should receive nothing")
+	}
+}
+
+func TestSession_SubscriptionsSurviveReconnect(t *testing.T) {
+	session, _ := newReliableTestSession()
+	session.Connect("alice", nil)
+	session.Subscribe("alice", "team:red")
+
+	session.Disconnect("alice")
+	session.EmitTopic("team:red", "Missed", nil)
+	if result := session.TickWithEvents(); len(result.Events) != 0 {
+		t.Errorf("disconnected subscriber should not receive events, got %v", result.Events)
+	}
+
+	session.Reconnect("alice", 0, nil)
+	if got := session.Subscriptions("alice"); len(got) != 1 || got[0] != "team:red" {
+		t.Fatalf("expected subscription to survive reconnect, got %v", got)
+	}
+	session.EmitReliableTopic("team:red", "Rally", nil)
+	result := session.TickWithEvents()
+	if events, _ := DecodeReliableEvents(result.ReliableEvents["alice"]); len(events) != 1 || events[0].Event.Type != "Rally" {
+		t.Errorf("expected reliable Rally after reconnect, got %+v", events)
+	}
+
+	session.Unsubscribe("alice", "team:red")
+	if session.IsSubscribed("alice", "team:red") {
+		t.Error("expected unsubscribed")
+	}
+	session.Subscribe("alice", "a", "b")
+	session.ClearSubscriptions("alice")
+	if got := session.Subscriptions("alice"); len(got) != 0 {
+		t.Errorf("expected no subscriptions after clear, got %v", got)
+	}
+}
+
+func TestEventBuffer_CoalescePolicy(t *testing.T) {
+	eb := NewEventBuffer[string]()
+	eb.SetPolicy("Score", CoalesceEvents())
+
+	events := map[string][]Event{
+		"alice": {{Type: "Score", Payload: []byte("1")}, {Type: "Other"}, {Type: "Score", Payload: []byte("3")}},
+		"bob":   {{Type: "Score", Payload: []byte("1")}},
+	}
+	reliable := map[string][]ReliableEvent{
+		"alice": {{Seq: 1, Event: Event{Type: "Score"}}, {Seq: 2, Event: Event{Type: "Score"}}},
+	}
+	eb.ApplyPolicies(events, reliable)
+
+	// The survivor keeps its last position
+	if alice := events["alice"]; len(alice) != 2 || alice[0].Type != "Other" || string(alice[1].Payload) != "3" {
+		t.Errorf("unexpected coalesced events: %+v", alice)
+	}
+	if len(events["bob"]) != 1 {
+		t.Errorf("expected bob's event kept, got %+v", events["bob"])
+	}
+	if r := reliable["alice"]; len(r) != 1 || r[0].Seq != 2 {
+		t.Errorf("expected reliable events coalesced to seq 2, got %+v", r)
+	}
+}
+
+func TestSession_CoalescePerRecipient(t *testing.T) {
+	session, _ := newReliableTestSession()
+	session.SetEventPolicy("Score", CoalesceEvents())
+	session.Connect("alice", nil)
+	session.Connect("bob", nil)
+
+	session.Emit("Score", 1)
+	session.EmitTo("bob", "Score", 2)
+	session.EmitExcept("alice", "Score", 3)
+	result := session.TickWithEvents()
+
+	// Each recipient gets one Score event, whatever the targets
+	alice, _ := DecodeEventBatch(result.Events["alice"])
+	bob, _ := DecodeEventBatch(result.Events["bob"])
+	if len(alice) != 1 || len(bob) != 1 {
+		t.Fatalf("expected one event per recipient, got %d and %d", len(alice), len(bob))
+	}
+	if p := string(alice[0].Payload); p != "1" {
+		t.Errorf("alice payload = %s, want 1", p)
+	}
+	if p := string(bob[0].Payload); p != "3" {
+		t.Errorf("bob payload = %s, want 3", p)
+	}
+}
+
+func TestSession_EmitRawTargets(t *testing.T) {
+	session, _ := newReliableTestSession()
+	for _, id := range []string{"alice", "bob", "carol"} {
+		session.Connect(id, nil)
+	}
+
+	session.EmitRawExcept("alice", Event{Type: "Except", Payload: []byte{1}})
+	session.EmitRawToMany([]string{"alice", "carol"}, Event{Type: "Many", Payload: []byte{2}})
+	result := session.TickWithEvents()
+
+	want := map[string][]string{"alice": {"Many"}, "bob": {"Except"}, "carol": {"Except", "Many"}}
+	for id, types := range want {
+		events, _ := DecodeEventBatch(result.Events[id])
+		var got []string
+		for _, e := range events {
+			got = append(got, e.Type)
+		}
+		if !reflect.DeepEqual(got, types) {
+			t.Errorf("%s got %v, want %v", id, got, types)
+		}
+	}
+}
+
+func TestEventBuffer_AggregatePolicy(t *testing.T) {
+	eb := NewEventBuffer[string]()
+	eb.SetPolicy("Hit", AggregateEvents(nil))
+
+	events := map[string][]Event{
+		"alice": {{Type: "Hit", Payload: []byte(`{"dmg":1}`)}, {Type: "Hit"}, {Type: "Hit", Payload: []byte(`{"dmg":3}`)}},
+	}
+	eb.ApplyPolicies(events, nil)
+	if len(events["alice"]) != 1 {
+		t.Fatalf("expected 1 aggregated event, got %d", len(events["alice"]))
+	}
+	if got := string(events["alice"][0].Payload); got != `[{"dmg":1},null,{"dmg":3}]` {
+		t.Errorf("unexpected aggregated payload: %s", got)
+	}
+}
+
+func TestSession_RateLimitPolicy(t *testing.T) {
+	session, _ := newReliableTestSession()
+	session.SetEventPolicy("Ping", RateLimitEvents(2, 4))
+	session.Connect("alice", nil)
+
+	countPings := func(result TickResult[string]) int {
+		events, _ := DecodeEventBatch(result.Events["alice"])
+		return len(events)
+	}
+
+	for i := 0; i < 5; i++ {
+		session.Emit("Ping", nil)
+	}
+	if n := countPings(session.TickWithEvents()); n != 2 {
+		t.Errorf("expected 2 pings within limit, got %d", n)
+	}
+
+	session.Emit("Ping", nil)
+	if n := countPings(session.TickWithEvents()); n != 0 {
+		t.Errorf("expected ping dropped in same window, got %d", n)
+	}
+
+	// Reliable events bypass the rate limit
+	session.EmitReliable("Ping", nil)
+	if events, _ := DecodeReliableEvents(session.TickWithEvents().ReliableEvents["alice"]); len(events) != 1 {
+		t.Errorf("expected reliable ping delivered, got %d", len(events))
+	}
+
+	// The window is counted in ticks, including plain ticks without events
+	session.Tick()
+	session.Emit("Ping", nil)
+	if n := countPings(session.TickWithEvents()); n != 1 {
+		t.Errorf("expected ping in new window, got %d", n)
+	}
+}
+
+func TestRateLimitEvents_ZeroTicksPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for a zero-tick window")
+		}
+	}()
+	RateLimitEvents(1, 0)
+}
+
+func TestEventBuffer_SetPolicyZeroTicksPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for a zero-tick window")
+		}
+	}()
+	NewEventBuffer[string]().SetPolicy("Ping", EventPolicy{Mode: PolicyRateLimit, Limit: 1})
+}
@@ -3,15 +3,55 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"sync"
 
 	"github.com/mxkacsa/statesync"
 )
 
+// bytesToRawJSON converts a byte slice to json.RawMessage (nil if empty).
+func bytesToRawJSON(b []byte) json.RawMessage {
+	if len(b) == 0 {
+		return nil
+	}
+	return json.RawMessage(b)
+}
+
+// mapValuesToPtr converts a map[K]V to map[K]*V so Go's encoding/json can
+// call pointer-receiver MarshalJSON on each entry (map values are not
+// addressable, so value-typed entries would serialize as "{}").
+func mapValuesToPtr[K comparable, V any](m map[K]V) map[K]*V {
+	if m == nil {
+		return nil
+	}
+	out := make(map[K]*V, len(m))
+	for k, v := range m {
+		vc := v
+		out[k] = &vc
+	}
+	return out
+}
+
+// mapValuesFromPtr is the inverse of mapValuesToPtr, used during unmarshal.
+func mapValuesFromPtr[K comparable, V any](m map[K]*V) map[K]V {
+	if m == nil {
+		return nil
+	}
+	out := make(map[K]V, len(m))
+	for k, v := range m {
+		if v != nil {
+			out[k] = *v
+		} else {
+			var zero V
+			out[k] = zero
+		}
+	}
+	return out
+}
+
 // Player is a tracked state type
 type Player struct {
-	mu      sync.RWMutex
 	changes *statesync.ChangeSet
 	schema  *statesync.Schema
 
@@ -32,10 +72,13 @@ func NewPlayer() *Player {
 	return t
 }
 
-// ResetToDefaults resets all fields to their default values
+// ResetToDefaults resets all fields to their default values.
+// A zero Player also gets its ChangeSet and schema.
 func (t *Player) ResetToDefaults() {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+	if t.changes == nil {
+		t.changes = statesync.NewChangeSet()
+		t.schema = PlayerSchema()
+	}
 	t.id = ""
 	t.name = ""
 	t.score = 0
@@ -48,7 +91,8 @@ func (t *Player) ResetToDefaults() {
 func PlayerSchema() *statesync.Schema {
 	return statesync.NewSchemaBuilder("Player").
 		WithID(2).String("ID").String("Name").Int64("Score").
-		Array("Hand", statesync.TypeInt32, nil).Bool("Ready").
+		Array("Hand", statesync.TypeInt32, nil).
+		Views("owner").Bool("Ready").
 		Build()
 }
 
@@ -60,8 +104,6 @@ func (t *Player) ClearChanges()                 { t.changes.Clear() }
 func (t *Player) MarkAllDirty()                 { t.changes.MarkAll(4) }
 
 func (t *Player) GetFieldValue(index uint8) interface{} {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
 	switch index {
 	case 0:
 		return t.id
@@ -70,91 +112,109 @@ func (t *Player) GetFieldValue(index uint8) interface{} {
 	case 2:
 		return t.score
 	case 3:
-		return t.hand
+		if t.hand == nil {
+			return nil
+		}
+		cp := make([]int32, len(t.hand))
+		copy(cp, t.hand)
+		return cp
 	case 4:
 		return t.ready
 	}
 	return nil
 }
 
+// SetFieldValue sets a field from a decoded value (see statesync.FieldSetter)
+func (t *Player) SetFieldValue(index uint16, value interface{}) error {
+	switch index {
+	case 0:
+		v, err := statesync.DecodedValue[string](value)
+		if err != nil {
+			return err
+		}
+		t.SetID(v)
+	case 1:
+		v, err := statesync.DecodedValue[string](value)
+		if err != nil {
+			return err
+		}
+		t.SetName(v)
+	case 2:
+		v, err := statesync.DecodedValue[int64](value)
+		if err != nil {
+			return err
+		}
+		t.SetScore(v)
+	case 3:
+		v, err := statesync.DecodedSlice(value, statesync.DecodedValue[int32])
+		if err != nil {
+			return err
+		}
+		t.SetHand(v)
+	case 4:
+		v, err := statesync.DecodedValue[bool](value)
+		if err != nil {
+			return err
+		}
+		t.SetReady(v)
+	}
+	return nil
+}
+
 // Getters and Setters
 
 // ID returns the current value
 func (t *Player) ID() string {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
 	return t.id
 }
 
 // SetID sets the value and marks it as changed
 func (t *Player) SetID(v string) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
 	if t.id != v {
 		t.id = v
 		t.changes.Mark(0, statesync.OpReplace)
 	}
-
 }
 
 // Name returns the current value
 func (t *Player) Name() string {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
 	return t.name
 }
 
 // SetName sets the value and marks it as changed
 func (t *Player) SetName(v string) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
 	if t.name != v {
 		t.name = v
 		t.changes.Mark(1, statesync.OpReplace)
 	}
-
 }
 
 // Score returns the current value
 func (t *Player) Score() int64 {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
 	return t.score
 }
 
 // SetScore sets the value and marks it as changed
 func (t *Player) SetScore(v int64) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
 	if t.score != v {
 		t.score = v
 		t.changes.Mark(2, statesync.OpReplace)
 	}
-
 }
 
 // Hand returns the current value
 func (t *Player) Hand() []int32 {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
 	return t.hand
 }
 
 // SetHand replaces the entire slice
 func (t *Player) SetHand(v []int32) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
 	t.hand = v
 	t.changes.Mark(3, statesync.OpReplace)
 }
 
 // AppendHand adds an element to the slice
 func (t *Player) AppendHand(v int32) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
 	t.hand = append(t.hand, v)
 	arr := t.changes.GetOrCreateArray(3)
 	arr.MarkAdd(len(t.hand)-1, v)
@@ -162,8 +222,6 @@ func (t *Player) AppendHand(v int32) {
 
 // RemoveHandAt removes an element at index
 func (t *Player) RemoveHandAt(index int) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
 	if index >= 0 && index < len(t.hand) {
 		t.hand = append(t.hand[:index], t.hand[index+1:]...)
 		arr := t.changes.GetOrCreateArray(3)
@@ -173,8 +231,6 @@ func (t *Player) RemoveHandAt(index int) {
 
 // UpdateHandAt updates an element at index
 func (t *Player) UpdateHandAt(index int, v int32) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
 	if index >= 0 && index < len(t.hand) {
 		t.hand[index] = v
 		arr := t.changes.GetOrCreateArray(3)
@@ -184,15 +240,11 @@ func (t *Player) UpdateHandAt(index int, v int32) {
 
 // HandLen returns the length of the slice
 func (t *Player) HandLen() int {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
 	return len(t.hand)
 }
 
 // HandAt returns the element at index
 func (t *Player) HandAt(index int) int32 {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
 	if index >= 0 && index < len(t.hand) {
 		return t.hand[index]
 	}
@@ -202,26 +254,75 @@ func (t *Player) HandAt(index int) int32 {
 
 // Ready returns the current value
 func (t *Player) Ready() bool {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
 	return t.ready
 }
 
 // SetReady sets the value and marks it as changed
 func (t *Player) SetReady(v bool) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
 	if t.ready != v {
 		t.ready = v
 		t.changes.Mark(4, statesync.OpReplace)
 	}
+}
 
+// Clone returns a deep copy of Player, including its ChangeSet (see statesync.Cloner)
+func (s *Player) Clone() *Player {
+	if s == nil {
+		return nil
+	}
+	return &Player{
+		changes: s.changes.Clone(),
+		schema:  s.schema,
+		id:      s.id,
+		name:    s.name,
+		score:   s.score,
+		hand:    statesync.CloneSlice(s.hand, nil),
+		ready:   s.ready,
+	}
+}
+
+// ---- JSON serialization ----
+
+type playerJSON struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Score int64   `json:"score"`
+	Hand  []int32 `json:"hand,omitempty"`
+	Ready bool    `json:"ready"`
+}
+
+func (t *Player) MarshalJSON() ([]byte, error) {
+	if t == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(playerJSON{
+		ID:    t.id,
+		Name:  t.name,
+		Score: t.score,
+		Hand:  t.hand,
+		Ready: t.ready,
+	})
+}
+
+func (t *Player) UnmarshalJSON(data []byte) error {
+	var j playerJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	init := NewPlayer()
+	*t = *init
+	t.SetID(j.ID)
+	t.SetName(j.Name)
+	t.SetScore(j.Score)
+	if j.Hand != nil {
+		t.SetHand(j.Hand)
+	}
+	t.SetReady(j.Ready)
+	return nil
 }
 
 // Drone is a tracked state type
 type Drone struct {
-	mu      sync.RWMutex
 	changes *statesync.ChangeSet
 	schema  *statesync.Schema
 
@@ -242,10 +343,13 @@ func NewDrone() *Drone {
 	return t
 }
 
-// ResetToDefaults resets all fields to their default values
+// ResetToDefaults resets all fields to their default values.
+// A zero Drone also gets its ChangeSet and schema.
 func (t *Drone) ResetToDefaults() {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+	if t.changes == nil {
+		t.changes = statesync.NewChangeSet()
+		t.schema = DroneSchema()
+	}
 	t.id = ""
 	t.x = 0
 	t.y = 0
@@ -269,8 +373,6 @@ func (t *Drone) ClearChanges()                 { t.changes.Clear() }
 func (t *Drone) MarkAllDirty()                 { t.changes.MarkAll(4) }
 
 func (t *Drone) GetFieldValue(index uint8) interface{} {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
 	switch index {
 	case 0:
 		return t.id
@@ -286,12 +388,48 @@ func (t *Drone) GetFieldValue(index uint8) interface{} {
 	return nil
 }
 
+// SetFieldValue sets a field from a decoded value (see statesync.FieldSetter)
+func (t *Drone) SetFieldValue(index uint16, value interface{}) error {
+	switch index {
+	case 0:
+		v, err := statesync.DecodedValue[string](value)
+		if err != nil {
+			return err
+		}
+		t.SetID(v)
+	case 1:
+		v, err := statesync.DecodedValue[float64](value)
+		if err != nil {
+			return err
+		}
+		t.SetX(v)
+	case 2:
+		v, err := statesync.DecodedValue[float64](value)
+		if err != nil {
+			return err
+		}
+		t.SetY(v)
+	case 3:
+		v, err := statesync.DecodedValue[int32](value)
+		if err != nil {
+			return err
+		}
+		t.SetHealth(v)
+	case 4:
+		v, err := statesync.DecodedValue[string](value)
+		if err != nil {
+			return err
+		}
+		t.SetOwnerID(v)
+	}
+	return nil
+}
+
 // FastEncoder implementation - zero allocation encoding
 // Generated only for types with all primitive fields (no maps/arrays/structs)
+// and at most 256 of them
 
 func (t *Drone) EncodeChangesTo(e *statesync.Encoder) {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
 
 	// Count and write number of changes
 	changes := t.changes
@@ -337,10 +475,8 @@ func (t *Drone) EncodeChangesTo(e *statesync.Encoder) {
 }
 
 func (t *Drone) EncodeAllTo(e *statesync.Encoder) {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
 
-	// Encode all fields directly (no interface{} boxing)
+	// Encode all synced fields directly (no interface{} boxing)
 	e.WriteString(t.id)
 	e.WriteFloat64(t.x)
 	e.WriteFloat64(t.y)
@@ -352,97 +488,121 @@ func (t *Drone) EncodeAllTo(e *statesync.Encoder) {
 
 // ID returns the current value
 func (t *Drone) ID() string {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
 	return t.id
 }
 
 // SetID sets the value and marks it as changed
 func (t *Drone) SetID(v string) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
 	if t.id != v {
 		t.id = v
 		t.changes.Mark(0, statesync.OpReplace)
 	}
-
 }
 
 // X returns the current value
 func (t *Drone) X() float64 {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
 	return t.x
 }
 
 // SetX sets the value and marks it as changed
 func (t *Drone) SetX(v float64) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
 	if t.x != v {
 		t.x = v
 		t.changes.Mark(1, statesync.OpReplace)
 	}
-
 }
 
 // Y returns the current value
 func (t *Drone) Y() float64 {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
 	return t.y
 }
 
 // SetY sets the value and marks it as changed
 func (t *Drone) SetY(v float64) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
 	if t.y != v {
 		t.y = v
 		t.changes.Mark(2, statesync.OpReplace)
 	}
-
 }
 
 // Health returns the current value
 func (t *Drone) Health() int32 {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
 	return t.health
 }
 
 // SetHealth sets the value and marks it as changed
 func (t *Drone) SetHealth(v int32) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
 	if t.health != v {
 		t.health = v
 		t.changes.Mark(3, statesync.OpReplace)
 	}
-
 }
 
 // OwnerID returns the current value
 func (t *Drone) OwnerID() string {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
 	return t.ownerid
 }
 
 // SetOwnerID sets the value and marks it as changed
 func (t *Drone) SetOwnerID(v string) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
 	if t.ownerid != v {
 		t.ownerid = v
 		t.changes.Mark(4, statesync.OpReplace)
 	}
+}
+
+// Clone returns a deep copy of Drone, including its ChangeSet (see statesync.Cloner)
+func (s *Drone) Clone() *Drone {
+	if s == nil {
+		return nil
+	}
+	return &Drone{
+		changes: s.changes.Clone(),
+		schema:  s.schema,
+		id:      s.id,
+		x:       s.x,
+		y:       s.y,
+		health:  s.health,
+		ownerid: s.ownerid,
+	}
+}
 
+// ---- JSON serialization ----
+
+type droneJSON struct {
+	ID      string  `json:"id"`
+	X       float64 `json:"x"`
+	Y       float64 `json:"y"`
+	Health  int32   `json:"health"`
+	OwnerID string  `json:"ownerId"`
+}
+
+func (t *Drone) MarshalJSON() ([]byte, error) {
+	if t == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(droneJSON{
+		ID:      t.id,
+		X:       t.x,
+		Y:       t.y,
+		Health:  t.health,
+		OwnerID: t.ownerid,
+	})
+}
+
+func (t *Drone) UnmarshalJSON(data []byte) error {
+	var j droneJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	init := NewDrone()
+	*t = *init
+	t.SetID(j.ID)
+	t.SetX(j.X)
+	t.SetY(j.Y)
+	t.SetHealth(j.Health)
+	t.SetOwnerID(j.OwnerID)
+	return nil
 }
 
 // GameState is a tracked state type
@@ -469,14 +629,19 @@ func NewGameState() *GameState {
 	return t
 }
 
-// ResetToDefaults resets all fields to their default values
+// ResetToDefaults resets all fields to their default values.
+// A zero GameState also gets its ChangeSet and schema.
 func (t *GameState) ResetToDefaults() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	if t.changes == nil {
+		t.changes = statesync.NewChangeSet()
+		t.schema = GameStateSchema()
+	}
 	t.round = 1
 	t.phase = "lobby"
-	t.players = nil
-	t.scores = nil
+	t.players = make([]Player, 0)
+	t.scores = make(map[string]int64)
 	t.secretseed = 0
 	t.speedmult = GetGameConfig().Speed
 	t.changes.MarkAll(5)
@@ -487,7 +652,8 @@ func GameStateSchema() *statesync.Schema {
 	return statesync.NewSchemaBuilder("GameState").
 		WithID(1).Int32("Round").String("Phase").
 		ArrayByKey("Players", statesync.TypeStruct, PlayerSchema(), "ID").
-		Map("Scores", statesync.TypeInt64, nil).Int64("SecretSeed").Float64("SpeedMult").
+		Map("Scores", statesync.TypeInt64, nil).Int64("SecretSeed").
+		Views("admin").Float64("SpeedMult").
 		Build()
 }
 
@@ -507,9 +673,21 @@ func (t *GameState) GetFieldValue(index uint8) interface{} {
 	case 1:
 		return t.phase
 	case 2:
-		return t.players
+		if t.players == nil {
+			return nil
+		}
+		cp := make([]Player, len(t.players))
+		copy(cp, t.players)
+		return cp
 	case 3:
-		return t.scores
+		if t.scores == nil {
+			return nil
+		}
+		cp := make(map[string]int64, len(t.scores))
+		for k, v := range t.scores {
+			cp[k] = v
+		}
+		return cp
 	case 4:
 		return t.secretseed
 	case 5:
@@ -518,6 +696,49 @@ func (t *GameState) GetFieldValue(index uint8) interface{} {
 	return nil
 }
 
+// SetFieldValue sets a field from a decoded value (see statesync.FieldSetter)
+func (t *GameState) SetFieldValue(index uint16, value interface{}) error {
+	switch index {
+	case 0:
+		v, err := statesync.DecodedValue[int32](value)
+		if err != nil {
+			return err
+		}
+		t.SetRound(v)
+	case 1:
+		v, err := statesync.DecodedValue[string](value)
+		if err != nil {
+			return err
+		}
+		t.SetPhase(v)
+	case 2:
+		v, err := statesync.DecodedSlice(value, statesync.DecodedStruct[Player])
+		if err != nil {
+			return err
+		}
+		t.SetPlayers(v)
+	case 3:
+		v, err := statesync.DecodedMap(value, statesync.DecodedValue[int64])
+		if err != nil {
+			return err
+		}
+		t.SetScores(v)
+	case 4:
+		v, err := statesync.DecodedValue[int64](value)
+		if err != nil {
+			return err
+		}
+		t.SetSecretSeed(v)
+	case 5:
+		v, err := statesync.DecodedValue[float64](value)
+		if err != nil {
+			return err
+		}
+		t.SetSpeedMult(v)
+	}
+	return nil
+}
+
 // Getters and Setters
 
 // Round returns the current value
@@ -531,12 +752,10 @@ func (t *GameState) Round() int32 {
 func (t *GameState) SetRound(v int32) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-
 	if t.round != v {
 		t.round = v
 		t.changes.Mark(0, statesync.OpReplace)
 	}
-
 }
 
 // Phase returns the current value
@@ -550,12 +769,10 @@ func (t *GameState) Phase() string {
 func (t *GameState) SetPhase(v string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-
 	if t.phase != v {
 		t.phase = v
 		t.changes.Mark(1, statesync.OpReplace)
 	}
-
 }
 
 // Players returns the current value
@@ -623,10 +840,18 @@ func (t *GameState) PlayersAt(index int) Player {
 }
 
 // Scores returns the current value
+// Returns a snapshot copy of the map (safe for concurrent iteration)
 func (t *GameState) Scores() map[string]int64 {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
-	return t.scores
+	if t.scores == nil {
+		return nil
+	}
+	cp := make(map[string]int64, len(t.scores))
+	for k, v := range t.scores {
+		cp[k] = v
+	}
+	return cp
 }
 
 // SetScores replaces the entire map
@@ -690,12 +915,10 @@ func (t *GameState) SecretSeed() int64 {
 func (t *GameState) SetSecretSeed(v int64) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-
 	if t.secretseed != v {
 		t.secretseed = v
 		t.changes.Mark(4, statesync.OpReplace)
 	}
-
 }
 
 // SpeedMult returns the current value
@@ -709,12 +932,144 @@ func (t *GameState) SpeedMult() float64 {
 func (t *GameState) SetSpeedMult(v float64) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-
 	if t.speedmult != v {
 		t.speedmult = v
 		t.changes.Mark(5, statesync.OpReplace)
 	}
+}
+
+// ShallowClone creates a shallow copy of GameState suitable for projections/filters.
+// The clone gets a deep-copied ChangeSet so projection modifications don't corrupt the original.
+// Maps are shallow-copied (new map, same value entries). Slices are deep-copied.
+func (s *GameState) ShallowClone() *GameState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	clone := &GameState{
+		changes:    s.changes.CloneForFilter(),
+		schema:     s.schema,
+		round:      s.round,
+		phase:      s.phase,
+		secretseed: s.secretseed,
+		speedmult:  s.speedmult,
+	}
+	if s.players != nil {
+		clone.players = make([]Player, len(s.players))
+		copy(clone.players, s.players)
+	}
+	if s.scores != nil {
+		clone.scores = make(map[string]int64, len(s.scores))
+		for k, v := range s.scores {
+			clone.scores[k] = v
+		}
+	}
 
+	return clone
+}
+
+// WithFieldsFrom returns a shallow copy of GameState sharing its ChangeSet, with the
+// given fields taken from src (see statesync.FieldCopier).
+func (s *GameState) WithFieldsFrom(src *GameState, fields []uint16) *GameState {
+	s.mu.RLock()
+	clone := &GameState{
+		changes:    s.changes,
+		schema:     s.schema,
+		round:      s.round,
+		phase:      s.phase,
+		players:    s.players,
+		scores:     s.scores,
+		secretseed: s.secretseed,
+		speedmult:  s.speedmult,
+	}
+	s.mu.RUnlock()
+
+	src.mu.RLock()
+	defer src.mu.RUnlock()
+	for _, idx := range fields {
+		switch idx {
+		case 0:
+			clone.round = src.round
+		case 1:
+			clone.phase = src.phase
+		case 2:
+			clone.players = src.players
+		case 3:
+			clone.scores = src.scores
+		case 4:
+			clone.secretseed = src.secretseed
+		case 5:
+			clone.speedmult = src.speedmult
+		}
+	}
+	return clone
+}
+
+// Clone returns a deep copy of GameState, including its ChangeSet (see statesync.Cloner)
+func (s *GameState) Clone() *GameState {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return &GameState{
+		changes:    s.changes.Clone(),
+		schema:     s.schema,
+		round:      s.round,
+		phase:      s.phase,
+		players:    statesync.CloneSlice(s.players, func(v Player) Player { return *v.Clone() }),
+		scores:     statesync.CloneMap(s.scores, nil),
+		secretseed: s.secretseed,
+		speedmult:  s.speedmult,
+	}
+}
+
+// ---- JSON serialization ----
+
+type gameStateJSON struct {
+	Round      int32            `json:"round"`
+	Phase      string           `json:"phase"`
+	Players    []Player         `json:"players,omitempty"`
+	Scores     map[string]int64 `json:"scores,omitempty"`
+	SecretSeed int64            `json:"secretSeed"`
+	SpeedMult  float64          `json:"speedMult"`
+}
+
+func (t *GameState) MarshalJSON() ([]byte, error) {
+	if t == nil {
+		return []byte("null"), nil
+	}
+	players := t.Players()
+	scores := t.Scores()
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return json.Marshal(gameStateJSON{
+		Round:      t.round,
+		Phase:      t.phase,
+		Players:    players,
+		Scores:     scores,
+		SecretSeed: t.secretseed,
+		SpeedMult:  t.speedmult,
+	})
+}
+
+func (t *GameState) UnmarshalJSON(data []byte) error {
+	var j gameStateJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	init := NewGameState()
+	*t = *init
+	t.SetRound(j.Round)
+	t.SetPhase(j.Phase)
+	if j.Players != nil {
+		t.SetPlayers(j.Players)
+	}
+	if j.Scores != nil {
+		t.SetScores(j.Scores)
+	}
+	t.SetSecretSeed(j.SecretSeed)
+	t.SetSpeedMult(j.SpeedMult)
+	return nil
 }
 
 // DroneMode is a tracked state type
@@ -740,11 +1095,16 @@ func NewDroneMode() *DroneMode {
 	return t
 }
 
-// ResetToDefaults resets all fields to their default values
+// ResetToDefaults resets all fields to their default values.
+// A zero DroneMode also gets its ChangeSet and schema.
 func (t *DroneMode) ResetToDefaults() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	t.drones = nil
+	if t.changes == nil {
+		t.changes = statesync.NewChangeSet()
+		t.schema = DroneModeSchema()
+	}
+	t.drones = make([]Drone, 0)
 	t.spawninterval = 5
 	t.maxdrones = 10
 	t.gameduration = 300
@@ -772,7 +1132,12 @@ func (t *DroneMode) GetFieldValue(index uint8) interface{} {
 	defer t.mu.RUnlock()
 	switch index {
 	case 0:
-		return t.drones
+		if t.drones == nil {
+			return nil
+		}
+		cp := make([]Drone, len(t.drones))
+		copy(cp, t.drones)
+		return cp
 	case 1:
 		return t.spawninterval
 	case 2:
@@ -785,6 +1150,43 @@ func (t *DroneMode) GetFieldValue(index uint8) interface{} {
 	return nil
 }
 
+// SetFieldValue sets a field from a decoded value (see statesync.FieldSetter)
+func (t *DroneMode) SetFieldValue(index uint16, value interface{}) error {
+	switch index {
+	case 0:
+		v, err := statesync.DecodedSlice(value, statesync.DecodedStruct[Drone])
+		if err != nil {
+			return err
+		}
+		t.SetDrones(v)
+	case 1:
+		v, err := statesync.DecodedValue[int32](value)
+		if err != nil {
+			return err
+		}
+		t.SetSpawnInterval(v)
+	case 2:
+		v, err := statesync.DecodedValue[int32](value)
+		if err != nil {
+			return err
+		}
+		t.SetMaxDrones(v)
+	case 3:
+		v, err := statesync.DecodedValue[int32](value)
+		if err != nil {
+			return err
+		}
+		t.SetGameDuration(v)
+	case 4:
+		v, err := statesync.DecodedValue[int32](value)
+		if err != nil {
+			return err
+		}
+		t.SetTimeRemaining(v)
+	}
+	return nil
+}
+
 // Getters and Setters
 
 // Drones returns the current value
@@ -862,12 +1264,10 @@ func (t *DroneMode) SpawnInterval() int32 {
 func (t *DroneMode) SetSpawnInterval(v int32) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-
 	if t.spawninterval != v {
 		t.spawninterval = v
 		t.changes.Mark(1, statesync.OpReplace)
 	}
-
 }
 
 // MaxDrones returns the current value
@@ -881,12 +1281,10 @@ func (t *DroneMode) MaxDrones() int32 {
 func (t *DroneMode) SetMaxDrones(v int32) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-
 	if t.maxdrones != v {
 		t.maxdrones = v
 		t.changes.Mark(2, statesync.OpReplace)
 	}
-
 }
 
 // GameDuration returns the current value
@@ -900,12 +1298,10 @@ func (t *DroneMode) GameDuration() int32 {
 func (t *DroneMode) SetGameDuration(v int32) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-
 	if t.gameduration != v {
 		t.gameduration = v
 		t.changes.Mark(3, statesync.OpReplace)
 	}
-
 }
 
 // TimeRemaining returns the current value
@@ -919,12 +1315,128 @@ func (t *DroneMode) TimeRemaining() int32 {
 func (t *DroneMode) SetTimeRemaining(v int32) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-
 	if t.timeremaining != v {
 		t.timeremaining = v
 		t.changes.Mark(4, statesync.OpReplace)
 	}
+}
+
+// ShallowClone creates a shallow copy of DroneMode suitable for projections/filters.
+// The clone gets a deep-copied ChangeSet so projection modifications don't corrupt the original.
+// Maps are shallow-copied (new map, same value entries). Slices are deep-copied.
+func (s *DroneMode) ShallowClone() *DroneMode {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	clone := &DroneMode{
+		changes:       s.changes.CloneForFilter(),
+		schema:        s.schema,
+		spawninterval: s.spawninterval,
+		maxdrones:     s.maxdrones,
+		gameduration:  s.gameduration,
+		timeremaining: s.timeremaining,
+	}
+	if s.drones != nil {
+		clone.drones = make([]Drone, len(s.drones))
+		copy(clone.drones, s.drones)
+	}
+
+	return clone
+}
+
+// WithFieldsFrom returns a shallow copy of DroneMode sharing its ChangeSet, with the
+// given fields taken from src (see statesync.FieldCopier).
+func (s *DroneMode) WithFieldsFrom(src *DroneMode, fields []uint16) *DroneMode {
+	s.mu.RLock()
+	clone := &DroneMode{
+		changes:       s.changes,
+		schema:        s.schema,
+		drones:        s.drones,
+		spawninterval: s.spawninterval,
+		maxdrones:     s.maxdrones,
+		gameduration:  s.gameduration,
+		timeremaining: s.timeremaining,
+	}
+	s.mu.RUnlock()
+
+	src.mu.RLock()
+	defer src.mu.RUnlock()
+	for _, idx := range fields {
+		switch idx {
+		case 0:
+			clone.drones = src.drones
+		case 1:
+			clone.spawninterval = src.spawninterval
+		case 2:
+			clone.maxdrones = src.maxdrones
+		case 3:
+			clone.gameduration = src.gameduration
+		case 4:
+			clone.timeremaining = src.timeremaining
+		}
+	}
+	return clone
+}
+
+// Clone returns a deep copy of DroneMode, including its ChangeSet (see statesync.Cloner)
+func (s *DroneMode) Clone() *DroneMode {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return &DroneMode{
+		changes:       s.changes.Clone(),
+		schema:        s.schema,
+		drones:        statesync.CloneSlice(s.drones, func(v Drone) Drone { return *v.Clone() }),
+		spawninterval: s.spawninterval,
+		maxdrones:     s.maxdrones,
+		gameduration:  s.gameduration,
+		timeremaining: s.timeremaining,
+	}
+}
+
+// ---- JSON serialization ----
+
+type droneModeJSON struct {
+	Drones        []Drone `json:"drones,omitempty"`
+	SpawnInterval int32   `json:"spawnInterval"`
+	MaxDrones     int32   `json:"maxDrones"`
+	GameDuration  int32   `json:"gameDuration"`
+	TimeRemaining int32   `json:"timeRemaining"`
+}
+
+func (t *DroneMode) MarshalJSON() ([]byte, error) {
+	if t == nil {
+		return []byte("null"), nil
+	}
+	drones := t.Drones()
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return json.Marshal(droneModeJSON{
+		Drones:        drones,
+		SpawnInterval: t.spawninterval,
+		MaxDrones:     t.maxdrones,
+		GameDuration:  t.gameduration,
+		TimeRemaining: t.timeremaining,
+	})
+}
 
+func (t *DroneMode) UnmarshalJSON(data []byte) error {
+	var j droneModeJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	init := NewDroneMode()
+	*t = *init
+	if j.Drones != nil {
+		t.SetDrones(j.Drones)
+	}
+	t.SetSpawnInterval(j.SpawnInterval)
+	t.SetMaxDrones(j.MaxDrones)
+	t.SetGameDuration(j.GameDuration)
+	t.SetTimeRemaining(j.TimeRemaining)
+	return nil
 }
 
 // TournamentMode is a tracked state type
@@ -949,13 +1461,18 @@ func NewTournamentMode() *TournamentMode {
 	return t
 }
 
-// ResetToDefaults resets all fields to their default values
+// ResetToDefaults resets all fields to their default values.
+// A zero TournamentMode also gets its ChangeSet and schema.
 func (t *TournamentMode) ResetToDefaults() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	if t.changes == nil {
+		t.changes = statesync.NewChangeSet()
+		t.schema = TournamentModeSchema()
+	}
 	t.bracketround = 1
-	t.matches = nil
-	t.eliminated = nil
+	t.matches = make(map[string]string)
+	t.eliminated = make([]string, 0)
 	t.prizepool = 0
 	t.changes.MarkAll(3)
 }
@@ -983,15 +1500,58 @@ func (t *TournamentMode) GetFieldValue(index uint8) interface{} {
 	case 0:
 		return t.bracketround
 	case 1:
-		return t.matches
+		if t.matches == nil {
+			return nil
+		}
+		cp := make(map[string]string, len(t.matches))
+		for k, v := range t.matches {
+			cp[k] = v
+		}
+		return cp
 	case 2:
-		return t.eliminated
+		if t.eliminated == nil {
+			return nil
+		}
+		cp := make([]string, len(t.eliminated))
+		copy(cp, t.eliminated)
+		return cp
 	case 3:
 		return t.prizepool
 	}
 	return nil
 }
 
+// SetFieldValue sets a field from a decoded value (see statesync.FieldSetter)
+func (t *TournamentMode) SetFieldValue(index uint16, value interface{}) error {
+	switch index {
+	case 0:
+		v, err := statesync.DecodedValue[int32](value)
+		if err != nil {
+			return err
+		}
+		t.SetBracketRound(v)
+	case 1:
+		v, err := statesync.DecodedMap(value, statesync.DecodedValue[string])
+		if err != nil {
+			return err
+		}
+		t.SetMatches(v)
+	case 2:
+		v, err := statesync.DecodedSlice(value, statesync.DecodedValue[string])
+		if err != nil {
+			return err
+		}
+		t.SetEliminated(v)
+	case 3:
+		v, err := statesync.DecodedValue[int64](value)
+		if err != nil {
+			return err
+		}
+		t.SetPrizePool(v)
+	}
+	return nil
+}
+
 // Getters and Setters
 
 // BracketRound returns the current value
@@ -1005,19 +1565,25 @@ func (t *TournamentMode) BracketRound() int32 {
 func (t *TournamentMode) SetBracketRound(v int32) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-
 	if t.bracketround != v {
 		t.bracketround = v
 		t.changes.Mark(0, statesync.OpReplace)
 	}
-
 }
 
 // Matches returns the current value
+// Returns a snapshot copy of the map (safe for concurrent iteration)
 func (t *TournamentMode) Matches() map[string]string {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
-	return t.matches
+	if t.matches == nil {
+		return nil
+	}
+	cp := make(map[string]string, len(t.matches))
+	for k, v := range t.matches {
+		cp[k] = v
+	}
+	return cp
 }
 
 // SetMatches replaces the entire map
@@ -1145,12 +1711,128 @@ func (t *TournamentMode) PrizePool() int64 {
 func (t *TournamentMode) SetPrizePool(v int64) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-
 	if t.prizepool != v {
 		t.prizepool = v
 		t.changes.Mark(3, statesync.OpReplace)
 	}
+}
 
+// ShallowClone creates a shallow copy of TournamentMode suitable for projections/filters.
+// The clone gets a deep-copied ChangeSet so projection modifications don't corrupt the original.
+// Maps are shallow-copied (new map, same value entries). Slices are deep-copied.
+func (s *TournamentMode) ShallowClone() *TournamentMode {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	clone := &TournamentMode{
+		changes:      s.changes.CloneForFilter(),
+		schema:       s.schema,
+		bracketround: s.bracketround,
+		prizepool:    s.prizepool,
+	}
+	if s.matches != nil {
+		clone.matches = make(map[string]string, len(s.matches))
+		for k, v := range s.matches {
+			clone.matches[k] = v
+		}
+	}
+	if s.eliminated != nil {
+		clone.eliminated = make([]string, len(s.eliminated))
+		copy(clone.eliminated, s.eliminated)
+	}
+
+	return clone
+}
+
+// WithFieldsFrom returns a shallow copy of TournamentMode sharing its ChangeSet, with the
+// given fields taken from src (see statesync.FieldCopier).
+func (s *TournamentMode) WithFieldsFrom(src *TournamentMode, fields []uint16) *TournamentMode {
+	s.mu.RLock()
+	clone := &TournamentMode{
+		changes:      s.changes,
+		schema:       s.schema,
+		bracketround: s.bracketround,
+		matches:      s.matches,
+		eliminated:   s.eliminated,
+		prizepool:    s.prizepool,
+	}
+	s.mu.RUnlock()
+
+	src.mu.RLock()
+	defer src.mu.RUnlock()
+	for _, idx := range fields {
+		switch idx {
+		case 0:
+			clone.bracketround = src.bracketround
+		case 1:
+			clone.matches = src.matches
+		case 2:
+			clone.eliminated = src.eliminated
+		case 3:
+			clone.prizepool = src.prizepool
+		}
+	}
+	return clone
+}
+
+// Clone returns a deep copy of TournamentMode, including its ChangeSet (see statesync.Cloner)
+func (s *TournamentMode) Clone() *TournamentMode {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return &TournamentMode{
+		changes:      s.changes.Clone(),
+		schema:       s.schema,
+		bracketround: s.bracketround,
+		matches:      statesync.CloneMap(s.matches, nil),
+		eliminated:   statesync.CloneSlice(s.eliminated, nil),
+		prizepool:    s.prizepool,
+	}
+}
+
+// ---- JSON serialization ----
+
+type tournamentModeJSON struct {
+	BracketRound int32             `json:"bracketRound"`
+	Matches      map[string]string `json:"matches,omitempty"`
+	Eliminated   []string          `json:"eliminated,omitempty"`
+	PrizePool    int64             `json:"prizePool"`
+}
+
+func (t *TournamentMode) MarshalJSON() ([]byte, error) {
+	if t == nil {
+		return []byte("null"), nil
+	}
+	matches := t.Matches()
+	eliminated := t.Eliminated()
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return json.Marshal(tournamentModeJSON{
+		BracketRound: t.bracketround,
+		Matches:      matches,
+		Eliminated:   eliminated,
+		PrizePool:    t.prizepool,
+	})
+}
+
+func (t *TournamentMode) UnmarshalJSON(data []byte) error {
+	var j tournamentModeJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	init := NewTournamentMode()
+	*t = *init
+	t.SetBracketRound(j.BracketRound)
+	if j.Matches != nil {
+		t.SetMatches(j.Matches)
+	}
+	if j.Eliminated != nil {
+		t.SetEliminated(j.Eliminated)
+	}
+	t.SetPrizePool(j.PrizePool)
+	return nil
 }
 
 // ==================================================
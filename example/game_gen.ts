@@ -12,6 +12,15 @@ export interface Player {
   Ready: boolean;
 }
 
+// Drone interface
+export interface Drone {
+  ID: string;
+  X: number;
+  Y: number;
+  Health: number;
+  OwnerID: string;
+}
+
 // GameState interface
 export interface GameState {
   Round: number;
@@ -19,6 +28,24 @@ export interface GameState {
   Players: Player[];
   Scores: Record<string, number>;
   SecretSeed: number;
+  SpeedMult: number;
+}
+
+// DroneMode interface
+export interface DroneMode {
+  Drones: Drone[];
+  SpawnInterval: number;
+  MaxDrones: number;
+  GameDuration: number;
+  TimeRemaining: number;
+}
+
+// TournamentMode interface
+export interface TournamentMode {
+  BracketRound: number;
+  Matches: Record<string, string>;
+  Eliminated: string[];
+  PrizePool: number;
 }
 
 
@@ -52,6 +79,33 @@ export const PlayerSchema: Schema = defineSchema(
   ]
 );
 
+export const DroneSchema: Schema = defineSchema(
+  4,
+  'Drone',
+  [
+    {
+      name: 'ID',
+      type: FieldType.String,
+    },
+    {
+      name: 'X',
+      type: FieldType.Float64,
+    },
+    {
+      name: 'Y',
+      type: FieldType.Float64,
+    },
+    {
+      name: 'Health',
+      type: FieldType.Int32,
+    },
+    {
+      name: 'OwnerID',
+      type: FieldType.String,
+    },
+  ]
+);
+
 export const GameStateSchema: Schema = defineSchema(
   1,
   'GameState',
@@ -80,6 +134,65 @@ export const GameStateSchema: Schema = defineSchema(
       name: 'SecretSeed',
       type: FieldType.Int64,
     },
+    {
+      name: 'SpeedMult',
+      type: FieldType.Float64,
+    },
+  ]
+);
+
+export const DroneModeSchema: Schema = defineSchema(
+  3,
+  'DroneMode',
+  [
+    {
+      name: 'Drones',
+      type: FieldType.Array,
+      elemType: FieldType.Struct,
+      childSchema: DroneSchema,
+      keyField: 'ID',
+    },
+    {
+      name: 'SpawnInterval',
+      type: FieldType.Int32,
+    },
+    {
+      name: 'MaxDrones',
+      type: FieldType.Int32,
+    },
+    {
+      name: 'GameDuration',
+      type: FieldType.Int32,
+    },
+    {
+      name: 'TimeRemaining',
+      type: FieldType.Int32,
+    },
+  ]
+);
+
+export const TournamentModeSchema: Schema = defineSchema(
+  5,
+  'TournamentMode',
+  [
+    {
+      name: 'BracketRound',
+      type: FieldType.Int32,
+    },
+    {
+      name: 'Matches',
+      type: FieldType.Map,
+      elemType: FieldType.String,
+    },
+    {
+      name: 'Eliminated',
+      type: FieldType.Array,
+      elemType: FieldType.String,
+    },
+    {
+      name: 'PrizePool',
+      type: FieldType.Int64,
+    },
   ]
 );
 
@@ -89,33 +202,97 @@ export function createRegistry(): SchemaRegistry {
   const registry = new SchemaRegistry();
 
   registry.register(PlayerSchema);
+  registry.register(DroneSchema);
   registry.register(GameStateSchema);
+  registry.register(DroneModeSchema);
+  registry.register(TournamentModeSchema);
   return registry;
 }
 
 
-// Player state container
-export function createPlayerState(registry?: SchemaRegistry): SyncState<Player> {
-  const reg = registry || createRegistry();
-  return new SyncState<Player>(PlayerSchema, reg, {
+// Default Player values
+export function defaultPlayer(): Player {
+  return {
     ID: '',
     Name: '',
     Score: 0,
     Hand: [],
     Ready: false,
-  });
+  };
 }
 
-// GameState state container
-export function createGameStateState(registry?: SchemaRegistry): SyncState<GameState> {
+// Player state container
+export function createPlayerState(registry?: SchemaRegistry): SyncState<Player> {
   const reg = registry || createRegistry();
-  return new SyncState<GameState>(GameStateSchema, reg, {
-    Round: 0,
-    Phase: '',
+  return new SyncState<Player>(PlayerSchema, reg, defaultPlayer());
+}
+
+// Default Drone values
+export function defaultDrone(): Drone {
+  return {
+    ID: '',
+    X: 0,
+    Y: 0,
+    Health: 100,
+    OwnerID: '',
+  };
+}
+
+// Drone state container
+export function createDroneState(registry?: SchemaRegistry): SyncState<Drone> {
+  const reg = registry || createRegistry();
+  return new SyncState<Drone>(DroneSchema, reg, defaultDrone());
+}
+
+// Default GameState values
+export function defaultGameState(): GameState {
+  return {
+    Round: 1,
+    Phase: 'lobby',
     Players: [],
     Scores: {},
     SecretSeed: 0,
-  });
+    SpeedMult: getGameConfig().speed,
+  };
+}
+
+// GameState state container
+export function createGameStateState(registry?: SchemaRegistry): SyncState<GameState> {
+  const reg = registry || createRegistry();
+  return new SyncState<GameState>(GameStateSchema, reg, defaultGameState());
+}
+
+// Default DroneMode values
+export function defaultDroneMode(): DroneMode {
+  return {
+    Drones: [],
+    SpawnInterval: 5,
+    MaxDrones: 10,
+    GameDuration: 300,
+    TimeRemaining: 300,
+  };
+}
+
+// DroneMode state container
+export function createDroneModeState(registry?: SchemaRegistry): SyncState<DroneMode> {
+  const reg = registry || createRegistry();
+  return new SyncState<DroneMode>(DroneModeSchema, reg, defaultDroneMode());
+}
+
+// Default TournamentMode values
+export function defaultTournamentMode(): TournamentMode {
+  return {
+    BracketRound: 1,
+    Matches: {},
+    Eliminated: [],
+    PrizePool: 0,
+  };
+}
+
+// TournamentMode state container
+export function createTournamentModeState(registry?: SchemaRegistry): SyncState<TournamentMode> {
+  const reg = registry || createRegistry();
+  return new SyncState<TournamentMode>(TournamentModeSchema, reg, defaultTournamentMode());
 }
 
 
@@ -132,5 +309,146 @@ export type ownerView = 'owner';
 export type ViewType = 'all' | 'admin' | 'owner';
 
 
+
 // Re-export types for convenience
 export { FieldType, Operation, Decoder, SchemaRegistry, SyncState } from '@statediff/client';
+
+// ==================================================
+// Schema Activation Manager - manages root schemas
+// ==================================================
+
+export type SchemaName =  | 'GameState' | 'DroneMode' | 'TournamentMode';
+
+export interface SchemaInstance<T> {
+  name: SchemaName;
+  state: SyncState<T>;
+  active: boolean;
+}
+
+class SchemaActivationManager {
+  private active: Map<SchemaName, boolean> = new Map();
+  private instances: Map<SchemaName, SyncState<any>> = new Map();
+  private registry: SchemaRegistry;
+
+  constructor() {
+    this.registry = createRegistry();
+    // Initialize with default activation states
+    this.active.set('GameState', true);
+    this.instances.set('GameState', createGameStateState(this.registry));
+    this.active.set('DroneMode', false);
+    this.active.set('TournamentMode', false);
+  }
+
+  isActive(name: SchemaName): boolean {
+    return this.active.get(name) ?? false;
+  }
+
+  activate(name: SchemaName): void {
+    if (this.active.get(name)) return;
+
+    // Create new instance with defaults
+    switch (name) {
+      case 'GameState':
+        this.instances.set(name, createGameStateState(this.registry));
+        break;
+      case 'DroneMode':
+        this.instances.set(name, createDroneModeState(this.registry));
+        break;
+      case 'TournamentMode':
+        this.instances.set(name, createTournamentModeState(this.registry));
+        break;
+    }
+    this.active.set(name, true);
+  }
+
+  deactivate(name: SchemaName): void {
+    this.instances.delete(name);
+    this.active.set(name, false);
+  }
+
+  get<T>(name: SchemaName): SyncState<T> | null {
+    return this.instances.get(name) as SyncState<T> | null;
+  }
+
+  getActive(): SchemaName[] {
+    const result: SchemaName[] = [];
+    this.active.forEach((active, name) => {
+      if (active) result.push(name);
+    });
+    return result;
+  }
+
+  resetAll(): void {
+    this.instances.clear();
+    this.active.set('GameState', true);
+    this.instances.set('GameState', createGameStateState(this.registry));
+    this.active.set('DroneMode', false);
+    this.active.set('TournamentMode', false);
+  }
+}
+
+// Singleton instance
+let schemaManagerInstance: SchemaActivationManager | null = null;
+
+export function getSchemaManager(): SchemaActivationManager {
+  if (!schemaManagerInstance) {
+    schemaManagerInstance = new SchemaActivationManager();
+  }
+  return schemaManagerInstance;
+}
+
+// Convenience functions for typed access
+
+
+
+export function getGameState(): SyncState<GameState> | null {
+  return getSchemaManager().get<GameState>('GameState');
+}
+
+export function activateGameState(): void {
+  getSchemaManager().activate('GameState');
+}
+
+export function deactivateGameState(): void {
+  getSchemaManager().deactivate('GameState');
+}
+
+export function isGameStateActive(): boolean {
+  return getSchemaManager().isActive('GameState');
+}
+
+
+export function getDroneMode(): SyncState<DroneMode> | null {
+  return getSchemaManager().get<DroneMode>('DroneMode');
+}
+
+export function activateDroneMode(): void {
+  getSchemaManager().activate('DroneMode');
+}
+
+export function deactivateDroneMode(): void {
+  getSchemaManager().deactivate('DroneMode');
+}
+
+export function isDroneModeActive(): boolean {
+  return getSchemaManager().isActive('DroneMode');
+}
+
+
+export function getTournamentMode(): SyncState<TournamentMode> | null {
+  return getSchemaManager().get<TournamentMode>('TournamentMode');
+}
+
+export function activateTournamentMode(): void {
+  getSchemaManager().activate('TournamentMode');
+}
+
+export function deactivateTournamentMode(): void {
+  getSchemaManager().deactivate('TournamentMode');
+}
+
+export function isTournamentModeActive(): boolean {
+  return getSchemaManager().isActive('TournamentMode');
+}
+
+
@@ -0,0 +1,180 @@
+package statesync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrInputMissing is returned in re-simulation when no recorded input is left for a source
+var ErrInputMissing = errors.New("no recorded external input")
+
+// ExternalInput is a serialized external payload (e.g. an API response) recorded
+// with the diff it produced, so the logic can be re-simulated without the outside world.
+type ExternalInput struct {
+	Source  string          `json:"source"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// InputRecorder records external inputs. DiffRecorder and ReplayWriter attach
+// recorded inputs to the next recorded diff.
+type InputRecorder interface {
+	RecordInput(source string, payload []byte)
+}
+
+// InputPlayback feeds recorded external inputs back in recording order, per source.
+// Safe for concurrent use, so injectors and an AsyncRunner can share one.
+type InputPlayback struct {
+	mu     sync.Mutex
+	inputs map[string][]playbackInput
+}
+
+// playbackInput is a recorded payload with the seq of the diff it was recorded with
+type playbackInput struct {
+	seq     uint64
+	payload json.RawMessage
+}
+
+// NewInputPlayback collects the external inputs of recorded diffs (e.g. ReplayReader.Records)
+func NewInputPlayback(records []DiffRecord) *InputPlayback {
+	p := &InputPlayback{inputs: make(map[string][]playbackInput)}
+	for _, r := range records {
+		for _, in := range r.Inputs {
+			p.inputs[in.Source] = append(p.inputs[in.Source], playbackInput{seq: r.Seq, payload: in.Payload})
+		}
+	}
+	return p
+}
+
+// Next returns the next recorded payload of a source
+func (p *InputPlayback) Next(source string) (json.RawMessage, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	queue := p.inputs[source]
+	if len(queue) == 0 {
+		return nil, false
+	}
+	p.inputs[source] = queue[1:]
+	return queue[0].payload, true
+}
+
+// take removes and returns the first recorded payload of a source that match accepts,
+// among those recorded with a diff up to seq (the seq the session's next Tick produces)
+func (p *InputPlayback) take(source string, seq uint64, match func(json.RawMessage) bool) (json.RawMessage, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	queue := p.inputs[source]
+	for i, in := range queue {
+		if in.seq > seq {
+			break
+		}
+		if match(in.payload) {
+			p.inputs[source] = append(queue[:i:i], queue[i+1:]...)
+			return in.payload, true
+		}
+	}
+	return nil, false
+}
+
+// Remaining returns the number of inputs not yet fed back
+func (p *InputPlayback) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for _, queue := range p.inputs {
+		n += len(queue)
+	}
+	return n
+}
+
+// WithRecorder records the payloads of InjectInput and Fetch (chainable)
+func (ei *ExternalInjector[T, A, ID]) WithRecorder(r InputRecorder) *ExternalInjector[T, A, ID] {
+	ei.recorder = r
+	return ei
+}
+
+// WithPlayback switches the injector to re-simulation: Fetch feeds recorded
+// payloads instead of calling the outside world (chainable)
+func (ei *ExternalInjector[T, A, ID]) WithPlayback(p *InputPlayback) *ExternalInjector[T, A, ID] {
+	ei.playback = p
+	return ei
+}
+
+// InjectInput serializes an external payload and applies it to the state with apply.
+// apply only sees the serialized payload, so re-simulation can run the same code with
+// the recorded payload. Only if apply succeeds is the payload recorded (see WithRecorder)
+// and the session ticked. A failed apply is rolled back if T implements Cloner; otherwise
+// apply should check the payload before changing the state.
+//
+// Example:
+//
+//	injector := NewExternalInjector(session, "external:chatgpt").WithRecorder(replayWriter)
+//	injector.InjectInput(response, func(state *GameState, payload json.RawMessage) error {
+//	    var resp ChatResponse
+//	    if err := json.Unmarshal(payload, &resp); err != nil {
+//	        return err
+//	    }
+//	    state.SetAIResponse(resp.Text)
+//	    return nil
+//	})
+func (ei *ExternalInjector[T, A, ID]) InjectInput(payload any, apply func(state *T, payload json.RawMessage) error) (map[ID][]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal input: %w", err)
+	}
+	return ei.injectRaw(data, apply)
+}
+
+// Fetch calls the outside world with fetch and injects the result like InjectInput.
+// In re-simulation (see WithPlayback) fetch is not called; the next recorded
+// payload of this injector's source is applied instead.
+func (ei *ExternalInjector[T, A, ID]) Fetch(fetch func() (any, error), apply func(state *T, payload json.RawMessage) error) (map[ID][]byte, error) {
+	if ei.playback != nil {
+		data, ok := ei.playback.Next(ei.source)
+		if !ok {
+			return nil, fmt.Errorf("%w for %s", ErrInputMissing, ei.source)
+		}
+		return ei.injectRaw(data, apply)
+	}
+	payload, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	return ei.InjectInput(payload, apply)
+}
+
+// injectRaw applies a serialized payload and, if apply succeeds, records it and ticks
+func (ei *ExternalInjector[T, A, ID]) injectRaw(data json.RawMessage, apply func(state *T, payload json.RawMessage) error) (map[ID][]byte, error) {
+	if err := ei.applyRaw(data, apply); err != nil {
+		return nil, err
+	}
+	return ei.session.Tick(), nil
+}
+
+// applyRaw applies a serialized payload without ticking and records it if apply succeeds.
+// No tick runs in between, so the payload is recorded with the diff it produced. If apply
+// fails, states implementing Cloner are rolled back like in TrackedState.UpdateAs.
+func (ei *ExternalInjector[T, A, ID]) applyRaw(data json.RawMessage, apply func(state *T, payload json.RawMessage) error) error {
+	ei.session.inputMu.Lock()
+	defer ei.session.inputMu.Unlock()
+
+	var applyErr error
+	ei.session.State().Update(func(state *T) {
+		cloner, rollback := any(*state).(Cloner[T])
+		var backup T
+		if rollback {
+			backup = cloner.Clone()
+		}
+		if applyErr = apply(state, data); applyErr != nil && rollback {
+			restoreState(state, backup)
+		}
+	})
+	if applyErr != nil {
+		return applyErr
+	}
+	if ei.recorder != nil {
+		ei.recorder.RecordInput(ei.source, data)
+	}
+	return nil
+}
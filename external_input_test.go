@@ -0,0 +1,257 @@
+package statesync
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type scoreResponse struct {
+	Score int64 `json:"score"`
+}
+
+func applyScoreResponse(state **ReplayTestState, payload json.RawMessage) error {
+	var resp scoreResponse
+	if err := json.Unmarshal(payload, &resp); err != nil {
+		return err
+	}
+	(*state).SetScore(resp.Score)
+	return nil
+}
+
+func newInputTestSession() *TrackedSession[*ReplayTestState, any, string] {
+	tracked := NewTrackedState[*ReplayTestState, any](NewReplayTestState(), nil)
+	return NewTrackedSession[*ReplayTestState, any, string](tracked)
+}
+
+func TestExternalInjector_RecordsInputs(t *testing.T) {
+	session := newInputTestSession()
+	recorder := NewDiffRecorder()
+	session.SetHooks(RecordingHooks[*ReplayTestState, string](recorder))
+
+	injector := NewExternalInjector(session, "external:scores").WithRecorder(recorder)
+	if _, err := injector.InjectInput(scoreResponse{Score: 42}, applyScoreResponse); err != nil {
+		t.Fatalf("InjectInput: %v", err)
+	}
+	// An input that changes nothing is still recorded
+	ignore := func(**ReplayTestState, json.RawMessage) error { return nil }
+	if _, err := injector.InjectInput(scoreResponse{Score: 43}, ignore); err != nil {
+		t.Fatalf("InjectInput: %v", err)
+	}
+
+	records := recorder.Records()
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	in := records[0].Inputs
+	if len(in) != 1 || in[0].Source != "external:scores" || string(in[0].Payload) != `{"score":42}` {
+		t.Errorf("unexpected inputs %+v", in)
+	}
+	if len(records[1].Data) != 0 || len(records[1].Inputs) != 1 {
+		t.Errorf("expected an input-only record, got %+v", records[1])
+	}
+
+	// Input-only records replay as no-ops
+	registry := NewSchemaRegistry()
+	registry.Register(NewReplayTestState().Schema())
+	replayer := NewMapReplayer(registry)
+	if err := replayer.ReplayAll(records); err != nil {
+		t.Fatalf("ReplayAll: %v", err)
+	}
+	if replayer.State()["Score"] != int64(42) {
+		t.Errorf("replayed state = %v", replayer.State())
+	}
+
+	// Failing apply is reported, and neither recorded nor ticked
+	wantErr := errors.New("bad payload")
+	seq := session.Seq()
+	_, err := injector.InjectInput(nil, func(**ReplayTestState, json.RawMessage) error { return wantErr })
+	if !errors.Is(err, wantErr) {
+		t.Errorf("expected apply error, got %v", err)
+	}
+	if session.Seq() != seq || len(recorder.Records()) != 2 {
+		t.Errorf("failed input ticked or was recorded: seq %d -> %d, %d records", seq, session.Seq(), len(recorder.Records()))
+	}
+	injector.InjectInput(scoreResponse{Score: 44}, applyScoreResponse)
+	if records := recorder.Records(); len(records) != 3 || len(records[2].Inputs) != 1 || string(records[2].Inputs[0].Payload) != `{"score":44}` {
+		t.Errorf("expected only the next input to be recorded, got %+v", records[2:])
+	}
+}
+
+func TestExternalInjector_RollsBackFailedApply(t *testing.T) {
+	tracked := NewTrackedState[*aclState, any](newAclState(), nil)
+	session := NewTrackedSession[*aclState, any, string](tracked)
+	session.Connect("alice", nil)
+	session.Tick()
+
+	root := tracked.GetBase()
+	injector := NewExternalInjector(session, "external:chat")
+	_, err := injector.InjectInput("hi", func(s **aclState, payload json.RawMessage) error {
+		(*s).Chat = "hi"
+		(*s).changes.Mark(1, OpReplace)
+		return errors.New("rejected halfway")
+	})
+	if err == nil {
+		t.Fatal("expected the apply error")
+	}
+	if tracked.GetBase() != root || root.Chat != "" {
+		t.Errorf("expected the partial apply to be rolled back, got chat %q", root.Chat)
+	}
+	if data := session.Tick()["alice"]; len(data) != 0 {
+		t.Errorf("expected nothing to send after a failed apply, got % x", data)
+	}
+}
+
+// gatedRecorder blocks in RecordInput until released
+type gatedRecorder struct {
+	*DiffRecorder
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (r *gatedRecorder) RecordInput(source string, payload []byte) {
+	close(r.entered)
+	<-r.release
+	r.DiffRecorder.RecordInput(source, payload)
+}
+
+func TestExternalInjector_RecordsInputWithItsDiff(t *testing.T) {
+	session := newInputTestSession()
+	recorder := &gatedRecorder{DiffRecorder: NewDiffRecorder(), entered: make(chan struct{}), release: make(chan struct{})}
+	session.SetHooks(RecordingHooks[*ReplayTestState, string](recorder.DiffRecorder))
+	injector := NewExternalInjector(session, "external:scores").WithRecorder(recorder)
+
+	applied := make(chan error)
+	go func() { applied <- injector.applyRaw(json.RawMessage(`{"score":42}`), applyScoreResponse) }()
+	<-recorder.entered
+
+	// A Tick while the input is being recorded waits for it
+	ticked := make(chan struct{})
+	go func() {
+		session.Tick()
+		close(ticked)
+	}()
+	select {
+	case <-ticked:
+		t.Error("Tick ran between applying and recording the input")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(recorder.release)
+	if err := <-applied; err != nil {
+		t.Fatalf("applyRaw: %v", err)
+	}
+	<-ticked
+
+	records := recorder.Records()
+	if len(records) != 1 || len(records[0].Inputs) != 1 || len(records[0].Data) == 0 {
+		t.Fatalf("expected the input recorded with its diff, got %+v", records)
+	}
+}
+
+func TestInputPlayback_ConcurrentUse(t *testing.T) {
+	var records []DiffRecord
+	for i := 0; i < 100; i++ {
+		records = append(records, DiffRecord{Seq: uint64(i), Inputs: []ExternalInput{{Source: "s", Payload: json.RawMessage(`1`)}}})
+	}
+	p := NewInputPlayback(records)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if _, ok := p.Next("s"); !ok {
+					return
+				}
+				p.Remaining()
+			}
+		}()
+	}
+	wg.Wait()
+	if n := p.Remaining(); n != 0 {
+		t.Errorf("expected all inputs taken, %d left", n)
+	}
+}
+
+func TestExternalInjector_ReplayFileInputs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inputs.replay")
+	w, err := CreateReplayFile(path, ReplayWriterConfig{})
+	if err != nil {
+		t.Fatalf("CreateReplayFile: %v", err)
+	}
+	session := newInputTestSession()
+	session.SetHooks(ReplayRecordingHooks[*ReplayTestState, string](w))
+
+	injector := NewExternalInjector(session, "external:scores").WithRecorder(w)
+	injector.InjectInput(scoreResponse{Score: 7}, applyScoreResponse)
+	injector.InjectInput(scoreResponse{Score: 7}, func(**ReplayTestState, json.RawMessage) error { return nil })
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	rr, err := OpenReplayFile(path)
+	if err != nil {
+		t.Fatalf("OpenReplayFile: %v", err)
+	}
+	defer rr.Close()
+	records, err := rr.Records()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("Records = %d, %v; want 2", len(records), err)
+	}
+	for _, r := range records {
+		if len(r.Inputs) != 1 || string(r.Inputs[0].Payload) != `{"score":7}` {
+			t.Errorf("seq %d: unexpected inputs %+v", r.Seq, r.Inputs)
+		}
+	}
+}
+
+func TestExternalInjector_Playback(t *testing.T) {
+	// Live: fetch from the "outside world" and record
+	live := newInputTestSession()
+	recorder := NewDiffRecorder()
+	live.SetHooks(RecordingHooks[*ReplayTestState, string](recorder))
+	injector := NewExternalInjector(live, "external:scores").WithRecorder(recorder)
+	for _, score := range []int64{10, 20} {
+		score := score
+		if _, err := injector.Fetch(func() (any, error) { return scoreResponse{Score: score}, nil }, applyScoreResponse); err != nil {
+			t.Fatalf("Fetch: %v", err)
+		}
+	}
+
+	// Re-simulation: recorded payloads are fed instead of calling fetch
+	playback := NewInputPlayback(recorder.Records())
+	if playback.Remaining() != 2 {
+		t.Fatalf("expected 2 recorded inputs, got %d", playback.Remaining())
+	}
+	resim := newInputTestSession()
+	resimRecorder := NewDiffRecorder()
+	resim.SetHooks(RecordingHooks[*ReplayTestState, string](resimRecorder))
+	injector = NewExternalInjector(resim, "external:scores").WithPlayback(playback)
+	fetch := func() (any, error) {
+		t.Fatal("fetch called during re-simulation")
+		return nil, nil
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := injector.Fetch(fetch, applyScoreResponse); err != nil {
+			t.Fatalf("Fetch: %v", err)
+		}
+	}
+	if resim.State().Get().Score != 20 {
+		t.Errorf("expected re-simulated score 20, got %d", resim.State().Get().Score)
+	}
+
+	liveRecords, resimRecords := recorder.Records(), resimRecorder.Records()
+	for i := range liveRecords {
+		if string(liveRecords[i].Data) != string(resimRecords[i].Data) {
+			t.Errorf("seq %d: re-simulated diff differs", liveRecords[i].Seq)
+		}
+	}
+
+	if _, err := injector.Fetch(fetch, applyScoreResponse); !errors.Is(err, ErrInputMissing) {
+		t.Errorf("expected ErrInputMissing, got %v", err)
+	}
+}
@@ -0,0 +1,498 @@
+package statesync
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"sort"
+)
+
+// sentView is what a filtered client was last sent. The ChangeSet of a filtered
+// state is derived from the base state, so it misses visibility transitions (an
+// element entering or leaving a filter). Comparing with the sent view turns them
+// into synthetic ops.
+type sentView struct {
+	fields []sentField
+}
+
+// sentField is the last sent state of a root field
+type sentField struct {
+	hidden  bool                // Hidden by the viewer or overlay (sent as zero)
+	isNil   bool                // Structs: sent as null
+	value   interface{}         // Primitives: last sent value
+	keys    []string            // Keyed arrays: element keys in order
+	entries map[string]struct{} // Maps: visible keys
+}
+
+// reset prepares the view for n fields, keeping allocated key buffers
+func (v *sentView) reset(n int) {
+	if cap(v.fields) < n {
+		v.fields = make([]sentField, n)
+	}
+	v.fields = v.fields[:n]
+}
+
+// clientSent double-buffers the sent view of a client so key buffers are reused
+type clientSent struct {
+	last, next *sentView
+	known      bool // last describes what the client has
+}
+
+func newClientSent() *clientSent {
+	return &clientSent{last: &sentView{}, next: &sentView{}}
+}
+
+// lastView returns the last sent view (nil if unknown)
+func (c *clientSent) lastView() *sentView {
+	if !c.known {
+		return nil
+	}
+	return c.last
+}
+
+// swap makes next the last sent view
+func (c *clientSent) swap() {
+	c.last, c.next = c.next, c.last
+	c.known = true
+}
+
+// syntheticOp is an array op computed from the sent keys (applied in order)
+type syntheticOp struct {
+	op       Operation
+	index    int // Position in the current array (add/replace/move target)
+	oldIndex int // Move source
+}
+
+// observe records what a full state encoding of t for viewer contains
+func (e *Encoder) observe(t Trackable, viewer *Viewer, into *sentView) {
+	schema := t.Schema()
+	if viewer != nil && isRestricted(schema) {
+		e.setViewer(viewer, t, schema)
+		defer e.setViewer(nil, nil, nil)
+	}
+	into.reset(len(schema.Fields))
+	for i := range schema.Fields {
+		e.observeField(t, uint16(i), &schema.Fields[i], &into.fields[i])
+	}
+}
+
+// observeField records the visible state of a root field and returns its value (nil if hidden).
+// Hidden fields are never read.
+func (e *Encoder) observeField(t Trackable, idx uint16, field *FieldMeta, f *sentField) interface{} {
+	keys, entries := f.keys[:0], f.entries
+	clear(entries)
+	*f = sentField{keys: keys, entries: entries}
+
+	if !e.visible(field) || overlayHidden(t, idx) {
+		f.hidden = true
+		return nil
+	}
+	value := fieldValue(t, idx)
+	switch field.Type {
+	case TypeArray:
+		if key := arrayKeyField(field); key != nil {
+			n := getArrayLength(value)
+			for i := 0; i < n; i++ {
+				f.keys = append(f.keys, elementKey(getArrayElement(value, i), key))
+			}
+		}
+	case TypeMap:
+		if f.entries == nil {
+			f.entries = make(map[string]struct{})
+		}
+		keys, _ := getMapKeysValues(value)
+		for _, k := range keys {
+			f.entries[k] = struct{}{}
+		}
+	case TypeStruct:
+		f.isNil = isNilValue(value)
+	default:
+		if b, ok := value.([]byte); ok {
+			value = append([]byte(nil), b...) // Don't alias a buffer the state may reuse
+		}
+		f.value = value
+	}
+	return value
+}
+
+// encodeSince encodes the changes of t for viewer relative to what the client was
+// last sent (last, nil if unknown), including synthetic ops for fields and keyed
+// array/map elements whose visibility changed. Records what is sent in next.
+// Returns nil if nothing changed for this client.
+func (e *Encoder) encodeSince(t Trackable, viewer *Viewer, last, next *sentView) []byte {
+	e.Reset()
+
+	schema := t.Schema()
+	changes := t.Changes()
+
+	// Message type
+	e.writeMsgType(MsgPatch, schema)
+
+	// Schema ID
+	e.writeUint16(schema.ID)
+
+	if viewer != nil && isRestricted(schema) {
+		e.setViewer(viewer, t, schema)
+		defer e.setViewer(nil, nil, nil)
+	}
+
+	dirty := e.dirtyFields[:0]
+	for range schema.Fields {
+		dirty = append(dirty, false)
+	}
+	for _, idx := range changes.ChangedWideFields() {
+		if int(idx) < len(dirty) {
+			dirty[idx] = true
+		}
+	}
+	e.dirtyFields = dirty
+
+	next.reset(len(schema.Fields))
+	start, count := e.pos, 0
+	for i := range schema.Fields {
+		field, idx := &schema.Fields[i], uint16(i)
+		isDirty := dirty[i]
+		cur := &next.fields[i]
+		value := e.observeField(t, idx, field, cur)
+
+		var prev *sentField
+		if last != nil && i < len(last.fields) {
+			prev = &last.fields[i]
+		}
+		switch {
+		case cur.hidden:
+			// Newly hidden fields are cleared like in a full state
+			if prev != nil && !prev.hidden {
+				e.writeZeroChange(idx, field)
+				count++
+			}
+		case prev == nil:
+			// Unknown: plain diff
+			if isDirty {
+				e.encodeFieldChange(t, idx, field, changes)
+				count++
+			}
+		case prev.hidden:
+			// Newly visible fields are sent in full
+			e.writeFullChange(idx, field, value)
+			count++
+		case field.Type == TypeArray && arrayKeyField(field) != nil:
+			if e.encodeKeyedArraySince(idx, field, value, changes, isDirty, prev.keys, cur.keys) {
+				count++
+			}
+		case field.Type == TypeMap:
+			if e.encodeMapSince(idx, field, value, changes, isDirty, prev.entries, cur.entries) {
+				count++
+			}
+		case field.Type == TypeArray:
+			if isDirty {
+				e.encodeFieldChange(t, idx, field, changes)
+				count++
+			}
+		case field.Type == TypeStruct:
+			if isDirty {
+				e.encodeFieldChange(t, idx, field, changes)
+				count++
+			} else if prev.isNil != cur.isNil {
+				e.writeFullChange(idx, field, value)
+				count++
+			}
+		default:
+			if isDirty || !sameValue(prev.value, cur.value) {
+				e.writeFullChange(idx, field, value)
+				count++
+			}
+		}
+	}
+
+	if count == 0 {
+		return nil
+	}
+	e.insertVarUint(start, uint64(count))
+	return e.Bytes()
+}
+
+// encodeKeyedArraySince writes synthetic ops turning the sent keys into the current
+// ones, plus replaces for changed elements. Returns false if nothing was written.
+func (e *Encoder) encodeKeyedArraySince(idx uint16, field *FieldMeta, value interface{}, changes *ChangeSet, dirty bool, sent, cur []string) bool {
+	var changed map[string]bool
+	if dirty {
+		arr := changes.GetWideArray(idx)
+		if changes.GetWideFieldChange(idx).Op == OpReplace || arr == nil {
+			e.writeFullChange(idx, field, value)
+			return true
+		}
+		var ok bool
+		if changed, ok = changedElementKeys(arr, arrayKeyField(field)); !ok {
+			e.writeFullChange(idx, field, value)
+			return true
+		}
+	}
+	if len(changed) == 0 && equalKeys(sent, cur) {
+		return false
+	}
+
+	ops := diffKeys(e.sentOps[:0], sent, cur, changed)
+	e.sentOps = ops
+	if len(ops) == 0 {
+		return false
+	}
+	if len(ops) > len(cur) {
+		// Sending the whole array is smaller
+		e.writeFullChange(idx, field, value)
+		return true
+	}
+
+	e.writeFieldIndex(int(idx))
+	e.writeByte(ArrayModeIncremental)
+	e.writeVarUint(uint64(len(ops)))
+	for _, op := range ops {
+		e.writeVarUint(uint64(op.index))
+		e.writeByte(uint8(op.op))
+		switch op.op {
+		case OpAdd, OpReplace:
+			e.encodeArrayElement(field, getArrayElement(value, op.index))
+		case OpMove:
+			e.writeVarUint(uint64(op.oldIndex))
+		}
+	}
+	return true
+}
+
+// diffKeys appends the ops that turn the sent keys into cur (keys are unique):
+// removes from the back first, then adds and moves in order, replacing changed keys
+func diffKeys(ops []syntheticOp, sent, cur []string, changed map[string]bool) []syntheticOp {
+	if equalKeys(sent, cur) {
+		for i, key := range cur {
+			if changed[key] {
+				ops = append(ops, syntheticOp{op: OpReplace, index: i})
+			}
+		}
+		return ops
+	}
+
+	visible := make(map[string]struct{}, len(cur))
+	for _, key := range cur {
+		visible[key] = struct{}{}
+	}
+	work := append([]string(nil), sent...)
+	for i := len(work) - 1; i >= 0; i-- {
+		if _, ok := visible[work[i]]; !ok {
+			ops = append(ops, syntheticOp{op: OpRemove, index: i})
+			work = append(work[:i], work[i+1:]...)
+		}
+	}
+
+	for i, key := range cur {
+		if i < len(work) && work[i] == key {
+			if changed[key] {
+				ops = append(ops, syntheticOp{op: OpReplace, index: i})
+			}
+			continue
+		}
+		from := -1
+		for j := i + 1; j < len(work); j++ {
+			if work[j] == key {
+				from = j
+				break
+			}
+		}
+		if from < 0 {
+			ops = append(ops, syntheticOp{op: OpAdd, index: i})
+			work = append(work, "")
+			copy(work[i+1:], work[i:])
+			work[i] = key
+			continue
+		}
+		// Always moves backwards, so decoders need no index adjustment
+		ops = append(ops, syntheticOp{op: OpMove, index: i, oldIndex: from})
+		copy(work[i+1:from+1], work[i:from])
+		work[i] = key
+		if changed[key] {
+			ops = append(ops, syntheticOp{op: OpReplace, index: i})
+		}
+	}
+	return ops
+}
+
+// encodeMapSince writes removes/adds for entries that left or entered the view and
+// replaces for changed visible entries. Returns false if nothing was written.
+func (e *Encoder) encodeMapSince(idx uint16, field *FieldMeta, value interface{}, changes *ChangeSet, dirty bool, sent, cur map[string]struct{}) bool {
+	var changed map[string]bool
+	if dirty {
+		m := changes.GetWideMap(idx)
+		if changes.GetWideFieldChange(idx).Op == OpReplace || m == nil {
+			e.writeFullChange(idx, field, value)
+			return true
+		}
+		changed = changedEntryKeys(m)
+	}
+
+	var removed []string
+	for key := range sent {
+		if _, ok := cur[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	keys, values := getMapKeysValues(value)
+	n := len(removed)
+	for _, key := range keys {
+		if _, ok := sent[key]; !ok || changed[key] {
+			n++
+		}
+	}
+	if n == 0 {
+		return false
+	}
+	sort.Strings(removed)
+
+	e.writeFieldIndex(int(idx))
+	e.writeByte(ArrayModeIncremental)
+	e.writeVarUint(uint64(n))
+	for _, key := range removed {
+		e.writeString(key)
+		e.writeByte(uint8(OpRemove))
+	}
+	for i, key := range keys {
+		op := OpReplace
+		if _, ok := sent[key]; !ok {
+			op = OpAdd
+		} else if !changed[key] {
+			continue
+		}
+		e.writeString(key)
+		e.writeByte(uint8(op))
+		e.encodeMapValue(field, key, values[i])
+	}
+	return true
+}
+
+// writeFullChange encodes a field's current value as a replacement
+func (e *Encoder) writeFullChange(idx uint16, field *FieldMeta, value interface{}) {
+	e.writeFieldIndex(int(idx))
+	switch field.Type {
+	case TypeArray:
+		e.writeByte(ArrayModeFull)
+		e.encodeArray(field, value)
+	case TypeMap:
+		e.writeByte(ArrayModeFull)
+		e.encodeMap(field, value)
+	case TypeStruct:
+		if isNilValue(value) {
+			e.writeByte(uint8(OpRemove))
+			return
+		}
+		e.writeByte(uint8(OpReplace))
+		e.encodeField(field, value)
+	default:
+		e.writeByte(uint8(OpReplace))
+		e.encodeField(field, value)
+	}
+}
+
+// writeZeroChange clears a field that became hidden (zero value, like in a full state)
+func (e *Encoder) writeZeroChange(idx uint16, field *FieldMeta) {
+	e.writeFieldIndex(int(idx))
+	switch field.Type {
+	case TypeArray, TypeMap:
+		e.writeByte(ArrayModeFull)
+		e.writeVarUint(0)
+	case TypeStruct:
+		e.writeByte(uint8(OpRemove))
+	default:
+		e.writeByte(uint8(OpReplace))
+		e.encodeField(field, nil)
+	}
+}
+
+// insertVarUint inserts a varint at pos, shifting the bytes after it
+func (e *Encoder) insertVarUint(pos int, v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	e.grow(n)
+	copy(e.buf[pos+n:e.pos+n], e.buf[pos:e.pos])
+	copy(e.buf[pos:], tmp[:n])
+	e.pos += n
+}
+
+// changedElementKeys returns the keys of added/replaced elements
+// (false if an element has no key to identify it by)
+func changedElementKeys(arr *ArrayChangeSet, key *FieldMeta) (map[string]bool, bool) {
+	arr.mu.RLock()
+	defer arr.mu.RUnlock()
+	changed := make(map[string]bool, len(arr.changes))
+	for _, change := range arr.changes {
+		if change.Op != OpAdd && change.Op != OpReplace {
+			continue // Removes and moves show in the keys
+		}
+		t, ok := change.Value.(Trackable)
+		if !ok || isNilValue(t) {
+			return nil, false
+		}
+		changed[ownerKey(fieldValue(t, key.Index))] = true
+	}
+	return changed, true
+}
+
+// changedEntryKeys returns the keys of added/replaced map entries
+func changedEntryKeys(m *MapChangeSet) map[string]bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	changed := make(map[string]bool, len(m.changes))
+	for key, change := range m.changes {
+		if change.Op == OpAdd || change.Op == OpReplace {
+			changed[key] = true
+		}
+	}
+	return changed
+}
+
+// arrayKeyField returns the key field of a keyed struct array (nil if not keyed)
+func arrayKeyField(field *FieldMeta) *FieldMeta {
+	if field.ElemType != TypeStruct || field.KeyField == "" {
+		return nil
+	}
+	return fieldNamed(field.ChildSchema, field.KeyField)
+}
+
+// elementKey returns the key of a keyed array element
+func elementKey(elem interface{}, key *FieldMeta) string {
+	t, ok := elem.(Trackable)
+	if !ok || isNilValue(t) {
+		return ""
+	}
+	return ownerKey(fieldValue(t, key.Index))
+}
+
+// isNilValue reports whether a struct value is nil (or a nil pointer)
+func isNilValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	rv := reflect.ValueOf(value)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+func equalKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sameValue compares primitive field values
+func sameValue(a, b interface{}) bool {
+	if ab, ok := a.([]byte); ok {
+		bb, ok := b.([]byte)
+		return ok && bytes.Equal(ab, bb)
+	}
+	if a != nil && !reflect.TypeOf(a).Comparable() {
+		return reflect.DeepEqual(a, b)
+	}
+	return a == b
+}
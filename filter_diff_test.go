@@ -0,0 +1,210 @@
+package statesync
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// fogFilter shows only the players in visible (and the seed once revealed)
+type fogFilter struct {
+	visible map[string]bool
+	reveal  bool
+}
+
+func (f *fogFilter) apply(s *projState) *projState {
+	clone := &projState{changes: s.changes.CloneForFilter(), Round: s.Round, ByName: s.ByName}
+	if f.reveal {
+		clone.Seed = s.Seed
+	}
+	for _, p := range s.Players {
+		if f.visible[p.ID] {
+			clone.Players = append(clone.Players, p)
+		}
+	}
+	return clone
+}
+
+// filterClient applies a filtered client's patches and checks them against a full state
+type filterClient struct {
+	t      *testing.T
+	dec    *Decoder
+	fields map[string]interface{}
+}
+
+func newFilterClient(t *testing.T) *filterClient {
+	registry := NewSchemaRegistry()
+	registry.Register(projStateSchema)
+	return &filterClient{t: t, dec: NewDecoder(registry), fields: make(map[string]interface{})}
+}
+
+func (c *filterClient) apply(data []byte) {
+	c.t.Helper()
+	if data == nil {
+		return
+	}
+	patch, err := c.dec.Decode(data)
+	if err != nil {
+		c.t.Fatalf("decode: %v", err)
+	}
+	if err := ApplyPatch(c.fields, patch, projStateSchema); err != nil {
+		c.t.Fatalf("apply: %v", err)
+	}
+}
+
+// check compares the client state with a full encoding of want
+func (c *filterClient) check(want Trackable) {
+	c.t.Helper()
+	full := decodeProjState(c.t, NewEncoder(nil).EncodeAll(want))
+	if !reflect.DeepEqual(c.fields, full) {
+		c.t.Errorf("client state diverged:\n got %v\nwant %v", c.fields, full)
+	}
+}
+
+func TestTrackedSession_FilterVisibilityChanges(t *testing.T) {
+	state := newProjState()
+	state.Players = append(state.Players, &projPlayer{changes: NewChangeSet(), ID: "carol", Hand: []int32{5}})
+	tracked := NewTrackedState[*projState, any](state, nil)
+	session := NewTrackedSession[*projState, any, string](tracked)
+	session.SetVisibilityDiffs(true)
+
+	fog := &fogFilter{visible: map[string]bool{"alice": true}}
+	session.Connect("alice", fog.apply)
+	client := newFilterClient(t)
+	client.apply(session.Tick()["alice"])
+	client.check(fog.apply(state))
+
+	steps := []struct {
+		name   string
+		change func()
+	}{
+		{"unit enters fog of war", func() { fog.visible["carol"] = true }},
+		{"unit leaves fog of war", func() { delete(fog.visible, "alice") }},
+		{"several at once", func() { fog.visible = map[string]bool{"bob": true, "alice": true, "carol": true} }},
+		{"field revealed", func() { fog.reveal = true }},
+		{"field hidden again", func() { fog.reveal = false }},
+		{"visible element changes while another leaves", func() {
+			tracked.UpdateInPlace(func(s *projState) {
+				s.Players[1].Notes = "changed"
+				s.changes.GetOrCreateArray(2).MarkReplace(1, s.Players[1])
+			})
+			delete(fog.visible, "carol")
+		}},
+		{"hidden element changes", func() {
+			tracked.UpdateInPlace(func(s *projState) {
+				s.Players[2].Notes = "secret"
+				s.changes.GetOrCreateArray(2).MarkReplace(2, s.Players[2])
+			})
+		}},
+		{"base reorders", func() {
+			tracked.UpdateInPlace(func(s *projState) {
+				s.Players[0], s.Players[1] = s.Players[1], s.Players[0]
+				s.changes.GetOrCreateArray(2).MarkMove(0, 1)
+			})
+		}},
+	}
+	for _, step := range steps {
+		step.change()
+		diffs := session.Tick()
+		client.apply(diffs["alice"])
+		client.check(fog.apply(state))
+		if t.Failed() {
+			t.Fatalf("after %q", step.name)
+		}
+	}
+
+	// Nothing visible changed: no diff
+	tracked.UpdateInPlace(func(s *projState) {
+		s.changes.GetOrCreateArray(2).MarkReplace(2, s.Players[2])
+	})
+	if data, ok := session.Tick()["alice"]; ok {
+		t.Errorf("expected no diff for a hidden element, got %v", data)
+	}
+}
+
+func TestTrackedSession_OverlayVisibilityChanges(t *testing.T) {
+	state := newProjState()
+	state.ByName["dave"] = &projPlayer{changes: NewChangeSet(), ID: "d"}
+	tracked := NewTrackedState[*projState, any](state, nil)
+	session := NewTrackedSession[*projState, any, string](tracked)
+	session.SetVisibilityDiffs(true)
+
+	showSeed, hidden := false, "dave"
+	overlay := func(s *projState, view *Overlay) {
+		if !showSeed {
+			view.Hide("Seed")
+		}
+		view.FilterEntries("ByName", func(key string, value interface{}) bool { return key != hidden })
+	}
+	session.Connect("alice", nil)
+	session.SetOverlay("alice", overlay)
+	client := newFilterClient(t)
+	client.apply(session.Tick()["alice"])
+
+	expected := func() Trackable {
+		view := NewOverlay(state)
+		overlay(state, view)
+		return view
+	}
+	client.check(expected())
+
+	hidden = "carol"
+	client.apply(session.Tick()["alice"])
+	client.check(expected())
+	if _, ok := client.fields["ByName"].(map[string]interface{})["dave"]; !ok {
+		t.Error("expected dave to enter the view")
+	}
+
+	showSeed = true
+	client.apply(session.Tick()["alice"])
+	client.check(expected())
+	if client.fields["Seed"] != int64(42) {
+		t.Errorf("expected Seed to be revealed, got %v", client.fields["Seed"])
+	}
+
+	showSeed = false
+	client.apply(session.Tick()["alice"])
+	client.check(expected())
+}
+
+func TestDiffKeys(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	pool := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	for i := 0; i < 500; i++ {
+		sent := randomKeys(rng, pool)
+		cur := randomKeys(rng, pool)
+		changed := map[string]bool{pool[rng.Intn(len(pool))]: true}
+
+		ops := diffKeys(nil, sent, cur, changed)
+		arr := make([]interface{}, len(sent))
+		for j, key := range sent {
+			arr[j] = key
+		}
+		changes := make([]DecodedArrayChange, len(ops))
+		for j, op := range ops {
+			changes[j] = DecodedArrayChange{Index: op.index, Op: op.op, OldIndex: op.oldIndex}
+			if op.op == OpAdd || op.op == OpReplace {
+				changes[j].Value = cur[op.index]
+			}
+		}
+		arr = applyArrayChanges(arr, changes)
+
+		got := make([]string, len(arr))
+		for j, v := range arr {
+			got[j] = v.(string)
+		}
+		if !equalKeys(got, cur) {
+			t.Fatalf("%v -> %v: ops %+v gave %v", sent, cur, ops, got)
+		}
+	}
+
+	if ops := diffKeys(nil, []string{"a", "b"}, []string{"a", "b"}, map[string]bool{"b": true}); len(ops) != 1 || ops[0].op != OpReplace || ops[0].index != 1 {
+		t.Errorf("expected a single replace, got %+v", ops)
+	}
+}
+
+func randomKeys(rng *rand.Rand, pool []string) []string {
+	keys := append([]string(nil), pool...)
+	rng.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+	return keys[:rng.Intn(len(keys)+1)]
+}
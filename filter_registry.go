@@ -1,40 +1,176 @@
 package statesync
 
 import (
+	"slices"
 	"sort"
 	"sync"
+	"time"
 )
 
+// FilterConfig configures a registered filter
+type FilterConfig struct {
+	// Priority orders filters in Compose: lower runs first.
+	// Filters with equal priority run in the order they were added.
+	Priority int
+
+	// Ticks expires the filter after this many ticks (0 = never).
+	// The filter applies to each of those ticks and is removed by the following Expire.
+	Ticks uint64
+
+	// TTL expires the filter after this long on the wall clock (0 = never)
+	TTL time.Duration
+}
+
+// ParamFilterFunc is a filter with per-viewer parameters (e.g. a vision radius)
+type ParamFilterFunc[T, P any] func(state T, params P) T
+
 // FilterRegistry manages active filters per viewer.
 // Filters can be dynamically added/removed at runtime.
 type FilterRegistry[T any, ID comparable] struct {
-	mu      sync.RWMutex
-	filters map[ID]map[string]FilterFunc[T] // viewerID -> filterID -> filter
+	mu        sync.RWMutex
+	filters   map[ID]map[string]*registeredFilter[T] // viewerID -> filterID -> filter
+	composed  map[ID]FilterFunc[T]                   // Compose cache (invalidated on change)
+	listeners []filterListener[ID]
+	seq       uint64 // Insertion order (filters and listeners)
+	now       func() time.Time
+}
+
+// filterListener is a change listener with the seq OnChange's unsubscribe removes it by
+type filterListener[ID comparable] struct {
+	seq uint64
+	fn  func(viewerID ID)
+}
+
+// registeredFilter is a filter with its ordering, parameters and lifetime
+type registeredFilter[T any] struct {
+	fn       FilterFunc[T]
+	priority int
+	seq      uint64
+
+	// Parameterized filters (bind rebuilds fn for new params)
+	params any
+	bind   func(params any) FilterFunc[T]
+
+	byTicks   bool
+	ticksLeft uint64
+	expires   time.Time // Zero = no TTL
 }
 
 // NewFilterRegistry creates a new filter registry
 func NewFilterRegistry[T any, ID comparable]() *FilterRegistry[T, ID] {
 	return &FilterRegistry[T, ID]{
-		filters: make(map[ID]map[string]FilterFunc[T]),
+		filters:  make(map[ID]map[string]*registeredFilter[T]),
+		composed: make(map[ID]FilterFunc[T]),
+		now:      time.Now,
 	}
 }
 
-// Add adds a filter for a viewer
-func (r *FilterRegistry[T, ID]) Add(viewerID ID, filterID string, filter FilterFunc[T]) {
+// OnChange registers a listener called when a viewer's composed filter changes
+// (a filter is added, removed, expired or gets new parameters).
+// The returned func unregisters it.
+func (r *FilterRegistry[T, ID]) OnChange(fn func(viewerID ID)) (unsubscribe func()) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.seq++
+	seq := r.seq
+	r.listeners = append(r.listeners, filterListener[ID]{seq: seq, fn: fn})
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.listeners = slices.DeleteFunc(r.listeners, func(l filterListener[ID]) bool { return l.seq == seq })
+	}
+}
+
+// Add adds a filter for a viewer (priority 0, no expiry)
+func (r *FilterRegistry[T, ID]) Add(viewerID ID, filterID string, filter FilterFunc[T]) {
+	r.AddWith(viewerID, filterID, filter, FilterConfig{})
+}
+
+// AddWith adds a filter for a viewer with a priority and lifetime.
+// A filter with the same ID is replaced.
+func (r *FilterRegistry[T, ID]) AddWith(viewerID ID, filterID string, filter FilterFunc[T], cfg FilterConfig) {
+	r.add(viewerID, filterID, &registeredFilter[T]{fn: filter}, cfg)
+}
+
+// AddParamFilter adds a filter with per-viewer parameters (see SetFilterParams)
+func AddParamFilter[T any, ID comparable, P any](r *FilterRegistry[T, ID], viewerID ID, filterID string, filter ParamFilterFunc[T, P], params P, cfg FilterConfig) {
+	bind := func(params any) FilterFunc[T] {
+		p := params.(P)
+		return func(state T) T { return filter(state, p) }
+	}
+	r.add(viewerID, filterID, &registeredFilter[T]{fn: bind(params), params: params, bind: bind}, cfg)
+}
+
+// SetFilterParams updates the parameters of a parameterized filter.
+// Returns false if the filter doesn't exist or takes other parameters.
+func SetFilterParams[T any, ID comparable, P any](r *FilterRegistry[T, ID], viewerID ID, filterID string, params P) bool {
+	r.mu.Lock()
+	f := r.filters[viewerID][filterID]
+	if f == nil || f.bind == nil {
+		r.mu.Unlock()
+		return false
+	}
+	if _, ok := f.params.(P); !ok {
+		r.mu.Unlock()
+		return false
+	}
+	f.params = params
+	f.fn = f.bind(params)
+	delete(r.composed, viewerID)
+	r.mu.Unlock()
+
+	r.notify(viewerID)
+	return true
+}
+
+// FilterParams returns the parameters of a parameterized filter
+func FilterParams[T any, ID comparable, P any](r *FilterRegistry[T, ID], viewerID ID, filterID string) (P, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f := r.filters[viewerID][filterID]
+	if f == nil {
+		var zero P
+		return zero, false
+	}
+	p, ok := f.params.(P)
+	return p, ok
+}
 
+// add registers a filter with a config
+func (r *FilterRegistry[T, ID]) add(viewerID ID, filterID string, f *registeredFilter[T], cfg FilterConfig) {
+	r.mu.Lock()
+	r.seq++
+	f.priority, f.seq = cfg.Priority, r.seq
+	if cfg.Ticks > 0 {
+		f.byTicks, f.ticksLeft = true, cfg.Ticks
+	}
+	if cfg.TTL > 0 {
+		f.expires = r.now().Add(cfg.TTL)
+	}
 	if r.filters[viewerID] == nil {
-		r.filters[viewerID] = make(map[string]FilterFunc[T])
+		r.filters[viewerID] = make(map[string]*registeredFilter[T])
 	}
-	r.filters[viewerID][filterID] = filter
+	r.filters[viewerID][filterID] = f
+	delete(r.composed, viewerID)
+	r.mu.Unlock()
+
+	r.notify(viewerID)
 }
 
 // Remove removes a filter from a viewer
 func (r *FilterRegistry[T, ID]) Remove(viewerID ID, filterID string) bool {
 	r.mu.Lock()
-	defer r.mu.Unlock()
+	ok := r.removeLocked(viewerID, filterID)
+	r.mu.Unlock()
 
+	if ok {
+		r.notify(viewerID)
+	}
+	return ok
+}
+
+// removeLocked removes a filter. Caller must hold r.mu.
+func (r *FilterRegistry[T, ID]) removeLocked(viewerID ID, filterID string) bool {
 	if r.filters[viewerID] == nil {
 		return false
 	}
@@ -42,6 +178,7 @@ func (r *FilterRegistry[T, ID]) Remove(viewerID ID, filterID string) bool {
 	_, ok := r.filters[viewerID][filterID]
 	if ok {
 		delete(r.filters[viewerID], filterID)
+		delete(r.composed, viewerID)
 		// Clean up empty maps
 		if len(r.filters[viewerID]) == 0 {
 			delete(r.filters, viewerID)
@@ -50,6 +187,60 @@ func (r *FilterRegistry[T, ID]) Remove(viewerID ID, filterID string) bool {
 	return ok
 }
 
+// Expire removes filters whose ticks or TTL ran out. Returns the number removed.
+// TrackedSession calls this at the start of each Tick (see SetFilterRegistry).
+func (r *FilterRegistry[T, ID]) Expire() int {
+	r.mu.Lock()
+	now := r.now()
+	var changed []ID
+	removed := 0
+	for viewerID, filters := range r.filters {
+		n := removed
+		for filterID, f := range filters {
+			if (f.byTicks && f.ticksLeft == 0) || (!f.expires.IsZero() && !now.Before(f.expires)) {
+				r.removeLocked(viewerID, filterID)
+				removed++
+			}
+		}
+		if removed > n {
+			changed = append(changed, viewerID)
+		}
+	}
+	r.mu.Unlock()
+
+	r.notify(changed...)
+	return removed
+}
+
+// Advance counts down tick-limited filters by one tick.
+// TrackedSession calls this after each Tick's broadcast (see SetFilterRegistry).
+func (r *FilterRegistry[T, ID]) Advance() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, filters := range r.filters {
+		for _, f := range filters {
+			if f.byTicks && f.ticksLeft > 0 {
+				f.ticksLeft--
+			}
+		}
+	}
+}
+
+// notify calls the change listeners for viewers (without holding the lock)
+func (r *FilterRegistry[T, ID]) notify(viewerIDs ...ID) {
+	if len(viewerIDs) == 0 {
+		return
+	}
+	r.mu.RLock()
+	listeners := slices.Clone(r.listeners)
+	r.mu.RUnlock()
+	for _, id := range viewerIDs {
+		for _, l := range listeners {
+			l.fn(id)
+		}
+	}
+}
+
 // Has checks if a filter exists for a viewer
 func (r *FilterRegistry[T, ID]) Has(viewerID ID, filterID string) bool {
 	r.mu.RLock()
@@ -67,13 +258,13 @@ func (r *FilterRegistry[T, ID]) Get(viewerID ID, filterID string) FilterFunc[T]
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	if r.filters[viewerID] == nil {
-		return nil
+	if f := r.filters[viewerID][filterID]; f != nil {
+		return f.fn
 	}
-	return r.filters[viewerID][filterID]
+	return nil
 }
 
-// GetAll returns all filter IDs for a viewer
+// GetAll returns all filter IDs for a viewer in the order Compose applies them
 func (r *FilterRegistry[T, ID]) GetAll(viewerID ID) []string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -81,26 +272,52 @@ func (r *FilterRegistry[T, ID]) GetAll(viewerID ID) []string {
 	if r.filters[viewerID] == nil {
 		return nil
 	}
+	return r.orderedLocked(viewerID)
+}
 
-	ids := make([]string, 0, len(r.filters[viewerID]))
-	for id := range r.filters[viewerID] {
+// orderedLocked returns the filter IDs of a viewer by priority, then insertion order.
+// Caller must hold r.mu.
+func (r *FilterRegistry[T, ID]) orderedLocked(viewerID ID) []string {
+	filters := r.filters[viewerID]
+	ids := make([]string, 0, len(filters))
+	for id := range filters {
 		ids = append(ids, id)
 	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := filters[ids[i]], filters[ids[j]]
+		if a.priority != b.priority {
+			return a.priority < b.priority
+		}
+		return a.seq < b.seq
+	})
 	return ids
 }
 
 // Clear removes all filters for a viewer
 func (r *FilterRegistry[T, ID]) Clear(viewerID ID) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
+	_, had := r.filters[viewerID]
 	delete(r.filters, viewerID)
+	delete(r.composed, viewerID)
+	r.mu.Unlock()
+
+	if had {
+		r.notify(viewerID)
+	}
 }
 
 // ClearAll removes all filters for all viewers
 func (r *FilterRegistry[T, ID]) ClearAll() {
 	r.mu.Lock()
-	defer r.mu.Unlock()
-	r.filters = make(map[ID]map[string]FilterFunc[T])
+	changed := make([]ID, 0, len(r.filters))
+	for viewerID := range r.filters {
+		changed = append(changed, viewerID)
+	}
+	r.filters = make(map[ID]map[string]*registeredFilter[T])
+	r.composed = make(map[ID]FilterFunc[T])
+	r.mu.Unlock()
+
+	r.notify(changed...)
 }
 
 // Count returns the number of filters for a viewer
@@ -114,26 +331,41 @@ func (r *FilterRegistry[T, ID]) Count(viewerID ID) int {
 	return len(r.filters[viewerID])
 }
 
-// Compose returns a single filter that applies all filters for a viewer
+// Compose returns a single filter that applies all filters for a viewer,
+// by priority (lower first), then in the order they were added
 func (r *FilterRegistry[T, ID]) Compose(viewerID ID) FilterFunc[T] {
 	r.mu.RLock()
+	if composed, ok := r.composed[viewerID]; ok {
+		r.mu.RUnlock()
+		return composed
+	}
+	r.mu.RUnlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if composed, ok := r.composed[viewerID]; ok {
+		return composed
+	}
+	composed := r.composeLocked(viewerID)
+	if composed != nil {
+		r.composed[viewerID] = composed
+	}
+	return composed
+}
+
+// composeLocked builds the composed filter of a viewer. Caller must hold r.mu.
+func (r *FilterRegistry[T, ID]) composeLocked(viewerID ID) FilterFunc[T] {
 	viewerFilters := r.filters[viewerID]
 	if len(viewerFilters) == 0 {
-		r.mu.RUnlock()
 		return nil
 	}
 
-	// Copy filters in deterministic order (sorted by filter ID) to avoid holding lock during execution
-	ids := make([]string, 0, len(viewerFilters))
-	for id := range viewerFilters {
-		ids = append(ids, id)
-	}
-	sort.Strings(ids)
-	fns := make([]FilterFunc[T], 0, len(viewerFilters))
+	// Copy filters in order to avoid holding lock during execution
+	ids := r.orderedLocked(viewerID)
+	fns := make([]FilterFunc[T], 0, len(ids))
 	for _, id := range ids {
-		fns = append(fns, viewerFilters[id])
+		fns = append(fns, viewerFilters[id].fn)
 	}
-	r.mu.RUnlock()
 
 	// Single filter - no composition needed
 	if len(fns) == 1 {
@@ -1,7 +1,9 @@
 package statesync
 
 import (
+	"reflect"
 	"testing"
+	"time"
 )
 
 type RegistryTestState struct {
@@ -196,3 +198,217 @@ func TestFilterRegistry_Get(t *testing.T) {
 		t.Error("non-existing filter should be nil")
 	}
 }
+
+func TestFilterRegistry_Priority(t *testing.T) {
+	registry := NewFilterRegistry[*RegistryTestState, string]()
+
+	double := func(s *RegistryTestState) *RegistryTestState {
+		return &RegistryTestState{Score: s.Score * 2, Name: s.Name}
+	}
+	addTen := func(s *RegistryTestState) *RegistryTestState {
+		return &RegistryTestState{Score: s.Score + 10, Name: s.Name}
+	}
+
+	// Equal priorities run in insertion order, not by ID
+	registry.Add("player1", "b-double", double)
+	registry.Add("player1", "a-addTen", addTen)
+	if got := registry.Compose("player1")(&RegistryTestState{Score: 5}).Score; got != 20 {
+		t.Errorf("expected (5*2)+10 = 20, got %d", got)
+	}
+
+	// Lower priority runs first
+	registry.AddWith("player1", "b-double", double, FilterConfig{Priority: 10})
+	if got := registry.Compose("player1")(&RegistryTestState{Score: 5}).Score; got != 30 {
+		t.Errorf("expected (5+10)*2 = 30, got %d", got)
+	}
+	if ids := registry.GetAll("player1"); ids[0] != "a-addTen" || ids[1] != "b-double" {
+		t.Errorf("unexpected order %v", ids)
+	}
+}
+
+func TestFilterRegistry_Params(t *testing.T) {
+	registry := NewFilterRegistry[*RegistryTestState, string]()
+	var changed []string
+	registry.OnChange(func(id string) { changed = append(changed, id) })
+
+	capScore := func(s *RegistryTestState, max int) *RegistryTestState {
+		if s.Score > max {
+			return &RegistryTestState{Score: max, Name: s.Name}
+		}
+		return s
+	}
+	AddParamFilter(registry, "player1", "cap", capScore, 10, FilterConfig{})
+	AddParamFilter(registry, "player2", "cap", capScore, 50, FilterConfig{})
+
+	state := &RegistryTestState{Score: 30}
+	if got := registry.Compose("player1")(state).Score; got != 10 {
+		t.Errorf("player1: expected 10, got %d", got)
+	}
+	if got := registry.Compose("player2")(state).Score; got != 30 {
+		t.Errorf("player2: expected 30, got %d", got)
+	}
+
+	if !SetFilterParams(registry, "player1", "cap", 20) {
+		t.Fatal("SetFilterParams failed")
+	}
+	if got := registry.Compose("player1")(state).Score; got != 20 {
+		t.Errorf("expected updated params to apply, got %d", got)
+	}
+	if max, ok := FilterParams[*RegistryTestState, string, int](registry, "player1", "cap"); !ok || max != 20 {
+		t.Errorf("FilterParams = %d, %v", max, ok)
+	}
+	if SetFilterParams(registry, "player1", "cap", "wrong type") || SetFilterParams(registry, "player1", "missing", 1) {
+		t.Error("expected SetFilterParams to fail")
+	}
+
+	want := []string{"player1", "player2", "player1"}
+	if len(changed) != len(want) {
+		t.Fatalf("expected notifications %v, got %v", want, changed)
+	}
+	for i := range want {
+		if changed[i] != want[i] {
+			t.Errorf("expected notifications %v, got %v", want, changed)
+		}
+	}
+}
+
+func TestFilterRegistry_Expiry(t *testing.T) {
+	registry := NewFilterRegistry[*RegistryTestState, string]()
+	now := time.Unix(1000, 0)
+	registry.now = func() time.Time { return now }
+	var changed []string
+	registry.OnChange(func(id string) { changed = append(changed, id) })
+
+	identity := func(s *RegistryTestState) *RegistryTestState { return s }
+	registry.AddWith("player1", "stun", identity, FilterConfig{Ticks: 2})
+	registry.AddWith("player2", "blind", identity, FilterConfig{TTL: time.Second})
+	registry.Add("player2", "forever", identity)
+	changed = nil
+
+	for tick := 0; tick < 2; tick++ {
+		if n := registry.Expire(); n != 0 {
+			t.Fatalf("tick %d: expected nothing to expire, got %d", tick, n)
+		}
+		registry.Advance()
+	}
+	if n := registry.Expire(); n != 1 || registry.Has("player1", "stun") {
+		t.Errorf("expected stun to expire after 2 ticks (removed %d)", n)
+	}
+
+	now = now.Add(time.Second)
+	if n := registry.Expire(); n != 1 || registry.Has("player2", "blind") || !registry.Has("player2", "forever") {
+		t.Errorf("expected blind to expire after its TTL (removed %d)", n)
+	}
+	if len(changed) != 2 || changed[0] != "player1" || changed[1] != "player2" {
+		t.Errorf("unexpected notifications %v", changed)
+	}
+}
+
+func TestTrackedSession_FilterRegistry(t *testing.T) {
+	tracked := NewTrackedState[*projState, any](newProjState(), nil)
+	session := NewTrackedSession[*projState, any, string](tracked)
+	registry := NewFilterRegistry[*projState, string]()
+	session.SetFilterRegistry(registry)
+	session.Connect("alice", nil)
+	session.Connect("bob", nil)
+	session.Tick()
+
+	hideSeed := func(s *projState) *projState {
+		return &projState{changes: s.changes.CloneForFilter(), Round: s.Round, Players: s.Players, ByName: s.ByName}
+	}
+	registry.AddWith("alice", "hideSeed", hideSeed, FilterConfig{Ticks: 2})
+
+	// The changed filter triggers a full state for alice only
+	diffs := session.Tick()
+	if data := diffs["alice"]; len(data) == 0 || data[0] != MsgFullState {
+		t.Fatalf("expected a full state for alice, got %v", data)
+	}
+	if fields := decodeProjState(t, diffs["alice"]); fields["Seed"] != int64(0) {
+		t.Errorf("expected Seed to be filtered, got %v", fields["Seed"])
+	}
+	if _, ok := diffs["bob"]; ok {
+		t.Error("bob's filters did not change")
+	}
+
+	if data, ok := session.Tick()["alice"]; ok {
+		t.Errorf("expected no diff while the filter is unchanged, got %v", data)
+	}
+
+	// After 2 ticks the filter expires and alice gets an unfiltered full state
+	diffs = session.Tick()
+	if registry.Has("alice", "hideSeed") {
+		t.Fatal("expected the filter to expire")
+	}
+	if data := diffs["alice"]; len(data) == 0 || data[0] != MsgFullState {
+		t.Fatalf("expected a full state after expiry, got %v", data)
+	}
+	if fields := decodeProjState(t, diffs["alice"]); fields["Seed"] != int64(42) {
+		t.Errorf("expected Seed after expiry, got %v", fields["Seed"])
+	}
+}
+
+func TestFilterRegistry_OnChangeUnsubscribe(t *testing.T) {
+	r := NewFilterRegistry[*RegistryTestState, string]()
+	var calls []string
+	unsubscribe := r.OnChange(func(id string) { calls = append(calls, "first:"+id) })
+	r.OnChange(func(id string) { calls = append(calls, "second:"+id) })
+
+	r.Add("player1", "f", nil)
+	unsubscribe()
+	unsubscribe() // No-op
+	r.Remove("player1", "f")
+
+	want := []string{"first:player1", "second:player1", "second:player1"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected %v, got %v", want, calls)
+	}
+}
+
+func TestTrackedSession_FilterRegistryReplaced(t *testing.T) {
+	tracked := NewTrackedState[*projState, any](newProjState(), nil)
+	session := NewTrackedSession[*projState, any, string](tracked)
+	old := NewFilterRegistry[*projState, string]()
+	session.SetFilterRegistry(old)
+	session.SetFilterRegistry(NewFilterRegistry[*projState, string]())
+	session.SetFilterRegistry(nil)
+
+	if n := len(old.listeners); n != 0 {
+		t.Errorf("expected the replaced registry to have no listeners, got %d", n)
+	}
+}
+
+func TestTrackedSession_FilterRegistryVisibilityDiffs(t *testing.T) {
+	state := newProjState()
+	tracked := NewTrackedState[*projState, any](state, nil)
+	session := NewTrackedSession[*projState, any, string](tracked)
+	session.SetVisibilityDiffs(true)
+	registry := NewFilterRegistry[*projState, string]()
+	session.SetFilterRegistry(registry)
+
+	fog := &fogFilter{visible: map[string]bool{"alice": true}}
+	registry.Add("alice", "fog", fog.apply)
+	session.Connect("alice", nil)
+	client := newFilterClient(t)
+	client.apply(session.Tick()["alice"])
+	client.check(fog.apply(state))
+
+	// New parameters for a still filtered client: a diff of what entered its view
+	reveal := &fogFilter{visible: map[string]bool{"alice": true, "bob": true}, reveal: true}
+	registry.Add("alice", "fog", reveal.apply)
+	data := session.Tick()["alice"]
+	if len(data) == 0 || data[0] != MsgPatch {
+		t.Fatalf("expected a patch for the changed filter, got %v", data)
+	}
+	client.apply(data)
+	client.check(reveal.apply(state))
+
+	// Unfiltered again: a full state, as the sent view can't be diffed against
+	registry.Remove("alice", "fog")
+	data = session.Tick()["alice"]
+	if len(data) == 0 || data[0] != MsgFullState {
+		t.Fatalf("expected a full state once unfiltered, got %v", data)
+	}
+	if fields := decodeProjState(t, data); fields["Seed"] != int64(42) || len(fields["Players"].([]interface{})) != 2 {
+		t.Errorf("unexpected unfiltered state %v", fields)
+	}
+}
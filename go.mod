@@ -4,10 +4,12 @@ go 1.24.0
 
 toolchain go1.24.5
 
-require golang.org/x/tools v0.40.0
+require (
+	github.com/mxkacsa/tinyconf v0.0.0-20251219170016-ccd1e2c82965
+	golang.org/x/tools v0.40.0
+)
 
 require (
-	github.com/mxkacsa/tinyconf v0.0.0-20251219170016-ccd1e2c82965 // indirect
 	github.com/pelletier/go-toml v1.9.5 // indirect
 	github.com/vmihailenco/msgpack/v5 v5.3.5 // indirect
 	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
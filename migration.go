@@ -0,0 +1,174 @@
+package statesync
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Migration errors
+var (
+	ErrMigrationMissing = errors.New("snapshot migration missing")
+	ErrSnapshotTooNew   = errors.New("snapshot is newer than the current schema version")
+)
+
+// MigrationFunc upgrades a snapshot's state by one version.
+// The state is the decoded JSON object (numbers are json.Number, nested structs are maps).
+type MigrationFunc func(state map[string]any) error
+
+// MigrationRegistry holds snapshot migrations per schema name.
+// The current version of a schema is one past its highest registered migration
+// (SnapshotVersion if it has none); snapshots are saved with that version.
+// Pass it in TrackedConfig.Migrations when saving and to LoadMigrated or
+// LoadSnapshotMigrated when loading. A nil registry has no migrations.
+type MigrationRegistry struct {
+	mu    sync.RWMutex
+	steps map[string]map[int]MigrationFunc // Schema name -> from version -> migration
+}
+
+// NewMigrationRegistry creates an empty migration registry
+func NewMigrationRegistry() *MigrationRegistry {
+	return &MigrationRegistry{steps: make(map[string]map[int]MigrationFunc)}
+}
+
+// Register adds a migration from version from to from+1 for a schema.
+// Panics if the migration is already registered or from is below SnapshotVersion.
+func (r *MigrationRegistry) Register(schema string, from int, fn MigrationFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if from < SnapshotVersion {
+		panic(fmt.Sprintf("statesync: migration %s v%d: versions start at %d", schema, from, SnapshotVersion))
+	}
+	steps := r.steps[schema]
+	if steps == nil {
+		steps = make(map[int]MigrationFunc)
+		r.steps[schema] = steps
+	}
+	if _, ok := steps[from]; ok {
+		panic(fmt.Sprintf("statesync: migration %s v%d already registered", schema, from))
+	}
+	steps[from] = fn
+}
+
+// CurrentVersion returns the version snapshots of a schema are saved with
+func (r *MigrationRegistry) CurrentVersion(schema string) int {
+	if r == nil {
+		return SnapshotVersion
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.currentVersionLocked(schema)
+}
+
+func (r *MigrationRegistry) currentVersionLocked(schema string) int {
+	current := SnapshotVersion
+	for from := range r.steps[schema] {
+		if from+1 > current {
+			current = from + 1
+		}
+	}
+	return current
+}
+
+// Migrate upgrades a state object from version to the current version.
+// Returns the new version. Version 0 (unversioned snapshots) is treated as SnapshotVersion.
+func (r *MigrationRegistry) Migrate(schema string, version int, state map[string]any) (int, error) {
+	current := SnapshotVersion
+	var steps map[int]MigrationFunc
+	if r != nil {
+		r.mu.RLock()
+		current = r.currentVersionLocked(schema)
+		steps = r.steps[schema]
+		r.mu.RUnlock()
+	}
+
+	if version < SnapshotVersion {
+		version = SnapshotVersion
+	}
+	if version > current {
+		return version, fmt.Errorf("%w: %s v%d, current v%d", ErrSnapshotTooNew, schema, version, current)
+	}
+	for ; version < current; version++ {
+		fn, ok := steps[version]
+		if !ok {
+			return version, fmt.Errorf("%w: %s v%d -> v%d", ErrMigrationMissing, schema, version, version+1)
+		}
+		if err := fn(state); err != nil {
+			return version, fmt.Errorf("migrate %s v%d -> v%d: %w", schema, version, version+1, err)
+		}
+	}
+	return version, nil
+}
+
+// migrateSnapshot upgrades a JSON snapshot to the current version of a schema.
+// Snapshots that are already current are returned unchanged.
+func (r *MigrationRegistry) migrateSnapshot(schema string, data []byte) ([]byte, error) {
+	var head struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+	if head.Version == r.CurrentVersion(schema) {
+		return data, nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+	var state map[string]any
+	dec := json.NewDecoder(bytes.NewReader(raw["state"]))
+	dec.UseNumber() // Keep int64 precision
+	if err := dec.Decode(&state); err != nil {
+		return nil, fmt.Errorf("unmarshal state: %w", err)
+	}
+	if state == nil {
+		state = make(map[string]any)
+	}
+
+	version, err := r.Migrate(schema, head.Version, state)
+	if err != nil {
+		return nil, err
+	}
+	if raw["state"], err = json.Marshal(state); err != nil {
+		return nil, fmt.Errorf("marshal state: %w", err)
+	}
+	if raw["version"], err = json.Marshal(version); err != nil {
+		return nil, err
+	}
+	return json.Marshal(raw)
+}
+
+// RenameField renames a key of a state object (no-op if the key is missing)
+func RenameField(state map[string]any, from, to string) {
+	if v, ok := state[from]; ok {
+		state[to] = v
+		delete(state, from)
+	}
+}
+
+// MigrateEach applies a migration to every object in a nested array or map of structs
+func MigrateEach(v any, fn MigrationFunc) error {
+	switch c := v.(type) {
+	case []any:
+		for _, elem := range c {
+			if obj, ok := elem.(map[string]any); ok {
+				if err := fn(obj); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]any:
+		for _, elem := range c {
+			if obj, ok := elem.(map[string]any); ok {
+				if err := fn(obj); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
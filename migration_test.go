@@ -0,0 +1,228 @@
+package statesync
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// migratedTestState is a test state whose migrations are registered in migratedTestMigrations
+type migratedTestState struct {
+	changes *ChangeSet
+	Health  int64  `json:"health"`
+	Title   string `json:"title"`
+}
+
+func (s *migratedTestState) Changes() *ChangeSet { return s.changes }
+func (s *migratedTestState) ClearChanges()       { s.changes.Clear() }
+func (s *migratedTestState) MarkAllDirty()       { s.changes.MarkAll(1) }
+func (s *migratedTestState) Schema() *Schema {
+	return &Schema{
+		ID:   7,
+		Name: "MigratedTestState",
+		Fields: []FieldMeta{
+			{Index: 0, Name: "Health", Type: TypeInt64},
+			{Index: 1, Name: "Title", Type: TypeString},
+		},
+	}
+}
+func (s *migratedTestState) GetFieldValue(index uint8) interface{} {
+	switch index {
+	case 0:
+		return s.Health
+	case 1:
+		return s.Title
+	}
+	return nil
+}
+
+func (s *migratedTestState) SetFieldValue(index uint16, value interface{}) (err error) {
+	switch index {
+	case 0:
+		s.Health, err = DecodedValue[int64](value)
+	case 1:
+		s.Title, err = DecodedValue[string](value)
+	}
+	return err
+}
+
+var migratedTestMigrations = func() *MigrationRegistry {
+	r := NewMigrationRegistry()
+	// v1: {"hp": ..., "name": ..., "legacy": ...}
+	r.Register("MigratedTestState", 1, func(state map[string]any) error {
+		RenameField(state, "hp", "health")
+		delete(state, "legacy")
+		return nil
+	})
+	// v2: {"health": ..., "name": ...}
+	r.Register("MigratedTestState", 2, func(state map[string]any) error {
+		RenameField(state, "name", "title")
+		return nil
+	})
+	return r
+}()
+
+// oldMigratedTestState is migratedTestState as saved at v1
+type oldMigratedTestState struct {
+	changes *ChangeSet
+	HP      int64
+	Name    string
+	Legacy  bool
+}
+
+func (s *oldMigratedTestState) Changes() *ChangeSet { return s.changes }
+func (s *oldMigratedTestState) ClearChanges()       { s.changes.Clear() }
+func (s *oldMigratedTestState) MarkAllDirty()       { s.changes.MarkAll(2) }
+func (s *oldMigratedTestState) Schema() *Schema {
+	return NewSchemaBuilder("MigratedTestState").WithID(7).Int64("Hp").String("Name").Bool("Legacy").Build()
+}
+func (s *oldMigratedTestState) GetFieldValue(index uint8) interface{} {
+	switch index {
+	case 0:
+		return s.HP
+	case 1:
+		return s.Name
+	case 2:
+		return s.Legacy
+	}
+	return nil
+}
+
+func TestMigration_Load(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.json")
+	old := `{"version": 1, "state": {"hp": 9007199254740993, "name": "knight", "legacy": true}, "savedAt": "2024-01-01T00:00:00Z"}`
+	os.WriteFile(path, []byte(old), 0644)
+
+	snap, err := LoadMigrated[*migratedTestState](path, migratedTestMigrations)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if snap.Version != 3 {
+		t.Errorf("Version = %d, want 3", snap.Version)
+	}
+	if snap.State.Health != 9007199254740993 || snap.State.Title != "knight" {
+		t.Errorf("migrated state = %+v", snap.State)
+	}
+
+	// Saving uses the current version
+	ts := NewTrackedState[*migratedTestState, any](&migratedTestState{changes: NewChangeSet(), Health: 1}, &TrackedConfig{Migrations: migratedTestMigrations})
+	if err := Save(path, ts, nil, nil); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	var saved struct{ Version int }
+	json.Unmarshal(data, &saved)
+	if saved.Version != 3 {
+		t.Errorf("saved version = %d, want 3", saved.Version)
+	}
+}
+
+func TestMigration_BinarySnapshot(t *testing.T) {
+	old := NewTrackedState[*oldMigratedTestState, any](&oldMigratedTestState{changes: NewChangeSet(), HP: 9007199254740993, Name: "knight", Legacy: true}, nil)
+	store := NewMemoryStore()
+	if err := SaveBinarySnapshot(store, "old", old, nil, nil); err != nil {
+		t.Fatalf("SaveBinarySnapshot: %v", err)
+	}
+
+	// The saved schema differs, so the state is decoded with the stored descriptor and migrated
+	snap, err := LoadSnapshotMigrated[*migratedTestState](store, "old", migratedTestMigrations)
+	if err != nil {
+		t.Fatalf("LoadSnapshotMigrated: %v", err)
+	}
+	if snap.Version != 3 || snap.State.Health != 9007199254740993 || snap.State.Title != "knight" {
+		t.Errorf("migrated snapshot = v%d %+v", snap.Version, snap.State)
+	}
+
+	// Current snapshots record the current version and load without migrating
+	cfg := &TrackedConfig{Migrations: migratedTestMigrations}
+	ts := NewTrackedState[*migratedTestState, any](snap.State, cfg)
+	if err := SaveBinarySnapshot(store, "new", ts, nil, nil); err != nil {
+		t.Fatalf("SaveBinarySnapshot: %v", err)
+	}
+	snap, err = LoadSnapshotMigrated[*migratedTestState](store, "new", migratedTestMigrations)
+	if err != nil || snap.Version != 3 || snap.State.Title != "knight" {
+		t.Errorf("current snapshot = %+v, %v", snap, err)
+	}
+	if _, err := LoadSnapshotMigrated[*migratedTestState](store, "new", nil); !errors.Is(err, ErrSnapshotTooNew) {
+		t.Errorf("expected ErrSnapshotTooNew without migrations, got %v", err)
+	}
+}
+
+func TestMigration_LoadWithoutMigrations(t *testing.T) {
+	store := NewMemoryStore()
+	cfg := &TrackedConfig{Migrations: migratedTestMigrations}
+	ts := NewTrackedState[*migratedTestState, any](&migratedTestState{changes: NewChangeSet(), Health: 5, Title: "knight"}, cfg)
+	if err := SaveBinarySnapshot(store, "new", ts, nil, nil); err != nil {
+		t.Fatalf("SaveBinarySnapshot: %v", err)
+	}
+
+	// LoadSnapshot decodes the snapshot as saved, whatever its version
+	snap, err := LoadSnapshot[*migratedTestState](store, "new")
+	if err != nil || snap.Version != 3 || snap.State.Health != 5 || snap.State.Title != "knight" {
+		t.Errorf("LoadSnapshot = %+v, %v", snap, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "new.json")
+	os.WriteFile(path, []byte(`{"version": 3, "state": {"health": 5, "title": "knight", "extra": 1}}`), 0644)
+	jsonSnap, err := Load[*migratedTestState](path)
+	if err != nil || jsonSnap.State.Health != 5 || jsonSnap.State.Title != "knight" {
+		t.Errorf("Load = %+v, %v", jsonSnap, err)
+	}
+
+	// A different schema without a migration path is rejected by LoadSnapshotMigrated
+	old := NewTrackedState[*oldMigratedTestState, any](&oldMigratedTestState{changes: NewChangeSet(), HP: 1}, nil)
+	if err := SaveBinarySnapshot(store, "old", old, nil, nil); err != nil {
+		t.Fatalf("SaveBinarySnapshot: %v", err)
+	}
+	if _, err := LoadSnapshotMigrated[*migratedTestState](store, "old", nil); !errors.Is(err, ErrMigrationMissing) {
+		t.Errorf("expected ErrMigrationMissing, got %v", err)
+	}
+}
+
+func TestMigration_Errors(t *testing.T) {
+	reg := NewMigrationRegistry()
+	reg.Register("S", 1, func(map[string]any) error { return nil })
+	reg.Register("S", 3, func(map[string]any) error { return nil })
+
+	if v := reg.CurrentVersion("S"); v != 4 {
+		t.Errorf("CurrentVersion = %d, want 4", v)
+	}
+	if v := reg.CurrentVersion("Other"); v != SnapshotVersion {
+		t.Errorf("CurrentVersion without migrations = %d, want %d", v, SnapshotVersion)
+	}
+	if _, err := reg.Migrate("S", 1, map[string]any{}); !errors.Is(err, ErrMigrationMissing) {
+		t.Errorf("expected ErrMigrationMissing, got %v", err)
+	}
+	if _, err := reg.Migrate("S", 5, map[string]any{}); !errors.Is(err, ErrSnapshotTooNew) {
+		t.Errorf("expected ErrSnapshotTooNew, got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("duplicate migration should panic")
+		}
+	}()
+	reg.Register("S", 1, func(map[string]any) error { return nil })
+}
+
+func TestMigrateEach(t *testing.T) {
+	state := map[string]any{
+		"players": []any{map[string]any{"hp": 1}, map[string]any{"hp": 2}},
+		"byId":    map[string]any{"a": map[string]any{"hp": 3}},
+	}
+	rename := func(obj map[string]any) error {
+		RenameField(obj, "hp", "health")
+		return nil
+	}
+	MigrateEach(state["players"], rename)
+	MigrateEach(state["byId"], rename)
+
+	if state["players"].([]any)[1].(map[string]any)["health"] != 2 {
+		t.Errorf("array elements not migrated: %v", state["players"])
+	}
+	if state["byId"].(map[string]any)["a"].(map[string]any)["health"] != 3 {
+		t.Errorf("map values not migrated: %v", state["byId"])
+	}
+}
@@ -0,0 +1,353 @@
+package statesync
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Multi-root protocol constants
+const (
+	// MsgRootRemove tells the client that a root schema was deactivated
+	// and its state should be dropped.
+	// Format: [MsgRootRemove][schemaID:uint16]
+	MsgRootRemove uint8 = 0x04
+)
+
+// Multi-session errors
+var (
+	ErrUnknownRoot   = errors.New("unknown root")
+	ErrRootExists    = errors.New("root already registered")
+	ErrRootSchemaID  = errors.New("root schema ID already in use")
+	ErrInvalidBatch  = errors.New("invalid patch batch")
+	errEmptyRootName = errors.New("root name must not be empty")
+)
+
+// Root is a type-erased root state that can be synced by a MultiSession.
+// Use RootOf to adapt a TrackedSession.
+type Root[ID comparable] interface {
+	// SchemaID returns the schema ID of the root state (used by clients to demultiplex)
+	SchemaID() uint16
+
+	// Connect starts syncing the root to a client (the next Tick sends full state)
+	Connect(id ID)
+
+	// Disconnect stops syncing the root to a client
+	Disconnect(id ID)
+
+	// Tick broadcasts pending changes and commits them
+	Tick() map[ID][]byte
+}
+
+// trackedRoot adapts a TrackedSession to the Root interface
+type trackedRoot[T Trackable, A any, ID comparable] struct {
+	session   *TrackedSession[T, A, ID]
+	filterFor func(id ID) FilterFunc[T]
+}
+
+// RootOf adapts a TrackedSession for use in a MultiSession.
+// filterFor returns the filter for a client when it is connected to the root;
+// pass nil to send the full state to everyone.
+func RootOf[T Trackable, A any, ID comparable](session *TrackedSession[T, A, ID], filterFor func(id ID) FilterFunc[T]) Root[ID] {
+	return &trackedRoot[T, A, ID]{session: session, filterFor: filterFor}
+}
+
+func (r *trackedRoot[T, A, ID]) SchemaID() uint16 {
+	var id uint16
+	r.session.ReadBase(func(state T) {
+		id = state.Schema().ID
+	})
+	return id
+}
+
+func (r *trackedRoot[T, A, ID]) Connect(id ID) {
+	var filter FilterFunc[T]
+	if r.filterFor != nil {
+		filter = r.filterFor(id)
+	}
+	r.session.Connect(id, filter)
+}
+
+func (r *trackedRoot[T, A, ID]) Disconnect(id ID) { r.session.Disconnect(id) }
+
+func (r *trackedRoot[T, A, ID]) Tick() map[ID][]byte { return r.session.Tick() }
+
+// multiRoot is a registered root with its activation state
+type multiRoot[ID comparable] struct {
+	name     string
+	root     Root[ID]
+	schemaID uint16
+	active   bool
+}
+
+// MultiSession syncs several root states (e.g. GameState, DroneMode, TournamentMode)
+// to clients over one connection. Roots can be activated and deactivated at runtime:
+// activation sends the full root state to every client on the next Tick,
+// deactivation sends a MsgRootRemove message so clients drop the root.
+//
+// Clients demultiplex messages by the schema ID in each message header.
+// When a client receives more than one message in a tick they are wrapped
+// in a single MsgPatchBatch message (see EncodePatchBatch).
+type MultiSession[ID comparable] struct {
+	mu      sync.RWMutex
+	roots   map[string]*multiRoot[ID]
+	order   []string // Registration order (deterministic message order)
+	clients map[ID]struct{}
+
+	// Removal messages waiting for the next Tick, per client
+	pendingRemovals map[ID][][]byte
+}
+
+// NewMultiSession creates an empty multi-root session
+func NewMultiSession[ID comparable]() *MultiSession[ID] {
+	return &MultiSession[ID]{
+		roots:           make(map[string]*multiRoot[ID]),
+		clients:         make(map[ID]struct{}),
+		pendingRemovals: make(map[ID][][]byte),
+	}
+}
+
+// AddRoot registers a root under a name.
+// If active is true, all connected clients receive its full state on the next Tick.
+func (m *MultiSession[ID]) AddRoot(name string, root Root[ID], active bool) error {
+	if name == "" {
+		return errEmptyRootName
+	}
+	schemaID := root.SchemaID()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.roots[name]; ok {
+		return fmt.Errorf("%w: %s", ErrRootExists, name)
+	}
+	for _, existing := range m.roots {
+		if existing.schemaID == schemaID {
+			return fmt.Errorf("%w: %s and %s both use ID %d", ErrRootSchemaID, existing.name, name, schemaID)
+		}
+	}
+
+	m.roots[name] = &multiRoot[ID]{name: name, root: root, schemaID: schemaID}
+	m.order = append(m.order, name)
+	if active {
+		m.activateLocked(m.roots[name])
+	}
+	return nil
+}
+
+// Activate starts syncing a root. Connected clients receive its full state on the next Tick.
+// Activating an already active root is a no-op.
+func (m *MultiSession[ID]) Activate(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.roots[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownRoot, name)
+	}
+	m.activateLocked(r)
+	return nil
+}
+
+// Deactivate stops syncing a root. Connected clients receive a MsgRootRemove
+// message on the next Tick. Deactivating an inactive root is a no-op.
+func (m *MultiSession[ID]) Deactivate(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.roots[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownRoot, name)
+	}
+	if !r.active {
+		return nil
+	}
+	r.active = false
+	removal := EncodeRootRemove(r.schemaID)
+	for id := range m.clients {
+		r.root.Disconnect(id)
+		m.pendingRemovals[id] = append(m.pendingRemovals[id], removal)
+	}
+	return nil
+}
+
+// activateLocked marks a root active and connects all clients. Caller must hold m.mu.
+func (m *MultiSession[ID]) activateLocked(r *multiRoot[ID]) {
+	if r.active {
+		return
+	}
+	r.active = true
+	for id := range m.clients {
+		r.root.Connect(id)
+	}
+}
+
+// IsActive returns true if the named root is currently active
+func (m *MultiSession[ID]) IsActive(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	r, ok := m.roots[name]
+	return ok && r.active
+}
+
+// ActiveRoots returns the names of all active roots in registration order
+func (m *MultiSession[ID]) ActiveRoots() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var names []string
+	for _, name := range m.order {
+		if m.roots[name].active {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Root returns the root registered under name (nil if unknown)
+func (m *MultiSession[ID]) Root(name string) Root[ID] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if r, ok := m.roots[name]; ok {
+		return r.root
+	}
+	return nil
+}
+
+// Connect adds a client to all active roots.
+// The next Tick sends the full state of every active root.
+func (m *MultiSession[ID]) Connect(id ID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clients[id] = struct{}{}
+	delete(m.pendingRemovals, id)
+	for _, name := range m.order {
+		if r := m.roots[name]; r.active {
+			r.root.Connect(id)
+		}
+	}
+}
+
+// Disconnect removes a client from all roots
+func (m *MultiSession[ID]) Disconnect(id ID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.clients, id)
+	delete(m.pendingRemovals, id)
+	for _, name := range m.order {
+		if r := m.roots[name]; r.active {
+			r.root.Disconnect(id)
+		}
+	}
+}
+
+// ClientCount returns the number of connected clients
+func (m *MultiSession[ID]) ClientCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.clients)
+}
+
+// Tick ticks every active root and returns one message per client.
+// Removal messages come first, followed by root messages in registration order.
+// A client with several messages receives them wrapped in a MsgPatchBatch.
+func (m *MultiSession[ID]) Tick() map[ID][]byte {
+	m.mu.Lock()
+	removals := m.pendingRemovals
+	m.pendingRemovals = make(map[ID][][]byte)
+	var active []Root[ID]
+	for _, name := range m.order {
+		if r := m.roots[name]; r.active {
+			active = append(active, r.root)
+		}
+	}
+	m.mu.Unlock()
+
+	perClient := make(map[ID][][]byte, len(removals))
+	for id, msgs := range removals {
+		perClient[id] = append(perClient[id], msgs...)
+	}
+	for _, root := range active {
+		for id, data := range root.Tick() {
+			if len(data) > 0 {
+				perClient[id] = append(perClient[id], data)
+			}
+		}
+	}
+
+	if len(perClient) == 0 {
+		return nil
+	}
+	result := make(map[ID][]byte, len(perClient))
+	for id, msgs := range perClient {
+		result[id] = EncodePatchBatch(msgs)
+	}
+	return result
+}
+
+// EncodeRootRemove encodes a MsgRootRemove message for a schema
+func EncodeRootRemove(schemaID uint16) []byte {
+	return []byte{MsgRootRemove, byte(schemaID), byte(schemaID >> 8)}
+}
+
+// EncodePatchBatch wraps several state messages into one message.
+// A single message is returned unchanged.
+// Format: [MsgPatchBatch][count:varint]{[len:varint][message:bytes]}...
+func EncodePatchBatch(msgs [][]byte) []byte {
+	switch len(msgs) {
+	case 0:
+		return nil
+	case 1:
+		return msgs[0]
+	}
+
+	size := 1 + varIntSize(uint64(len(msgs)))
+	for _, msg := range msgs {
+		size += varIntSize(uint64(len(msg))) + len(msg)
+	}
+
+	buf := make([]byte, size)
+	buf[0] = MsgPatchBatch
+	pos := 1
+	pos += putVarUint(buf[pos:], uint64(len(msgs)))
+	for _, msg := range msgs {
+		pos += putVarUint(buf[pos:], uint64(len(msg)))
+		pos += copy(buf[pos:], msg)
+	}
+	return buf
+}
+
+// SplitPatchBatch splits a MsgPatchBatch message into its state messages.
+// Any other message is returned as a single-element slice.
+func SplitPatchBatch(data []byte) ([][]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrBufferTooSmall
+	}
+	if data[0] != MsgPatchBatch {
+		return [][]byte{data}, nil
+	}
+
+	pos := 1
+	count, n := readVarUint(data[pos:])
+	if n == 0 {
+		return nil, ErrInvalidBatch
+	}
+	pos += n
+	if count > uint64(len(data)-pos) {
+		return nil, ErrInvalidBatch
+	}
+
+	msgs := make([][]byte, 0, count)
+	for i := uint64(0); i < count; i++ {
+		msgLen, n := readVarUint(data[pos:])
+		if n == 0 {
+			return nil, ErrInvalidBatch
+		}
+		pos += n
+		if msgLen > uint64(len(data)-pos) {
+			return nil, ErrInvalidBatch
+		}
+		msgs = append(msgs, data[pos:pos+int(msgLen)])
+		pos += int(msgLen)
+	}
+	return msgs, nil
+}
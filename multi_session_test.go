@@ -0,0 +1,196 @@
+package statesync
+
+import (
+	"errors"
+	"testing"
+)
+
+func newTestMultiSession(t *testing.T) (*MultiSession[string], *simpleTestState, *ReplayTestState) {
+	t.Helper()
+
+	game := &simpleTestState{changes: NewChangeSet()}
+	gameSession := NewTrackedSession[*simpleTestState, any, string](NewTrackedState[*simpleTestState, any](game, nil))
+
+	mode := NewReplayTestState()
+	modeSession := NewTrackedSession[*ReplayTestState, any, string](NewTrackedState[*ReplayTestState, any](mode, nil))
+
+	ms := NewMultiSession[string]()
+	if err := ms.AddRoot("GameState", RootOf(gameSession, nil), true); err != nil {
+		t.Fatalf("AddRoot GameState: %v", err)
+	}
+	if err := ms.AddRoot("DroneMode", RootOf(modeSession, nil), false); err != nil {
+		t.Fatalf("AddRoot DroneMode: %v", err)
+	}
+	return ms, game, mode
+}
+
+func multiTestRegistry() *SchemaRegistry {
+	registry := NewSchemaRegistry()
+	registry.Register((&simpleTestState{}).Schema())
+	registry.Register(NewReplayTestState().Schema())
+	return registry
+}
+
+func TestMultiSession_ConnectSendsActiveRootsOnly(t *testing.T) {
+	ms, game, _ := newTestMultiSession(t)
+	game.Score = 3
+	game.changes.Mark(0, OpReplace)
+
+	ms.Connect("alice")
+	diffs := ms.Tick()
+
+	data, ok := diffs["alice"]
+	if !ok {
+		t.Fatal("expected message for alice")
+	}
+	if data[0] != MsgFullState {
+		t.Fatalf("expected single full state message, got type 0x%02x", data[0])
+	}
+
+	patches, err := NewDecoder(multiTestRegistry()).DecodeBatch(data)
+	if err != nil {
+		t.Fatalf("DecodeBatch: %v", err)
+	}
+	if len(patches) != 1 || patches[0].SchemaID != 1 {
+		t.Fatalf("expected one GameState patch, got %+v", patches)
+	}
+}
+
+func TestMultiSession_ActivateSendsFullState(t *testing.T) {
+	ms, _, mode := newTestMultiSession(t)
+	ms.Connect("alice")
+	ms.Tick()
+
+	mode.SetScore(42)
+	if err := ms.Activate("DroneMode"); err != nil {
+		t.Fatalf("Activate: %v", err)
+	}
+	if !ms.IsActive("DroneMode") {
+		t.Fatal("DroneMode should be active")
+	}
+
+	diffs := ms.Tick()
+	patches, err := NewDecoder(multiTestRegistry()).DecodeBatch(diffs["alice"])
+	if err != nil {
+		t.Fatalf("DecodeBatch: %v", err)
+	}
+	if len(patches) != 1 || patches[0].SchemaID != 100 {
+		t.Fatalf("expected DroneMode full state, got %+v", patches)
+	}
+	if patches[0].Changes[0].Value != int64(42) {
+		t.Errorf("expected Score 42, got %v", patches[0].Changes[0].Value)
+	}
+}
+
+func TestMultiSession_DeactivateSendsRemoval(t *testing.T) {
+	ms, game, mode := newTestMultiSession(t)
+	ms.Activate("DroneMode")
+	ms.Connect("alice")
+	ms.Tick()
+
+	if err := ms.Deactivate("DroneMode"); err != nil {
+		t.Fatalf("Deactivate: %v", err)
+	}
+	game.Name = "playing"
+	game.changes.Mark(1, OpReplace)
+	mode.SetScore(7) // Inactive root changes must not be sent
+
+	diffs := ms.Tick()
+	data := diffs["alice"]
+	if data[0] != MsgPatchBatch {
+		t.Fatalf("expected batch, got type 0x%02x", data[0])
+	}
+
+	patches, err := NewDecoder(multiTestRegistry()).DecodeBatch(data)
+	if err != nil {
+		t.Fatalf("DecodeBatch: %v", err)
+	}
+	if len(patches) != 2 {
+		t.Fatalf("expected removal + patch, got %d patches", len(patches))
+	}
+	if !patches[0].Removed || patches[0].SchemaID != 100 {
+		t.Errorf("expected removal of schema 100 first, got %+v", patches[0])
+	}
+	if patches[1].Removed || patches[1].SchemaID != 1 {
+		t.Errorf("expected GameState patch second, got %+v", patches[1])
+	}
+
+	if got := ms.ActiveRoots(); len(got) != 1 || got[0] != "GameState" {
+		t.Errorf("expected [GameState] active, got %v", got)
+	}
+}
+
+func TestMultiSession_DisconnectDropsPendingRemovals(t *testing.T) {
+	ms, _, _ := newTestMultiSession(t)
+	ms.Connect("alice")
+	ms.Tick()
+
+	ms.Deactivate("GameState")
+	ms.Disconnect("alice")
+
+	if diffs := ms.Tick(); len(diffs) != 0 {
+		t.Errorf("expected no messages after disconnect, got %v", diffs)
+	}
+	if ms.ClientCount() != 0 {
+		t.Errorf("expected 0 clients, got %d", ms.ClientCount())
+	}
+}
+
+func TestMultiSession_AddRootErrors(t *testing.T) {
+	ms, _, _ := newTestMultiSession(t)
+
+	other := NewTrackedSession[*simpleTestState, any, string](NewTrackedState[*simpleTestState, any](&simpleTestState{changes: NewChangeSet()}, nil))
+	if err := ms.AddRoot("GameState", RootOf(other, nil), false); !errors.Is(err, ErrRootExists) {
+		t.Errorf("expected ErrRootExists, got %v", err)
+	}
+	if err := ms.AddRoot("Other", RootOf(other, nil), false); !errors.Is(err, ErrRootSchemaID) {
+		t.Errorf("expected ErrRootSchemaID, got %v", err)
+	}
+	if err := ms.Activate("Missing"); !errors.Is(err, ErrUnknownRoot) {
+		t.Errorf("expected ErrUnknownRoot, got %v", err)
+	}
+}
+
+func TestMultiSession_RootFilter(t *testing.T) {
+	game := NewTestGameState()
+	session := NewTrackedSession[*TestGameState, any, string](NewTrackedState[*TestGameState, any](game, nil))
+
+	var filteredFor []string
+	root := RootOf(session, func(id string) FilterFunc[*TestGameState] {
+		filteredFor = append(filteredFor, id)
+		return func(s *TestGameState) *TestGameState { return s }
+	})
+
+	ms := NewMultiSession[string]()
+	ms.AddRoot("GameState", root, true)
+	ms.Connect("bob")
+
+	if len(filteredFor) != 1 || filteredFor[0] != "bob" {
+		t.Errorf("expected filter requested for bob, got %v", filteredFor)
+	}
+	if session.GetFilter("bob") == nil {
+		t.Error("expected filter installed on underlying session")
+	}
+}
+
+func TestPatchBatch_RoundTrip(t *testing.T) {
+	msgs := [][]byte{{MsgPatch, 1, 0, 0}, EncodeRootRemove(300)}
+	batch := EncodePatchBatch(msgs)
+
+	split, err := SplitPatchBatch(batch)
+	if err != nil {
+		t.Fatalf("SplitPatchBatch: %v", err)
+	}
+	if len(split) != 2 || string(split[0]) != string(msgs[0]) || string(split[1]) != string(msgs[1]) {
+		t.Errorf("round trip mismatch: %v", split)
+	}
+
+	single := EncodePatchBatch(msgs[:1])
+	if string(single) != string(msgs[0]) {
+		t.Error("single message should not be wrapped")
+	}
+
+	if _, err := SplitPatchBatch([]byte{MsgPatchBatch, 2, 5, 1}); !errors.Is(err, ErrInvalidBatch) {
+		t.Errorf("expected ErrInvalidBatch for truncated batch, got %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+package statesync
+
+import "fmt"
+
+// OverlayFunc masks or replaces parts of the state for a specific client without
+// copying it (see Overlay). It replaces ShallowClone-based filters.
+type OverlayFunc[T any] func(state T, view *Overlay)
+
+// Overlay is a copy-on-write view of a Trackable. Fields and array/map elements can
+// be hidden or replaced; everything else is read from the base on demand, so no
+// clone of the state is made. The encoder reads through the overlay:
+//   - Hidden fields are encoded as zero values and skipped in patches
+//   - Replaced fields are encoded with the replacement when the base field changed
+//   - Arrays/maps with replaced or filtered elements are sent as full replacements
+//     when they changed (incremental ops refer to base indices)
+//
+// Overlays are read-only views: changes are tracked by the base.
+type Overlay struct {
+	base   Trackable
+	fields map[uint16]*overlayField
+}
+
+// overlayField holds the overrides of a single field
+type overlayField struct {
+	hidden   bool
+	replaced bool
+	value    interface{}
+
+	// Array element overrides (base indices)
+	keepElem func(index int, elem interface{}) bool
+	elems    map[int]interface{}
+
+	// Map entry overrides
+	keepEntry func(key string, value interface{}) bool
+	entries   map[string]interface{}
+}
+
+// NewOverlay creates an overlay over base that initially shows everything
+func NewOverlay(base Trackable) *Overlay {
+	return &Overlay{base: base}
+}
+
+// Base returns the underlying Trackable
+func (o *Overlay) Base() Trackable {
+	return o.base
+}
+
+// Hide hides a field (encoded as its zero value, never read)
+func (o *Overlay) Hide(field string) *Overlay {
+	f := o.field(field)
+	f.hidden = true
+	return o
+}
+
+// Replace shows value instead of a field's base value
+func (o *Overlay) Replace(field string, value interface{}) *Overlay {
+	f := o.field(field)
+	f.replaced, f.value = true, value
+	return o
+}
+
+// FilterElements shows only the array elements keep returns true for.
+// keep receives the base index and element and is called lazily at encode time.
+func (o *Overlay) FilterElements(field string, keep func(index int, elem interface{}) bool) *Overlay {
+	o.field(field).keepElem = keep
+	return o
+}
+
+// ReplaceElement shows value instead of the array element at a base index
+// (e.g. a nested Overlay hiding part of the element)
+func (o *Overlay) ReplaceElement(field string, index int, value interface{}) *Overlay {
+	f := o.field(field)
+	if f.elems == nil {
+		f.elems = make(map[int]interface{})
+	}
+	f.elems[index] = value
+	return o
+}
+
+// FilterEntries shows only the map entries keep returns true for
+func (o *Overlay) FilterEntries(field string, keep func(key string, value interface{}) bool) *Overlay {
+	o.field(field).keepEntry = keep
+	return o
+}
+
+// ReplaceEntry shows value instead of the map entry at key
+func (o *Overlay) ReplaceEntry(field string, key string, value interface{}) *Overlay {
+	f := o.field(field)
+	if f.entries == nil {
+		f.entries = make(map[string]interface{})
+	}
+	f.entries[key] = value
+	return o
+}
+
+// field returns the overrides of a field by name, creating them if needed
+func (o *Overlay) field(name string) *overlayField {
+	schema := o.base.Schema()
+	meta := fieldNamed(schema, name)
+	if meta == nil {
+		panic(fmt.Sprintf("statesync: overlay: schema %q has no field %q", schema.Name, name))
+	}
+	if o.fields == nil {
+		o.fields = make(map[uint16]*overlayField)
+	}
+	f := o.fields[meta.Index]
+	if f == nil {
+		f = &overlayField{}
+		o.fields[meta.Index] = f
+	}
+	return f
+}
+
+// Schema returns the base schema
+func (o *Overlay) Schema() *Schema { return o.base.Schema() }
+
+// Changes returns the base changes (read-only)
+func (o *Overlay) Changes() *ChangeSet { return o.base.Changes() }
+
+// ClearChanges is a no-op: changes belong to the base
+func (o *Overlay) ClearChanges() {}
+
+// MarkAllDirty is a no-op: changes belong to the base
+func (o *Overlay) MarkAllDirty() {}
+
+// GetFieldValue returns the overridden value of a field, or the base value
+func (o *Overlay) GetFieldValue(index uint8) interface{} {
+	return o.GetWideFieldValue(uint16(index))
+}
+
+// GetWideFieldValue is GetFieldValue for any field index (see WideTrackable)
+func (o *Overlay) GetWideFieldValue(index uint16) interface{} {
+	f := o.fields[index]
+	switch {
+	case f == nil:
+		return fieldValue(o.base, index)
+	case f.hidden:
+		return nil
+	case f.replaced:
+		return f.value
+	case f.keepElem != nil || f.elems != nil:
+		return &arrayOverlay{base: fieldValue(o.base, index), field: f}
+	case f.keepEntry != nil || f.entries != nil:
+		return &mapOverlay{base: fieldValue(o.base, index), field: f}
+	}
+	return fieldValue(o.base, index)
+}
+
+// hidden reports whether a field is hidden
+func (o *Overlay) hidden(index uint16) bool {
+	f := o.fields[index]
+	return f != nil && f.hidden
+}
+
+// overridden reports whether a field's value or elements differ from the base
+func (o *Overlay) overridden(index uint16) bool {
+	f := o.fields[index]
+	return f != nil && !f.hidden
+}
+
+// overlayHidden reports whether t is an overlay hiding a field
+func overlayHidden(t Trackable, index uint16) bool {
+	o, ok := t.(*Overlay)
+	return ok && o.hidden(index)
+}
+
+// overlayOverridden reports whether t is an overlay overriding a field's value or elements
+func overlayOverridden(t Trackable, index uint16) bool {
+	o, ok := t.(*Overlay)
+	return ok && o.overridden(index)
+}
+
+// applyOverlay wraps state in an overlay built by fn (state itself if fn is nil)
+func applyOverlay[T Trackable](state T, fn OverlayFunc[T]) Trackable {
+	if fn == nil {
+		return state
+	}
+	view := NewOverlay(state)
+	fn(state, view)
+	return view
+}
+
+// arrayOverlay is a lazy array view with filtered or replaced elements
+type arrayOverlay struct {
+	base    interface{}
+	field   *overlayField
+	indices []int // Visible base indices
+	built   bool
+}
+
+// visible returns the visible base indices (computed on first use)
+func (a *arrayOverlay) visible() []int {
+	if a.built {
+		return a.indices
+	}
+	a.built = true
+	n := getArrayLength(a.base)
+	for i := 0; i < n; i++ {
+		if a.field.keepElem == nil || a.field.keepElem(i, getArrayElement(a.base, i)) {
+			a.indices = append(a.indices, i)
+		}
+	}
+	return a.indices
+}
+
+// Len returns the number of visible elements
+func (a *arrayOverlay) Len() int {
+	return len(a.visible())
+}
+
+// At returns the i-th visible element
+func (a *arrayOverlay) At(i int) interface{} {
+	idx := a.visible()[i]
+	if v, ok := a.field.elems[idx]; ok {
+		return v
+	}
+	return getArrayElement(a.base, idx)
+}
+
+// mapOverlay is a lazy map view with filtered or replaced entries
+type mapOverlay struct {
+	base  interface{}
+	field *overlayField
+}
+
+// keysValues returns the visible entries sorted by key
+func (m *mapOverlay) keysValues() ([]string, []interface{}) {
+	keys, values := getMapKeysValues(m.base)
+	n := 0
+	for i, key := range keys {
+		value := values[i]
+		if m.field.keepEntry != nil && !m.field.keepEntry(key, value) {
+			continue
+		}
+		if v, ok := m.field.entries[key]; ok {
+			value = v
+		}
+		keys[n], values[n] = key, value
+		n++
+	}
+	return keys[:n], values[:n]
+}
@@ -0,0 +1,147 @@
+package statesync
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOverlay_EncodeAll(t *testing.T) {
+	state := newProjState()
+	view := NewOverlay(state).
+		Hide("Seed").
+		Replace("Round", int32(99)).
+		FilterElements("Players", func(i int, elem interface{}) bool { return i != 0 }).
+		ReplaceElement("Players", 1, NewOverlay(state.Players[1]).Hide("Hand")).
+		FilterEntries("ByName", func(key string, value interface{}) bool { return key != "carol" })
+
+	fields := decodeProjState(t, NewEncoder(nil).EncodeAll(view))
+	if state.secretReads != 0 {
+		t.Errorf("hidden field was read %d times", state.secretReads)
+	}
+	if fields["Round"] != int32(99) || fields["Seed"] != int64(0) {
+		t.Errorf("unexpected root fields: Round=%v Seed=%v", fields["Round"], fields["Seed"])
+	}
+	players := fields["Players"].([]interface{})
+	if len(players) != 1 {
+		t.Fatalf("expected 1 visible player, got %d", len(players))
+	}
+	bob := players[0].(map[string]interface{})
+	if bob["ID"] != "bob" || len(bob["Hand"].([]interface{})) != 0 || bob["Notes"] != "ok" {
+		t.Errorf("unexpected masked player %v", bob)
+	}
+	if len(fields["ByName"].(map[string]interface{})) != 0 {
+		t.Errorf("expected no visible entries, got %v", fields["ByName"])
+	}
+
+	// The base is untouched
+	if state.Round != 3 || len(state.Players) != 2 || !reflect.DeepEqual(state.Players[1].Hand, []int32{3}) {
+		t.Errorf("base state was modified")
+	}
+	full := decodeProjState(t, NewEncoder(nil).EncodeAll(state))
+	if full["Seed"] != int64(42) || len(full["Players"].([]interface{})) != 2 {
+		t.Errorf("unexpected base encoding %v", full)
+	}
+}
+
+func TestOverlay_EncodeChanges(t *testing.T) {
+	state := newProjState()
+	enc := NewEncoder(nil)
+	view := NewOverlay(state).Hide("Seed")
+
+	// Only a hidden field changed: nothing to send
+	state.changes.Mark(1, OpReplace)
+	if data := enc.Encode(view); data != nil {
+		t.Errorf("expected no patch, got %v", data)
+	}
+	if state.secretReads != 0 {
+		t.Errorf("hidden field was read %d times", state.secretReads)
+	}
+
+	// Arrays with overridden elements are sent in full
+	state.changes.Clear()
+	state.Players = append(state.Players, &projPlayer{changes: NewChangeSet(), ID: "dave"})
+	state.changes.GetOrCreateArray(2).MarkAdd(2, state.Players[2])
+	view.FilterElements("Players", func(i int, elem interface{}) bool {
+		return elem.(*projPlayer).ID != "alice"
+	})
+
+	registry := NewSchemaRegistry()
+	registry.Register(projStateSchema)
+	patch, err := NewDecoder(registry).Decode(enc.Encode(view))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(patch.Changes) != 1 || patch.Changes[0].ArrayChanges != nil {
+		t.Fatalf("expected a full array replacement, got %+v", patch.Changes)
+	}
+	if n := len(patch.Changes[0].Value.([]interface{})); n != 2 {
+		t.Errorf("expected 2 visible players, got %d", n)
+	}
+
+	// Without overrides the base ops are kept
+	patch, err = NewDecoder(registry).Decode(enc.Encode(NewOverlay(state)))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(patch.Changes[0].ArrayChanges) != 1 {
+		t.Errorf("expected incremental array changes, got %+v", patch.Changes[0])
+	}
+}
+
+func TestOverlay_UnknownFieldPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for unknown field")
+		}
+	}()
+	NewOverlay(newProjState()).Hide("Missing")
+}
+
+func TestTrackedSession_Overlay(t *testing.T) {
+	state := newProjState()
+	tracked := NewTrackedState[*projState, any](state, nil)
+	session := NewTrackedSession[*projState, any, string](tracked)
+	session.Connect("alice", nil)
+	session.SetOverlay("alice", func(s *projState, view *Overlay) {
+		view.Hide("Seed")
+		for i, p := range s.Players {
+			if p.ID != "alice" {
+				view.ReplaceElement("Players", i, NewOverlay(p).Hide("Hand"))
+			}
+		}
+	})
+	session.Connect("spectator", nil)
+
+	diffs := session.Tick()
+	fields := decodeProjState(t, diffs["alice"])
+	if fields["Seed"] != int64(0) || len(projPlayerFields(fields, 1)["Hand"].([]interface{})) != 0 {
+		t.Errorf("alice's full state leaked hidden data: %v", fields)
+	}
+	if len(projPlayerFields(fields, 0)["Hand"].([]interface{})) != 2 {
+		t.Errorf("alice should see her hand: %v", projPlayerFields(fields, 0))
+	}
+	if fields := decodeProjState(t, diffs["spectator"]); fields["Seed"] != int64(42) {
+		t.Errorf("spectator without overlay should see everything")
+	}
+
+	tracked.UpdateInPlace(func(s *projState) {
+		s.Seed = 7
+		s.changes.Mark(1, OpReplace)
+	})
+	diffs = session.Tick()
+	if _, ok := diffs["alice"]; ok {
+		t.Errorf("alice should get no diff for a hidden change")
+	}
+	if _, ok := diffs["spectator"]; !ok {
+		t.Errorf("spectator should get the Seed change")
+	}
+
+	session.Disconnect("alice")
+	if session.GetOverlay("alice") == nil {
+		t.Error("overlay should be kept across Disconnect")
+	}
+	session.SetOverlay("alice", nil)
+	if session.GetOverlay("alice") != nil {
+		t.Error("expected overlay to be cleared")
+	}
+}
@@ -10,14 +10,14 @@ import (
 
 // Snapshot represents saved state that can be restored
 type Snapshot[T any] struct {
-	Version int             `json:"version"` // Schema version for forward compatibility
+	Version int             `json:"version"` // Schema version (see MigrationRegistry)
 	State   T               `json:"state"`
 	Effects []EffectMeta    `json:"effects,omitempty"`
 	SavedAt time.Time       `json:"savedAt"`
 	Extra   json.RawMessage `json:"extra,omitempty"`
 }
 
-// Current snapshot version
+// SnapshotVersion is the version of snapshots of schemas without migrations
 const SnapshotVersion = 1
 
 // RestoreResult contains the restored state and any non-fatal errors
@@ -28,9 +28,18 @@ type RestoreResult[T Trackable, A any] struct {
 
 // EffectMeta stores effect info for recreation
 type EffectMeta struct {
-	ID     string          `json:"id"`
-	Type   string          `json:"type"`
-	Params json.RawMessage `json:"params,omitempty"`
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Params    json.RawMessage `json:"params,omitempty"`
+	Activator json.RawMessage `json:"activator,omitempty"` // Set by EffectRegistry
+	Lifetime  *EffectLifetime `json:"lifetime,omitempty"`  // Remaining lifetime of a TimedEffect
+}
+
+// EffectLifetime stores the remaining lifetime of a TimedEffect
+type EffectLifetime struct {
+	Ticks     uint64        `json:"ticks,omitempty"`
+	Remaining time.Duration `json:"remaining,omitempty"`
+	ByTime    bool          `json:"byTime,omitempty"`
 }
 
 // EffectFactory recreates effects from metadata.
@@ -39,26 +48,9 @@ type EffectFactory[T Trackable, A any] func(meta EffectMeta) (Effect[T, A], erro
 
 // Save writes state to a JSON file (atomic write)
 func Save[T Trackable, A any](path string, state *TrackedState[T, A], effects []EffectMeta, extra any) error {
-	var extraJSON json.RawMessage
-	if extra != nil {
-		var err error
-		extraJSON, err = json.Marshal(extra)
-		if err != nil {
-			return fmt.Errorf("marshal extra: %w", err)
-		}
-	}
-
-	snap := Snapshot[T]{
-		Version: SnapshotVersion,
-		State:   state.GetBase(),
-		Effects: effects,
-		SavedAt: time.Now(),
-		Extra:   extraJSON,
-	}
-
-	data, err := json.MarshalIndent(snap, "", "  ")
+	data, err := encodeSnapshot(state, effects, extra)
 	if err != nil {
-		return fmt.Errorf("marshal: %w", err)
+		return err
 	}
 
 	// Atomic write: temp file + rename
@@ -80,8 +72,61 @@ func Save[T Trackable, A any](path string, state *TrackedState[T, A], effects []
 	return nil
 }
 
-// Load reads state from a JSON file
+// encodeSnapshot marshals the base state, effects and extra data as a JSON snapshot.
+// The state is marshaled under its read lock.
+func encodeSnapshot[T Trackable, A any](state *TrackedState[T, A], effects []EffectMeta, extra any) ([]byte, error) {
+	var extraJSON json.RawMessage
+	if extra != nil {
+		var err error
+		extraJSON, err = json.Marshal(extra)
+		if err != nil {
+			return nil, fmt.Errorf("marshal extra: %w", err)
+		}
+	}
+
+	var data []byte
+	var err error
+	state.ReadBase(func(base T) {
+		snap := Snapshot[T]{
+			Version: state.migrations.CurrentVersion(base.Schema().Name),
+			State:   base,
+			Effects: effects,
+			SavedAt: time.Now(),
+			Extra:   extraJSON,
+		}
+		data, err = json.MarshalIndent(snap, "", "  ")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+	return data, nil
+}
+
+// Load reads state from a snapshot file (JSON or binary, see EncodeBinarySnapshot).
+// The snapshot is decoded as saved, whatever its version; fields T doesn't have are ignored.
+// Use LoadMigrated to upgrade older snapshots and reject incompatible ones.
 func Load[T any](path string) (*Snapshot[T], error) {
+	data, err := readSnapshotFile(path)
+	if data == nil {
+		return nil, err
+	}
+	return decodeSnapshot[T](data, nil, false)
+}
+
+// LoadMigrated reads state from a snapshot file and upgrades older snapshots with migrations.
+// Unlike Load, it fails with ErrSnapshotTooNew for snapshots newer than the current version
+// and with ErrMigrationMissing if a binary snapshot was saved with a different schema and
+// no migration leads from its version to the current one.
+func LoadMigrated[T any](path string, migrations *MigrationRegistry) (*Snapshot[T], error) {
+	data, err := readSnapshotFile(path)
+	if data == nil {
+		return nil, err
+	}
+	return decodeSnapshot[T](data, migrations, true)
+}
+
+// readSnapshotFile reads a snapshot file (nil, nil if it doesn't exist)
+func readSnapshotFile(path string) ([]byte, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -89,6 +134,21 @@ func Load[T any](path string) (*Snapshot[T], error) {
 		}
 		return nil, fmt.Errorf("read: %w", err)
 	}
+	return data, nil
+}
+
+// decodeSnapshot unmarshals a JSON snapshot, or decodes a binary one.
+// If migrate is set, versions are checked and older snapshots are upgraded with migrations.
+func decodeSnapshot[T any](data []byte, migrations *MigrationRegistry, migrate bool) (*Snapshot[T], error) {
+	if len(data) > 0 && data[0] == MsgSnapshot {
+		return decodeBinarySnapshot[T](data, migrations, migrate)
+	}
+	if schema := schemaOf[T](); schema != nil && migrate {
+		var err error
+		if data, err = migrations.migrateSnapshot(schema.Name, data); err != nil {
+			return nil, err
+		}
+	}
 
 	var snap Snapshot[T]
 	if err := json.Unmarshal(data, &snap); err != nil {
@@ -101,17 +161,29 @@ func Load[T any](path string) (*Snapshot[T], error) {
 // Restore loads state and recreates effects.
 // Returns RestoreResult which includes both the state and any effect recreation errors.
 // Effect errors are non-fatal - the state is still returned with successfully recreated effects.
-// Note: Restored effects have zero-value activator - set them after restore if needed.
+// Note: Restored effects have zero-value activator - set them after restore if needed,
+// or use EffectRegistry.Restore which also restores activators and TimedEffect lifetimes.
 // The initializer function should create a new Trackable state from the loaded data.
+// If cfg.Migrations is set, the snapshot is loaded with LoadMigrated, otherwise with Load.
 func Restore[T Trackable, A any](path string, initializer func(loaded T) T, cfg *TrackedConfig, factory EffectFactory[T, A]) (*RestoreResult[T, A], error) {
-	snap, err := Load[T](path)
+	var snap *Snapshot[T]
+	var err error
+	if migrations := cfg.migrationRegistry(); migrations != nil {
+		snap, err = LoadMigrated[T](path, migrations)
+	} else {
+		snap, err = Load[T](path)
+	}
 	if err != nil {
 		return nil, err
 	}
 	if snap == nil {
 		return nil, nil // No saved state
 	}
+	return RestoreSnapshot(snap, initializer, cfg, factory), nil
+}
 
+// RestoreSnapshot recreates state and effects from a loaded snapshot (see Restore)
+func RestoreSnapshot[T Trackable, A any](snap *Snapshot[T], initializer func(loaded T) T, cfg *TrackedConfig, factory EffectFactory[T, A]) *RestoreResult[T, A] {
 	// Use initializer to create proper Trackable state from loaded data
 	initial := initializer(snap.State)
 	state := NewTrackedState[T, A](initial, cfg)
@@ -136,7 +208,7 @@ func Restore[T Trackable, A any](path string, initializer func(loaded T) T, cfg
 		}
 	}
 
-	return result, nil
+	return result
 }
 
 // MakeEffectMeta creates metadata for an effect.
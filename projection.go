@@ -0,0 +1,121 @@
+package statesync
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Built-in projections
+const (
+	// ViewAll is visible to every viewer (same as no projections)
+	ViewAll = "all"
+
+	// ViewOwner is visible to the viewer whose ID matches the owner of the enclosing struct
+	// (Schema.OwnerField, or the key of the array/map element holding it)
+	ViewOwner = "owner"
+)
+
+// Viewer is the context a client is encoded for. Fields with Projections are only
+// encoded if the viewer has one of them (see FieldMeta.Projections).
+type Viewer struct {
+	ID    string   // Compared with owner fields for ViewOwner
+	Views []string // Granted projections (e.g. "admin")
+}
+
+// NewViewer creates a viewer with granted projections
+func NewViewer(id string, views ...string) *Viewer {
+	return &Viewer{ID: id, Views: views}
+}
+
+// Has reports whether the viewer was granted a projection
+func (v *Viewer) Has(view string) bool {
+	for _, granted := range v.Views {
+		if granted == view {
+			return true
+		}
+	}
+	return false
+}
+
+// CanSee reports whether the viewer may see a field of a struct owned by owner
+// (hasOwner is false if the struct has no owner). A nil viewer sees everything.
+func (v *Viewer) CanSee(field *FieldMeta, owner string, hasOwner bool) bool {
+	if v == nil || len(field.Projections) == 0 {
+		return true
+	}
+	for _, p := range field.Projections {
+		switch {
+		case p == ViewAll:
+			return true
+		case p == ViewOwner && hasOwner && owner == v.ID:
+			return true
+		case v.Has(p):
+			return true
+		}
+	}
+	return false
+}
+
+// restrictedSchemas caches whether a schema (or a nested schema) has projections
+var restrictedSchemas sync.Map // *Schema -> bool
+
+// isRestricted reports whether any field of the schema or its nested schemas has projections
+func isRestricted(schema *Schema) bool {
+	if schema == nil {
+		return false
+	}
+	if cached, ok := restrictedSchemas.Load(schema); ok {
+		return cached.(bool)
+	}
+	restricted := hasProjections(schema, make(map[*Schema]bool))
+	restrictedSchemas.Store(schema, restricted)
+	return restricted
+}
+
+func hasProjections(schema *Schema, visited map[*Schema]bool) bool {
+	if schema == nil || visited[schema] {
+		return false
+	}
+	visited[schema] = true
+	for i := range schema.Fields {
+		field := &schema.Fields[i]
+		if len(field.Projections) > 0 || hasProjections(field.ChildSchema, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// ownerKey formats an owner field or key value for comparison with Viewer.ID
+func ownerKey(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
+
+// structOwner returns the owner of a struct: its OwnerField if the schema has one,
+// otherwise the owner given by the enclosing container
+func structOwner(t Trackable, schema *Schema, inherited string, hasInherited bool) (string, bool) {
+	if schema.OwnerField == "" {
+		return inherited, hasInherited
+	}
+	field := fieldNamed(schema, schema.OwnerField)
+	if field == nil {
+		return "", false
+	}
+	return ownerKey(fieldValue(t, field.Index)), true
+}
+
+// fieldNamed finds a field by name (also in schemas not built with AddField)
+func fieldNamed(schema *Schema, name string) *FieldMeta {
+	if schema == nil {
+		return nil
+	}
+	for i := range schema.Fields {
+		if schema.Fields[i].Name == name {
+			return &schema.Fields[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package statesync
+
+import (
+	"reflect"
+	"testing"
+)
+
+type projPlayer struct {
+	changes *ChangeSet
+	ID      string
+	Hand    []int32 // owner only
+	Notes   string  // admin only
+}
+
+var projPlayerSchema = NewSchemaBuilder("ProjPlayer").WithID(61).
+	String("ID").
+	Array("Hand", TypeInt32, nil).Views(ViewOwner).
+	String("Notes").Views("admin").
+	Build()
+
+func (p *projPlayer) Schema() *Schema     { return projPlayerSchema }
+func (p *projPlayer) Changes() *ChangeSet { return p.changes }
+func (p *projPlayer) ClearChanges()       { p.changes.Clear() }
+func (p *projPlayer) MarkAllDirty()       { p.changes.MarkAll(2) }
+func (p *projPlayer) GetFieldValue(index uint8) interface{} {
+	switch index {
+	case 0:
+		return p.ID
+	case 1:
+		return p.Hand
+	case 2:
+		return p.Notes
+	}
+	return nil
+}
+
+type projState struct {
+	changes     *ChangeSet
+	Round       int32
+	Seed        int64 // admin only
+	Players     []*projPlayer
+	ByName      map[string]*projPlayer
+	secretReads int
+}
+
+var projStateSchema = NewSchemaBuilder("ProjState").WithID(60).
+	Int32("Round").
+	Int64("Seed").Views("admin").
+	ArrayByKey("Players", TypeStruct, projPlayerSchema, "ID").
+	Map("ByName", TypeStruct, projPlayerSchema).
+	Build()
+
+func (s *projState) Schema() *Schema     { return projStateSchema }
+func (s *projState) Changes() *ChangeSet { return s.changes }
+func (s *projState) ClearChanges()       { s.changes.Clear() }
+func (s *projState) MarkAllDirty()       { s.changes.MarkAll(3) }
+func (s *projState) GetFieldValue(index uint8) interface{} {
+	switch index {
+	case 0:
+		return s.Round
+	case 1:
+		s.secretReads++
+		return s.Seed
+	case 2:
+		return s.Players
+	case 3:
+		return s.ByName
+	}
+	return nil
+}
+
+func newProjState() *projState {
+	return &projState{
+		changes: NewChangeSet(),
+		Round:   3,
+		Seed:    42,
+		Players: []*projPlayer{
+			{changes: NewChangeSet(), ID: "alice", Hand: []int32{1, 2}, Notes: "cheater?"},
+			{changes: NewChangeSet(), ID: "bob", Hand: []int32{3}, Notes: "ok"},
+		},
+		ByName: map[string]*projPlayer{
+			"carol": {changes: NewChangeSet(), ID: "c", Hand: []int32{9}},
+		},
+	}
+}
+
+func decodeProjState(t *testing.T, data []byte) map[string]interface{} {
+	t.Helper()
+	registry := NewSchemaRegistry()
+	registry.Register(projStateSchema)
+	patch, err := NewDecoder(registry).Decode(data)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	fields := make(map[string]interface{})
+	if err := ApplyPatch(fields, patch, projStateSchema); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	return fields
+}
+
+func projPlayerFields(fields map[string]interface{}, i int) map[string]interface{} {
+	return fields["Players"].([]interface{})[i].(map[string]interface{})
+}
+
+func TestViewer_CanSee(t *testing.T) {
+	public := &FieldMeta{Name: "Round"}
+	admin := &FieldMeta{Name: "Seed", Projections: []string{"admin"}}
+	owner := &FieldMeta{Name: "Hand", Projections: []string{ViewOwner}}
+	all := &FieldMeta{Name: "Name", Projections: []string{ViewAll}}
+
+	var server *Viewer
+	alice := NewViewer("alice")
+	mod := NewViewer("mod", "admin")
+
+	tests := []struct {
+		viewer *Viewer
+		field  *FieldMeta
+		owner  string
+		has    bool
+		want   bool
+	}{
+		{server, admin, "", false, true},
+		{alice, public, "", false, true},
+		{alice, all, "", false, true},
+		{alice, admin, "", false, false},
+		{mod, admin, "", false, true},
+		{alice, owner, "alice", true, true},
+		{alice, owner, "bob", true, false},
+		{alice, owner, "", false, false},
+		{mod, owner, "alice", true, false},
+	}
+	for i, tt := range tests {
+		if got := tt.viewer.CanSee(tt.field, tt.owner, tt.has); got != tt.want {
+			t.Errorf("case %d: CanSee(%s) = %v, want %v", i, tt.field.Name, got, tt.want)
+		}
+	}
+}
+
+func TestEncoder_EncodeAllFor(t *testing.T) {
+	state := newProjState()
+	enc := NewEncoder(nil)
+
+	fields := decodeProjState(t, enc.EncodeAllFor(state, NewViewer("alice")))
+	if state.secretReads != 0 {
+		t.Errorf("hidden field was read %d times", state.secretReads)
+	}
+	if fields["Round"] != int32(3) || fields["Seed"] != int64(0) {
+		t.Errorf("unexpected root fields: Round=%v Seed=%v", fields["Round"], fields["Seed"])
+	}
+	alice, bob := projPlayerFields(fields, 0), projPlayerFields(fields, 1)
+	if !reflect.DeepEqual(alice["Hand"], []interface{}{int32(1), int32(2)}) {
+		t.Errorf("alice should see her hand, got %v", alice["Hand"])
+	}
+	if len(bob["Hand"].([]interface{})) != 0 || alice["Notes"] != "" {
+		t.Errorf("alice should not see bob's hand or notes: %v %v", bob["Hand"], alice["Notes"])
+	}
+
+	// Map values are owned by their key
+	carol := fields["ByName"].(map[string]interface{})["carol"].(map[string]interface{})
+	if len(carol["Hand"].([]interface{})) != 0 {
+		t.Errorf("alice should not see carol's hand, got %v", carol["Hand"])
+	}
+	fields = decodeProjState(t, enc.EncodeAllFor(state, NewViewer("carol")))
+	carol = fields["ByName"].(map[string]interface{})["carol"].(map[string]interface{})
+	if !reflect.DeepEqual(carol["Hand"], []interface{}{int32(9)}) {
+		t.Errorf("carol should see her hand, got %v", carol["Hand"])
+	}
+
+	// Admins see admin fields but not other players' owner fields
+	fields = decodeProjState(t, enc.EncodeAllFor(state, NewViewer("mod", "admin")))
+	if fields["Seed"] != int64(42) || projPlayerFields(fields, 1)["Notes"] != "ok" {
+		t.Errorf("admin should see Seed and Notes: %v", fields)
+	}
+	if len(projPlayerFields(fields, 0)["Hand"].([]interface{})) != 0 {
+		t.Errorf("admin should not see alice's hand")
+	}
+
+	// No viewer encodes everything, like EncodeAll
+	if !reflect.DeepEqual(enc.EncodeAllFor(state, nil), enc.EncodeAll(state)) {
+		t.Error("EncodeAllFor(nil) should match EncodeAll")
+	}
+}
+
+func TestEncoder_EncodeFor(t *testing.T) {
+	state := newProjState()
+	enc := NewEncoder(nil)
+
+	// Only a hidden field changed: nothing to send
+	state.changes.Mark(1, OpReplace)
+	if data := enc.EncodeFor(state, NewViewer("alice")); data != nil {
+		t.Errorf("expected no patch for alice, got %v", data)
+	}
+	if state.secretReads != 0 {
+		t.Errorf("hidden field was read %d times", state.secretReads)
+	}
+	fields := decodeProjState(t, enc.EncodeFor(state, NewViewer("mod", "admin")))
+	if fields["Seed"] != int64(42) {
+		t.Errorf("admin should get Seed, got %v", fields)
+	}
+
+	// Visible fields are sent, hidden ones skipped
+	state.changes.Mark(0, OpReplace)
+	fields = decodeProjState(t, enc.EncodeFor(state, NewViewer("alice")))
+	if _, ok := fields["Seed"]; ok || fields["Round"] != int32(3) {
+		t.Errorf("unexpected patch for alice: %v", fields)
+	}
+}
+
+func TestEncoder_OwnerField(t *testing.T) {
+	schema := NewSchemaBuilder("OwnedCard").WithID(62).Owner("Holder").
+		String("Holder").
+		String("Face").Views(ViewOwner).
+		Build()
+	card := &fieldValueTrackable{schema: schema, values: []interface{}{"bob", "ace"}}
+
+	registry := NewSchemaRegistry()
+	registry.Register(schema)
+	dec := NewDecoder(registry)
+	for viewer, want := range map[string]string{"bob": "ace", "alice": ""} {
+		patch, err := dec.Decode(NewEncoder(nil).EncodeAllFor(card, NewViewer(viewer)))
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if patch.Changes[1].Value != want {
+			t.Errorf("%s sees Face=%v, want %q", viewer, patch.Changes[1].Value, want)
+		}
+	}
+}
+
+// fieldValueTrackable is a Trackable backed by a value slice
+type fieldValueTrackable struct {
+	schema *Schema
+	values []interface{}
+}
+
+func (f *fieldValueTrackable) Schema() *Schema                       { return f.schema }
+func (f *fieldValueTrackable) Changes() *ChangeSet                   { return NewChangeSet() }
+func (f *fieldValueTrackable) ClearChanges()                         {}
+func (f *fieldValueTrackable) MarkAllDirty()                         {}
+func (f *fieldValueTrackable) GetFieldValue(index uint8) interface{} { return f.values[index] }
+
+func TestTrackedSession_Viewer(t *testing.T) {
+	state := newProjState()
+	tracked := NewTrackedState[*projState, any](state, nil)
+	session := NewTrackedSession[*projState, any, string](tracked)
+	session.Connect("alice", nil)
+	session.SetViewer("alice", NewViewer("alice"))
+	session.Connect("mod", nil)
+	session.SetViewer("mod", NewViewer("mod", "admin"))
+	session.Connect("spectator", nil)
+
+	diffs := session.Tick()
+	if fields := decodeProjState(t, diffs["alice"]); fields["Seed"] != int64(0) {
+		t.Errorf("alice's full state leaked Seed: %v", fields["Seed"])
+	}
+	if fields := decodeProjState(t, diffs["spectator"]); fields["Seed"] != int64(42) {
+		t.Errorf("spectator without viewer should see everything")
+	}
+
+	tracked.UpdateInPlace(func(s *projState) {
+		s.Seed = 7
+		s.changes.Mark(1, OpReplace)
+	})
+	diffs = session.Tick()
+	if _, ok := diffs["alice"]; ok {
+		t.Errorf("alice should get no diff for a hidden change")
+	}
+	if fields := decodeProjState(t, diffs["mod"]); fields["Seed"] != int64(7) {
+		t.Errorf("admin should get Seed, got %v", fields)
+	}
+
+	if session.GetViewer("alice") == nil {
+		t.Error("expected alice's viewer")
+	}
+	session.Disconnect("alice")
+	if session.GetViewer("alice") == nil {
+		t.Error("viewer should be kept across Disconnect")
+	}
+	session.SetViewer("alice", nil)
+	if session.GetViewer("alice") != nil {
+		t.Error("expected viewer to be cleared")
+	}
+}
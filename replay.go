@@ -28,6 +28,9 @@ type DiffRecord struct {
 	// Optional: Events that were emitted with this diff
 	Events []Event `json:"events,omitempty"`
 
+	// Optional: External inputs applied in this diff (see ExternalInjector.InjectInput)
+	Inputs []ExternalInput `json:"inputs,omitempty"`
+
 	// Optional: Delta time for deterministic replay (in nanoseconds)
 	DeltaNs int64 `json:"deltaNs,omitempty"`
 }
@@ -39,6 +42,7 @@ type DiffRecorder struct {
 	records []DiffRecord
 	source  string
 	tick    uint64
+	inputs  []ExternalInput // Attached to the next record
 }
 
 // NewDiffRecorder creates a new diff recorder
@@ -62,15 +66,23 @@ func (dr *DiffRecorder) SetTick(tick uint64) {
 	dr.tick = tick
 }
 
-// Record captures a diff with the current source and tick
-func (dr *DiffRecorder) Record(seq uint64, data []byte, events []Event, delta time.Duration) {
-	if len(data) == 0 {
-		return // Skip empty diffs
-	}
+// RecordInput records an external input; it is attached to the next record
+func (dr *DiffRecorder) RecordInput(source string, payload []byte) {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	dr.inputs = append(dr.inputs, ExternalInput{Source: source, Payload: append([]byte(nil), payload...)})
+}
 
+// Record captures a diff with the current source and tick.
+// Empty diffs are skipped unless external inputs are pending.
+func (dr *DiffRecorder) Record(seq uint64, data []byte, events []Event, delta time.Duration) {
 	dr.mu.Lock()
 	defer dr.mu.Unlock()
 
+	if len(data) == 0 && len(dr.inputs) == 0 {
+		return // Skip empty diffs
+	}
+
 	record := DiffRecord{
 		Seq:       seq,
 		Tick:      dr.tick,
@@ -85,6 +97,8 @@ func (dr *DiffRecorder) Record(seq uint64, data []byte, events []Event, delta ti
 		record.Events = make([]Event, len(events))
 		copy(record.Events, events)
 	}
+	record.Inputs = dr.inputs
+	dr.inputs = nil
 
 	dr.records = append(dr.records, record)
 }
@@ -162,6 +176,10 @@ func (mr *MapReplayer) Reset() {
 // Replay applies a single diff record to the state.
 // Returns the delta time for deterministic timing.
 func (mr *MapReplayer) Replay(record DiffRecord) (time.Duration, error) {
+	if len(record.Data) == 0 {
+		return time.Duration(record.DeltaNs), nil // Only external inputs, no changes
+	}
+
 	patch, err := mr.decoder.Decode(record.Data)
 	if err != nil {
 		return 0, err
@@ -208,8 +226,10 @@ func (mr *MapReplayer) ReplayRange(records []DiffRecord, fromSeq, toSeq uint64)
 // ExternalInjector provides a way to inject data from external sources (like API responses)
 // into the schema. All injected data goes through the normal diff tracking.
 type ExternalInjector[T Trackable, A any, ID comparable] struct {
-	session *TrackedSession[T, A, ID]
-	source  string
+	session  *TrackedSession[T, A, ID]
+	source   string
+	recorder InputRecorder
+	playback *InputPlayback
 }
 
 // NewExternalInjector creates an injector for external data sources
@@ -223,6 +243,7 @@ func NewExternalInjector[T Trackable, A any, ID comparable](session *TrackedSess
 // Inject applies external data to the state.
 // The updateFn should modify the state to incorporate the external data.
 // Returns the diffs that were created (for persistence).
+// The closure is not recorded; use InjectInput or Fetch to make re-simulation possible.
 //
 // Example:
 //
@@ -0,0 +1,604 @@
+package statesync
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Replay file format:
+//
+//	[magic "SSRP"][version:uint8]
+//	[frame]...                                    Diffs and keyframes in seq order
+//	[index frame][indexOffset:uint64 LE]["SSRI"]  Written by Close
+//
+// Frame: [kind:byte][payloadLen:uvarint][payload][crc32:uint32 LE]
+// Diff/keyframe payload: [seq:uvarint][tick:uvarint][ts:int64 unix nanos][deltaNs:varint]
+// [sourceLen:uvarint][source][eventCount:uvarint]([typeLen:uvarint][type][payloadLen:uvarint][payload])...
+// [inputCount:uvarint]([sourceLen:uvarint][source][payloadLen:uvarint][payload])...[data]
+// (inputs since version 2)
+// Index payload: [count:uvarint]([seq:uvarint][tick:uvarint][offset:uvarint])...
+//
+// A keyframe at seq N holds the full state after the diff of seq N (a MsgFullState message).
+// Files without an index (e.g. after a crash) are indexed by scanning; a torn last frame is ignored.
+const (
+	replayMagic       = "SSRP"
+	replayIndexMagic  = "SSRI"
+	ReplayFileVersion = 2
+
+	replayFrameDiff     byte = 1
+	replayFrameKeyframe byte = 2
+	replayFrameIndex    byte = 3
+
+	replayHeaderSize = len(replayMagic) + 1
+	replayFooterSize = 8 + len(replayIndexMagic)
+
+	// DefaultKeyframeEvery is the default number of seqs between keyframes
+	DefaultKeyframeEvery = 100
+)
+
+// Replay file errors
+var (
+	ErrInvalidReplay = errors.New("invalid replay file")
+	ErrReplayClosed  = errors.New("replay writer closed")
+)
+
+// ReplayIndexEntry locates a keyframe in a replay file
+type ReplayIndexEntry struct {
+	Seq    uint64
+	Tick   uint64
+	Offset int64
+}
+
+// ReplayWriterConfig configures keyframes of a ReplayWriter
+type ReplayWriterConfig struct {
+	// Keyframe returns the current full state (e.g. TrackedState.EncodeAll).
+	// Without it only diffs are written and seeking replays from the start.
+	Keyframe func() []byte
+
+	// KeyframeEvery writes a keyframe after every N seqs (default DefaultKeyframeEvery)
+	KeyframeEvery uint64
+}
+
+// ReplayWriter streams diffs and periodic keyframes to a replay file while recording.
+// It has the same Record/SetSource/SetTick API as DiffRecorder.
+type ReplayWriter struct {
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	index  []ReplayIndexEntry
+	cfg    ReplayWriterConfig
+	source string
+	tick   uint64
+	buf    []byte
+	inputs []ExternalInput // Attached to the next record
+	err    error           // First write error (returned by Err and Close)
+
+	hasKeyframe  bool
+	lastKeyframe uint64
+}
+
+// CreateReplayFile creates (or truncates) a replay file for recording
+func CreateReplayFile(path string, cfg ReplayWriterConfig) (*ReplayWriter, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("mkdir: %w", err)
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create: %w", err)
+	}
+	if cfg.KeyframeEvery == 0 {
+		cfg.KeyframeEvery = DefaultKeyframeEvery
+	}
+	header := append([]byte(replayMagic), ReplayFileVersion)
+	if _, err := file.Write(header); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("write: %w", err)
+	}
+	return &ReplayWriter{file: file, size: int64(len(header)), cfg: cfg, source: "server"}, nil
+}
+
+// SetSource sets the source identifier for subsequent records
+func (w *ReplayWriter) SetSource(source string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.source = source
+}
+
+// SetTick sets the current tick for subsequent records
+func (w *ReplayWriter) SetTick(tick uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.tick = tick
+}
+
+// RecordInput records an external input; it is written with the next record
+func (w *ReplayWriter) RecordInput(source string, payload []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.inputs = append(w.inputs, ExternalInput{Source: source, Payload: append([]byte(nil), payload...)})
+}
+
+// Record writes a diff with the current source and tick, followed by a keyframe
+// when one is due (the first record and then every KeyframeEvery seqs).
+// Empty diffs are skipped unless external inputs are pending.
+func (w *ReplayWriter) Record(seq uint64, data []byte, events []Event, delta time.Duration) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(data) == 0 && len(w.inputs) == 0 {
+		return nil // Skip empty diffs
+	}
+
+	record := DiffRecord{
+		Seq:       seq,
+		Tick:      w.tick,
+		Timestamp: time.Now(),
+		Source:    w.source,
+		Data:      data,
+		Events:    events,
+		Inputs:    w.inputs,
+		DeltaNs:   delta.Nanoseconds(),
+	}
+	w.inputs = nil
+	if err := w.writeFrameLocked(replayFrameDiff, record); err != nil {
+		return err
+	}
+	if w.cfg.Keyframe != nil && (!w.hasKeyframe || seq-w.lastKeyframe >= w.cfg.KeyframeEvery) {
+		return w.writeKeyframeLocked(seq, w.tick, w.cfg.Keyframe())
+	}
+	return nil
+}
+
+// WriteKeyframe writes the full state after seq, e.g. seq 0 for the initial state
+// (session seqs start at 1). Binary snapshots (EncodeBinarySnapshot) are valid keyframes too.
+func (w *ReplayWriter) WriteKeyframe(seq, tick uint64, fullState []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writeKeyframeLocked(seq, tick, fullState)
+}
+
+func (w *ReplayWriter) writeKeyframeLocked(seq, tick uint64, fullState []byte) error {
+	offset := w.size
+	record := DiffRecord{Seq: seq, Tick: tick, Timestamp: time.Now(), Source: w.source, Data: fullState}
+	if err := w.writeFrameLocked(replayFrameKeyframe, record); err != nil {
+		return err
+	}
+	w.index = append(w.index, ReplayIndexEntry{Seq: seq, Tick: tick, Offset: offset})
+	w.hasKeyframe = true
+	w.lastKeyframe = seq
+	return nil
+}
+
+// writeFrameLocked appends a frame to the file. Caller must hold w.mu.
+func (w *ReplayWriter) writeFrameLocked(kind byte, record DiffRecord) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.file == nil {
+		return ErrReplayClosed
+	}
+	w.buf = appendReplayFrame(w.buf[:0], kind, appendReplayRecord(nil, record))
+	if _, err := w.file.Write(w.buf); err != nil {
+		w.err = fmt.Errorf("write: %w", err)
+		return w.err
+	}
+	w.size += int64(len(w.buf))
+	return nil
+}
+
+// Err returns the first write error (hooks cannot return errors)
+func (w *ReplayWriter) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Close writes the keyframe index and closes the file
+func (w *ReplayWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return w.err
+	}
+	file := w.file
+	w.file = nil
+
+	if w.err == nil {
+		payload := binary.AppendUvarint(nil, uint64(len(w.index)))
+		for _, e := range w.index {
+			payload = binary.AppendUvarint(payload, e.Seq)
+			payload = binary.AppendUvarint(payload, e.Tick)
+			payload = binary.AppendUvarint(payload, uint64(e.Offset))
+		}
+		buf := appendReplayFrame(nil, replayFrameIndex, payload)
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(w.size))
+		buf = append(buf, replayIndexMagic...)
+		if _, err := file.Write(buf); err != nil {
+			w.err = fmt.Errorf("write index: %w", err)
+		} else if err := file.Sync(); err != nil {
+			w.err = fmt.Errorf("sync: %w", err)
+		}
+	}
+	if err := file.Close(); err != nil && w.err == nil {
+		w.err = fmt.Errorf("close: %w", err)
+	}
+	return w.err
+}
+
+// ReplayRecordingHooks creates SessionHooks that stream all diffs to a replay file.
+// Write errors are available from ReplayWriter.Err and Close.
+//
+// Example:
+//
+//	w, _ := CreateReplayFile("match.replay", ReplayWriterConfig{Keyframe: state.EncodeAll})
+//	session.SetHooks(ReplayRecordingHooks[*GameState, string](w))
+//	defer w.Close()
+func ReplayRecordingHooks[T Trackable, ID comparable](w *ReplayWriter) SessionHooks[T, ID] {
+	return SessionHooks[T, ID]{
+		OnAfterBroadcast: func(diffs map[ID][]byte, baseDiff []byte, seq uint64) {
+			w.Record(seq, baseDiff, nil, 0)
+		},
+	}
+}
+
+// appendReplayFrame encodes a frame
+func appendReplayFrame(buf []byte, kind byte, payload []byte) []byte {
+	start := len(buf)
+	buf = append(buf, kind)
+	buf = binary.AppendUvarint(buf, uint64(len(payload)))
+	buf = append(buf, payload...)
+	return binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf[start:]))
+}
+
+// appendReplayRecord encodes a diff or keyframe payload
+func appendReplayRecord(buf []byte, r DiffRecord) []byte {
+	buf = binary.AppendUvarint(buf, r.Seq)
+	buf = binary.AppendUvarint(buf, r.Tick)
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(r.Timestamp.UnixNano()))
+	buf = binary.AppendVarint(buf, r.DeltaNs)
+	buf = binary.AppendUvarint(buf, uint64(len(r.Source)))
+	buf = append(buf, r.Source...)
+	buf = binary.AppendUvarint(buf, uint64(len(r.Events)))
+	for _, e := range r.Events {
+		buf = binary.AppendUvarint(buf, uint64(len(e.Type)))
+		buf = append(buf, e.Type...)
+		buf = binary.AppendUvarint(buf, uint64(len(e.Payload)))
+		buf = append(buf, e.Payload...)
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(r.Inputs)))
+	for _, in := range r.Inputs {
+		buf = binary.AppendUvarint(buf, uint64(len(in.Source)))
+		buf = append(buf, in.Source...)
+		buf = binary.AppendUvarint(buf, uint64(len(in.Payload)))
+		buf = append(buf, in.Payload...)
+	}
+	return append(buf, r.Data...)
+}
+
+// parseReplayRecord decodes a diff or keyframe payload of a file version
+func parseReplayRecord(p []byte, version byte) (DiffRecord, error) {
+	var r DiffRecord
+	pos := 0
+	uvarint := func() (uint64, bool) {
+		v, n := binary.Uvarint(p[pos:])
+		if n <= 0 {
+			return 0, false
+		}
+		pos += n
+		return v, true
+	}
+	bytesField := func() ([]byte, bool) {
+		n, ok := uvarint()
+		if !ok || n > uint64(len(p)-pos) {
+			return nil, false
+		}
+		b := p[pos : pos+int(n)]
+		pos += int(n)
+		return b, true
+	}
+
+	var ok bool
+	if r.Seq, ok = uvarint(); !ok {
+		return r, ErrInvalidReplay
+	}
+	if r.Tick, ok = uvarint(); !ok || len(p)-pos < 8 {
+		return r, ErrInvalidReplay
+	}
+	r.Timestamp = time.Unix(0, int64(binary.LittleEndian.Uint64(p[pos:])))
+	pos += 8
+	delta, n := binary.Varint(p[pos:])
+	if n <= 0 {
+		return r, ErrInvalidReplay
+	}
+	r.DeltaNs = delta
+	pos += n
+	source, ok := bytesField()
+	if !ok {
+		return r, ErrInvalidReplay
+	}
+	r.Source = string(source)
+	count, ok := uvarint()
+	if !ok || count > uint64(len(p)-pos) {
+		return r, ErrInvalidReplay
+	}
+	for i := uint64(0); i < count; i++ {
+		typ, ok1 := bytesField()
+		payload, ok2 := bytesField()
+		if !ok1 || !ok2 {
+			return r, ErrInvalidReplay
+		}
+		r.Events = append(r.Events, Event{Type: string(typ), Payload: payload})
+	}
+	if version >= 2 {
+		count, ok := uvarint()
+		if !ok || count > uint64(len(p)-pos) {
+			return r, ErrInvalidReplay
+		}
+		for i := uint64(0); i < count; i++ {
+			source, ok1 := bytesField()
+			payload, ok2 := bytesField()
+			if !ok1 || !ok2 {
+				return r, ErrInvalidReplay
+			}
+			r.Inputs = append(r.Inputs, ExternalInput{Source: string(source), Payload: payload})
+		}
+	}
+	r.Data = p[pos:]
+	return r, nil
+}
+
+// ReplayFrame is a diff or keyframe read from a replay file
+type ReplayFrame struct {
+	DiffRecord
+	Keyframe bool  // Data is a full state (MsgFullState) instead of a diff
+	Next     int64 // Offset of the following frame (to continue reading with Frames)
+}
+
+// ReplayReader reads a replay file with O(log n) seeking via the keyframe index
+type ReplayReader struct {
+	r       io.ReaderAt
+	close   func() error
+	version byte
+	end     int64 // End of the frame section
+	index   []ReplayIndexEntry
+}
+
+// OpenReplayFile opens a replay file for reading. The file may still be recording.
+func OpenReplayFile(path string) (*ReplayReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("stat: %w", err)
+	}
+	rr, err := NewReplayReader(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	rr.close = file.Close
+	return rr, nil
+}
+
+// NewReplayReader reads a replay from any ReaderAt (e.g. bytes.Reader)
+func NewReplayReader(r io.ReaderAt, size int64) (*ReplayReader, error) {
+	header := make([]byte, replayHeaderSize)
+	if _, err := r.ReadAt(header, 0); err != nil || string(header[:len(replayMagic)]) != replayMagic {
+		return nil, ErrInvalidReplay
+	}
+	if header[len(replayMagic)] > ReplayFileVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidReplay, header[len(replayMagic)])
+	}
+
+	rr := &ReplayReader{r: r, version: header[len(replayMagic)], end: size}
+	if rr.readIndex(size) {
+		return rr, nil
+	}
+
+	// No index (still recording or crashed): scan the frames
+	rr.index = nil
+	offset := int64(replayHeaderSize)
+	for {
+		kind, payload, next, err := rr.readFrame(offset, size)
+		if err != nil || kind == replayFrameIndex {
+			break
+		}
+		if kind == replayFrameKeyframe {
+			rec, err := parseReplayRecord(payload, rr.version)
+			if err != nil {
+				break
+			}
+			rr.index = append(rr.index, ReplayIndexEntry{Seq: rec.Seq, Tick: rec.Tick, Offset: offset})
+		}
+		offset = next
+	}
+	rr.end = offset
+	return rr, nil
+}
+
+// readIndex reads the index written by ReplayWriter.Close
+func (rr *ReplayReader) readIndex(size int64) bool {
+	if size < int64(replayHeaderSize+replayFooterSize) {
+		return false
+	}
+	footer := make([]byte, replayFooterSize)
+	if _, err := rr.r.ReadAt(footer, size-int64(replayFooterSize)); err != nil || string(footer[8:]) != replayIndexMagic {
+		return false
+	}
+	indexOffset := int64(binary.LittleEndian.Uint64(footer))
+	if indexOffset < int64(replayHeaderSize) || indexOffset >= size {
+		return false
+	}
+	kind, payload, _, err := rr.readFrame(indexOffset, size-int64(replayFooterSize))
+	if err != nil || kind != replayFrameIndex {
+		return false
+	}
+
+	count, pos := binary.Uvarint(payload)
+	if pos <= 0 || count > uint64(len(payload)) {
+		return false
+	}
+	rr.index = make([]ReplayIndexEntry, 0, count)
+	for i := uint64(0); i < count; i++ {
+		var vals [3]uint64
+		for j := range vals {
+			v, n := binary.Uvarint(payload[pos:])
+			if n <= 0 {
+				return false
+			}
+			vals[j] = v
+			pos += n
+		}
+		rr.index = append(rr.index, ReplayIndexEntry{Seq: vals[0], Tick: vals[1], Offset: int64(vals[2])})
+	}
+	rr.end = indexOffset
+	return true
+}
+
+// readFrame reads the frame at offset. Frames must end before limit.
+func (rr *ReplayReader) readFrame(offset, limit int64) (kind byte, payload []byte, next int64, err error) {
+	head := make([]byte, 1+binary.MaxVarintLen64)
+	if n := limit - offset; n < int64(len(head)) {
+		if n < 2 {
+			return 0, nil, 0, io.EOF
+		}
+		head = head[:n]
+	}
+	if _, err := rr.r.ReadAt(head, offset); err != nil && err != io.EOF {
+		return 0, nil, 0, err
+	}
+	length, m := binary.Uvarint(head[1:])
+	if m <= 0 || length > uint64(limit-offset) {
+		return 0, nil, 0, ErrInvalidReplay
+	}
+	size := 1 + int64(m) + int64(length) + 4
+	if offset+size > limit {
+		return 0, nil, 0, ErrInvalidReplay // Torn frame
+	}
+	frame := make([]byte, size)
+	if _, err := rr.r.ReadAt(frame, offset); err != nil {
+		return 0, nil, 0, err
+	}
+	body := frame[:size-4]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(frame[size-4:]) {
+		return 0, nil, 0, ErrInvalidReplay
+	}
+	return frame[0], body[1+m:], offset + size, nil
+}
+
+// Keyframes returns the keyframe index in seq order
+func (rr *ReplayReader) Keyframes() []ReplayIndexEntry {
+	return rr.index
+}
+
+// Frames calls fn for every frame from offset (a keyframe Offset, or 0 for the start)
+// until fn returns false or the frames end
+func (rr *ReplayReader) Frames(offset int64, fn func(ReplayFrame) bool) error {
+	if offset < int64(replayHeaderSize) {
+		offset = int64(replayHeaderSize)
+	}
+	for offset < rr.end {
+		kind, payload, next, err := rr.readFrame(offset, rr.end)
+		if err != nil {
+			return err
+		}
+		offset = next
+		if kind != replayFrameDiff && kind != replayFrameKeyframe {
+			continue
+		}
+		rec, err := parseReplayRecord(payload, rr.version)
+		if err != nil {
+			return err
+		}
+		if !fn(ReplayFrame{DiffRecord: rec, Keyframe: kind == replayFrameKeyframe, Next: next}) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Records returns all diffs (without keyframes), e.g. for MapReplayer.ReplayAll
+func (rr *ReplayReader) Records() ([]DiffRecord, error) {
+	var records []DiffRecord
+	err := rr.Frames(0, func(f ReplayFrame) bool {
+		if !f.Keyframe {
+			records = append(records, f.DiffRecord)
+		}
+		return true
+	})
+	return records, err
+}
+
+// Seek returns what is needed to rebuild the state at seq: the nearest keyframe at or
+// before seq (nil if there is none, then replay starts from an empty state) and the diffs after it.
+func (rr *ReplayReader) Seek(seq uint64) (keyframe *DiffRecord, diffs []DiffRecord, err error) {
+	i := sort.Search(len(rr.index), func(i int) bool { return rr.index[i].Seq > seq })
+	return rr.seekFrom(i-1, func(r DiffRecord) bool { return r.Seq <= seq })
+}
+
+// SeekTick is like Seek for a tick (see ReplayWriter.SetTick)
+func (rr *ReplayReader) SeekTick(tick uint64) (keyframe *DiffRecord, diffs []DiffRecord, err error) {
+	i := sort.Search(len(rr.index), func(i int) bool { return rr.index[i].Tick > tick })
+	return rr.seekFrom(i-1, func(r DiffRecord) bool { return r.Tick <= tick })
+}
+
+// seekFrom reads keyframe k (-1 for the start) and the following diffs while include returns true
+func (rr *ReplayReader) seekFrom(k int, include func(DiffRecord) bool) (*DiffRecord, []DiffRecord, error) {
+	var keyframe *DiffRecord
+	var diffs []DiffRecord
+	offset := int64(0)
+	if k >= 0 {
+		offset = rr.index[k].Offset
+	}
+	err := rr.Frames(offset, func(f ReplayFrame) bool {
+		if f.Keyframe {
+			if keyframe == nil && k >= 0 {
+				rec := f.DiffRecord
+				keyframe = &rec
+			}
+			return true
+		}
+		if !include(f.DiffRecord) {
+			return false
+		}
+		diffs = append(diffs, f.DiffRecord)
+		return true
+	})
+	return keyframe, diffs, err
+}
+
+// Close closes the underlying file (if opened with OpenReplayFile)
+func (rr *ReplayReader) Close() error {
+	if rr.close == nil {
+		return nil
+	}
+	return rr.close()
+}
+
+// Seek rebuilds the state at seq from a replay file (nearest keyframe plus the diffs after it)
+func (mr *MapReplayer) Seek(rr *ReplayReader, seq uint64) error {
+	keyframe, diffs, err := rr.Seek(seq)
+	if err != nil {
+		return err
+	}
+	mr.Reset()
+	if keyframe != nil {
+		if _, err := mr.Replay(*keyframe); err != nil {
+			return err
+		}
+	}
+	return mr.ReplayAll(diffs)
+}
@@ -0,0 +1,109 @@
+package statesync
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// recordReplay records ticks 1..10 (Score = seq*10) with a keyframe every 3 seqs
+func recordReplay(t *testing.T, path string) {
+	t.Helper()
+	state := NewReplayTestState()
+	state.SetName("start")
+	tracked := NewTrackedState[*ReplayTestState, any](state, nil)
+	session := NewTrackedSession[*ReplayTestState, any, string](tracked)
+
+	w, err := CreateReplayFile(path, ReplayWriterConfig{Keyframe: tracked.EncodeAll, KeyframeEvery: 3})
+	if err != nil {
+		t.Fatalf("CreateReplayFile: %v", err)
+	}
+	w.WriteKeyframe(0, 0, tracked.EncodeAll())
+	tracked.Commit()
+	session.SetHooks(ReplayRecordingHooks[*ReplayTestState, string](w))
+
+	for i := int64(1); i <= 10; i++ {
+		w.SetTick(uint64(i * 2))
+		state.SetScore(i * 10)
+		session.Tick()
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestReplayFile_Seek(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "match.replay")
+	recordReplay(t, path)
+
+	rr, err := OpenReplayFile(path)
+	if err != nil {
+		t.Fatalf("OpenReplayFile: %v", err)
+	}
+	defer rr.Close()
+
+	var seqs []uint64
+	for _, e := range rr.Keyframes() {
+		seqs = append(seqs, e.Seq)
+	}
+	if len(seqs) != 4 || seqs[0] != 0 || seqs[1] != 3 || seqs[3] != 9 {
+		t.Errorf("keyframes = %v, want [0 3 6 9]", seqs)
+	}
+
+	keyframe, diffs, err := rr.Seek(7)
+	if err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	if keyframe == nil || keyframe.Seq != 6 || len(diffs) != 1 || diffs[0].Seq != 7 {
+		t.Errorf("Seek(7) = keyframe %v, %d diffs; want keyframe 6 + diff 7", keyframe, len(diffs))
+	}
+
+	registry := NewSchemaRegistry()
+	registry.Register(NewReplayTestState().Schema())
+	replayer := NewMapReplayer(registry)
+	if err := replayer.Seek(rr, 7); err != nil {
+		t.Fatalf("MapReplayer.Seek: %v", err)
+	}
+	if replayer.State()["Score"] != int64(70) || replayer.State()["Name"] != "start" {
+		t.Errorf("state at seq 7 = %v", replayer.State())
+	}
+
+	// Seeking by tick (tick = seq*2)
+	keyframe, diffs, _ = rr.SeekTick(9)
+	if keyframe.Seq != 3 || len(diffs) != 1 || diffs[0].Tick != 8 {
+		t.Errorf("SeekTick(9) = keyframe %d, %d diffs", keyframe.Seq, len(diffs))
+	}
+
+	records, err := rr.Records()
+	if err != nil || len(records) != 10 {
+		t.Errorf("Records = %d, %v; want 10 diffs", len(records), err)
+	}
+}
+
+func TestReplayFile_Unindexed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "match.replay")
+	recordReplay(t, path)
+	data, _ := os.ReadFile(path)
+
+	// Simulate a crash: drop the index and leave a torn frame at the end
+	rr, _ := NewReplayReader(bytes.NewReader(data), int64(len(data)))
+	frames := data[:rr.end]
+	torn := append(append([]byte{}, frames...), replayFrameDiff, 50, 1, 2)
+
+	rr, err := NewReplayReader(bytes.NewReader(torn), int64(len(torn)))
+	if err != nil {
+		t.Fatalf("NewReplayReader: %v", err)
+	}
+	if len(rr.Keyframes()) != 4 {
+		t.Errorf("rebuilt %d keyframes, want 4", len(rr.Keyframes()))
+	}
+	records, err := rr.Records()
+	if err != nil || len(records) != 10 {
+		t.Errorf("Records = %d, %v; want 10 diffs", len(records), err)
+	}
+
+	if _, err := NewReplayReader(bytes.NewReader([]byte("nope!")), 5); err == nil {
+		t.Error("expected an error for a non-replay file")
+	}
+}
@@ -45,6 +45,18 @@ func (s *ReplayTestState) Schema() *Schema {
 	}
 }
 
+func (s *ReplayTestState) SetFieldValue(index uint16, value interface{}) (err error) {
+	switch index {
+	case 0:
+		s.Score, err = DecodedValue[int64](value)
+	case 1:
+		s.Name, err = DecodedValue[string](value)
+	case 2:
+		s.Items, err = DecodedSlice(value, DecodedValue[string])
+	}
+	return err
+}
+
 func (s *ReplayTestState) SetScore(v int64) {
 	s.Score = v
 	s.changes.Mark(0, OpReplace)
@@ -385,6 +397,29 @@ func (s *simpleTestState) Schema() *Schema {
 	}
 }
 
+func (s *simpleTestState) WithFieldsFrom(src *simpleTestState, fields []uint16) *simpleTestState {
+	c := *s
+	for _, idx := range fields {
+		switch idx {
+		case 0:
+			c.Score = src.Score
+		case 1:
+			c.Name = src.Name
+		}
+	}
+	return &c
+}
+
+func (s *simpleTestState) SetFieldValue(index uint16, value interface{}) (err error) {
+	switch index {
+	case 0:
+		s.Score, err = DecodedValue[int64](value)
+	case 1:
+		s.Name, err = DecodedValue[string](value)
+	}
+	return err
+}
+
 func (s *simpleTestState) GetFieldValue(index uint8) interface{} {
 	switch index {
 	case 0:
@@ -61,9 +61,19 @@ func (ft FieldType) Size() int {
 	}
 }
 
+// Field count limits
+const (
+	// MaxCompactFields is the number of fields whose indices fit in a byte on the wire.
+	// Larger schemas are encoded with varint field indices (see MsgFlagWideIndex).
+	MaxCompactFields = 256
+
+	// MaxFields is the maximum number of fields in a schema
+	MaxFields = 65536
+)
+
 // FieldMeta describes a single field in a schema
 type FieldMeta struct {
-	Index    uint8     // Field index (0-255)
+	Index    uint16    // Field index (see MaxFields)
 	Name     string    // Field name (for JSON compat)
 	Type     FieldType // Wire type
 	ElemType FieldType // Element type (for arrays/maps)
@@ -74,16 +84,20 @@ type FieldMeta struct {
 	// For arrays with key-based tracking
 	KeyField string // Field name to use as key (e.g., "ID")
 
-	// For projections (future DSL feature)
-	Projections []string // Which views can see this field
+	// Which views can see this field (empty = everyone, see Viewer and ViewOwner)
+	Projections []string
+
+	// Who can write this field in UpdateAs (empty = anyone, see WritePermission)
+	Write WritePermission
 }
 
 // Schema describes a trackable type
 type Schema struct {
-	ID     uint16         // Unique schema identifier
-	Name   string         // Type name
-	Fields []FieldMeta    // Fields in index order
-	byName map[string]int // name -> field index lookup
+	ID         uint16         // Unique schema identifier
+	Name       string         // Type name
+	Fields     []FieldMeta    // Fields in index order
+	OwnerField string         // Field holding the owner's ID (for ViewOwner projections and WriteOwner)
+	byName     map[string]int // name -> field index lookup
 }
 
 // NewSchema creates a new schema definition
@@ -98,10 +112,10 @@ func NewSchema(id uint16, name string) *Schema {
 
 // AddField adds a field to the schema
 func (s *Schema) AddField(field FieldMeta) *Schema {
-	if len(s.Fields) >= 256 {
-		panic(fmt.Sprintf("statesync: schema %q exceeds maximum of 256 fields", s.Name))
+	if len(s.Fields) >= MaxFields {
+		panic(fmt.Sprintf("statesync: schema %q exceeds maximum of %d fields", s.Name, MaxFields))
 	}
-	if field.Index != uint8(len(s.Fields)) {
+	if int(field.Index) != len(s.Fields) {
 		panic(fmt.Sprintf("field index %d doesn't match position %d", field.Index, len(s.Fields)))
 	}
 	s.byName[field.Name] = len(s.Fields)
@@ -111,6 +125,11 @@ func (s *Schema) AddField(field FieldMeta) *Schema {
 
 // Field returns field meta by index
 func (s *Schema) Field(index uint8) *FieldMeta {
+	return s.WideField(uint16(index))
+}
+
+// WideField is Field for any field index, including those from 256 on (see Wide)
+func (s *Schema) WideField(index uint16) *FieldMeta {
 	if int(index) >= len(s.Fields) {
 		return nil
 	}
@@ -130,12 +149,23 @@ func (s *Schema) FieldCount() int {
 	return len(s.Fields)
 }
 
-// MaxIndex returns the maximum field index
+// MaxIndex returns the maximum field index (at most 255, see MaxWideIndex)
 func (s *Schema) MaxIndex() uint8 {
+	return uint8(min(s.MaxWideIndex(), MaxCompactFields-1))
+}
+
+// MaxWideIndex returns the maximum field index of schemas of any size
+func (s *Schema) MaxWideIndex() uint16 {
 	if len(s.Fields) == 0 {
 		return 0
 	}
-	return uint8(len(s.Fields) - 1)
+	return uint16(len(s.Fields) - 1)
+}
+
+// Wide reports whether the schema has more fields than fit in a byte index,
+// so its messages use varint field indices (see MsgFlagWideIndex)
+func (s *Schema) Wide() bool {
+	return len(s.Fields) > MaxCompactFields
 }
 
 // Trackable interface for types that support change tracking
@@ -157,6 +187,37 @@ type Trackable interface {
 	GetFieldValue(index uint8) interface{}
 }
 
+// WideTrackable is implemented by Trackables whose schema has more than 256 fields.
+// When implemented, GetWideFieldValue is used for every field instead of GetFieldValue.
+type WideTrackable interface {
+	GetWideFieldValue(index uint16) interface{}
+}
+
+// FieldSetter is the write counterpart of GetFieldValue, generated by schemagen.
+// SetFieldValue sets a field from a value as decoded by Decoder (see ApplyPatch):
+// primitives have the Go type of their wire type, structs and maps are
+// map[string]interface{} and arrays []interface{} (see DecodedValue and DecodedStruct).
+type FieldSetter interface {
+	SetFieldValue(index uint16, value interface{}) error
+}
+
+// defaultsResetter is implemented by generated types. ResetToDefaults also
+// initializes the ChangeSet and schema of a zero value.
+type defaultsResetter interface {
+	ResetToDefaults()
+}
+
+// fieldValue returns the value of a field by index (see WideTrackable)
+func fieldValue(t Trackable, index uint16) interface{} {
+	if w, ok := t.(WideTrackable); ok {
+		return w.GetWideFieldValue(index)
+	}
+	if index >= MaxCompactFields {
+		return nil
+	}
+	return t.GetFieldValue(uint8(index))
+}
+
 // isNilTrackable checks if a Trackable value is nil, handling typed nil pointers.
 // In Go, any((*T)(nil)) == nil is false because the interface carries type info.
 // This function uses reflect to properly detect nil pointer values.
@@ -362,7 +423,7 @@ func (b *SchemaBuilder) Bytes(name string) *SchemaBuilder {
 // Struct adds a nested struct field
 func (b *SchemaBuilder) Struct(name string, childSchema *Schema) *SchemaBuilder {
 	b.schema.AddField(FieldMeta{
-		Index:       uint8(len(b.schema.Fields)),
+		Index:       uint16(len(b.schema.Fields)),
 		Name:        name,
 		Type:        TypeStruct,
 		ChildSchema: childSchema,
@@ -373,7 +434,7 @@ func (b *SchemaBuilder) Struct(name string, childSchema *Schema) *SchemaBuilder
 // Array adds an array field
 func (b *SchemaBuilder) Array(name string, elemType FieldType, childSchema *Schema) *SchemaBuilder {
 	b.schema.AddField(FieldMeta{
-		Index:       uint8(len(b.schema.Fields)),
+		Index:       uint16(len(b.schema.Fields)),
 		Name:        name,
 		Type:        TypeArray,
 		ElemType:    elemType,
@@ -385,7 +446,7 @@ func (b *SchemaBuilder) Array(name string, elemType FieldType, childSchema *Sche
 // ArrayByKey adds an array field with key-based tracking
 func (b *SchemaBuilder) ArrayByKey(name string, elemType FieldType, childSchema *Schema, keyField string) *SchemaBuilder {
 	b.schema.AddField(FieldMeta{
-		Index:       uint8(len(b.schema.Fields)),
+		Index:       uint16(len(b.schema.Fields)),
 		Name:        name,
 		Type:        TypeArray,
 		ElemType:    elemType,
@@ -398,7 +459,7 @@ func (b *SchemaBuilder) ArrayByKey(name string, elemType FieldType, childSchema
 // Map adds a map field
 func (b *SchemaBuilder) Map(name string, elemType FieldType, childSchema *Schema) *SchemaBuilder {
 	b.schema.AddField(FieldMeta{
-		Index:       uint8(len(b.schema.Fields)),
+		Index:       uint16(len(b.schema.Fields)),
 		Name:        name,
 		Type:        TypeMap,
 		ElemType:    elemType,
@@ -407,17 +468,38 @@ func (b *SchemaBuilder) Map(name string, elemType FieldType, childSchema *Schema
 	return b
 }
 
+// Views restricts the last added field to viewers with one of the projections
+func (b *SchemaBuilder) Views(views ...string) *SchemaBuilder {
+	if len(b.schema.Fields) == 0 {
+		panic(fmt.Sprintf("statesync: Views called on schema %q without fields", b.schema.Name))
+	}
+	b.schema.Fields[len(b.schema.Fields)-1].Projections = views
+	return b
+}
+
+// Write restricts writes to the last added field (see WritePermission and UpdateAs)
+func (b *SchemaBuilder) Write(perm WritePermission) *SchemaBuilder {
+	if len(b.schema.Fields) == 0 {
+		panic(fmt.Sprintf("statesync: Write called on schema %q without fields", b.schema.Name))
+	}
+	b.schema.Fields[len(b.schema.Fields)-1].Write = perm
+	return b
+}
+
+// Owner sets the field holding the owner's ID (for ViewOwner projections and WriteOwner)
+func (b *SchemaBuilder) Owner(field string) *SchemaBuilder {
+	b.schema.OwnerField = field
+	return b
+}
+
 // Build returns the completed schema
 func (b *SchemaBuilder) Build() *Schema {
 	return b.schema
 }
 
 func (b *SchemaBuilder) field(name string, typ FieldType) *SchemaBuilder {
-	if len(b.schema.Fields) >= 256 {
-		panic(fmt.Sprintf("statesync: schema %q exceeds maximum of 256 fields", b.schema.Name))
-	}
 	b.schema.AddField(FieldMeta{
-		Index: uint8(len(b.schema.Fields)),
+		Index: uint16(len(b.schema.Fields)),
 		Name:  name,
 		Type:  typ,
 	})
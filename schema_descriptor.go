@@ -0,0 +1,372 @@
+package statesync
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Schema descriptor protocol constants
+const (
+	// MsgSchema is a schema descriptor (see SchemaDescriptor), sent on connect so clients
+	// without compiled-in schemas can decode the session.
+	// Format: [MsgSchema][version:uint8][count:varint] then per schema:
+	//         [id:uint16][name:string][ownerField:string][fieldCount:varint] then per field:
+	//         [index:uint8 (varint in version 2)][name:string][type:uint8][elemType:uint8][child:varint (0 = none, else position+1)]
+	//         [keyField:string][viewCount:varint][views:string...][write:string]
+	MsgSchema uint8 = 0x06
+
+	// SchemaDescriptorVersion is the current schema descriptor version
+	SchemaDescriptorVersion uint8 = 1
+
+	// SchemaDescriptorVersionWide is used when a schema has more than 256 fields (varint field indices)
+	SchemaDescriptorVersionWide uint8 = 2
+)
+
+// ErrInvalidDescriptor is returned for malformed schema descriptors
+var ErrInvalidDescriptor = errors.New("invalid schema descriptor")
+
+// SchemaDescriptor is a self-describing list of schemas (all fields, types, keys, views
+// and write permissions). It round-trips through JSON and the binary MsgSchema message.
+type SchemaDescriptor struct {
+	Version uint8               `json:"version"`
+	Schemas []SchemaDescription `json:"schemas"`
+}
+
+// SchemaDescription describes a single schema
+type SchemaDescription struct {
+	ID         uint16             `json:"id"`
+	Name       string             `json:"name"`
+	OwnerField string             `json:"ownerField,omitempty"`
+	Fields     []FieldDescription `json:"fields"`
+}
+
+// FieldDescription describes a single field. Child is the position+1 of the nested
+// schema in SchemaDescriptor.Schemas (0 = none), so nested schemas without an ID work.
+type FieldDescription struct {
+	Index    uint16          `json:"index"`
+	Name     string          `json:"name"`
+	Type     FieldType       `json:"type"`
+	ElemType FieldType       `json:"elemType,omitempty"`
+	Child    int             `json:"child,omitempty"`
+	KeyField string          `json:"keyField,omitempty"`
+	Views    []string        `json:"views,omitempty"`
+	Write    WritePermission `json:"write,omitempty"`
+}
+
+// Schemas returns the registered schemas sorted by ID
+func (r *SchemaRegistry) Schemas() []*Schema {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	schemas := make([]*Schema, 0, len(r.schemas))
+	for _, s := range r.schemas {
+		schemas = append(schemas, s)
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].ID < schemas[j].ID })
+	return schemas
+}
+
+// Describe builds a descriptor of the registered schemas and the schemas nested in them
+func (r *SchemaRegistry) Describe() *SchemaDescriptor {
+	return DescribeSchemas(r.Schemas()...)
+}
+
+// DescribeSchemas builds a descriptor of schemas and the schemas nested in them
+func DescribeSchemas(schemas ...*Schema) *SchemaDescriptor {
+	// Number schemas first so fields can refer to nested (and recursive) schemas
+	position := make(map[*Schema]int)
+	var order []*Schema
+	var visit func(s *Schema)
+	visit = func(s *Schema) {
+		if s == nil {
+			return
+		}
+		if _, ok := position[s]; ok {
+			return
+		}
+		position[s] = len(order)
+		order = append(order, s)
+		for i := range s.Fields {
+			visit(s.Fields[i].ChildSchema)
+		}
+	}
+	for _, s := range schemas {
+		visit(s)
+	}
+
+	desc := &SchemaDescriptor{Version: SchemaDescriptorVersion, Schemas: make([]SchemaDescription, len(order))}
+	for i, s := range order {
+		if s.Wide() {
+			desc.Version = SchemaDescriptorVersionWide
+		}
+		d := SchemaDescription{ID: s.ID, Name: s.Name, OwnerField: s.OwnerField, Fields: make([]FieldDescription, len(s.Fields))}
+		for j := range s.Fields {
+			f := &s.Fields[j]
+			d.Fields[j] = FieldDescription{
+				Index:    f.Index,
+				Name:     f.Name,
+				Type:     f.Type,
+				ElemType: f.ElemType,
+				KeyField: f.KeyField,
+				Views:    f.Projections,
+				Write:    f.Write,
+			}
+			if f.ChildSchema != nil {
+				d.Fields[j].Child = position[f.ChildSchema] + 1
+			}
+		}
+		desc.Schemas[i] = d
+	}
+	return desc
+}
+
+// Build creates the described schemas (nested schemas are linked by pointer)
+func (d *SchemaDescriptor) Build() ([]*Schema, error) {
+	schemas := make([]*Schema, len(d.Schemas))
+	for i, s := range d.Schemas {
+		schemas[i] = NewSchema(s.ID, s.Name)
+		schemas[i].OwnerField = s.OwnerField
+	}
+	for i, s := range d.Schemas {
+		for j, f := range s.Fields {
+			if int(f.Index) != j {
+				return nil, fmt.Errorf("%w: schema %q field %q has index %d at position %d", ErrInvalidDescriptor, s.Name, f.Name, f.Index, j)
+			}
+			if f.Child < 0 || f.Child > len(schemas) {
+				return nil, fmt.Errorf("%w: schema %q field %q refers to schema %d", ErrInvalidDescriptor, s.Name, f.Name, f.Child)
+			}
+			field := FieldMeta{
+				Index:       f.Index,
+				Name:        f.Name,
+				Type:        f.Type,
+				ElemType:    f.ElemType,
+				KeyField:    f.KeyField,
+				Projections: f.Views,
+				Write:       f.Write,
+			}
+			if f.Child > 0 {
+				field.ChildSchema = schemas[f.Child-1]
+			}
+			schemas[i].AddField(field)
+		}
+	}
+	return schemas, nil
+}
+
+// RegisterInto builds the described schemas and registers those with an ID
+// (nested schemas without one are only reachable through their parent)
+func (d *SchemaDescriptor) RegisterInto(r *SchemaRegistry) error {
+	schemas, err := d.Build()
+	if err != nil {
+		return err
+	}
+	for _, s := range schemas {
+		if s.ID == 0 {
+			continue
+		}
+		if existing := r.Get(s.ID); existing != nil && existing.Name != s.Name {
+			return fmt.Errorf("%w: schema %q and %q both use ID %d", ErrDuplicateSchemaID, existing.Name, s.Name, s.ID)
+		}
+		r.Register(s)
+	}
+	return nil
+}
+
+// Registry builds a new registry from the descriptor
+func (d *SchemaDescriptor) Registry() (*SchemaRegistry, error) {
+	r := NewSchemaRegistry()
+	if err := d.RegisterInto(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Encode encodes the descriptor as a MsgSchema message
+func (d *SchemaDescriptor) Encode() []byte {
+	e := NewEncoder(nil)
+	e.writeByte(MsgSchema)
+	e.writeByte(d.Version)
+	e.writeVarUint(uint64(len(d.Schemas)))
+	for _, s := range d.Schemas {
+		e.writeUint16(s.ID)
+		e.writeString(s.Name)
+		e.writeString(s.OwnerField)
+		e.writeVarUint(uint64(len(s.Fields)))
+		for _, f := range s.Fields {
+			if d.Version >= SchemaDescriptorVersionWide {
+				e.writeVarUint(uint64(f.Index))
+			} else {
+				e.writeByte(uint8(f.Index))
+			}
+			e.writeString(f.Name)
+			e.writeByte(uint8(f.Type))
+			e.writeByte(uint8(f.ElemType))
+			e.writeVarUint(uint64(f.Child))
+			e.writeString(f.KeyField)
+			e.writeVarUint(uint64(len(f.Views)))
+			for _, v := range f.Views {
+				e.writeString(v)
+			}
+			e.writeString(string(f.Write))
+		}
+	}
+	return append([]byte(nil), e.Bytes()...)
+}
+
+// EncodeSchemaDescriptor encodes the schemas of a registry as a MsgSchema message
+func EncodeSchemaDescriptor(r *SchemaRegistry) []byte {
+	return r.Describe().Encode()
+}
+
+// DecodeSchemaDescriptor decodes a MsgSchema message
+func DecodeSchemaDescriptor(data []byte) (*SchemaDescriptor, error) {
+	d := &Decoder{buf: data}
+	desc, err := d.decodeDescriptor()
+	if err != nil {
+		if errors.Is(err, ErrInvalidDescriptor) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDescriptor, err)
+	}
+	return desc, nil
+}
+
+// NewDecoderFromDescriptor creates a decoder whose registry is built from a MsgSchema message
+func NewDecoderFromDescriptor(data []byte) (*Decoder, error) {
+	desc, err := DecodeSchemaDescriptor(data)
+	if err != nil {
+		return nil, err
+	}
+	registry, err := desc.Registry()
+	if err != nil {
+		return nil, err
+	}
+	return NewDecoder(registry), nil
+}
+
+// decodeDescriptor reads a MsgSchema message from the start of the buffer
+func (d *Decoder) decodeDescriptor() (*SchemaDescriptor, error) {
+	d.pos = 0
+	msgType, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	if msgType != MsgSchema {
+		return nil, fmt.Errorf("%w: message type %d", ErrInvalidDescriptor, msgType)
+	}
+	version, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	if version != SchemaDescriptorVersion && version != SchemaDescriptorVersionWide {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidDescriptor, version)
+	}
+	count, err := d.readCount()
+	if err != nil {
+		return nil, err
+	}
+
+	desc := &SchemaDescriptor{Version: version, Schemas: make([]SchemaDescription, count)}
+	for i := range desc.Schemas {
+		s := &desc.Schemas[i]
+		if s.ID, err = d.readUint16(); err != nil {
+			return nil, err
+		}
+		if s.Name, err = d.readString(); err != nil {
+			return nil, err
+		}
+		if s.OwnerField, err = d.readString(); err != nil {
+			return nil, err
+		}
+		n, err := d.readCount()
+		if err != nil {
+			return nil, err
+		}
+		s.Fields = make([]FieldDescription, n)
+		for j := range s.Fields {
+			if err := d.decodeFieldDescription(&s.Fields[j], version >= SchemaDescriptorVersionWide); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return desc, nil
+}
+
+// decodeFieldDescription reads a single field of a MsgSchema message (wide: varint index)
+func (d *Decoder) decodeFieldDescription(f *FieldDescription, wide bool) error {
+	index, err := d.readFieldIndex(wide)
+	if err != nil {
+		return err
+	}
+	if index >= MaxFields {
+		return fmt.Errorf("%w: field index %d", ErrInvalidDescriptor, index)
+	}
+	f.Index = uint16(index)
+	if f.Name, err = d.readString(); err != nil {
+		return err
+	}
+	typ, err := d.readByte()
+	if err != nil {
+		return err
+	}
+	elemType, err := d.readByte()
+	if err != nil {
+		return err
+	}
+	f.Type, f.ElemType = FieldType(typ), FieldType(elemType)
+	child, err := d.readVarUint()
+	if err != nil {
+		return err
+	}
+	if child > math.MaxUint16+1 {
+		return fmt.Errorf("%w: field %q refers to schema %d", ErrInvalidDescriptor, f.Name, child)
+	}
+	f.Child = int(child)
+	if f.KeyField, err = d.readString(); err != nil {
+		return err
+	}
+	views, err := d.readCount()
+	if err != nil {
+		return err
+	}
+	for k := 0; k < views; k++ {
+		view, err := d.readString()
+		if err != nil {
+			return err
+		}
+		f.Views = append(f.Views, view)
+	}
+	write, err := d.readString()
+	f.Write = WritePermission(write)
+	return err
+}
+
+// readCount reads a varint length, bounded by the remaining buffer
+func (d *Decoder) readCount() (int, error) {
+	n, err := d.readVarUint()
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(len(d.buf)-d.pos) {
+		return 0, ErrBufferTooSmall
+	}
+	return int(n), nil
+}
+
+// LoadSchemas registers the schemas of a MsgSchema message with the decoder's registry
+func (d *Decoder) LoadSchemas(data []byte) error {
+	desc, err := DecodeSchemaDescriptor(data)
+	if err != nil {
+		return err
+	}
+	if d.registry == nil {
+		d.registry = NewSchemaRegistry()
+	}
+	return desc.RegisterInto(d.registry)
+}
+
+// Registry returns the decoder's schema registry
+func (d *Decoder) Registry() *SchemaRegistry {
+	return d.registry
+}
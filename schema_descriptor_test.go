@@ -0,0 +1,122 @@
+package statesync
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSchemaDescriptor_RoundTrip(t *testing.T) {
+	registry := NewSchemaRegistry()
+	registry.Register(projStateSchema)
+	registry.Register(aclStateSchema)
+
+	desc := registry.Describe()
+	if len(desc.Schemas) != 4 {
+		t.Fatalf("expected 4 schemas (2 nested), got %d", len(desc.Schemas))
+	}
+
+	decoded, err := DecodeSchemaDescriptor(desc.Encode())
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, desc) {
+		t.Errorf("binary round trip differs:\n got %+v\nwant %+v", decoded, desc)
+	}
+
+	data, err := json.Marshal(desc)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var fromJSON SchemaDescriptor
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(&fromJSON, desc) {
+		t.Errorf("JSON round trip differs:\n got %+v\nwant %+v", fromJSON, desc)
+	}
+
+	rebuilt, err := decoded.Registry()
+	if err != nil {
+		t.Fatalf("registry: %v", err)
+	}
+	state := rebuilt.Get(projStateSchema.ID)
+	if state == nil || state.Fingerprint() != projStateSchema.Fingerprint() {
+		t.Fatalf("rebuilt schema differs from the original")
+	}
+	players := state.FieldByName("Players")
+	if players.KeyField != "ID" || players.ChildSchema.Name != "ProjPlayer" || players.ChildSchema.Fields[1].Projections[0] != ViewOwner {
+		t.Errorf("unexpected rebuilt field %+v", players)
+	}
+	if f := rebuilt.Get(aclStateSchema.ID).FieldByName("Bank"); f.Write != WriteServer {
+		t.Errorf("expected write permission to survive, got %q", f.Write)
+	}
+}
+
+func TestDecoder_SelfDescribing(t *testing.T) {
+	tracked := NewTrackedState[*projState, any](newProjState(), nil)
+	session := NewTrackedSession[*projState, any, string](tracked)
+	session.Connect("dashboard", nil)
+
+	// A decoder without compiled-in schemas learns them from the handshake
+	dec := NewDecoder(NewSchemaRegistry())
+	patch, err := dec.Decode(session.SchemaDescriptor())
+	if err != nil || !patch.Schemas {
+		t.Fatalf("expected schemas to be loaded, got %+v, %v", patch, err)
+	}
+	patch, err = dec.Decode(session.Full("dashboard"))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	schema := dec.Registry().Get(patch.SchemaID)
+	fields := make(map[string]interface{})
+	if err := ApplyPatch(fields, patch, schema); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if want := decodeProjState(t, session.Full("dashboard")); !reflect.DeepEqual(fields, want) {
+		t.Errorf("self-described decode differs:\n got %v\nwant %v", fields, want)
+	}
+
+	if _, err := NewDecoderFromDescriptor(session.SchemaDescriptor()); err != nil {
+		t.Errorf("NewDecoderFromDescriptor: %v", err)
+	}
+}
+
+func TestSchemaDescriptor_Recursive(t *testing.T) {
+	node := NewSchema(90, "Node")
+	node.AddField(FieldMeta{Index: 0, Name: "Value", Type: TypeInt32})
+	node.AddField(FieldMeta{Index: 1, Name: "Children", Type: TypeArray, ElemType: TypeStruct, ChildSchema: node})
+
+	schemas, err := DescribeSchemas(node).Build()
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if len(schemas) != 1 || schemas[0].Fields[1].ChildSchema != schemas[0] {
+		t.Errorf("expected a self-referencing schema, got %+v", schemas)
+	}
+}
+
+func TestSchemaDescriptor_Invalid(t *testing.T) {
+	registry := NewSchemaRegistry()
+	registry.Register(projStateSchema)
+	data := EncodeSchemaDescriptor(registry)
+
+	for i := 0; i < len(data); i++ {
+		if _, err := DecodeSchemaDescriptor(data[:i]); !errors.Is(err, ErrInvalidDescriptor) {
+			t.Fatalf("truncated at %d: expected ErrInvalidDescriptor, got %v", i, err)
+		}
+	}
+
+	desc := registry.Describe()
+	desc.Schemas[0].Fields[2].Child = 9
+	if _, err := desc.Build(); !errors.Is(err, ErrInvalidDescriptor) {
+		t.Errorf("expected ErrInvalidDescriptor for a bad child reference, got %v", err)
+	}
+
+	other := NewSchemaRegistry()
+	other.Register(NewSchema(projStateSchema.ID, "Other"))
+	if err := registry.Describe().RegisterInto(other); !errors.Is(err, ErrDuplicateSchemaID) {
+		t.Errorf("expected ErrDuplicateSchemaID, got %v", err)
+	}
+}
@@ -0,0 +1,765 @@
+package statesync
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Snapshot store errors
+var (
+	ErrSnapshotNotFound   = errors.New("snapshot not found")
+	ErrInvalidSnapshotKey = errors.New("invalid snapshot key")
+	ErrStoreClosed        = errors.New("snapshot store closed")
+)
+
+// SnapshotStore persists encoded snapshots under keys.
+// Keys of automatic snapshots sort in save order (see AutoSnapshotConfig),
+// so List returns keys sorted by name.
+type SnapshotStore interface {
+	// Put stores data under key, replacing any existing snapshot
+	Put(key string, data []byte) error
+
+	// Get returns the snapshot stored under key (ErrSnapshotNotFound if missing)
+	Get(key string) ([]byte, error)
+
+	// Delete removes a snapshot. Deleting a missing key is not an error.
+	Delete(key string) error
+
+	// List returns all keys sorted by name
+	List() ([]string, error)
+}
+
+// SaveSnapshot encodes state and effects as a JSON snapshot and stores it under key
+func SaveSnapshot[T Trackable, A any](store SnapshotStore, key string, state *TrackedState[T, A], effects []EffectMeta, extra any) error {
+	data, err := encodeSnapshot(state, effects, extra)
+	if err != nil {
+		return err
+	}
+	return store.Put(key, data)
+}
+
+// LoadSnapshot reads a snapshot from a store as saved, whatever its version (like Load).
+// Returns nil, nil if the key does not exist.
+func LoadSnapshot[T any](store SnapshotStore, key string) (*Snapshot[T], error) {
+	return loadSnapshot[T](store, key, nil, false)
+}
+
+// LoadSnapshotMigrated reads a snapshot from a store and upgrades older snapshots with
+// migrations. Incompatible snapshots are rejected like in LoadMigrated.
+func LoadSnapshotMigrated[T any](store SnapshotStore, key string, migrations *MigrationRegistry) (*Snapshot[T], error) {
+	return loadSnapshot[T](store, key, migrations, true)
+}
+
+func loadSnapshot[T any](store SnapshotStore, key string, migrations *MigrationRegistry, migrate bool) (*Snapshot[T], error) {
+	data, err := store.Get(key)
+	if errors.Is(err, ErrSnapshotNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeSnapshot[T](data, migrations, migrate)
+}
+
+// LatestSnapshotKey returns the last key with the given prefix ("" if there is none)
+func LatestSnapshotKey(store SnapshotStore, prefix string) (string, error) {
+	keys, err := store.List()
+	if err != nil {
+		return "", err
+	}
+	for i := len(keys) - 1; i >= 0; i-- {
+		if strings.HasPrefix(keys[i], prefix) {
+			return keys[i], nil
+		}
+	}
+	return "", nil
+}
+
+// PruneSnapshots keeps the newest keep snapshots with the given prefix and deletes the rest
+func PruneSnapshots(store SnapshotStore, prefix string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	keys, err := store.List()
+	if err != nil {
+		return err
+	}
+	var matching []string
+	for _, key := range keys {
+		if strings.HasPrefix(key, prefix) {
+			matching = append(matching, key)
+		}
+	}
+	for i := 0; i < len(matching)-keep; i++ {
+		if err := store.Delete(matching[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSnapshotKey rejects keys that can't be used as file names
+func validateSnapshotKey(key string) error {
+	if key == "" || key == "." || key == ".." || strings.ContainsAny(key, `/\`) {
+		return fmt.Errorf("%w: %q", ErrInvalidSnapshotKey, key)
+	}
+	return nil
+}
+
+// ============================================================================
+// Directory store
+// ============================================================================
+
+// snapshotFileExt is the file extension used by DirStore
+const snapshotFileExt = ".snap"
+
+// DirStore stores each snapshot as a file in a local directory (atomic writes).
+// If keep > 0, each Put deletes the oldest snapshots with the same prefix as its key
+// (the key without trailing digits, see AutoSnapshotConfig) so at most keep remain.
+type DirStore struct {
+	mu   sync.Mutex
+	dir  string
+	keep int
+}
+
+// NewDirStore creates a directory store. keep = 0 disables rotation.
+func NewDirStore(dir string, keep int) *DirStore {
+	return &DirStore{dir: dir, keep: keep}
+}
+
+func (d *DirStore) path(key string) string {
+	return filepath.Join(d.dir, key+snapshotFileExt)
+}
+
+func (d *DirStore) Put(key string, data []byte) error {
+	if err := validateSnapshotKey(key); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return fmt.Errorf("mkdir: %w", err)
+	}
+	path := d.path(key)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	if d.keep > 0 {
+		keys, err := d.listLocked()
+		if err != nil {
+			return err
+		}
+		prefix := strings.TrimRight(key, "0123456789")
+		var rotated []string
+		for _, k := range keys {
+			if strings.TrimRight(k, "0123456789") == prefix {
+				rotated = append(rotated, k)
+			}
+		}
+		for i := 0; i < len(rotated)-d.keep; i++ {
+			if err := os.Remove(d.path(rotated[i])); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("rotate: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (d *DirStore) Get(key string) ([]byte, error) {
+	if err := validateSnapshotKey(key); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(d.path(key))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrSnapshotNotFound, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	return data, nil
+}
+
+func (d *DirStore) Delete(key string) error {
+	if err := validateSnapshotKey(key); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := os.Remove(d.path(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("delete: %w", err)
+	}
+	return nil
+}
+
+func (d *DirStore) List() ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.listLocked()
+}
+
+// listLocked lists snapshot keys in the directory. Caller must hold d.mu.
+func (d *DirStore) listLocked() ([]string, error) {
+	entries, err := os.ReadDir(d.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list: %w", err)
+	}
+	var keys []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, snapshotFileExt) {
+			continue
+		}
+		keys = append(keys, strings.TrimSuffix(name, snapshotFileExt))
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// ============================================================================
+// Key-value file store
+// ============================================================================
+
+// KV file record operations
+const (
+	kvOpPut    byte = 1
+	kvOpDelete byte = 2
+)
+
+// kvEntry locates a value in the KV file
+type kvEntry struct {
+	offset int64
+	size   int
+}
+
+// KVFileStore is an embedded key-value store in a single append-only file.
+// Every Put and Delete appends a checksummed record and is synced to disk;
+// a torn record at the end of the file (e.g. after a crash) is discarded on open.
+// Call Compact to reclaim space from overwritten and deleted snapshots.
+//
+// Record format: [op:byte][keyLen:uvarint][key][valueLen:uvarint][value][crc32:uint32 LE]
+type KVFileStore struct {
+	mu    sync.Mutex
+	path  string
+	file  *os.File
+	size  int64
+	index map[string]kvEntry
+	dead  int64 // Bytes used by overwritten or deleted records
+}
+
+// OpenKVFileStore opens (or creates) a KV file store
+func OpenKVFileStore(path string) (*KVFileStore, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("mkdir: %w", err)
+		}
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	s := &KVFileStore{path: path, file: file, index: make(map[string]kvEntry)}
+	if err := s.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load rebuilds the index from the file and truncates a torn tail
+func (s *KVFileStore) load() error {
+	data, err := io.ReadAll(s.file)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+
+	pos := 0
+	for pos < len(data) {
+		op, key, valueStart, valueLen, n, ok := parseKVRecord(data[pos:])
+		if !ok {
+			break
+		}
+		if old, exists := s.index[key]; exists {
+			s.dead += int64(kvRecordSize(key, old.size))
+		}
+		switch op {
+		case kvOpPut:
+			s.index[key] = kvEntry{offset: int64(pos + valueStart), size: valueLen}
+		case kvOpDelete:
+			delete(s.index, key)
+			s.dead += int64(n)
+		}
+		pos += n
+	}
+
+	s.size = int64(pos)
+	if pos < len(data) {
+		if err := s.file.Truncate(s.size); err != nil {
+			return fmt.Errorf("truncate: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseKVRecord parses one record. ok is false for a torn or corrupt record.
+func parseKVRecord(data []byte) (op byte, key string, valueStart, valueLen, n int, ok bool) {
+	if len(data) < 1 {
+		return
+	}
+	op = data[0]
+	pos := 1
+	keyLen, m := binary.Uvarint(data[pos:])
+	if m <= 0 || keyLen > uint64(len(data)-pos-m) {
+		return
+	}
+	pos += m
+	key = string(data[pos : pos+int(keyLen)])
+	pos += int(keyLen)
+	vLen, m := binary.Uvarint(data[pos:])
+	if m <= 0 || vLen > uint64(len(data)-pos-m) {
+		return
+	}
+	pos += m
+	valueStart, valueLen = pos, int(vLen)
+	pos += valueLen
+	if len(data)-pos < 4 {
+		return
+	}
+	if crc32.ChecksumIEEE(data[:pos]) != binary.LittleEndian.Uint32(data[pos:]) {
+		return
+	}
+	if op != kvOpPut && op != kvOpDelete {
+		return
+	}
+	return op, key, valueStart, valueLen, pos + 4, true
+}
+
+// appendKVRecord encodes a record
+func appendKVRecord(buf []byte, op byte, key string, value []byte) []byte {
+	start := len(buf)
+	buf = append(buf, op)
+	buf = binary.AppendUvarint(buf, uint64(len(key)))
+	buf = append(buf, key...)
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+	buf = append(buf, value...)
+	return binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf[start:]))
+}
+
+// kvRecordSize returns the encoded size of a record
+func kvRecordSize(key string, valueLen int) int {
+	return 1 + varIntSize(uint64(len(key))) + len(key) + varIntSize(uint64(valueLen)) + valueLen + 4
+}
+
+// writeLocked appends a record and syncs it. Caller must hold s.mu.
+func (s *KVFileStore) writeLocked(op byte, key string, value []byte) error {
+	if s.file == nil {
+		return ErrStoreClosed
+	}
+	record := appendKVRecord(nil, op, key, value)
+	if _, err := s.file.WriteAt(record, s.size); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("sync: %w", err)
+	}
+
+	if old, exists := s.index[key]; exists {
+		s.dead += int64(kvRecordSize(key, old.size))
+	}
+	if op == kvOpPut {
+		s.index[key] = kvEntry{offset: s.size + int64(len(record)-4-len(value)), size: len(value)}
+	} else {
+		delete(s.index, key)
+		s.dead += int64(len(record))
+	}
+	s.size += int64(len(record))
+	return nil
+}
+
+func (s *KVFileStore) Put(key string, data []byte) error {
+	if key == "" {
+		return fmt.Errorf("%w: %q", ErrInvalidSnapshotKey, key)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writeLocked(kvOpPut, key, data)
+}
+
+func (s *KVFileStore) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil, ErrStoreClosed
+	}
+	entry, ok := s.index[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSnapshotNotFound, key)
+	}
+	data := make([]byte, entry.size)
+	if _, err := s.file.ReadAt(data, entry.offset); err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	return data, nil
+}
+
+func (s *KVFileStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.index[key]; !ok {
+		return nil
+	}
+	return s.writeLocked(kvOpDelete, key, nil)
+}
+
+func (s *KVFileStore) List() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.index))
+	for key := range s.index {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Garbage returns the number of bytes Compact would reclaim
+func (s *KVFileStore) Garbage() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dead
+}
+
+// Compact rewrites the file with only the live snapshots (atomic rename)
+func (s *KVFileStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrStoreClosed
+	}
+
+	keys := make([]string, 0, len(s.index))
+	for key := range s.index {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf []byte
+	index := make(map[string]kvEntry, len(keys))
+	for _, key := range keys {
+		entry := s.index[key]
+		value := make([]byte, entry.size)
+		if _, err := s.file.ReadAt(value, entry.offset); err != nil {
+			return fmt.Errorf("read: %w", err)
+		}
+		buf = appendKVRecord(buf, kvOpPut, key, value)
+		index[key] = kvEntry{offset: int64(len(buf) - 4 - len(value)), size: len(value)}
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, buf, 0644); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("rename: %w", err)
+	}
+	file, err := os.OpenFile(s.path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("reopen: %w", err)
+	}
+	s.file.Close()
+	s.file = file
+	s.index = index
+	s.size = int64(len(buf))
+	s.dead = 0
+	return nil
+}
+
+// Close closes the underlying file
+func (s *KVFileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// ============================================================================
+// Memory store
+// ============================================================================
+
+// MemoryStore keeps snapshots in memory (for tests)
+type MemoryStore struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: make(map[string][]byte)}
+}
+
+func (m *MemoryStore) Put(key string, data []byte) error {
+	if key == "" {
+		return fmt.Errorf("%w: %q", ErrInvalidSnapshotKey, key)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *MemoryStore) Get(key string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.data[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSnapshotNotFound, key)
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (m *MemoryStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, key)
+	return nil
+}
+
+func (m *MemoryStore) List() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := make([]string, 0, len(m.data))
+	for key := range m.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// ============================================================================
+// Automatic session snapshots
+// ============================================================================
+
+// DefaultSnapshotPrefix is the key prefix of automatic snapshots
+const DefaultSnapshotPrefix = "snapshot-"
+
+// AutoSnapshotConfig configures automatic session snapshots (see TrackedSession.EnableAutoSnapshot).
+// Snapshot keys are Prefix followed by the zero-padded tick sequence, so they sort in save order.
+type AutoSnapshotConfig[T Trackable, A any] struct {
+	Store      SnapshotStore
+	EveryTicks uint64                // Snapshot every N ticks (0 = disabled)
+	Interval   time.Duration         // Snapshot on a timer (0 = disabled)
+	Keep       int                   // Keep the newest N automatic snapshots (0 = all)
+	Prefix     string                // Key prefix (default DefaultSnapshotPrefix)
+	Effects    *EffectRegistry[T, A] // Saves active effects if set (unregistered effects fail the snapshot)
+	Binary     bool                  // Save binary snapshots (see EncodeBinarySnapshot) instead of JSON
+	OnError    func(err error)       // Called when a snapshot fails (optional)
+}
+
+// snapshotKey returns the automatic snapshot key for a tick sequence
+func snapshotKey(prefix string, seq uint64) string {
+	return fmt.Sprintf("%s%020d", prefix, seq)
+}
+
+// EnableAutoSnapshot starts saving snapshots every N ticks and/or on a timer.
+// Snapshots are encoded when they are taken and written to the store in the background,
+// so ticks don't wait for the store. It replaces any previous auto-snapshot configuration.
+func (s *TrackedSession[T, A, ID]) EnableAutoSnapshot(cfg AutoSnapshotConfig[T, A]) error {
+	if cfg.Store == nil {
+		return errors.New("statesync: auto snapshot requires a store")
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = DefaultSnapshotPrefix
+	}
+
+	s.DisableAutoSnapshot()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.autoSnapshot = &cfg
+	s.autoSnapshotWriter = newSnapshotWriter(cfg.Store, cfg.Prefix, cfg.Keep, cfg.OnError)
+	if cfg.Interval > 0 {
+		stop := make(chan struct{})
+		s.autoSnapshotStop = stop
+		go s.runAutoSnapshotTimer(&cfg, s.autoSnapshotWriter, stop)
+	}
+	return nil
+}
+
+// DisableAutoSnapshot stops automatic snapshots. It returns once a snapshot waiting
+// to be written has been written.
+func (s *TrackedSession[T, A, ID]) DisableAutoSnapshot() {
+	s.mu.Lock()
+	if s.autoSnapshotStop != nil {
+		close(s.autoSnapshotStop)
+		s.autoSnapshotStop = nil
+	}
+	w := s.autoSnapshotWriter
+	s.autoSnapshot = nil
+	s.autoSnapshotWriter = nil
+	s.mu.Unlock()
+
+	if w != nil {
+		w.close()
+	}
+}
+
+// SnapshotNow saves an automatic snapshot immediately and returns its key
+func (s *TrackedSession[T, A, ID]) SnapshotNow() (string, error) {
+	s.mu.RLock()
+	auto := s.autoSnapshot
+	seq := s.seq - 1
+	s.mu.RUnlock()
+	if auto == nil {
+		return "", errors.New("statesync: auto snapshot not enabled")
+	}
+	key := snapshotKey(auto.Prefix, seq)
+	data, err := s.encodeAutoSnapshot(auto)
+	if err != nil {
+		return "", err
+	}
+	return key, writeAutoSnapshot(auto.Store, auto.Prefix, auto.Keep, key, data)
+}
+
+// runAutoSnapshotTimer saves a snapshot every interval until stop is closed
+func (s *TrackedSession[T, A, ID]) runAutoSnapshotTimer(cfg *AutoSnapshotConfig[T, A], w *snapshotWriter, stop chan struct{}) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.takeAutoSnapshot(cfg, w, s.Seq()-1)
+		}
+	}
+}
+
+// takeAutoSnapshot encodes a snapshot for a tick sequence and hands it to the writer.
+// Errors are reported to OnError.
+func (s *TrackedSession[T, A, ID]) takeAutoSnapshot(cfg *AutoSnapshotConfig[T, A], w *snapshotWriter, seq uint64) {
+	data, err := s.encodeAutoSnapshot(cfg)
+	if err != nil {
+		if cfg.OnError != nil {
+			cfg.OnError(err)
+		}
+		return
+	}
+	w.enqueue(snapshotKey(cfg.Prefix, seq), data)
+}
+
+// encodeAutoSnapshot encodes the state (and active effects if configured)
+func (s *TrackedSession[T, A, ID]) encodeAutoSnapshot(cfg *AutoSnapshotConfig[T, A]) ([]byte, error) {
+	var effects []EffectMeta
+	if cfg.Effects != nil {
+		var err error
+		if effects, err = cfg.Effects.Metas(s.state); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Binary {
+		return EncodeBinarySnapshot(s.state, effects, nil)
+	}
+	return encodeSnapshot(s.state, effects, nil)
+}
+
+// writeAutoSnapshot stores a snapshot and prunes old automatic snapshots
+func writeAutoSnapshot(store SnapshotStore, prefix string, keep int, key string, data []byte) error {
+	if err := store.Put(key, data); err != nil {
+		return err
+	}
+	return PruneSnapshots(store, prefix, keep)
+}
+
+// snapshotWriter writes automatic snapshots in the background. If the store is slower
+// than snapshots are taken, a newer snapshot replaces the one waiting to be written.
+type snapshotWriter struct {
+	store   SnapshotStore
+	prefix  string
+	keep    int
+	onError func(err error)
+
+	mu     sync.Mutex
+	key    string
+	data   []byte // Waiting snapshot (nil = none)
+	closed bool
+	wake   chan struct{}
+	done   chan struct{}
+}
+
+func newSnapshotWriter(store SnapshotStore, prefix string, keep int, onError func(err error)) *snapshotWriter {
+	w := &snapshotWriter{
+		store:   store,
+		prefix:  prefix,
+		keep:    keep,
+		onError: onError,
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// enqueue hands an encoded snapshot to the writer (dropped once the writer is closed)
+func (w *snapshotWriter) enqueue(key string, data []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	w.key, w.data = key, data
+	select {
+	case w.wake <- struct{}{}:
+	default: // Already signalled
+	}
+}
+
+func (w *snapshotWriter) run() {
+	defer close(w.done)
+	for range w.wake {
+		w.mu.Lock()
+		key, data := w.key, w.data
+		w.data = nil
+		w.mu.Unlock()
+		if data == nil {
+			continue
+		}
+		if err := writeAutoSnapshot(w.store, w.prefix, w.keep, key, data); err != nil && w.onError != nil {
+			w.onError(err)
+		}
+	}
+}
+
+// close stops the writer after the waiting snapshot has been written
+func (w *snapshotWriter) close() {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.wake)
+	}
+	w.mu.Unlock()
+	<-w.done
+}
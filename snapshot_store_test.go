@@ -0,0 +1,265 @@
+package statesync
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testSnapshotStore(t *testing.T, store SnapshotStore) {
+	t.Helper()
+
+	if _, err := store.Get("missing"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("expected ErrSnapshotNotFound, got %v", err)
+	}
+
+	store.Put("b", []byte("2"))
+	store.Put("a", []byte("1"))
+	store.Put("b", []byte("22"))
+
+	data, err := store.Get("b")
+	if err != nil || string(data) != "22" {
+		t.Errorf("Get(b) = %q, %v; want 22", data, err)
+	}
+	keys, _ := store.List()
+	if fmt.Sprint(keys) != "[a b]" {
+		t.Errorf("List = %v, want [a b]", keys)
+	}
+
+	if err := store.Delete("a"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := store.Delete("a"); err != nil {
+		t.Errorf("deleting a missing key should not fail: %v", err)
+	}
+	keys, _ = store.List()
+	if fmt.Sprint(keys) != "[b]" {
+		t.Errorf("List after delete = %v, want [b]", keys)
+	}
+}
+
+func TestSnapshotStores(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		testSnapshotStore(t, NewMemoryStore())
+	})
+	t.Run("dir", func(t *testing.T) {
+		testSnapshotStore(t, NewDirStore(t.TempDir(), 0))
+	})
+	t.Run("kv", func(t *testing.T) {
+		store, err := OpenKVFileStore(filepath.Join(t.TempDir(), "snapshots.kv"))
+		if err != nil {
+			t.Fatalf("OpenKVFileStore: %v", err)
+		}
+		defer store.Close()
+		testSnapshotStore(t, store)
+	})
+}
+
+func TestDirStore_Rotation(t *testing.T) {
+	store := NewDirStore(t.TempDir(), 2)
+	// Only keys with the prefix of the written key rotate
+	store.Put("checkpoint-1", []byte{0})
+	store.Put("save", []byte{0})
+	for i := 1; i <= 4; i++ {
+		if err := store.Put(fmt.Sprintf("s%d", i), []byte{byte(i)}); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	keys, _ := store.List()
+	if fmt.Sprint(keys) != "[checkpoint-1 s3 s4 save]" {
+		t.Errorf("List = %v, want [checkpoint-1 s3 s4 save]", keys)
+	}
+	if err := store.Put("../escape", nil); !errors.Is(err, ErrInvalidSnapshotKey) {
+		t.Errorf("expected ErrInvalidSnapshotKey, got %v", err)
+	}
+}
+
+func TestKVFileStore_ReopenAndCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshots.kv")
+	store, err := OpenKVFileStore(path)
+	if err != nil {
+		t.Fatalf("OpenKVFileStore: %v", err)
+	}
+	store.Put("a", []byte("first"))
+	store.Put("a", []byte("second"))
+	store.Put("b", []byte("keep"))
+	store.Delete("b")
+	store.Close()
+
+	// Simulate a torn write at the end of the file
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.Write([]byte{kvOpPut, 5, 'x'})
+	f.Close()
+
+	store, err = OpenKVFileStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.Close()
+
+	if data, _ := store.Get("a"); string(data) != "second" {
+		t.Errorf("Get(a) = %q, want second", data)
+	}
+	if _, err := store.Get("b"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("deleted key should stay deleted, got %v", err)
+	}
+	if store.Garbage() == 0 {
+		t.Error("expected garbage from overwritten and deleted records")
+	}
+
+	if err := store.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	if store.Garbage() != 0 {
+		t.Errorf("Garbage after Compact = %d", store.Garbage())
+	}
+	store.Put("c", []byte("after"))
+	if data, _ := store.Get("a"); string(data) != "second" {
+		t.Errorf("Get(a) after compact = %q", data)
+	}
+	if data, _ := store.Get("c"); string(data) != "after" {
+		t.Errorf("Get(c) after compact = %q", data)
+	}
+}
+
+func TestSession_AutoSnapshotEveryTicks(t *testing.T) {
+	session, state := newTimedTestSession()
+	store := NewMemoryStore()
+	if err := session.EnableAutoSnapshot(AutoSnapshotConfig[*simpleTestState, any]{
+		Store:      store,
+		EveryTicks: 2,
+		Keep:       2,
+		OnError:    func(err error) { t.Errorf("snapshot failed: %v", err) },
+	}); err != nil {
+		t.Fatalf("EnableAutoSnapshot: %v", err)
+	}
+	defer session.DisableAutoSnapshot()
+
+	for i := 0; i < 6; i++ {
+		session.State().UpdateInPlace(func(s *simpleTestState) {
+			s.Score++
+		})
+		if _, seq := session.TickWithSeq(); seq%2 == 0 {
+			waitForSnapshot(t, store, snapshotKey(DefaultSnapshotPrefix, seq))
+		}
+	}
+
+	keys, _ := store.List()
+	if len(keys) != 2 || keys[1] != snapshotKey(DefaultSnapshotPrefix, 6) {
+		t.Fatalf("keys = %v, want the 2 newest snapshots", keys)
+	}
+
+	latest, _ := LatestSnapshotKey(store, DefaultSnapshotPrefix)
+	snap, err := LoadSnapshot[*simpleTestState](store, latest)
+	if err != nil || snap == nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	restored := RestoreSnapshot[*simpleTestState, any](snap, initSimpleTestState, nil, nil)
+	if got := restored.State.GetBase().Score; got != state.Score || got != 6 {
+		t.Errorf("restored Score = %d, want 6", got)
+	}
+}
+
+// waitForSnapshot waits until the background writer has stored key
+func waitForSnapshot(t *testing.T, store SnapshotStore, key string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := store.Get(key); err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("snapshot %s not written", key)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// slowStore blocks Put until release is closed
+type slowStore struct {
+	*MemoryStore
+	release chan struct{}
+}
+
+func (s *slowStore) Put(key string, data []byte) error {
+	<-s.release
+	return s.MemoryStore.Put(key, data)
+}
+
+func TestSession_AutoSnapshotInBackground(t *testing.T) {
+	session, _ := newTimedTestSession()
+	store := &slowStore{MemoryStore: NewMemoryStore(), release: make(chan struct{})}
+	session.EnableAutoSnapshot(AutoSnapshotConfig[*simpleTestState, any]{Store: store, EveryTicks: 1})
+
+	// Ticks don't wait for the store
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			session.Tick()
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Tick blocked on the snapshot store")
+	}
+
+	// Disable writes the newest waiting snapshot before returning
+	close(store.release)
+	session.DisableAutoSnapshot()
+	if key, _ := LatestSnapshotKey(store, DefaultSnapshotPrefix); key != snapshotKey(DefaultSnapshotPrefix, 3) {
+		t.Errorf("latest snapshot = %q, want the one of seq 3", key)
+	}
+}
+
+func TestSession_AutoSnapshotWriteError(t *testing.T) {
+	session, _ := newTimedTestSession()
+	errs := make(chan error, 1)
+	session.EnableAutoSnapshot(AutoSnapshotConfig[*simpleTestState, any]{
+		Store:      NewDirStore(t.TempDir(), 0),
+		EveryTicks: 1,
+		Prefix:     "bad/",
+		OnError:    func(err error) { errs <- err },
+	})
+	defer session.DisableAutoSnapshot()
+
+	session.Tick()
+	select {
+	case err := <-errs:
+		if !errors.Is(err, ErrInvalidSnapshotKey) {
+			t.Errorf("expected ErrInvalidSnapshotKey, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("write error not reported to OnError")
+	}
+}
+
+func TestSession_AutoSnapshotInterval(t *testing.T) {
+	session, _ := newTimedTestSession()
+	store := NewMemoryStore()
+	session.EnableAutoSnapshot(AutoSnapshotConfig[*simpleTestState, any]{
+		Store:    store,
+		Interval: 5 * time.Millisecond,
+	})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		keys, _ := store.List()
+		if len(keys) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no snapshot saved by the timer")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	session.DisableAutoSnapshot()
+
+	if _, err := session.SnapshotNow(); err == nil {
+		t.Error("SnapshotNow should fail when auto snapshot is disabled")
+	}
+}
@@ -0,0 +1,172 @@
+package statesync
+
+import (
+	"sync"
+	"time"
+)
+
+// Timed wraps an effect so it expires after a number of session ticks.
+// The effect is visible in the broadcast of each of those ticks and is removed
+// at the start of the following Tick (see TrackedSession.Tick).
+func Timed[T, A any](inner Effect[T, A], ticks uint64) *TimedEffect[T, A] {
+	return &TimedEffect[T, A]{inner: inner, ticks: ticks}
+}
+
+// TimedFor wraps an effect so it expires after an amount of simulated time.
+// Simulated time only advances by the session's effect time step (see SetEffectTimeStep)
+// or by explicit AdvanceEffects calls, never by the wall clock.
+func TimedFor[T, A any](inner Effect[T, A], d time.Duration) *TimedEffect[T, A] {
+	return &TimedEffect[T, A]{inner: inner, remaining: d, byTime: true}
+}
+
+// TimedEffect is a deterministic, tick-driven timed effect.
+// It forwards Activatable, ExpireHook, Stackable, Prioritized, Exclusive and FieldEffect to the wrapped effect.
+type TimedEffect[T, A any] struct {
+	mu        sync.RWMutex
+	inner     Effect[T, A]
+	ticks     uint64        // Remaining ticks (tick-based)
+	remaining time.Duration // Remaining simulated time (time-based)
+	byTime    bool
+	onExpire  func(T, A) T
+}
+
+// WithOnExpire sets a hook that is called when the effect expires (after the inner effect's ExpireHook)
+func (e *TimedEffect[T, A]) WithOnExpire(fn func(state T, activator A) T) *TimedEffect[T, A] {
+	e.onExpire = fn
+	return e
+}
+
+// timedWithLifetime recreates a timed effect from a saved lifetime
+func timedWithLifetime[T, A any](inner Effect[T, A], lt EffectLifetime) *TimedEffect[T, A] {
+	return &TimedEffect[T, A]{inner: inner, ticks: lt.Ticks, remaining: lt.Remaining, byTime: lt.ByTime}
+}
+
+// Lifetime returns the remaining lifetime (for persistence)
+func (e *TimedEffect[T, A]) Lifetime() EffectLifetime {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return EffectLifetime{Ticks: e.ticks, Remaining: e.remaining, ByTime: e.byTime}
+}
+
+// Inner returns the wrapped effect
+func (e *TimedEffect[T, A]) Inner() Effect[T, A] { return e.inner }
+
+// RemainingTicks returns the number of ticks left (0 for time-based effects)
+func (e *TimedEffect[T, A]) RemainingTicks() uint64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.ticks
+}
+
+// Remaining returns the simulated time left (0 for tick-based effects)
+func (e *TimedEffect[T, A]) Remaining() time.Duration {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.remaining
+}
+
+// Advance consumes one tick, or dt of simulated time for time-based effects
+func (e *TimedEffect[T, A]) Advance(dt time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.byTime {
+		e.remaining -= dt
+		if e.remaining < 0 {
+			e.remaining = 0
+		}
+	} else if e.ticks > 0 {
+		e.ticks--
+	}
+}
+
+// Expired returns true once the lifetime is used up
+func (e *TimedEffect[T, A]) Expired() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.byTime {
+		return e.remaining <= 0
+	}
+	return e.ticks == 0
+}
+
+func (e *TimedEffect[T, A]) ID() string { return e.inner.ID() }
+
+func (e *TimedEffect[T, A]) Apply(s T, activator A) T { return e.inner.Apply(s, activator) }
+
+func (e *TimedEffect[T, A]) Activator() A { return e.inner.Activator() }
+
+func (e *TimedEffect[T, A]) SetActivator(activator A) { e.inner.SetActivator(activator) }
+
+func (e *TimedEffect[T, A]) OnActivate(s T, activator A) T {
+	if act, ok := e.inner.(Activatable[T, A]); ok {
+		return act.OnActivate(s, activator)
+	}
+	return s
+}
+
+func (e *TimedEffect[T, A]) OnExpire(s T, activator A) T {
+	if hook, ok := e.inner.(ExpireHook[T, A]); ok {
+		s = hook.OnExpire(s, activator)
+	}
+	if e.onExpire != nil {
+		s = e.onExpire(s, activator)
+	}
+	return s
+}
+
+func (e *TimedEffect[T, A]) Kind() string {
+	kind, _, _ := effectStacking(e.inner)
+	return kind
+}
+
+func (e *TimedEffect[T, A]) MaxStacks() int {
+	_, maxStacks, _ := effectStacking(e.inner)
+	return maxStacks
+}
+
+func (e *TimedEffect[T, A]) StackBehavior() StackBehavior {
+	_, _, behavior := effectStacking(e.inner)
+	return behavior
+}
+
+func (e *TimedEffect[T, A]) Priority() int { return effectPriority(e.inner) }
+
+func (e *TimedEffect[T, A]) ExclusiveGroup() string {
+	group, _ := effectGroup(e.inner)
+	return group
+}
+
+func (e *TimedEffect[T, A]) Strength() int {
+	_, strength := effectGroup(e.inner)
+	return strength
+}
+
+func (e *TimedEffect[T, A]) ReadsFields() []uint16 {
+	reads, _, _ := effectFields(e.inner)
+	return reads
+}
+
+func (e *TimedEffect[T, A]) WritesFields() []uint16 {
+	_, writes, _ := effectFields(e.inner)
+	return writes
+}
+
+// Effect lifecycle event reasons
+const (
+	EffectEnded    = "removed"  // Removed with RemoveEffect or ClearEffects
+	EffectExpired  = "expired"  // Lifetime used up
+	EffectReplaced = "replaced" // Replaced by a stack refresh, full stack or exclusive group
+)
+
+// EffectEvent is the payload of effect start/end events (see SetEffectEvents)
+type EffectEvent struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind,omitempty"`
+	Reason string `json:"reason,omitempty"` // End events only
+}
+
+// newEffectEvent creates the event payload for an effect
+func newEffectEvent(e any, id, reason string) EffectEvent {
+	kind, _, _ := effectStacking(e)
+	return EffectEvent{ID: id, Kind: kind, Reason: reason}
+}
@@ -0,0 +1,183 @@
+package statesync
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func newTimedTestSession() (*TrackedSession[*simpleTestState, any, string], *simpleTestState) {
+	state := &simpleTestState{changes: NewChangeSet()}
+	session := NewTrackedSession[*simpleTestState, any, string](NewTrackedState[*simpleTestState, any](state, nil))
+	return session, state
+}
+
+func TestTimedEffect_ExpiresAfterTicks(t *testing.T) {
+	session, _ := newTimedTestSession()
+	session.AddEffect(Timed[*simpleTestState, any](appendNameEffect("burn", "!"), 2), nil)
+
+	for tick := 1; tick <= 2; tick++ {
+		if got := session.Get().Name; got != "!" {
+			t.Fatalf("tick %d: Name = %q, want effect applied", tick, got)
+		}
+		session.Tick()
+	}
+	if !session.HasEffect("burn") {
+		t.Fatal("expired effect should stay until the next Tick")
+	}
+
+	session.Tick()
+	if session.HasEffect("burn") {
+		t.Error("effect should be removed after 2 ticks")
+	}
+	if got := session.Get().Name; got != "" {
+		t.Errorf("Name = %q, want effect removed", got)
+	}
+}
+
+func TestTimedEffect_SimulatedTime(t *testing.T) {
+	session, _ := newTimedTestSession()
+	session.SetEffectTimeStep(50 * time.Millisecond)
+
+	effect := TimedFor[*simpleTestState, any](appendNameEffect("haste", "h"), 100*time.Millisecond)
+	session.AddEffect(effect, nil)
+
+	session.Tick()
+	if effect.Remaining() != 50*time.Millisecond {
+		t.Errorf("Remaining = %v, want 50ms", effect.Remaining())
+	}
+	session.Tick()
+	if !effect.Expired() || !session.HasEffect("haste") {
+		t.Fatal("effect should be expired but still active until the next Tick")
+	}
+	session.Tick()
+	if session.HasEffect("haste") {
+		t.Error("effect should be removed")
+	}
+}
+
+func TestTimedEffect_OnExpireBroadcast(t *testing.T) {
+	session, state := newTimedTestSession()
+	session.Connect("alice", nil)
+	session.Tick()
+
+	expired := 0
+	effect := Timed[*simpleTestState, any](appendNameEffect("shield", "s"), 1).
+		WithOnExpire(func(s *simpleTestState, _ any) *simpleTestState {
+			expired++
+			s.Score = 99
+			s.changes.Mark(0, OpReplace)
+			return s
+		})
+	session.AddEffect(effect, nil)
+
+	session.Tick()
+	if expired != 0 {
+		t.Fatal("OnExpire called too early")
+	}
+
+	diffs := session.Tick()
+	if expired != 1 {
+		t.Fatalf("OnExpire called %d times, want 1", expired)
+	}
+	if state.Score != 99 {
+		t.Errorf("Score = %d, want 99", state.Score)
+	}
+	if len(diffs["alice"]) == 0 {
+		t.Error("OnExpire changes should be broadcast in the removal tick")
+	}
+}
+
+func TestTimedEffect_ForwardsStacking(t *testing.T) {
+	session, _ := newTimedTestSession()
+
+	activations := 0
+	newBuff := func(id string) Effect[*TestGameState, any] {
+		inner := &testActivatableEffect{
+			id:         id,
+			onActivate: func(s *TestGameState, _ any) *TestGameState { activations++; return s },
+			apply:      func(s *TestGameState, _ any) *TestGameState { return s },
+		}
+		return Timed[*TestGameState, any](&testStackableEffect{testActivatableEffect: *inner, kind: "buff", behavior: StackRefresh}, 3)
+	}
+
+	ts := NewTrackedState[*TestGameState, any](NewTestGameState(), nil)
+	ts.AddEffect(newBuff("buff-1"), nil)
+	ts.AddEffect(newBuff("buff-2"), nil)
+	if n := ts.StackCount("buff"); n != 1 {
+		t.Errorf("StackCount = %d, want 1", n)
+	}
+	if activations != 1 {
+		t.Errorf("OnActivate called %d times, want 1", activations)
+	}
+
+	if err := session.AddEffect(Timed[*simpleTestState, any](appendNameEffect("p", "p").WithPriority(-1), 1), nil); err != nil {
+		t.Fatal(err)
+	}
+	session.AddEffect(appendNameEffect("q", "q"), nil)
+	if got := session.Get().Name; got != "pq" {
+		t.Errorf("Name = %q, want priority forwarded", got)
+	}
+}
+
+func TestTimedEffect_LifecycleEvents(t *testing.T) {
+	session, _ := newTimedTestSession()
+	session.SetEffectEvents("effect:start", "effect:end")
+	session.Connect("alice", nil)
+
+	session.AddEffect(Timed[*simpleTestState, any](appendNameEffect("stun", "x").WithStacking("stun", 0, StackRefresh), 1), nil)
+	events := tickEffectEvents(t, session)
+	if len(events) != 1 || events[0].Type != "effect:start" {
+		t.Fatalf("expected start event, got %+v", events)
+	}
+	var payload EffectEvent
+	json.Unmarshal(events[0].Payload, &payload)
+	if payload.ID != "stun" || payload.Kind != "stun" {
+		t.Errorf("unexpected start payload %+v", payload)
+	}
+
+	events = tickEffectEvents(t, session)
+	if len(events) != 1 || events[0].Type != "effect:end" {
+		t.Fatalf("expected end event, got %+v", events)
+	}
+	json.Unmarshal(events[0].Payload, &payload)
+	if payload.Reason != EffectExpired {
+		t.Errorf("Reason = %q, want %q", payload.Reason, EffectExpired)
+	}
+
+	session.AddEffect(appendNameEffect("a", "a").WithExclusiveGroup("g", 1), nil)
+	session.AddEffect(appendNameEffect("b", "b").WithExclusiveGroup("g", 2), nil)
+	session.RemoveEffect("b")
+	events = tickEffectEvents(t, session)
+	var reasons []string
+	for _, ev := range events {
+		if ev.Type == "effect:end" {
+			json.Unmarshal(ev.Payload, &payload)
+			reasons = append(reasons, payload.ID+":"+payload.Reason)
+		}
+	}
+	if len(reasons) != 2 || reasons[0] != "a:replaced" || reasons[1] != "b:removed" {
+		t.Errorf("unexpected end events %v", reasons)
+	}
+}
+
+// tickEffectEvents ticks the session and decodes alice's events
+func tickEffectEvents(t *testing.T, session *TrackedSession[*simpleTestState, any, string]) []Event {
+	t.Helper()
+	data := session.TickWithEvents().Events["alice"]
+	if len(data) == 0 {
+		return nil
+	}
+	if data[0] == MsgEvent {
+		ev, err := DecodeEvent(data)
+		if err != nil {
+			t.Fatalf("DecodeEvent: %v", err)
+		}
+		return []Event{ev}
+	}
+	events, err := DecodeEventBatch(data)
+	if err != nil {
+		t.Fatalf("DecodeEventBatch: %v", err)
+	}
+	return events
+}
@@ -2,6 +2,7 @@ package statesync
 
 import (
 	"encoding/json"
+	"sort"
 	"sync"
 	"time"
 )
@@ -46,6 +47,20 @@ type TrackedSession[T Trackable, A any, ID comparable] struct {
 	// Client filters (nil = full state)
 	clients map[ID]FilterFunc[T]
 
+	// Client viewer contexts for field projections (kept across Disconnect)
+	viewers map[ID]*Viewer
+
+	// Client overlays (kept across Disconnect)
+	overlays map[ID]OverlayFunc[T]
+
+	// What filtered clients were last sent, for visibility transitions (kept across Disconnect)
+	sent            map[ID]*clientSent
+	visibilityDiffs bool // Diff filtered clients against sent (see SetVisibilityDiffs)
+
+	// Registered filters composed with each client's filter (nil = none)
+	filterRegistry    *FilterRegistry[T, ID]
+	filterUnsubscribe func() // Removes the session's OnChange listener from filterRegistry
+
 	// Full state tracking for new clients
 	clientNeedsFull map[ID]bool
 
@@ -64,18 +79,37 @@ type TrackedSession[T Trackable, A any, ID comparable] struct {
 	onBroadcast   func(map[ID][]byte)
 	tickWrapper   func(tick func()) // Optional wrapper for external locking around ticks
 
+	// Held for a whole tick and while an ExternalInjector applies and records an input,
+	// so the input is recorded with the diff it produced (hooks must not inject inputs)
+	inputMu sync.Mutex
+
 	// Pipeline hooks
 	hooks SessionHooks[T, ID]
 
 	// Event system
-	events *EventBuffer[ID]
+	events        *EventBuffer[ID]
+	eventSeq      uint64                     // Last assigned reliable event sequence
+	eventClients  map[ID]uint64              // Recipients of reliable events: 0 while connected, else the seq they left at
+	reliableLimit int                        // Max unacknowledged reliable events kept per client (0 = unlimited)
+	subscriptions map[ID]map[string]struct{} // Topic subscriptions (kept across reconnects)
+
+	// Effect lifecycle
+	effectStep       time.Duration // Simulated time per Tick for TimedFor effects
+	effectStartEvent string        // Event type emitted when an effect starts ("" = disabled)
+	effectEndEvent   string        // Event type emitted when an effect ends ("" = disabled)
+
+	// Automatic snapshots
+	autoSnapshot       *AutoSnapshotConfig[T, A]
+	autoSnapshotWriter *snapshotWriter
+	autoSnapshotStop   chan struct{}
 }
 
 // historyEntry stores diffs at a specific sequence number
 type historyEntry[ID comparable] struct {
 	seq      uint64
-	baseDiff []byte        // Diff without filter (for reconnection)
-	diffs    map[ID][]byte // Per-client diffs (for clients with filter)
+	baseDiff []byte                 // Diff without filter (for reconnection)
+	diffs    map[ID][]byte          // Per-client diffs (for clients with filter)
+	events   map[ID][]ReliableEvent // Unacknowledged reliable events sent at this seq
 }
 
 // NewTrackedSession creates a new session with binary state sync
@@ -83,10 +117,16 @@ func NewTrackedSession[T Trackable, A any, ID comparable](state *TrackedState[T,
 	return &TrackedSession[T, A, ID]{
 		state:           state,
 		clients:         make(map[ID]FilterFunc[T]),
+		viewers:         make(map[ID]*Viewer),
+		overlays:        make(map[ID]OverlayFunc[T]),
+		sent:            make(map[ID]*clientSent),
 		clientNeedsFull: make(map[ID]bool),
 		clientSeq:       make(map[ID]uint64),
 		seq:             1, // Start at 1 so 0 means "no previous sequence"
 		events:          NewEventBuffer[ID](),
+		subscriptions:   make(map[ID]map[string]struct{}),
+		eventClients:    make(map[ID]uint64),
+		reliableLimit:   DefaultReliableEventLimit,
 	}
 }
 
@@ -100,6 +140,9 @@ func (s *TrackedSession[T, A, ID]) SetHooks(hooks SessionHooks[T, ID]) {
 // SetHistorySize configures the reconnection history buffer size.
 // Set to 0 to disable history (clients always get full state on reconnect).
 // Recommended: 30-60 for 20 tick/s games (1.5-3 seconds of history).
+// size counts ticks with state changes or reliable events: a tick that only sent
+// reliable events also takes an entry, so it shortens the state history that
+// Reconnect can replay. Size it for both when using EmitReliable.
 func (s *TrackedSession[T, A, ID]) SetHistorySize(size int) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -141,15 +184,45 @@ func (s *TrackedSession[T, A, ID]) Connect(id ID, filter FilterFunc[T]) {
 	defer s.mu.Unlock()
 	s.clients[id] = filter
 	s.clientNeedsFull[id] = true
+	s.knowClientLocked(id)
 }
 
-// Disconnect removes a client
+// knowClientLocked makes a client a connected recipient of reliable events. Caller must hold s.mu.
+func (s *TrackedSession[T, A, ID]) knowClientLocked(id ID) {
+	s.eventClients[id] = 0
+}
+
+// Disconnect removes a client.
+// Topic subscriptions and unacknowledged reliable events are kept so they survive
+// a Reconnect while the history still covers the client (see Leave).
 func (s *TrackedSession[T, A, ID]) Disconnect(id ID) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.disconnectLocked(id)
+}
+
+// disconnectLocked removes a client from the connected set. Caller must hold s.mu.
+func (s *TrackedSession[T, A, ID]) disconnectLocked(id ID) {
+	if _, ok := s.clients[id]; ok {
+		if _, known := s.eventClients[id]; known {
+			s.eventClients[id] = s.seq
+		}
+	}
 	delete(s.clients, id)
 	delete(s.clientNeedsFull, id)
 	delete(s.clientSeq, id)
+	s.events.forgetClient(id)
+}
+
+// Leave removes a client that is not coming back. Unlike Disconnect, it also drops the
+// client's topic subscriptions, unacknowledged reliable events and visibility tracking.
+func (s *TrackedSession[T, A, ID]) Leave(id ID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disconnectLocked(id)
+	s.forgetEventsLocked(id)
+	delete(s.subscriptions, id)
+	delete(s.sent, id)
 }
 
 // ClientCount returns the number of connected clients
@@ -194,17 +267,145 @@ func (s *TrackedSession[T, A, ID]) SetFilter(id ID, filter FilterFunc[T]) {
 	}
 }
 
+// SetViewer sets the viewer context a client is encoded for (nil = all fields).
+// Fields with projections the viewer lacks are never encoded for this client.
+// The viewer is kept across Disconnect so it applies after a Reconnect.
+func (s *TrackedSession[T, A, ID]) SetViewer(id ID, viewer *Viewer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if viewer == nil {
+		delete(s.viewers, id)
+		return
+	}
+	s.viewers[id] = viewer
+}
+
+// GetViewer returns the viewer context of a client (nil if none)
+func (s *TrackedSession[T, A, ID]) GetViewer(id ID) *Viewer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.viewers[id]
+}
+
+// SetOverlay sets a copy-on-write overlay for a client (nil = none). The overlay is
+// applied after the filter and lets fields and elements be hidden or replaced
+// without cloning the state. It is kept across Disconnect like the viewer.
+func (s *TrackedSession[T, A, ID]) SetOverlay(id ID, overlay OverlayFunc[T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if overlay == nil {
+		delete(s.overlays, id)
+		return
+	}
+	s.overlays[id] = overlay
+}
+
+// GetOverlay returns the overlay of a client (nil if none)
+func (s *TrackedSession[T, A, ID]) GetOverlay(id ID) OverlayFunc[T] {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.overlays[id]
+}
+
+// SetVisibilityDiffs makes diffs of filtered and overlaid clients relative to what each
+// client was last sent, so elements and fields entering or leaving its view produce
+// synthetic ops even when the base state didn't change them. Off by default: it scans
+// every visible field of these clients per broadcast instead of only the changed ones.
+func (s *TrackedSession[T, A, ID]) SetVisibilityDiffs(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.visibilityDiffs = enabled
+	if !enabled {
+		clear(s.sent)
+	}
+}
+
+// SetFilterRegistry composes each client's filter with its filters in r (see
+// FilterRegistry.ComposeWith). Each Tick expires filters that ran out before
+// broadcasting and advances tick-limited filters after it. A client whose composed
+// filter changes gets a full state on the next broadcast, or with visibility diffs
+// (see SetVisibilityDiffs) a diff against what it was sent while it stays filtered.
+// Replacing the registry (or passing nil) stops listening to the previous one.
+func (s *TrackedSession[T, A, ID]) SetFilterRegistry(r *FilterRegistry[T, ID]) {
+	var unsubscribe func()
+	if r != nil {
+		unsubscribe = r.OnChange(func(id ID) { s.filtersChanged(r, id) })
+	}
+	s.mu.Lock()
+	previous := s.filterUnsubscribe
+	s.filterRegistry, s.filterUnsubscribe = r, unsubscribe
+	s.mu.Unlock()
+	if previous != nil {
+		previous()
+	}
+}
+
+// filtersChanged schedules a full state for a client whose registered filters changed.
+// With visibility diffs, a client that was already sent a filtered view and is still
+// filtered gets the elements and fields entering or leaving its view as a diff instead.
+func (s *TrackedSession[T, A, ID]) filtersChanged(r *FilterRegistry[T, ID], id ID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.filterRegistry != r {
+		return
+	}
+	filter, ok := s.clients[id]
+	if !ok {
+		return
+	}
+	if s.visibilityDiffs && s.sent[id] != nil {
+		if s.sentLocked(id, s.filterLocked(id, filter), s.overlays[id]) != nil {
+			return
+		}
+		// Unfiltered again: the sent view goes stale, a full state follows
+		delete(s.sent, id)
+	}
+	s.clientNeedsFull[id] = true
+}
+
+// filterLocked returns a client's filter composed with its registered filters.
+// Caller must hold s.mu.
+func (s *TrackedSession[T, A, ID]) filterLocked(id ID, filter FilterFunc[T]) FilterFunc[T] {
+	if s.filterRegistry == nil {
+		return filter
+	}
+	return s.filterRegistry.ComposeWith(id, filter)
+}
+
+// sentLocked returns what was last sent to a filtered client (nil if the client isn't
+// filtered or visibility diffs are off). Caller must hold s.mu.
+func (s *TrackedSession[T, A, ID]) sentLocked(id ID, filter FilterFunc[T], overlay OverlayFunc[T]) *clientSent {
+	if !s.visibilityDiffs || (filter == nil && overlay == nil) {
+		return nil
+	}
+	sent := s.sent[id]
+	if sent == nil {
+		sent = newClientSent()
+		s.sent[id] = sent
+	}
+	return sent
+}
+
 // State returns the underlying TrackedState
 func (s *TrackedSession[T, A, ID]) State() *TrackedState[T, A] {
 	return s.state
 }
 
+// SchemaDescriptor returns a MsgSchema message describing the state's schemas.
+// Send it on connect (before Full) to clients without compiled-in schemas.
+func (s *TrackedSession[T, A, ID]) SchemaDescriptor() []byte {
+	return EncodeSchemaDescriptor(s.state.Registry())
+}
+
 // Full returns the full binary state for a specific client (for initial sync)
 func (s *TrackedSession[T, A, ID]) Full(id ID) []byte {
-	s.mu.RLock()
-	filter := s.clients[id]
+	s.mu.Lock()
+	filter := s.filterLocked(id, s.clients[id])
+	viewer := s.viewers[id]
+	overlay := s.overlays[id]
+	sent := s.sentLocked(id, filter, overlay)
 	hooks := s.hooks
-	s.mu.RUnlock()
+	s.mu.Unlock()
 
 	state := s.state.Get()
 
@@ -234,7 +435,11 @@ func (s *TrackedSession[T, A, ID]) Full(id ID) []byte {
 	}
 
 	// Encode
-	data := s.state.lockedEncodeAll(state)
+	view := applyOverlay(state, overlay)
+	data := s.state.lockedEncodeAll(view, viewer)
+	if sent != nil {
+		s.state.lockedObserve(view, viewer, sent)
+	}
 
 	// Hook: after encode
 	if hooks.OnAfterEncode != nil {
@@ -247,19 +452,22 @@ func (s *TrackedSession[T, A, ID]) Full(id ID) []byte {
 // Diff returns the binary diff for a specific client
 func (s *TrackedSession[T, A, ID]) Diff(id ID) []byte {
 	s.mu.Lock()
-	filter := s.clients[id]
+	filter := s.filterLocked(id, s.clients[id])
+	viewer := s.viewers[id]
+	overlay := s.overlays[id]
 	needsFull := s.clientNeedsFull[id]
 	if needsFull {
 		s.clientNeedsFull[id] = false
 	}
+	sent := s.sentLocked(id, filter, overlay)
 	s.mu.Unlock()
 
 	if needsFull {
 		return s.Full(id)
 	}
 
-	if filter != nil {
-		return s.state.EncodeWithFilter(filter)
+	if filter != nil || overlay != nil || viewer != nil {
+		return s.state.encodeWithFilterFor(filter, overlay, viewer, sent)
 	}
 	return s.state.Encode()
 }
@@ -268,9 +476,22 @@ func (s *TrackedSession[T, A, ID]) Diff(id ID) []byte {
 func (s *TrackedSession[T, A, ID]) Broadcast() map[ID][]byte {
 	s.mu.Lock()
 	clients := make(map[ID]FilterFunc[T], len(s.clients))
+	viewers := make(map[ID]*Viewer, len(s.viewers))
+	overlays := make(map[ID]OverlayFunc[T], len(s.overlays))
+	sents := make(map[ID]*clientSent)
 	needsFullMap := make(map[ID]bool, len(s.clients))
 	for id, filter := range s.clients {
+		filter = s.filterLocked(id, filter)
 		clients[id] = filter
+		if viewer := s.viewers[id]; viewer != nil {
+			viewers[id] = viewer
+		}
+		if overlay := s.overlays[id]; overlay != nil {
+			overlays[id] = overlay
+		}
+		if sent := s.sentLocked(id, filter, overlays[id]); sent != nil {
+			sents[id] = sent
+		}
 		if s.clientNeedsFull[id] {
 			needsFullMap[id] = true
 			s.clientNeedsFull[id] = false
@@ -294,6 +515,9 @@ func (s *TrackedSession[T, A, ID]) Broadcast() map[ID][]byte {
 
 	for id, filter := range clients {
 		needsFull := needsFullMap[id]
+		viewer := viewers[id]
+		overlay := overlays[id]
+		sent := sents[id]
 
 		var data []byte
 
@@ -326,21 +550,28 @@ func (s *TrackedSession[T, A, ID]) Broadcast() map[ID][]byte {
 		// Encode
 		if needsFull {
 			// New client needs full state
-			data = s.state.lockedEncodeAll(state)
-		} else if filter == nil {
+			view := applyOverlay(state, overlay)
+			data = s.state.lockedEncodeAll(view, viewer)
+			if sent != nil {
+				s.state.lockedObserve(view, viewer, sent)
+			}
+		} else if sent != nil {
+			// Filtered diff, including elements and fields whose visibility changed
+			data = s.state.lockedEncodeSince(applyOverlay(state, overlay), viewer, sent)
+		} else if filter == nil && overlay == nil && viewer == nil {
 			// Use cached full diff for unfiltered clients.
 			// Bytes() already returns a copy (safe), so no additional copying needed.
 			if !fullDiffComputed {
-				fullDiff = s.state.lockedEncode(rawState)
+				fullDiff = s.state.lockedEncode(rawState, nil)
 				fullDiffComputed = true
 			}
 			data = fullDiff
 		} else {
-			// Filtered diff
+			// Filtered diff (or restricted by the overlay or the viewer's projections)
 			if !state.Changes().HasChanges() {
 				continue
 			}
-			data = s.state.lockedEncode(state)
+			data = s.state.lockedEncode(applyOverlay(state, overlay), viewer)
 		}
 
 		// Hook: after encode
@@ -370,6 +601,29 @@ func (s *TrackedSession[T, A, ID]) Tick() map[ID][]byte {
 // tickInternal performs the actual tick and returns both diffs and the sequence number
 // atomically, preventing concurrent Tick() calls from causing seq mismatches.
 func (s *TrackedSession[T, A, ID]) tickInternal() (map[ID][]byte, uint64) {
+	s.inputMu.Lock()
+	defer s.inputMu.Unlock()
+
+	// Every tick ages event rate limit windows, whether or not events are drained
+	s.events.Advance()
+
+	// Timed effects that ran out last tick are removed before broadcasting,
+	// so their removal and OnExpire changes go out with this tick
+	for _, e := range s.state.ExpireTimedEffects() {
+		s.emitEffectEnd(e, EffectExpired)
+	}
+
+	// Likewise for registered filters (their clients are resent their view)
+	s.mu.RLock()
+	filters := s.filterRegistry
+	s.mu.RUnlock()
+	if filters != nil {
+		filters.Expire()
+	}
+
+	// Fields of FieldEffects depending on direct mutations are marked before encoding
+	s.state.markDependentFields()
+
 	diffs := s.Broadcast()
 
 	// Store base diff before commit (for reconnection without filter)
@@ -385,27 +639,28 @@ func (s *TrackedSession[T, A, ID]) tickInternal() (map[ID][]byte, uint64) {
 
 	s.state.Commit()
 
+	s.mu.RLock()
+	step := s.effectStep
+	s.mu.RUnlock()
+	s.state.AdvanceEffects(step)
+	if filters != nil {
+		filters.Advance()
+	}
+
 	// Handle sequence and history (under lock)
 	s.mu.Lock()
 	currentSeq := s.seq
 	s.seq++
 
-	// Store in history if enabled and there are changes.
+	// Store in history if enabled and there are changes (filtered clients can get
+	// diffs for visibility changes alone).
 	// Encoder.Bytes() already returns owned copies, so no additional deep copy needed.
-	if s.historySize > 0 && len(baseDiff) > 0 {
-		entry := historyEntry[ID]{
+	if s.historySize > 0 && (len(baseDiff) > 0 || len(diffs) > 0) {
+		s.appendHistoryLocked(historyEntry[ID]{
 			seq:      currentSeq,
 			baseDiff: baseDiff,
 			diffs:    diffs,
-		}
-
-		if len(s.history) < s.historySize {
-			s.history = append(s.history, entry)
-		} else {
-			// Ring buffer: shift left and add at end
-			copy(s.history, s.history[1:])
-			s.history[len(s.history)-1] = entry
-		}
+		})
 	}
 	s.mu.Unlock()
 
@@ -414,9 +669,69 @@ func (s *TrackedSession[T, A, ID]) tickInternal() (map[ID][]byte, uint64) {
 		hooks.OnAfterBroadcast(diffs, baseDiff, currentSeq)
 	}
 
+	s.mu.RLock()
+	auto, writer := s.autoSnapshot, s.autoSnapshotWriter
+	s.mu.RUnlock()
+	if auto != nil && auto.EveryTicks > 0 && currentSeq%auto.EveryTicks == 0 {
+		s.takeAutoSnapshot(auto, writer, currentSeq)
+	}
+
 	return diffs, currentSeq
 }
 
+// appendHistoryLocked adds an entry to the history ring buffer. Caller must hold s.mu.
+func (s *TrackedSession[T, A, ID]) appendHistoryLocked(entry historyEntry[ID]) {
+	if len(s.history) < s.historySize {
+		s.history = append(s.history, entry)
+	} else {
+		// Ring buffer: shift left and add at end
+		copy(s.history, s.history[1:])
+		s.history[len(s.history)-1] = entry
+	}
+}
+
+// historyEntryLocked returns the history entry for seq, adding an empty one if the tick
+// had no changes. Returns nil if history is disabled or seq is older than the history.
+// Caller must hold s.mu.
+func (s *TrackedSession[T, A, ID]) historyEntryLocked(seq uint64) *historyEntry[ID] {
+	if s.historySize == 0 {
+		return nil
+	}
+	i := len(s.history)
+	for i > 0 && s.history[i-1].seq >= seq {
+		i--
+	}
+	if i < len(s.history) && s.history[i].seq == seq {
+		return &s.history[i]
+	}
+	if i == len(s.history) {
+		s.appendHistoryLocked(historyEntry[ID]{seq: seq})
+		return &s.history[len(s.history)-1]
+	}
+	if len(s.history) == s.historySize {
+		// Full: the oldest entry makes room, unless it is the one being inserted
+		if i == 0 {
+			return nil
+		}
+		copy(s.history, s.history[1:i])
+		i--
+	} else {
+		s.history = append(s.history, historyEntry[ID]{})
+		copy(s.history[i+1:], s.history[i:])
+	}
+	s.history[i] = historyEntry[ID]{seq: seq}
+	return &s.history[i]
+}
+
+// reconnectableLocked reports whether a client that left at seq can still reconnect
+// incrementally, so reliable events are worth keeping for it. Caller must hold s.mu.
+func (s *TrackedSession[T, A, ID]) reconnectableLocked(leftAt uint64) bool {
+	if s.historySize == 0 {
+		return false
+	}
+	return len(s.history) == 0 || leftAt > s.history[0].seq
+}
+
 // TickWithSeq performs Tick and returns both diffs and the sequence number.
 // The returned sequence should be sent to clients so they can acknowledge receipt.
 func (s *TrackedSession[T, A, ID]) TickWithSeq() (map[ID][]byte, uint64) {
@@ -431,7 +746,7 @@ func (s *TrackedSession[T, A, ID]) TickWithSeq() (map[ID][]byte, uint64) {
 func (s *TrackedSession[T, A, ID]) GetPendingSince(id ID, sinceSeq uint64) ([][]byte, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.getPendingSince(id, sinceSeq, s.clients[id])
+	return s.getPendingSince(id, sinceSeq, s.filterLocked(id, s.clients[id]))
 }
 
 // getPendingSince is the internal implementation that accepts an explicit filter.
@@ -463,8 +778,12 @@ func (s *TrackedSession[T, A, ID]) getPendingSince(id ID, sinceSeq uint64, clien
 			// Try client-specific diff first (has filter applied)
 			if data, ok := entry.diffs[id]; ok && len(data) > 0 {
 				pending = append(pending, data)
-			} else if clientFilter != nil {
-				// Client has filter but no filtered diff available for this entry -
+			} else if len(entry.baseDiff) == 0 {
+				// Entries holding only diffs of other filtered clients carry no
+				// state change for this client
+				continue
+			} else if clientFilter != nil || s.overlays[id] != nil || s.viewers[id] != nil {
+				// Client has filter, overlay or viewer but no filtered diff available for this entry -
 				// can't safely use unfiltered base diff, client needs full state
 				return nil, false
 			} else if len(entry.baseDiff) > 0 {
@@ -480,11 +799,26 @@ func (s *TrackedSession[T, A, ID]) getPendingSince(id ID, sinceSeq uint64, clien
 // Reconnect handles a client reconnecting with their last known sequence.
 // Returns the data to send and whether it's a full state (true) or incremental updates (false).
 // If updates is nil, there's nothing to send (client is up to date).
+// On an incremental reconnect, unacknowledged reliable events (including those emitted
+// while the client was away) are replayed as a MsgReliableEvents message after the state
+// updates. A full state supersedes them, so they are dropped instead.
 func (s *TrackedSession[T, A, ID]) Reconnect(id ID, lastSeq uint64, filter FilterFunc[T]) (updates [][]byte, isFull bool) {
+	updates, isFull = s.reconnect(id, lastSeq, filter)
+	if isFull {
+		s.mu.Lock()
+		s.dropEventsLocked(id)
+		s.mu.Unlock()
+	} else if events := s.UnackedEvents(id); events != nil {
+		updates = append(updates, events)
+	}
+	return updates, isFull
+}
+
+func (s *TrackedSession[T, A, ID]) reconnect(id ID, lastSeq uint64, filter FilterFunc[T]) (updates [][]byte, isFull bool) {
 	// Try to get incremental updates from history.
 	// Use getPendingSince with the filter directly -- the client isn't in s.clients yet.
 	s.mu.RLock()
-	pending, ok := s.getPendingSince(id, lastSeq, filter)
+	pending, ok := s.getPendingSince(id, lastSeq, s.filterLocked(id, filter))
 	capturedSeq := s.seq - 1
 	s.mu.RUnlock()
 
@@ -493,6 +827,7 @@ func (s *TrackedSession[T, A, ID]) Reconnect(id ID, lastSeq uint64, filter Filte
 		s.mu.Lock()
 		s.clients[id] = filter
 		s.clientNeedsFull[id] = false
+		s.knowClientLocked(id)
 		if len(pending) > 0 {
 			s.clientSeq[id] = lastSeq
 		} else {
@@ -512,6 +847,7 @@ func (s *TrackedSession[T, A, ID]) Reconnect(id ID, lastSeq uint64, filter Filte
 	s.clients[id] = filter
 	s.clientNeedsFull[id] = true
 	s.clientSeq[id] = s.seq - 1
+	s.knowClientLocked(id)
 	s.mu.Unlock()
 
 	fullData := s.Full(id)
@@ -653,16 +989,40 @@ func (s *TrackedSession[T, A, ID]) ApplyUpdate(fn func(*T)) map[ID][]byte {
 	})
 }
 
+// UpdateAs modifies the state on behalf of a client and enforces write permissions
+// (see TrackedState.UpdateAs). Changes are sent with the next Tick.
+func (s *TrackedSession[T, A, ID]) UpdateAs(clientID ID, fn func(*T)) error {
+	return s.state.UpdateAs(ownerKey(clientID), fn)
+}
+
 // Effect management proxies
 
 // AddEffect adds an effect to the underlying state
 func (s *TrackedSession[T, A, ID]) AddEffect(e Effect[T, A], activator A) error {
-	return s.state.AddEffect(e, activator)
+	replaced, err := s.state.addEffect(e, activator, true)
+	if err != nil {
+		return err
+	}
+	for _, old := range replaced {
+		s.emitEffectEnd(old, EffectReplaced)
+	}
+	s.mu.RLock()
+	startEvent := s.effectStartEvent
+	s.mu.RUnlock()
+	if startEvent != "" {
+		s.Emit(startEvent, newEffectEvent(e, e.ID(), ""))
+	}
+	return nil
 }
 
 // RemoveEffect removes an effect from the underlying state
 func (s *TrackedSession[T, A, ID]) RemoveEffect(id string) bool {
-	return s.state.RemoveEffect(id)
+	e := s.state.GetEffect(id)
+	if !s.state.RemoveEffect(id) {
+		return false
+	}
+	s.emitEffectEnd(e, EffectEnded)
+	return true
 }
 
 // HasEffect checks if an effect exists
@@ -675,9 +1035,45 @@ func (s *TrackedSession[T, A, ID]) GetEffect(id string) Effect[T, A] {
 	return s.state.GetEffect(id)
 }
 
+// StackCount returns the number of active effects of a kind
+func (s *TrackedSession[T, A, ID]) StackCount(kind string) int {
+	return s.state.StackCount(kind)
+}
+
 // ClearEffects removes all effects
 func (s *TrackedSession[T, A, ID]) ClearEffects() {
+	effects := s.state.Effects()
 	s.state.ClearEffects()
+	for _, e := range effects {
+		s.emitEffectEnd(e, EffectEnded)
+	}
+}
+
+// SetEffectTimeStep sets the simulated time that passes per Tick for TimedFor effects.
+// Tick-based Timed effects always advance by one per Tick. Default: 0 (time-based effects don't expire).
+func (s *TrackedSession[T, A, ID]) SetEffectTimeStep(dt time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.effectStep = dt
+}
+
+// SetEffectEvents enables events with an EffectEvent payload when effects added or removed
+// through the session start and end. An empty event type disables that event.
+func (s *TrackedSession[T, A, ID]) SetEffectEvents(startType, endType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.effectStartEvent = startType
+	s.effectEndEvent = endType
+}
+
+// emitEffectEnd emits the effect end event if enabled
+func (s *TrackedSession[T, A, ID]) emitEffectEnd(e Effect[T, A], reason string) {
+	s.mu.RLock()
+	endEvent := s.effectEndEvent
+	s.mu.RUnlock()
+	if endEvent != "" {
+		s.Emit(endEvent, newEffectEvent(e, e.ID(), reason))
+	}
 }
 
 // Convenience methods
@@ -792,6 +1188,214 @@ func (s *TrackedSession[T, A, ID]) EmitRawTo(clientID ID, event Event) error {
 	return nil
 }
 
+// EmitRawExcept sends a pre-encoded event to all clients except one.
+func (s *TrackedSession[T, A, ID]) EmitRawExcept(exceptID ID, event Event) error {
+	s.events.Add(PendingEvent[ID]{
+		Event:  event,
+		Target: TargetExcept,
+		Except: exceptID,
+	})
+	return nil
+}
+
+// EmitRawToMany sends a pre-encoded event to multiple specific clients.
+func (s *TrackedSession[T, A, ID]) EmitRawToMany(clientIDs []ID, event Event) error {
+	s.events.Add(PendingEvent[ID]{
+		Event:  event,
+		Target: TargetMany,
+		ToMany: clientIDs,
+	})
+	return nil
+}
+
+// EmitReliable sends a reliable event to all known clients.
+// Reliable events carry a sequence number and are kept in the reconnection history until
+// the client acknowledges them with AckEvents, so they expire with the history (see
+// SetHistorySize and SetReliableEventLimit). Clients that are disconnected get them on an
+// incremental Reconnect; a client is known from Connect until Leave, ForgetClientEvents or
+// until the history no longer covers its disconnect.
+func (s *TrackedSession[T, A, ID]) EmitReliable(eventType string, payload any) error {
+	return s.emitReliable(PendingEvent[ID]{Target: TargetAll}, eventType, payload)
+}
+
+// EmitReliableTo sends a reliable event to a specific client
+func (s *TrackedSession[T, A, ID]) EmitReliableTo(clientID ID, eventType string, payload any) error {
+	return s.emitReliable(PendingEvent[ID]{Target: TargetOne, To: clientID}, eventType, payload)
+}
+
+// EmitReliableExcept sends a reliable event to all clients except one
+func (s *TrackedSession[T, A, ID]) EmitReliableExcept(exceptID ID, eventType string, payload any) error {
+	return s.emitReliable(PendingEvent[ID]{Target: TargetExcept, Except: exceptID}, eventType, payload)
+}
+
+// EmitReliableToMany sends a reliable event to multiple specific clients
+func (s *TrackedSession[T, A, ID]) EmitReliableToMany(clientIDs []ID, eventType string, payload any) error {
+	return s.emitReliable(PendingEvent[ID]{Target: TargetMany, ToMany: clientIDs}, eventType, payload)
+}
+
+func (s *TrackedSession[T, A, ID]) emitReliable(pe PendingEvent[ID], eventType string, payload any) error {
+	encoded, err := encodePayload(payload)
+	if err != nil {
+		return err
+	}
+	pe.Event = Event{Type: eventType, Payload: encoded}
+	pe.Reliable = true
+	s.events.Add(pe)
+	return nil
+}
+
+// AckEvents acknowledges that a client has received reliable events up to seq.
+// Acknowledged events are dropped.
+func (s *TrackedSession[T, A, ID]) AckEvents(id ID, seq uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.history {
+		events, ok := s.history[i].events[id]
+		if !ok {
+			continue
+		}
+		kept := events[:0]
+		for _, e := range events {
+			if e.Seq > seq {
+				kept = append(kept, e)
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.history[i].events, id)
+		} else {
+			s.history[i].events[id] = kept
+		}
+	}
+}
+
+// UnackedEvents returns all reliable events for a client that are not yet acknowledged,
+// encoded as one MsgReliableEvents message (nil if none).
+// Reconnect already includes them; clients drop events they have already seen by sequence.
+func (s *TrackedSession[T, A, ID]) UnackedEvents(id ID) []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var events []ReliableEvent
+	for _, entry := range s.history {
+		events = append(events, entry.events[id]...)
+	}
+	return EncodeReliableEvents(events)
+}
+
+// SetReliableEventLimit sets how many unacknowledged reliable events are kept per client
+// (default DefaultReliableEventLimit, 0 = unlimited). The oldest are dropped first.
+func (s *TrackedSession[T, A, ID]) SetReliableEventLimit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reliableLimit = n
+}
+
+// ForgetClientEvents drops the unacknowledged reliable events of a client and stops storing
+// new ones for it until it connects again. Leave does this for clients that won't come back.
+func (s *TrackedSession[T, A, ID]) ForgetClientEvents(id ID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.forgetEventsLocked(id)
+}
+
+// forgetEventsLocked stops storing reliable events for a client. Caller must hold s.mu.
+func (s *TrackedSession[T, A, ID]) forgetEventsLocked(id ID) {
+	delete(s.eventClients, id)
+	s.dropEventsLocked(id)
+}
+
+// dropEventsLocked drops the stored reliable events of a client. Caller must hold s.mu.
+func (s *TrackedSession[T, A, ID]) dropEventsLocked(id ID) {
+	for i := range s.history {
+		delete(s.history[i].events, id)
+	}
+}
+
+// EmitTopic sends an event to all connected clients subscribed to a topic
+func (s *TrackedSession[T, A, ID]) EmitTopic(topic string, eventType string, payload any) error {
+	encoded, err := encodePayload(payload)
+	if err != nil {
+		return err
+	}
+	s.events.Add(PendingEvent[ID]{
+		Event:  Event{Type: eventType, Payload: encoded},
+		Target: TargetTopic,
+		Topic:  topic,
+	})
+	return nil
+}
+
+// EmitReliableTopic sends a reliable event to all known clients subscribed to a topic
+func (s *TrackedSession[T, A, ID]) EmitReliableTopic(topic string, eventType string, payload any) error {
+	return s.emitReliable(PendingEvent[ID]{Target: TargetTopic, Topic: topic}, eventType, payload)
+}
+
+// Subscribe adds topic subscriptions for a client (e.g. "team:red", "chat:global").
+// Subscriptions can be made before the client connects and survive reconnects.
+func (s *TrackedSession[T, A, ID]) Subscribe(id ID, topics ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subs := s.subscriptions[id]
+	if subs == nil {
+		subs = make(map[string]struct{}, len(topics))
+		s.subscriptions[id] = subs
+	}
+	for _, topic := range topics {
+		subs[topic] = struct{}{}
+	}
+}
+
+// Unsubscribe removes topic subscriptions for a client
+func (s *TrackedSession[T, A, ID]) Unsubscribe(id ID, topics ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subs := s.subscriptions[id]
+	for _, topic := range topics {
+		delete(subs, topic)
+	}
+	if len(subs) == 0 {
+		delete(s.subscriptions, id)
+	}
+}
+
+// ClearSubscriptions removes all topic subscriptions for a client.
+// Call this when a client leaves for good.
+func (s *TrackedSession[T, A, ID]) ClearSubscriptions(id ID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscriptions, id)
+}
+
+// Subscriptions returns the topics a client is subscribed to, sorted
+func (s *TrackedSession[T, A, ID]) Subscriptions(id ID) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	topics := make([]string, 0, len(s.subscriptions[id]))
+	for topic := range s.subscriptions[id] {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// IsSubscribed checks if a client is subscribed to a topic
+func (s *TrackedSession[T, A, ID]) IsSubscribed(id ID, topic string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.subscriptions[id][topic]
+	return ok
+}
+
+// SetEventPolicy bounds events of one type (coalesce, rate limit or aggregate).
+// Example: session.SetEventPolicy("ScoreChanged", CoalesceEvents())
+func (s *TrackedSession[T, A, ID]) SetEventPolicy(eventType string, policy EventPolicy) {
+	s.events.SetPolicy(eventType, policy)
+}
+
+// ClearEventPolicy removes the policy for an event type
+func (s *TrackedSession[T, A, ID]) ClearEventPolicy(eventType string) {
+	s.events.ClearPolicy(eventType)
+}
+
 // PendingEvents returns the number of events waiting to be broadcast
 func (s *TrackedSession[T, A, ID]) PendingEvents() int {
 	return s.events.Count()
@@ -811,6 +1415,9 @@ type TickResult[ID comparable] struct {
 	// Multiple events are batched into a single []byte using EncodeEventBatch
 	Events map[ID][]byte
 
+	// ReliableEvents contains encoded reliable events per client (MsgReliableEvents)
+	ReliableEvents map[ID][]byte
+
 	// Seq is the sequence number for this tick
 	Seq uint64
 }
@@ -839,57 +1446,178 @@ func (s *TrackedSession[T, A, ID]) TickWithEvents() TickResult[ID] {
 		return TickResult[ID]{Diffs: diffs, Seq: seq}
 	}
 
-	// Get client list under lock
-	s.mu.RLock()
+	// Get client lists and assign reliable sequence numbers under lock.
+	// Reliable events also go to known clients that are disconnected.
+	s.mu.Lock()
 	clientIDs := make([]ID, 0, len(s.clients))
 	for id := range s.clients {
 		clientIDs = append(clientIDs, id)
 	}
-	s.mu.RUnlock()
+	recipientIDs := clientIDs
+	for id, leftAt := range s.eventClients {
+		if leftAt == 0 {
+			continue
+		}
+		if !s.reconnectableLocked(leftAt) {
+			// Gone longer than the history covers: a Reconnect gets full state anyway
+			s.forgetEventsLocked(id)
+			continue
+		}
+		recipientIDs = append(recipientIDs[:len(recipientIDs):len(recipientIDs)], id)
+	}
+	eventSeqs := make([]uint64, len(pending))
+	var topicClients map[string][]ID
+	for i, pe := range pending {
+		if pe.Reliable {
+			s.eventSeq++
+			eventSeqs[i] = s.eventSeq
+		}
+		if pe.Target == TargetTopic {
+			if topicClients == nil {
+				topicClients = make(map[string][]ID)
+			}
+			if _, ok := topicClients[pe.Topic]; !ok {
+				topicClients[pe.Topic] = s.topicClientsLocked(recipientIDs, pe.Topic)
+			}
+		}
+	}
+	s.mu.Unlock()
 
-	// Build client lookup set for TargetOne/TargetMany validation
+	// Build lookup sets for TargetOne/TargetMany validation
 	clientSet := make(map[ID]struct{}, len(clientIDs))
 	for _, id := range clientIDs {
 		clientSet[id] = struct{}{}
 	}
+	recipientSet := make(map[ID]struct{}, len(recipientIDs))
+	for _, id := range recipientIDs {
+		recipientSet[id] = struct{}{}
+	}
 
-	// Group events by client
+	// Group events by recipient
 	clientEvents := make(map[ID][]Event, len(clientIDs))
-	for _, pe := range pending {
+	var stored map[ID][]ReliableEvent
+	for i, pe := range pending {
+		deliver := func(id ID) {
+			if pe.Reliable {
+				if stored == nil {
+					stored = make(map[ID][]ReliableEvent)
+				}
+				stored[id] = append(stored[id], ReliableEvent{Seq: eventSeqs[i], Event: pe.Event})
+				return
+			}
+			if _, connected := clientSet[id]; connected {
+				clientEvents[id] = append(clientEvents[id], pe.Event)
+			}
+		}
+
 		switch pe.Target {
 		case TargetAll:
-			for _, id := range clientIDs {
-				clientEvents[id] = append(clientEvents[id], pe.Event)
+			for _, id := range recipientIDs {
+				deliver(id)
 			}
 		case TargetOne:
-			if _, ok := clientSet[pe.To]; ok {
-				clientEvents[pe.To] = append(clientEvents[pe.To], pe.Event)
+			if _, ok := recipientSet[pe.To]; ok {
+				deliver(pe.To)
 			}
 		case TargetExcept:
-			for _, id := range clientIDs {
+			for _, id := range recipientIDs {
 				if id != pe.Except {
-					clientEvents[id] = append(clientEvents[id], pe.Event)
+					deliver(id)
 				}
 			}
 		case TargetMany:
 			for _, id := range pe.ToMany {
-				if _, ok := clientSet[id]; ok {
-					clientEvents[id] = append(clientEvents[id], pe.Event)
+				if _, ok := recipientSet[id]; ok {
+					deliver(id)
 				}
 			}
+		case TargetTopic:
+			for _, id := range topicClients[pe.Topic] {
+				deliver(id)
+			}
 		}
 	}
 
-	// Encode events per client
+	// Coalesce, aggregate and rate limit per recipient, then encode events per client
+	s.events.ApplyPolicies(clientEvents, stored)
 	events := make(map[ID][]byte, len(clientEvents))
 	for id, evts := range clientEvents {
 		events[id] = EncodeEventBatch(evts)
 	}
 
+	var reliable map[ID][]byte
+	if len(stored) > 0 {
+		s.storeReliableEvents(seq, stored)
+		for id, evts := range stored {
+			if _, connected := clientSet[id]; connected {
+				if reliable == nil {
+					reliable = make(map[ID][]byte)
+				}
+				reliable[id] = EncodeReliableEvents(evts)
+			}
+		}
+	}
+
 	return TickResult[ID]{
-		Diffs:  diffs,
-		Events: events,
-		Seq:    seq,
+		Diffs:          diffs,
+		Events:         events,
+		ReliableEvents: reliable,
+		Seq:            seq,
+	}
+}
+
+// topicClientsLocked returns the connected clients subscribed to a topic. Caller must hold s.mu.
+func (s *TrackedSession[T, A, ID]) topicClientsLocked(clientIDs []ID, topic string) []ID {
+	var ids []ID
+	for _, id := range clientIDs {
+		if _, ok := s.subscriptions[id][topic]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// storeReliableEvents keeps reliable events in the history entry of the tick they were
+// sent in, until their recipients acknowledge them or the entry leaves the history
+func (s *TrackedSession[T, A, ID]) storeReliableEvents(seq uint64, events map[ID][]ReliableEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.historyEntryLocked(seq)
+	if entry == nil {
+		return
+	}
+	for id, evts := range events {
+		// Skip clients forgotten (Leave, ForgetClientEvents) since the events were grouped
+		if _, ok := s.eventClients[id]; !ok {
+			continue
+		}
+		if entry.events == nil {
+			entry.events = make(map[ID][]ReliableEvent)
+		}
+		entry.events[id] = append(entry.events[id], evts...)
+		s.limitEventsLocked(id)
+	}
+}
+
+// limitEventsLocked drops a client's oldest reliable events beyond reliableLimit.
+// Caller must hold s.mu.
+func (s *TrackedSession[T, A, ID]) limitEventsLocked(id ID) {
+	if s.reliableLimit <= 0 {
+		return
+	}
+	kept := 0
+	for i := len(s.history) - 1; i >= 0; i-- {
+		events := s.history[i].events[id]
+		switch {
+		case len(events) == 0:
+		case kept == s.reliableLimit:
+			delete(s.history[i].events, id)
+		case kept+len(events) > s.reliableLimit:
+			s.history[i].events[id] = events[len(events)-(s.reliableLimit-kept):]
+			kept = s.reliableLimit
+		default:
+			kept += len(events)
+		}
 	}
 }
 
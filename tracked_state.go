@@ -1,7 +1,10 @@
 package statesync
 
 import (
+	"fmt"
+	"sort"
 	"sync"
+	"time"
 )
 
 // TrackedState manages state with automatic change tracking
@@ -10,13 +13,47 @@ type TrackedState[T Trackable, A any] struct {
 	mu          sync.RWMutex
 	current     T
 	effects     []Effect[T, A]
-	encoderPool sync.Pool // pool of *Encoder instances (eliminates encoder lock contention)
+	effectSeq   uint64            // Last assigned effect insertion sequence
+	effectAdded map[string]uint64 // Insertion sequence per active effect ID (oldest stacks are evicted first)
+	encoderPool sync.Pool         // pool of *Encoder instances (eliminates encoder lock contention)
 	registry    *SchemaRegistry
+
+	// Incremental effect application (see TrackedConfig.CacheEffects)
+	cacheEffects bool
+	effMu        sync.Mutex
+	effOuts      []T // Output of each effect in application order
+	effValid     int // Number of valid entries in effOuts (prefix)
+
+	writeAudit func(WriteAudit) // Called for checked writes in UpdateAs
+	migrations *MigrationRegistry
 }
 
 // TrackedConfig configuration for TrackedState
 type TrackedConfig struct {
 	Registry *SchemaRegistry
+
+	// CacheEffects caches the output of each effect between mutations, so Get only
+	// re-applies effects from the first one whose input changed and nothing when neither
+	// the base state nor the effects changed. After a base mutation, the cached output of
+	// leading FieldEffects that don't read a changed field is reused if the state type
+	// implements FieldCopier: their written fields are copied onto the new base state.
+	// Otherwise all effects are re-applied.
+	// Only enable it if the base state is mutated exclusively through TrackedState
+	// (Update, UpdateInPlace, Set) or InvalidateEffects is called after direct mutations,
+	// and effects return states sharing the base ChangeSet.
+	CacheEffects bool
+
+	// Migrations sets the version snapshots are saved with and upgrades older
+	// snapshots in Restore (nil: no migrations, see MigrationRegistry)
+	Migrations *MigrationRegistry
+}
+
+// migrationRegistry returns the configured migrations (nil for a nil config)
+func (c *TrackedConfig) migrationRegistry() *MigrationRegistry {
+	if c == nil {
+		return nil
+	}
+	return c.Migrations
 }
 
 // NewTrackedState creates a new TrackedState
@@ -30,9 +67,11 @@ func NewTrackedState[T Trackable, A any](initial T, cfg *TrackedConfig) *Tracked
 	}
 
 	ts := &TrackedState[T, A]{
-		current:  initial,
-		effects:  make([]Effect[T, A], 0),
-		registry: registry,
+		current:      initial,
+		effects:      make([]Effect[T, A], 0),
+		registry:     registry,
+		cacheEffects: cfg != nil && cfg.CacheEffects,
+		migrations:   cfg.migrationRegistry(),
 	}
 	ts.encoderPool.New = func() interface{} {
 		return NewEncoder(registry)
@@ -65,6 +104,60 @@ func (s *TrackedState[T, A]) Update(fn func(*T)) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	fn(&s.current)
+	s.baseChangedLocked()
+}
+
+// UpdateAs modifies the state on behalf of a client (e.g. for a command) and enforces
+// the schema's write permissions (see FieldMeta.Write). An empty clientID is the server.
+// For a client, the state is cloned first (see Cloner; ErrNotCloneable if T isn't one),
+// fn runs on the state and changed fields with a permission are compared with the clone.
+// On a *PermissionError the clone is copied back into the state, so nothing of fn reaches
+// clients; the root keeps its identity, nested values are the clone's.
+func (s *TrackedState[T, A]) UpdateAs(clientID string, fn func(*T)) error {
+	s.mu.Lock()
+	schema := s.current.Schema()
+	if clientID == "" || !isWriteRestricted(schema) {
+		fn(&s.current)
+		s.baseChangedLocked()
+		s.mu.Unlock()
+		return nil
+	}
+	cloner, ok := any(s.current).(Cloner[T])
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %T", ErrNotCloneable, s.current)
+	}
+
+	backup := cloner.Clone()
+	before := snapshotWrites(backup, schema, false)
+	fn(&s.current)
+	after := snapshotWrites(s.current, s.current.Schema(), false)
+
+	check := &writeCheck{clientID: clientID}
+	check.checkStruct(before, after, schema, "", "", false)
+	if check.denied != nil {
+		restoreState(&s.current, backup)
+	}
+	s.baseChangedLocked()
+	audit := s.writeAudit
+	s.mu.Unlock()
+
+	if audit != nil {
+		for _, a := range check.audits {
+			audit(a)
+		}
+	}
+	if check.denied != nil {
+		return check.denied
+	}
+	return nil
+}
+
+// SetWriteAudit sets a hook called for every write UpdateAs checks (allowed or denied)
+func (s *TrackedState[T, A]) SetWriteAudit(fn func(WriteAudit)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writeAudit = fn
 }
 
 // UpdateInPlace provides write access to the current state value directly.
@@ -74,6 +167,7 @@ func (s *TrackedState[T, A]) UpdateInPlace(fn func(T)) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	fn(s.current)
+	s.baseChangedLocked()
 }
 
 // Read provides safe read access to the state.
@@ -99,6 +193,7 @@ func (s *TrackedState[T, A]) Set(newState T) {
 	defer s.mu.Unlock()
 	s.current = newState
 	s.current.MarkAllDirty()
+	s.invalidateEffectsFrom(0)
 }
 
 // Encode returns the binary encoded changes
@@ -123,8 +218,35 @@ func (s *TrackedState[T, A]) EncodeAll() []byte {
 	return s.poolEncodeAll(state)
 }
 
+// EncodeFor encodes the changes visible to viewer (see FieldMeta.Projections)
+func (s *TrackedState[T, A]) EncodeFor(viewer *Viewer) []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state := s.withEffects(s.current)
+	if !state.Changes().HasChanges() {
+		return nil
+	}
+	return s.lockedEncode(state, viewer)
+}
+
+// EncodeAllFor encodes the full state for viewer (hidden fields are zero)
+func (s *TrackedState[T, A]) EncodeAllFor(viewer *Viewer) []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state := s.withEffects(s.current)
+	return s.lockedEncodeAll(state, viewer)
+}
+
 // EncodeWithFilter encodes with a filter function
 func (s *TrackedState[T, A]) EncodeWithFilter(filter func(T) T) []byte {
+	return s.encodeWithFilterFor(filter, nil, nil, nil)
+}
+
+// encodeWithFilterFor encodes the changes of the filtered (and overlaid) state visible to viewer.
+// With sent, changes are relative to what the client was last sent (see encodeSince).
+func (s *TrackedState[T, A]) encodeWithFilterFor(filter func(T) T, overlay OverlayFunc[T], viewer *Viewer, sent *clientSent) []byte {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -132,10 +254,16 @@ func (s *TrackedState[T, A]) EncodeWithFilter(filter func(T) T) []byte {
 	if filter != nil {
 		state = filter(state)
 	}
-	if isNilTrackable(state) || !state.Changes().HasChanges() {
+	if isNilTrackable(state) {
 		return nil
 	}
-	return s.poolEncode(state)
+	if sent != nil {
+		return s.lockedEncodeSince(applyOverlay(state, overlay), viewer, sent)
+	}
+	if !state.Changes().HasChanges() {
+		return nil
+	}
+	return s.lockedEncode(applyOverlay(state, overlay), viewer)
 }
 
 // EncodeAllWithFilter encodes full state with filter
@@ -169,14 +297,37 @@ func (s *TrackedState[T, A]) poolEncodeAll(state Trackable) []byte {
 	return data
 }
 
-// lockedEncode encodes changes for a pre-resolved state using the encoder pool.
-func (s *TrackedState[T, A]) lockedEncode(state Trackable) []byte {
-	return s.poolEncode(state)
+// lockedEncode encodes changes visible to viewer for a pre-resolved state using the encoder pool.
+func (s *TrackedState[T, A]) lockedEncode(state Trackable, viewer *Viewer) []byte {
+	enc := s.encoderPool.Get().(*Encoder)
+	data := enc.EncodeFor(state, viewer)
+	s.encoderPool.Put(enc)
+	return data
 }
 
-// lockedEncodeAll encodes full state for a pre-resolved state using the encoder pool.
-func (s *TrackedState[T, A]) lockedEncodeAll(state Trackable) []byte {
-	return s.poolEncodeAll(state)
+// lockedEncodeAll encodes full state for viewer for a pre-resolved state using the encoder pool.
+func (s *TrackedState[T, A]) lockedEncodeAll(state Trackable, viewer *Viewer) []byte {
+	enc := s.encoderPool.Get().(*Encoder)
+	data := enc.EncodeAllFor(state, viewer)
+	s.encoderPool.Put(enc)
+	return data
+}
+
+// lockedEncodeSince encodes changes visible to viewer relative to what a filtered client was last sent
+func (s *TrackedState[T, A]) lockedEncodeSince(state Trackable, viewer *Viewer, sent *clientSent) []byte {
+	enc := s.encoderPool.Get().(*Encoder)
+	data := enc.encodeSince(state, viewer, sent.lastView(), sent.next)
+	s.encoderPool.Put(enc)
+	sent.swap()
+	return data
+}
+
+// lockedObserve records what a full state encoding for viewer sends to a filtered client
+func (s *TrackedState[T, A]) lockedObserve(state Trackable, viewer *Viewer, sent *clientSent) {
+	enc := s.encoderPool.Get().(*Encoder)
+	enc.observe(state, viewer, sent.next)
+	s.encoderPool.Put(enc)
+	sent.swap()
 }
 
 // Commit clears all tracked changes
@@ -198,32 +349,123 @@ func (s *TrackedState[T, A]) HasChanges() bool {
 
 // AddEffect adds an effect to the state.
 // If the effect implements Activatable, OnActivate is called once with write access to the base state.
+// Stackable, Exclusive and Prioritized effects are resolved against the active effects first:
+// replaced effects are removed, and the new effect is placed by priority.
 func (s *TrackedState[T, A]) AddEffect(e Effect[T, A], activator A) error {
+	_, err := s.addEffect(e, activator, true)
+	return err
+}
+
+// addEffect adds an effect and returns the active effects it replaced.
+// activate is false when restoring effects whose OnActivate already ran before the snapshot.
+func (s *TrackedState[T, A]) addEffect(e Effect[T, A], activator A, activate bool) ([]Effect[T, A], error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for _, existing := range s.effects {
-		if existing.ID() == e.ID() {
-			return ErrEffectExists
-		}
+	replaced, refresh, err := s.resolveEffectLocked(e)
+	if err != nil {
+		return nil, err
+	}
+	for _, old := range replaced {
+		s.removeEffectLocked(old.ID())
 	}
 
 	e.SetActivator(activator)
-	s.effects = append(s.effects, e)
+	s.insertEffectLocked(e)
+	s.markEffectWritesLocked(e)
 
 	// Call OnActivate for one-time setup (notifications, initial values, etc.)
-	if act, ok := any(e).(Activatable[T, A]); ok {
+	// A refreshed stack is the same logical effect, so it isn't activated again.
+	if act, ok := any(e).(Activatable[T, A]); ok && activate && !refresh {
 		s.current = act.OnActivate(s.current, activator)
+		s.baseChangedLocked()
 	}
 
-	return nil
+	return replaced, nil
+}
+
+// resolveEffectLocked checks a new effect against the active ones and returns the
+// effects it replaces. refresh is true if it refreshes an active stack. Caller must hold s.mu.
+func (s *TrackedState[T, A]) resolveEffectLocked(e Effect[T, A]) (replaced []Effect[T, A], refresh bool, err error) {
+	kind, maxStacks, behavior := effectStacking(e)
+	group, strength := effectGroup(e)
+
+	var stacks []Effect[T, A]
+	for _, existing := range s.effects {
+		if group != "" {
+			if g, st := effectGroup(existing); g == group {
+				// The incumbent wins ties
+				if st >= strength {
+					return nil, false, ErrEffectWeaker
+				}
+				replaced = append(replaced, existing)
+				continue
+			}
+		}
+		if kind != "" {
+			if k, _, _ := effectStacking(existing); k == kind {
+				stacks = append(stacks, existing)
+				continue
+			}
+		}
+		if existing.ID() == e.ID() {
+			return nil, false, ErrEffectExists
+		}
+	}
+
+	if len(stacks) == 0 {
+		return replaced, false, nil
+	}
+	switch behavior {
+	case StackRefresh:
+		return append(replaced, stacks...), true, nil
+	case StackAdd:
+		for _, st := range stacks {
+			if st.ID() == e.ID() {
+				return nil, false, ErrEffectExists
+			}
+		}
+		if maxStacks > 0 && len(stacks) >= maxStacks {
+			// Evict the oldest stacks, whatever their priority
+			sort.SliceStable(stacks, func(i, j int) bool {
+				return s.effectAdded[stacks[i].ID()] < s.effectAdded[stacks[j].ID()]
+			})
+			replaced = append(replaced, stacks[:len(stacks)-maxStacks+1]...)
+		}
+		return replaced, false, nil
+	default:
+		return nil, false, ErrEffectExists
+	}
+}
+
+// insertEffectLocked inserts an effect after all effects with lower or equal priority. Caller must hold s.mu.
+func (s *TrackedState[T, A]) insertEffectLocked(e Effect[T, A]) {
+	priority := effectPriority(e)
+	i := len(s.effects)
+	for i > 0 && effectPriority(s.effects[i-1]) > priority {
+		i--
+	}
+	s.effects = append(s.effects, nil)
+	copy(s.effects[i+1:], s.effects[i:])
+	s.effects[i] = e
+	s.invalidateEffectsFrom(i)
+
+	if s.effectAdded == nil {
+		s.effectAdded = make(map[string]uint64)
+	}
+	s.effectSeq++
+	s.effectAdded[e.ID()] = s.effectSeq
 }
 
 // RemoveEffect removes an effect by ID
 func (s *TrackedState[T, A]) RemoveEffect(id string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.removeEffectLocked(id)
+}
 
+// removeEffectLocked removes an effect by ID and cancels its timer. Caller must hold s.mu.
+func (s *TrackedState[T, A]) removeEffectLocked(id string) bool {
 	for i, e := range s.effects {
 		if e.ID() == id {
 			// Cancel any scheduled expiration timer
@@ -231,6 +473,9 @@ func (s *TrackedState[T, A]) RemoveEffect(id string) bool {
 				sched.CancelScheduledExpiration()
 			}
 			s.effects = append(s.effects[:i], s.effects[i+1:]...)
+			delete(s.effectAdded, id)
+			s.markEffectWritesLocked(e)
+			s.invalidateEffectsFrom(i)
 			return true
 		}
 	}
@@ -272,11 +517,28 @@ func (s *TrackedState[T, A]) ClearEffects() {
 		if sched, ok := any(e).(Schedulable); ok {
 			sched.CancelScheduledExpiration()
 		}
+		s.markEffectWritesLocked(e)
 	}
 	s.effects = make([]Effect[T, A], 0)
+	s.effectAdded = nil
+	s.invalidateEffectsFrom(0)
+}
+
+// StackCount returns the number of active effects of a kind (see Stackable)
+func (s *TrackedState[T, A]) StackCount(kind string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, e := range s.effects {
+		if k, _, _ := effectStacking(e); k == kind && kind != "" {
+			count++
+		}
+	}
+	return count
 }
 
-// Effects returns a copy of all active effects
+// Effects returns a copy of all active effects in application order
 func (s *TrackedState[T, A]) Effects() []Effect[T, A] {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -287,24 +549,62 @@ func (s *TrackedState[T, A]) Effects() []Effect[T, A] {
 	return append([]Effect[T, A]{}, s.effects...)
 }
 
-// CleanupExpired removes all expired effects.
+// CleanupExpired removes all expired effects, calling their ExpireHook.
 // Returns the number of effects removed.
 func (s *TrackedState[T, A]) CleanupExpired() int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return len(s.removeExpiredLocked(false))
+}
+
+// AdvanceEffects advances all TickDriven effects by one tick and dt of simulated time.
+// Expired effects stay active until ExpireTimedEffects (or CleanupExpired) removes them.
+func (s *TrackedState[T, A]) AdvanceEffects(dt time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	first := -1
+	for i, e := range s.effects {
+		if td, ok := any(e).(TickDriven); ok {
+			td.Advance(dt)
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+	if first >= 0 {
+		s.invalidateEffectsFrom(first)
+	}
+}
+
+// ExpireTimedEffects removes expired TickDriven effects, calling their ExpireHook.
+// Returns the removed effects in application order.
+func (s *TrackedState[T, A]) ExpireTimedEffects() []Effect[T, A] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.removeExpiredLocked(true)
+}
 
+// removeExpiredLocked removes expired effects (only TickDriven ones if tickOnly) and
+// returns them. Caller must hold s.mu.
+func (s *TrackedState[T, A]) removeExpiredLocked(tickOnly bool) []Effect[T, A] {
 	if len(s.effects) == 0 {
-		return 0
+		return nil
 	}
 
-	removed := 0
+	var removed []Effect[T, A]
 	active := s.effects[:0]
-	for _, e := range s.effects {
-		if exp, ok := any(e).(Expirable); ok && exp.Expired() {
+	for i, e := range s.effects {
+		exp, ok := any(e).(Expirable)
+		if _, timed := any(e).(TickDriven); ok && (timed || !tickOnly) && exp.Expired() {
 			if sched, ok := any(e).(Schedulable); ok {
 				sched.CancelScheduledExpiration()
 			}
-			removed++
+			if removed == nil {
+				s.invalidateEffectsFrom(i)
+			}
+			removed = append(removed, e)
+			delete(s.effectAdded, e.ID())
+			s.markEffectWritesLocked(e)
 			continue
 		}
 		active = append(active, e)
@@ -315,16 +615,163 @@ func (s *TrackedState[T, A]) CleanupExpired() int {
 	}
 	s.effects = active
 
+	for _, e := range removed {
+		if hook, ok := any(e).(ExpireHook[T, A]); ok {
+			s.current = hook.OnExpire(s.current, e.Activator())
+			s.baseChangedLocked()
+		}
+	}
 	return removed
 }
 
-// withEffects applies all effects to the state
+// InvalidateEffects drops cached effect outputs (see TrackedConfig.CacheEffects) and marks
+// the fields of FieldEffects depending on changed fields.
+// Call it after mutating the base state directly instead of through Update.
+func (s *TrackedState[T, A]) InvalidateEffects() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.markDependentFieldsLocked()
+	s.invalidateEffectsFrom(0)
+}
+
+// baseChangedLocked is called after a mutation of the base state: it marks the fields of
+// FieldEffects depending on changed fields and drops the cached outputs of effects whose
+// inputs changed. Caller must hold s.mu.
+func (s *TrackedState[T, A]) baseChangedLocked() {
+	s.markDependentFieldsLocked()
+	if !s.cacheEffects {
+		return
+	}
+
+	// Leading FieldEffects that don't read a dirty field keep their output
+	cs := s.current.Changes()
+	keep := 0
+	for _, e := range s.effects {
+		reads, _, ok := effectFields(e)
+		if !ok || cs == nil || anyFieldDirty(cs, reads) {
+			break
+		}
+		keep++
+	}
+
+	s.effMu.Lock()
+	defer s.effMu.Unlock()
+	if keep > s.effValid {
+		keep = s.effValid
+	}
+	// Cached outputs hold the old base state: copy the kept effects' writes onto the new one
+	var written []uint16
+	for i := 0; i < keep; i++ {
+		_, writes, _ := effectFields(s.effects[i])
+		written = append(written, writes...)
+		out, ok := rebaseState(s.current, s.effOuts[i], written)
+		if !ok {
+			keep = 0
+			break
+		}
+		s.effOuts[i] = out
+	}
+	s.effValid = keep
+}
+
+// invalidateEffectsFrom drops cached effect outputs from effect index i onwards
+func (s *TrackedState[T, A]) invalidateEffectsFrom(i int) {
+	if !s.cacheEffects {
+		return
+	}
+	s.effMu.Lock()
+	if i < s.effValid {
+		s.effValid = i
+	}
+	s.effMu.Unlock()
+}
+
+// markEffectWritesLocked marks the fields written by a FieldEffect as changed,
+// so clients receive the effect's output when it starts or ends. Caller must hold s.mu.
+func (s *TrackedState[T, A]) markEffectWritesLocked(e Effect[T, A]) {
+	if _, writes, ok := effectFields(e); ok {
+		markFields(s.current.Changes(), writes)
+	}
+}
+
+// markDependentFields is markDependentFieldsLocked for callers not holding s.mu
+func (s *TrackedState[T, A]) markDependentFields() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.markDependentFieldsLocked()
+}
+
+// markDependentFieldsLocked marks the fields written by FieldEffects whose read fields changed.
+// Effects are visited in application order, so chained dependencies propagate. Caller must hold s.mu.
+func (s *TrackedState[T, A]) markDependentFieldsLocked() {
+	if len(s.effects) == 0 || isNilTrackable(s.current) {
+		return
+	}
+	cs := s.current.Changes()
+	if cs == nil || !cs.HasChanges() {
+		return
+	}
+	for _, e := range s.effects {
+		if reads, writes, ok := effectFields(e); ok && anyFieldDirty(cs, reads) {
+			markFields(cs, writes)
+		}
+	}
+}
+
+// anyFieldDirty reports whether any of the fields is dirty
+func anyFieldDirty(cs *ChangeSet, fields []uint16) bool {
+	for _, idx := range fields {
+		if cs.IsWideFieldDirty(idx) {
+			return true
+		}
+	}
+	return false
+}
+
+// markFields marks fields as replaced, keeping existing operations of dirty fields
+func markFields(cs *ChangeSet, fields []uint16) {
+	if cs == nil {
+		return
+	}
+	for _, idx := range fields {
+		if !cs.IsWideFieldDirty(idx) {
+			cs.MarkWide(idx, OpReplace)
+		}
+	}
+}
+
+// withEffects applies all effects to the state in priority order
 // Note: This creates a copy to avoid mutating the base state
 func (s *TrackedState[T, A]) withEffects(state T) T {
+	if len(s.effects) == 0 {
+		return state
+	}
+
+	if !s.cacheEffects {
+		result := state
+		for _, e := range s.effects {
+			result = e.Apply(result, e.Activator())
+		}
+		return result
+	}
+
+	s.effMu.Lock()
+	defer s.effMu.Unlock()
+	if len(s.effOuts) != len(s.effects) {
+		outs := make([]T, len(s.effects))
+		copy(outs, s.effOuts[:s.effValid])
+		s.effOuts = outs
+	}
 	result := state
-	for _, e := range s.effects {
+	if s.effValid > 0 {
+		result = s.effOuts[s.effValid-1]
+	}
+	for i := s.effValid; i < len(s.effects); i++ {
+		e := s.effects[i]
 		result = e.Apply(result, e.Activator())
+		s.effOuts[i] = result
 	}
+	s.effValid = len(s.effects)
 	return result
 }
 
@@ -333,6 +780,11 @@ func (s *TrackedState[T, A]) Registry() *SchemaRegistry {
 	return s.registry
 }
 
+// Migrations returns the migration registry from TrackedConfig (nil if none)
+func (s *TrackedState[T, A]) Migrations() *MigrationRegistry {
+	return s.migrations
+}
+
 // ErrEffectExists is returned when adding a duplicate effect
 var ErrEffectExists = &DuplicateEffectError{}
 
@@ -342,3 +794,13 @@ type DuplicateEffectError struct{}
 func (e *DuplicateEffectError) Error() string {
 	return "effect with this ID already exists"
 }
+
+// ErrEffectWeaker is returned when adding an exclusive effect that is not stronger than the active one in its group
+var ErrEffectWeaker = &EffectConflictError{}
+
+// EffectConflictError indicates an equally strong or stronger effect in the same exclusive group is active
+type EffectConflictError struct{}
+
+func (e *EffectConflictError) Error() string {
+	return "an effect at least as strong in the same exclusive group is active"
+}
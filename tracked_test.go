@@ -2,6 +2,8 @@ package statesync
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"sync"
 	"testing"
 )
@@ -1110,27 +1112,30 @@ func TestBroadcastMultiClientBufferSafety(t *testing.T) {
 	}
 }
 
-// T3: Test AddField panics at 256 fields
+// T3: Test AddField beyond 256 fields (wide schemas) and index mismatch panics
 func TestAddField256Limit(t *testing.T) {
 	schema := NewSchema(1, "BigSchema")
 
-	// Add 256 fields (indices 0–255) — should succeed
-	for i := 0; i < 256; i++ {
+	// Add 257 fields (indices 0–256) — should succeed, the schema becomes wide
+	for i := 0; i <= 256; i++ {
 		schema.AddField(FieldMeta{
-			Index: uint8(i),
+			Index: uint16(i),
 			Name:  "field" + string(rune('a'+i%26)) + string(rune('0'+i/26)),
 			Type:  TypeInt32,
 		})
 	}
+	if !schema.Wide() || schema.MaxWideIndex() != 256 {
+		t.Errorf("expected a wide schema with max index 256, got wide=%v max=%d", schema.Wide(), schema.MaxWideIndex())
+	}
 
-	// 257th field should panic
+	// A field whose index doesn't match its position should panic
 	defer func() {
 		if r := recover(); r == nil {
-			t.Error("T3 REGRESSION: AddField did not panic at 256 fields")
+			t.Error("T3 REGRESSION: AddField did not panic on index mismatch")
 		}
 	}()
 	schema.AddField(FieldMeta{
-		Index: 0, // Would wrap to 0 as uint8(256)
+		Index: 0, // What uint8(257) used to wrap to
 		Name:  "overflow",
 		Type:  TypeInt32,
 	})
@@ -1584,3 +1589,324 @@ func (e *testActivatableEffect) SetActivator(a any)                     { e.acti
 func (e *testActivatableEffect) OnActivate(s *TestGameState, a any) *TestGameState {
 	return e.onActivate(s, a)
 }
+
+// appendNameEffect returns an effect that appends suffix to the state's Name
+func appendNameEffect(id, suffix string) *FuncEffect[*simpleTestState, any] {
+	return Func(id, func(s *simpleTestState, _ any) *simpleTestState {
+		c := *s
+		c.Name += suffix
+		return &c
+	})
+}
+
+func TestTrackedState_EffectPriorityOrder(t *testing.T) {
+	ts := NewTrackedState[*simpleTestState, any](&simpleTestState{changes: NewChangeSet()}, nil)
+
+	ts.AddEffect(appendNameEffect("late", "c").WithPriority(10), nil)
+	ts.AddEffect(appendNameEffect("first", "a"), nil)
+	ts.AddEffect(appendNameEffect("early", "b").WithPriority(-5), nil)
+	ts.AddEffect(appendNameEffect("second", "d"), nil)
+
+	if got := ts.Get().Name; got != "badc" {
+		t.Errorf("Name = %q, want %q", got, "badc")
+	}
+
+	var ids []string
+	for _, e := range ts.Effects() {
+		ids = append(ids, e.ID())
+	}
+	if fmt.Sprint(ids) != "[early first second late]" {
+		t.Errorf("Effects() order = %v", ids)
+	}
+}
+
+func TestTrackedState_EffectStackAdd(t *testing.T) {
+	ts := NewTrackedState[*simpleTestState, any](&simpleTestState{changes: NewChangeSet()}, nil)
+
+	for i := 1; i <= 4; i++ {
+		e := appendNameEffect(fmt.Sprintf("poison-%d", i), fmt.Sprint(i)).WithStacking("poison", 3, StackAdd)
+		if err := ts.AddEffect(e, nil); err != nil {
+			t.Fatalf("AddEffect %d: %v", i, err)
+		}
+	}
+
+	if n := ts.StackCount("poison"); n != 3 {
+		t.Errorf("StackCount = %d, want 3", n)
+	}
+	if ts.HasEffect("poison-1") {
+		t.Error("oldest stack should be dropped when full")
+	}
+	if got := ts.Get().Name; got != "234" {
+		t.Errorf("Name = %q, want 234", got)
+	}
+
+	dup := appendNameEffect("poison-4", "x").WithStacking("poison", 3, StackAdd)
+	if err := ts.AddEffect(dup, nil); err != ErrEffectExists {
+		t.Errorf("expected ErrEffectExists for duplicate stack ID, got %v", err)
+	}
+}
+
+func TestTrackedState_EffectStackRefresh(t *testing.T) {
+	ts := NewTrackedState[*TestGameState, any](NewTestGameState(), nil)
+
+	activations := 0
+	newHaste := func(id string) Effect[*TestGameState, any] {
+		return &testStackableEffect{
+			testActivatableEffect: testActivatableEffect{
+				id: id,
+				onActivate: func(s *TestGameState, _ any) *TestGameState {
+					activations++
+					return s
+				},
+				apply: func(s *TestGameState, _ any) *TestGameState { return s },
+			},
+			kind:     "haste",
+			behavior: StackRefresh,
+		}
+	}
+
+	if err := ts.AddEffect(newHaste("haste-1"), nil); err != nil {
+		t.Fatalf("AddEffect: %v", err)
+	}
+	if err := ts.AddEffect(newHaste("haste-2"), nil); err != nil {
+		t.Fatalf("refresh AddEffect: %v", err)
+	}
+	if err := ts.AddEffect(newHaste("haste-2"), nil); err != nil {
+		t.Fatalf("refresh with same ID: %v", err)
+	}
+
+	if activations != 1 {
+		t.Errorf("OnActivate called %d times, want 1", activations)
+	}
+	if n := ts.StackCount("haste"); n != 1 {
+		t.Errorf("StackCount = %d, want 1", n)
+	}
+	if !ts.HasEffect("haste-2") || ts.HasEffect("haste-1") {
+		t.Error("refresh should replace the active effect")
+	}
+}
+
+func TestTrackedState_EffectStackReject(t *testing.T) {
+	ts := NewTrackedState[*simpleTestState, any](&simpleTestState{changes: NewChangeSet()}, nil)
+
+	ts.AddEffect(appendNameEffect("stun-1", "a").WithStacking("stun", 0, StackReject), nil)
+	err := ts.AddEffect(appendNameEffect("stun-2", "b").WithStacking("stun", 0, StackReject), nil)
+	if err != ErrEffectExists {
+		t.Errorf("expected ErrEffectExists, got %v", err)
+	}
+}
+
+func TestTrackedState_EffectExclusiveGroup(t *testing.T) {
+	ts := NewTrackedState[*simpleTestState, any](&simpleTestState{changes: NewChangeSet()}, nil)
+
+	ts.AddEffect(appendNameEffect("small-shield", "s").WithExclusiveGroup("shield", 10), nil)
+	if err := ts.AddEffect(appendNameEffect("big-shield", "B").WithExclusiveGroup("shield", 50), nil); err != nil {
+		t.Fatalf("stronger effect should replace weaker: %v", err)
+	}
+	if ts.HasEffect("small-shield") {
+		t.Error("weaker effect should be replaced")
+	}
+
+	err := ts.AddEffect(appendNameEffect("tiny-shield", "t").WithExclusiveGroup("shield", 5), nil)
+	if !errors.Is(err, ErrEffectWeaker) {
+		t.Errorf("expected ErrEffectWeaker, got %v", err)
+	}
+	if got := ts.Get().Name; got != "B" {
+		t.Errorf("Name = %q, want B", got)
+	}
+}
+
+func TestTrackedState_EffectExclusiveGroupTieKeepsIncumbent(t *testing.T) {
+	ts := NewTrackedState[*simpleTestState, any](&simpleTestState{changes: NewChangeSet()}, nil)
+
+	ts.AddEffect(appendNameEffect("shield-a", "a").WithExclusiveGroup("shield", 10), nil)
+	err := ts.AddEffect(appendNameEffect("shield-b", "b").WithExclusiveGroup("shield", 10), nil)
+	if !errors.Is(err, ErrEffectWeaker) {
+		t.Errorf("expected ErrEffectWeaker on equal strength, got %v", err)
+	}
+	if !ts.HasEffect("shield-a") || ts.HasEffect("shield-b") {
+		t.Error("equal strength should keep the active effect")
+	}
+}
+
+func TestTrackedState_EffectStackAddEvictsOldest(t *testing.T) {
+	ts := NewTrackedState[*simpleTestState, any](&simpleTestState{changes: NewChangeSet()}, nil)
+
+	// The oldest stack has the highest priority, so it is applied last
+	ts.AddEffect(appendNameEffect("poison-1", "1").WithStacking("poison", 2, StackAdd).WithPriority(5), nil)
+	ts.AddEffect(appendNameEffect("poison-2", "2").WithStacking("poison", 2, StackAdd), nil)
+	ts.AddEffect(appendNameEffect("poison-3", "3").WithStacking("poison", 2, StackAdd), nil)
+
+	if ts.HasEffect("poison-1") || !ts.HasEffect("poison-2") || !ts.HasEffect("poison-3") {
+		t.Errorf("expected the oldest stack to be evicted, got %d stacks", ts.StackCount("poison"))
+	}
+
+	ts.AddEffect(appendNameEffect("poison-4", "4").WithStacking("poison", 2, StackAdd).WithPriority(-5), nil)
+	if ts.HasEffect("poison-2") || !ts.HasEffect("poison-3") || !ts.HasEffect("poison-4") {
+		t.Error("expected poison-2 to be evicted next")
+	}
+}
+
+// testStackableEffect is a testActivatableEffect with stacking rules
+type testStackableEffect struct {
+	testActivatableEffect
+	kind     string
+	behavior StackBehavior
+}
+
+func (e *testStackableEffect) Kind() string                 { return e.kind }
+func (e *testStackableEffect) MaxStacks() int               { return 0 }
+func (e *testStackableEffect) StackBehavior() StackBehavior { return e.behavior }
+
+// decodedFields returns the field indices changed in an encoded patch
+func decodedFields(t *testing.T, ts *TrackedState[*simpleTestState, any], data []byte) []uint16 {
+	t.Helper()
+	if data == nil {
+		return nil
+	}
+	patch, err := NewDecoder(ts.Registry()).Decode(data)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	var fields []uint16
+	for _, c := range patch.Changes {
+		fields = append(fields, c.FieldIndex)
+	}
+	return fields
+}
+
+func TestTrackedState_FieldEffectMarksWrites(t *testing.T) {
+	ts := NewTrackedState[*simpleTestState, any](&simpleTestState{changes: NewChangeSet()}, nil)
+
+	ts.AddEffect(appendNameEffect("title", "!").WithFields(nil, []uint16{1}), nil)
+	if got := decodedFields(t, ts, ts.Encode()); fmt.Sprint(got) != "[1]" {
+		t.Errorf("after AddEffect changed fields = %v, want [1]", got)
+	}
+	ts.Commit()
+
+	ts.RemoveEffect("title")
+	if got := decodedFields(t, ts, ts.Encode()); fmt.Sprint(got) != "[1]" {
+		t.Errorf("after RemoveEffect changed fields = %v, want [1]", got)
+	}
+}
+
+func TestTrackedState_FieldEffectDependencies(t *testing.T) {
+	ts := NewTrackedState[*simpleTestState, any](&simpleTestState{changes: NewChangeSet()}, nil)
+
+	// Name depends on Score
+	ts.AddEffect(Func("rank", func(s *simpleTestState, _ any) *simpleTestState {
+		c := *s
+		c.Name = fmt.Sprintf("rank-%d", s.Score/10)
+		return &c
+	}).WithFields([]uint16{0}, []uint16{1}), nil)
+	ts.Commit()
+
+	if ts.Encode() != nil {
+		t.Fatal("expected no changes")
+	}
+
+	ts.Update(func(s **simpleTestState) {
+		(*s).Score = 25
+		(*s).changes.Mark(0, OpReplace)
+	})
+	if got := decodedFields(t, ts, ts.Encode()); fmt.Sprint(got) != "[0 1]" {
+		t.Errorf("changed fields = %v, want [0 1]", got)
+	}
+	if got := ts.Get().Name; got != "rank-2" {
+		t.Errorf("Name = %q, want rank-2", got)
+	}
+
+	// Reads don't mark dependent fields; direct mutations need InvalidateEffects
+	ts.Commit()
+	ts.GetBase().changes.Mark(0, OpReplace)
+	ts.Get()
+	if ts.GetBase().changes.IsFieldDirty(1) {
+		t.Error("Get should not mark dependent fields")
+	}
+	ts.InvalidateEffects()
+	if !ts.GetBase().changes.IsFieldDirty(1) {
+		t.Error("expected InvalidateEffects to mark dependent fields")
+	}
+}
+
+func TestTrackedState_CacheEffects(t *testing.T) {
+	ts := NewTrackedState[*simpleTestState, any](&simpleTestState{changes: NewChangeSet()}, &TrackedConfig{CacheEffects: true})
+
+	applied := map[string]int{}
+	counting := func(id, suffix string) *FuncEffect[*simpleTestState, any] {
+		return Func(id, func(s *simpleTestState, _ any) *simpleTestState {
+			applied[id]++
+			c := *s
+			c.Name += suffix
+			return &c
+		})
+	}
+
+	ts.AddEffect(counting("a", "a"), nil)
+	ts.Get()
+	ts.Get()
+	if applied["a"] != 1 {
+		t.Errorf("effect a applied %d times, want 1 (cached)", applied["a"])
+	}
+
+	ts.AddEffect(counting("b", "b").WithPriority(1), nil)
+	if got := ts.Get().Name; got != "ab" {
+		t.Errorf("Name = %q, want ab", got)
+	}
+	if applied["a"] != 1 || applied["b"] != 1 {
+		t.Errorf("expected only the new effect to be applied, got %v", applied)
+	}
+
+	ts.Update(func(s **simpleTestState) { (*s).Name = "x" })
+	if got := ts.Get().Name; got != "xab" {
+		t.Errorf("Name = %q, want xab", got)
+	}
+	if applied["a"] != 2 || applied["b"] != 2 {
+		t.Errorf("expected all effects re-applied after Update, got %v", applied)
+	}
+
+	ts.RemoveEffect("a")
+	if got := ts.Get().Name; got != "xb" {
+		t.Errorf("Name = %q, want xb", got)
+	}
+}
+
+func TestTrackedState_CacheEffectsReappliesDependents(t *testing.T) {
+	ts := NewTrackedState[*simpleTestState, any](&simpleTestState{changes: NewChangeSet()}, &TrackedConfig{CacheEffects: true})
+
+	applied := map[string]int{}
+	ts.AddEffect(Func("title", func(s *simpleTestState, _ any) *simpleTestState {
+		applied["title"]++
+		c := *s
+		c.Name = "boss"
+		return &c
+	}).WithFields([]uint16{}, []uint16{1}), nil)
+	ts.AddEffect(Func("double", func(s *simpleTestState, _ any) *simpleTestState {
+		applied["double"]++
+		c := *s
+		c.Score *= 2
+		return &c
+	}).WithFields([]uint16{0}, []uint16{0}).WithPriority(1), nil)
+	ts.Get()
+	ts.Commit()
+	ts.Get()
+	if applied["title"] != 1 || applied["double"] != 1 {
+		t.Fatalf("expected Commit to keep cached outputs, got %v", applied)
+	}
+
+	// Score changes: only the effect reading it is re-applied, on top of the new base
+	ts.Update(func(s **simpleTestState) {
+		(*s).Score = 5
+		(*s).changes.Mark(0, OpReplace)
+	})
+	got := ts.Get()
+	if got.Score != 10 || got.Name != "boss" {
+		t.Errorf("effective state = %d %q, want 10 boss", got.Score, got.Name)
+	}
+	if applied["title"] != 1 || applied["double"] != 2 {
+		t.Errorf("expected only double to be re-applied, got %v", applied)
+	}
+	if ts.GetBase().Score != 5 {
+		t.Errorf("base Score = %d, want 5", ts.GetBase().Score)
+	}
+}
@@ -0,0 +1,246 @@
+package statesync
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Replayer rebuilds the real Go state T from a replay file.
+// It steps forward and backward through the recorded diffs, seeks to any seq or tick
+// via the keyframe index, and can re-run game logic to verify determinism.
+type Replayer[T Trackable] struct {
+	rr       *ReplayReader
+	schema   *Schema
+	registry *SchemaRegistry
+	maps     *MapReplayer
+	init     func(T) T
+	record   *DiffRecord // Last applied diff or keyframe (nil at the start)
+	next     int64       // Offset of the next frame
+}
+
+// NewReplayer creates a typed replayer positioned before the first frame.
+// init prepares decoded states for use (e.g. to set up change tracking) and may be nil.
+func NewReplayer[T Trackable](rr *ReplayReader, init func(T) T) *Replayer[T] {
+	schema := schemaOf[T]()
+	registry := NewSchemaRegistry()
+	registry.Register(schema)
+	return &Replayer[T]{
+		rr:       rr,
+		schema:   schema,
+		registry: registry,
+		maps:     NewMapReplayer(registry),
+		init:     init,
+	}
+}
+
+// Reset moves back before the first frame
+func (r *Replayer[T]) Reset() {
+	r.maps.Reset()
+	r.record = nil
+	r.next = 0
+}
+
+// Seq returns the seq of the last applied diff or keyframe (0 at the start)
+func (r *Replayer[T]) Seq() uint64 {
+	if r.record == nil {
+		return 0
+	}
+	return r.record.Seq
+}
+
+// Tick returns the tick of the last applied diff or keyframe (0 at the start)
+func (r *Replayer[T]) Tick() uint64 {
+	if r.record == nil {
+		return 0
+	}
+	return r.record.Tick
+}
+
+// Record returns the last applied diff or keyframe (nil at the start)
+func (r *Replayer[T]) Record() *DiffRecord {
+	return r.record
+}
+
+// Fields returns the current state as decoded fields (do not modify)
+func (r *Replayer[T]) Fields() map[string]interface{} {
+	return r.maps.State()
+}
+
+// State decodes a fresh T at the current position
+func (r *Replayer[T]) State() (T, error) {
+	state, err := StateFromFields[T](r.maps.State())
+	if err != nil {
+		return state, err
+	}
+	if r.init != nil {
+		state = r.init(state)
+	}
+	return state, nil
+}
+
+// Step applies the next diff. At the start, the initial keyframe is loaded first.
+// Returns false at the end of the replay.
+func (r *Replayer[T]) Step() (bool, error) {
+	var applied bool
+	var replayErr error
+	err := r.rr.Frames(r.next, func(f ReplayFrame) bool {
+		r.next = f.Next
+		if f.Keyframe {
+			if r.record == nil {
+				// Nothing loaded yet (as in seekFrom)
+				replayErr = r.apply(f.DiffRecord)
+				return replayErr == nil
+			}
+			return true // The state already matches
+		}
+		replayErr = r.apply(f.DiffRecord)
+		applied = replayErr == nil
+		return false
+	})
+	if err != nil {
+		return false, err
+	}
+	return applied, replayErr
+}
+
+// StepBack moves to the state before the last applied diff.
+// Returns false if already at the start.
+func (r *Replayer[T]) StepBack() (bool, error) {
+	if r.record == nil {
+		return false, nil
+	}
+	if r.record.Seq == 0 {
+		r.Reset()
+		return true, nil
+	}
+	return true, r.Seek(r.record.Seq - 1)
+}
+
+// Seek moves to the state after seq (the last recorded diff at or before seq)
+func (r *Replayer[T]) Seek(seq uint64) error {
+	index := r.rr.Keyframes()
+	k := sort.Search(len(index), func(i int) bool { return index[i].Seq > seq }) - 1
+	return r.seekFrom(k, func(rec DiffRecord) bool { return rec.Seq <= seq })
+}
+
+// SeekTick moves to the state after tick (see ReplayWriter.SetTick)
+func (r *Replayer[T]) SeekTick(tick uint64) error {
+	index := r.rr.Keyframes()
+	k := sort.Search(len(index), func(i int) bool { return index[i].Tick > tick }) - 1
+	return r.seekFrom(k, func(rec DiffRecord) bool { return rec.Tick <= tick })
+}
+
+// seekFrom loads keyframe k (-1 for the start) and applies the following diffs while include returns true
+func (r *Replayer[T]) seekFrom(k int, include func(DiffRecord) bool) error {
+	r.Reset()
+	if k >= 0 {
+		r.next = r.rr.Keyframes()[k].Offset
+	}
+	var replayErr error
+	loaded := false
+	err := r.rr.Frames(r.next, func(f ReplayFrame) bool {
+		if f.Keyframe {
+			if !loaded && k >= 0 {
+				loaded = true
+				replayErr = r.apply(f.DiffRecord)
+			}
+		} else if include(f.DiffRecord) {
+			replayErr = r.apply(f.DiffRecord)
+		} else {
+			return false
+		}
+		r.next = f.Next
+		return replayErr == nil
+	})
+	if err != nil {
+		return err
+	}
+	return replayErr
+}
+
+// apply replays a diff or keyframe and makes it the current record
+func (r *Replayer[T]) apply(rec DiffRecord) error {
+	if _, err := r.maps.Replay(rec); err != nil {
+		return err
+	}
+	r.record = &rec
+	return nil
+}
+
+// Divergence is the first recorded seq where re-running logic produced a different state
+type Divergence struct {
+	Seq       uint64
+	Tick      uint64
+	Fields    []string               // Top-level fields that differ
+	Recorded  map[string]interface{} // State from the replay
+	Simulated map[string]interface{} // State produced by the logic
+}
+
+// Verify checks determinism: starting at seq from, it hands the state before every
+// following diff up to seq to (inclusive) to step, and compares step's result with the
+// recorded state. The replay should start with a keyframe so the recorded state is complete.
+//
+// Example with the logicgen rule engine:
+//
+//	div, err := replayer.Verify(0, math.MaxUint64, func(s *GameState, next DiffRecord) (*GameState, error) {
+//	    return s, eval.NewEngine(s, rules).TickWithDelta(ctx, time.Duration(next.DeltaNs))
+//	})
+func (r *Replayer[T]) Verify(from, to uint64, step func(state T, next DiffRecord) (T, error)) (*Divergence, error) {
+	if err := r.Seek(from); err != nil {
+		return nil, err
+	}
+	enc := NewEncoder(r.registry)
+	dec := NewDecoder(r.registry)
+	for {
+		prev, err := r.State()
+		if err != nil {
+			return nil, err
+		}
+		ok, err := r.Step()
+		if err != nil {
+			return nil, err
+		}
+		if !ok || r.record.Seq > to {
+			return nil, nil
+		}
+
+		simulated, err := step(prev, *r.record)
+		if err != nil {
+			return nil, err
+		}
+		patch, err := dec.Decode(enc.EncodeAll(simulated))
+		if err != nil {
+			return nil, err
+		}
+		fields := make(map[string]interface{})
+		if err := ApplyPatch(fields, patch, r.schema); err != nil {
+			return nil, err
+		}
+		if diff := diffFieldMaps(r.maps.State(), fields); len(diff) > 0 {
+			return &Divergence{
+				Seq:       r.record.Seq,
+				Tick:      r.record.Tick,
+				Fields:    diff,
+				Recorded:  r.maps.State(),
+				Simulated: fields,
+			}, nil
+		}
+	}
+}
+
+// diffFieldMaps returns the sorted keys whose values differ between two decoded states
+func diffFieldMaps(a, b map[string]interface{}) []string {
+	var diff []string
+	for k, v := range a {
+		if !reflect.DeepEqual(v, b[k]) {
+			diff = append(diff, k)
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			diff = append(diff, k)
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
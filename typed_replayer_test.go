@@ -0,0 +1,123 @@
+package statesync
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func initReplayTestState(s *ReplayTestState) *ReplayTestState {
+	s.changes = NewChangeSet()
+	return s
+}
+
+func openTestReplayer(t *testing.T) *Replayer[*ReplayTestState] {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "match.replay")
+	recordReplay(t, path)
+	rr, err := OpenReplayFile(path)
+	if err != nil {
+		t.Fatalf("OpenReplayFile: %v", err)
+	}
+	t.Cleanup(func() { rr.Close() })
+	return NewReplayer(rr, initReplayTestState)
+}
+
+func replayedScore(t *testing.T, r *Replayer[*ReplayTestState]) int64 {
+	t.Helper()
+	s, err := r.State()
+	if err != nil {
+		t.Fatalf("State: %v", err)
+	}
+	return s.Score
+}
+
+func TestReplayer_Stepping(t *testing.T) {
+	r := openTestReplayer(t)
+
+	if err := r.Seek(7); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	state, _ := r.State()
+	if state.Score != 70 || state.Name != "start" || r.Seq() != 7 {
+		t.Errorf("at seq %d: Score=%d Name=%q, want seq 7, 70, start", r.Seq(), state.Score, state.Name)
+	}
+
+	if ok, err := r.Step(); !ok || err != nil || replayedScore(t, r) != 80 {
+		t.Errorf("Step = %v, %v; Score %d, want 80", ok, err, replayedScore(t, r))
+	}
+	r.StepBack()
+	r.StepBack()
+	if r.Seq() != 6 || replayedScore(t, r) != 60 {
+		t.Errorf("after two StepBack: seq %d, Score %d; want 6, 60", r.Seq(), replayedScore(t, r))
+	}
+
+	// Tick = seq*2
+	r.SeekTick(9)
+	if r.Seq() != 4 || r.Tick() != 8 {
+		t.Errorf("SeekTick(9): seq %d tick %d, want 4, 8", r.Seq(), r.Tick())
+	}
+
+	// Step to the end
+	r.Seek(9)
+	r.Step()
+	if ok, _ := r.Step(); ok {
+		t.Error("Step past the end should return false")
+	}
+	if replayedScore(t, r) != 100 {
+		t.Errorf("final Score = %d, want 100", replayedScore(t, r))
+	}
+
+	// Back to the initial keyframe and then the start
+	r.Seek(0)
+	if r.Seq() != 0 || replayedScore(t, r) != 0 {
+		t.Errorf("Seek(0): seq %d Score %d", r.Seq(), replayedScore(t, r))
+	}
+	if ok, _ := r.StepBack(); !ok || r.Record() != nil {
+		t.Error("StepBack from the initial keyframe should reset to the start")
+	}
+	if ok, _ := r.StepBack(); ok {
+		t.Error("StepBack at the start should return false")
+	}
+
+	// Stepping from the start loads the initial keyframe first
+	if ok, err := r.Step(); !ok || err != nil {
+		t.Fatalf("Step from the start = %v, %v", ok, err)
+	}
+	stepped, _ := r.State()
+	r.Seek(1)
+	sought, _ := r.State()
+	if r.Seq() != 1 || stepped.Name != sought.Name || stepped.Score != sought.Score || stepped.Name != "start" {
+		t.Errorf("Step from the start: Name=%q Score=%d, want Seek(1)'s Name=%q Score=%d", stepped.Name, stepped.Score, sought.Name, sought.Score)
+	}
+}
+
+func TestReplayer_Verify(t *testing.T) {
+	r := openTestReplayer(t)
+
+	deterministic := func(s *ReplayTestState, next DiffRecord) (*ReplayTestState, error) {
+		s.SetScore(s.Score + 10)
+		return s, nil
+	}
+	div, err := r.Verify(0, 10, deterministic)
+	if err != nil || div != nil {
+		t.Fatalf("Verify = %+v, %v; want no divergence", div, err)
+	}
+
+	drifting := func(s *ReplayTestState, next DiffRecord) (*ReplayTestState, error) {
+		s.SetScore(s.Score + 10)
+		if next.Seq == 5 {
+			s.SetScore(999)
+		}
+		return s, nil
+	}
+	div, err = r.Verify(2, 10, drifting)
+	if err != nil || div == nil {
+		t.Fatalf("Verify = %v, %v; want a divergence", div, err)
+	}
+	if div.Seq != 5 || len(div.Fields) != 1 || div.Fields[0] != "Score" {
+		t.Errorf("divergence at seq %d fields %v, want seq 5 [Score]", div.Seq, div.Fields)
+	}
+	if div.Recorded["Score"] != int64(50) || div.Simulated["Score"] != int64(999) {
+		t.Errorf("recorded %v simulated %v", div.Recorded["Score"], div.Simulated["Score"])
+	}
+}
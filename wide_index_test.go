@@ -0,0 +1,240 @@
+package statesync
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// wideState has more than 256 fields: 299 int32 fields and a map at index 299
+type wideState struct {
+	changes *ChangeSet
+	Values  [299]int32
+	Tags    map[string]int32
+}
+
+var wideStateSchema = func() *Schema {
+	b := NewSchemaBuilder("WideState").WithID(95)
+	for i := 0; i < 299; i++ {
+		b.Int32("F" + strconv.Itoa(i))
+	}
+	return b.Map("Tags", TypeInt32, nil).Build()
+}()
+
+func (s *wideState) Schema() *Schema     { return wideStateSchema }
+func (s *wideState) Changes() *ChangeSet { return s.changes }
+func (s *wideState) ClearChanges()       { s.changes.Clear() }
+func (s *wideState) MarkAllDirty()       { s.changes.MarkAllWide(wideStateSchema.MaxWideIndex()) }
+func (s *wideState) GetFieldValue(index uint8) interface{} {
+	return s.GetWideFieldValue(uint16(index))
+}
+func (s *wideState) GetWideFieldValue(index uint16) interface{} {
+	switch {
+	case index < 299:
+		return s.Values[index]
+	case index == 299:
+		return s.Tags
+	}
+	return nil
+}
+
+func newWideState() *wideState {
+	s := &wideState{changes: NewChangeSet(), Tags: map[string]int32{"a": 1}}
+	for i := range s.Values {
+		s.Values[i] = int32(i)
+	}
+	return s
+}
+
+func TestChangeSet_WideIndices(t *testing.T) {
+	cs := NewChangeSet()
+	cs.MarkWide(300, OpReplace)
+	cs.Mark(3, OpReplace)
+	cs.MarkWideWithIndex(260, OpMove, 1, 2)
+
+	if !cs.HasChanges() || !cs.IsWideFieldDirty(300) || cs.IsWideFieldDirty(301) || cs.IsFieldDirty(44) || !cs.IsWideFieldDirty(3) {
+		t.Fatal("unexpected dirty state for wide indices")
+	}
+	if got := cs.GetWideFieldChange(260); got != (FieldChange{Op: OpMove, OldIndex: 1, NewIndex: 2}) {
+		t.Errorf("unexpected change %+v", got)
+	}
+	if got := cs.ChangedWideFields(); !reflect.DeepEqual(got, []uint16{3, 260, 300}) {
+		t.Errorf("expected sorted fields [3 260 300], got %v", got)
+	}
+	// The compact API only sees the first 256 fields
+	if got := cs.ChangedFields(); !reflect.DeepEqual(got, []uint8{3}) {
+		t.Errorf("expected compact fields [3], got %v", got)
+	}
+
+	clone := cs.CloneForFilter()
+	cs.Clear()
+	if cs.HasChanges() || cs.GetWideFieldChange(300).Op != OpNone {
+		t.Error("expected Clear to reset wide indices")
+	}
+	if !clone.IsWideFieldDirty(300) {
+		t.Error("expected the clone to keep wide indices")
+	}
+
+	cs.MarkAllWide(299)
+	if len(cs.ChangedWideFields()) != 300 {
+		t.Errorf("expected 300 changed fields, got %d", len(cs.ChangedWideFields()))
+	}
+}
+
+func TestEncoder_WideSchema(t *testing.T) {
+	registry := NewSchemaRegistry()
+	registry.Register(wideStateSchema)
+	state := newWideState()
+	encoder := NewEncoder(registry)
+	decoder := NewDecoder(registry)
+
+	full := encoder.EncodeAll(state)
+	if full[0] != MsgFullState|MsgFlagWideIndex {
+		t.Fatalf("expected a flagged full state, got type %#x", full[0])
+	}
+	patch, err := decoder.Decode(full)
+	if err != nil {
+		t.Fatalf("decode full state: %v", err)
+	}
+	fields := make(map[string]interface{})
+	if err := ApplyPatch(fields, patch, wideStateSchema); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if len(fields) != 300 || fields["F298"] != int32(298) || !reflect.DeepEqual(fields["Tags"], map[string]interface{}{"a": int32(1)}) {
+		t.Fatalf("unexpected full state: F298=%v Tags=%v (%d fields)", fields["F298"], fields["Tags"], len(fields))
+	}
+
+	state.Values[3], state.Values[290] = -3, -290
+	state.changes.Mark(3, OpReplace)
+	state.changes.MarkWide(290, OpReplace)
+	state.Tags["b"] = 2
+	state.changes.GetOrCreateWideMap(299).MarkAdd("b", int32(2))
+
+	data := encoder.Encode(state)
+	if data[0] != MsgPatch|MsgFlagWideIndex {
+		t.Fatalf("expected a flagged patch, got type %#x", data[0])
+	}
+	patch, err = decoder.Decode(data)
+	if err != nil {
+		t.Fatalf("decode patch: %v", err)
+	}
+	var indices []uint16
+	for _, c := range patch.Changes {
+		indices = append(indices, c.FieldIndex)
+	}
+	if !reflect.DeepEqual(indices, []uint16{3, 290, 299}) {
+		t.Fatalf("expected changes to fields [3 290 299], got %v", indices)
+	}
+	if err := ApplyPatch(fields, patch, wideStateSchema); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if fields["F290"] != int32(-290) || !reflect.DeepEqual(fields["Tags"], map[string]interface{}{"a": int32(1), "b": int32(2)}) {
+		t.Errorf("unexpected patched state: F290=%v Tags=%v", fields["F290"], fields["Tags"])
+	}
+
+	// Overlays read wide fields through GetWideFieldValue
+	view := NewOverlay(state).Hide("F290")
+	patch, err = decoder.Decode(encoder.EncodeAll(view))
+	if err != nil {
+		t.Fatalf("decode overlay: %v", err)
+	}
+	if v := patch.Changes[290].Value; v != int32(0) {
+		t.Errorf("expected hidden F290 to be zero, got %v", v)
+	}
+	if v := patch.Changes[291].Value; v != int32(291) {
+		t.Errorf("expected F291 through the overlay, got %v", v)
+	}
+}
+
+func TestTrackedSession_WideSchema(t *testing.T) {
+	tracked := NewTrackedState[*wideState, any](newWideState(), nil)
+	session := NewTrackedSession[*wideState, any, string](tracked)
+	session.Connect("alice", nil)
+	decoder := NewDecoder(tracked.Registry())
+
+	patch, err := decoder.Decode(session.Tick()["alice"])
+	if err != nil || len(patch.Changes) != 300 {
+		t.Fatalf("expected a full state with 300 fields, got %v", err)
+	}
+
+	tracked.UpdateInPlace(func(s *wideState) {
+		s.Values[280] = 1
+		s.changes.MarkWide(280, OpReplace)
+	})
+	patch, err = decoder.Decode(session.Tick()["alice"])
+	if err != nil || len(patch.Changes) != 1 || patch.Changes[0].FieldIndex != 280 || patch.Changes[0].Value != int32(1) {
+		t.Fatalf("expected a patch of field 280, got %+v, %v", patch, err)
+	}
+}
+
+func TestTrackedSession_WideSchemaFiltered(t *testing.T) {
+	tracked := NewTrackedState[*wideState, any](newWideState(), nil)
+	session := NewTrackedSession[*wideState, any, string](tracked)
+	session.Connect("alice", func(s *wideState) *wideState { return s })
+	decoder := NewDecoder(tracked.Registry())
+	session.Tick()
+
+	// Filtered clients are diffed against what they were sent
+	tracked.UpdateInPlace(func(s *wideState) {
+		s.Values[280] = 1
+		s.changes.MarkWide(280, OpReplace)
+	})
+	patch, err := decoder.Decode(session.Tick()["alice"])
+	if err != nil || len(patch.Changes) != 1 || patch.Changes[0].FieldIndex != 280 || patch.Changes[0].Value != int32(1) {
+		t.Fatalf("expected a patch of field 280, got %+v, %v", patch, err)
+	}
+}
+
+func TestEncoder_CompactSchemaUnflagged(t *testing.T) {
+	registry := NewSchemaRegistry()
+	registry.Register(aclStateSchema)
+	state := newAclState()
+	state.changes.Mark(1, OpReplace)
+
+	encoder := NewEncoder(registry)
+	if data := encoder.EncodeAll(state); data[0] != MsgFullState || data[3] != 4 {
+		t.Errorf("expected a compact full state with a byte field count, got % x", data[:4])
+	}
+	if data := encoder.Encode(state); data[0] != MsgPatch || data[4] != 1 {
+		t.Errorf("expected a compact patch with a byte field index, got % x", data[:5])
+	}
+}
+
+func TestDecoder_WideIndexErrors(t *testing.T) {
+	registry := NewSchemaRegistry()
+	registry.Register(wideStateSchema)
+	decoder := NewDecoder(registry)
+
+	// Field index past the schema
+	data := []byte{MsgPatch | MsgFlagWideIndex, 95, 0, 1, 0xAC, 0x02, byte(OpReplace), 0, 0, 0, 0}
+	if _, err := decoder.Decode(data); !errors.Is(err, ErrInvalidField) {
+		t.Errorf("expected ErrInvalidField for field index 300, got %v", err)
+	}
+	// Flag on a message type that doesn't support it
+	if _, err := decoder.Decode([]byte{MsgRootRemove | MsgFlagWideIndex, 95, 0}); err != ErrInvalidMessage {
+		t.Errorf("expected ErrInvalidMessage, got %v", err)
+	}
+}
+
+func TestSchemaDescriptor_Wide(t *testing.T) {
+	desc := DescribeSchemas(wideStateSchema)
+	if desc.Version != SchemaDescriptorVersionWide {
+		t.Fatalf("expected version %d, got %d", SchemaDescriptorVersionWide, desc.Version)
+	}
+	decoded, err := DecodeSchemaDescriptor(desc.Encode())
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	schemas, err := decoded.Build()
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if schemas[0].Fingerprint() != wideStateSchema.Fingerprint() {
+		t.Error("rebuilt wide schema differs from the original")
+	}
+
+	if v := DescribeSchemas(aclStateSchema).Version; v != SchemaDescriptorVersion {
+		t.Errorf("expected compact schemas to keep version %d, got %d", SchemaDescriptorVersion, v)
+	}
+}
@@ -0,0 +1,348 @@
+package statesync
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// WritePermission specifies who can modify a field (see FieldMeta.Write)
+type WritePermission string
+
+const (
+	// WriteAnyone lets every client write the field (default)
+	WriteAnyone WritePermission = ""
+
+	// WriteServer lets only the server write the field (Update, or UpdateAs with an empty client ID)
+	WriteServer WritePermission = "server"
+
+	// WriteOwner lets only the owner of the enclosing struct write the field
+	// (Schema.OwnerField, or the key of the array/map element holding it, as for ViewOwner)
+	WriteOwner WritePermission = "owner"
+)
+
+// ErrNotCloneable is returned by UpdateAs when a client update needs a write check
+// but the state type doesn't implement Cloner
+var ErrNotCloneable = errors.New("state type does not implement Cloner")
+
+// Cloner is implemented by state types that can deep-copy themselves. Clone returns a
+// copy sharing no mutable data (fields, elements, ChangeSets) with the receiver.
+// schemagen generates it; UpdateAs clones the state before a client update and
+// restores the clone if the client made a write it may not.
+type Cloner[T any] interface {
+	Clone() T
+}
+
+// restoreState copies src into *dst. Pointer states are copied into the value *dst
+// points to, so holders of the pointer see the restored state.
+func restoreState[T any](dst *T, src T) {
+	d, v := reflect.ValueOf(*dst), reflect.ValueOf(src)
+	if d.Kind() == reflect.Pointer && !d.IsNil() && !v.IsNil() {
+		d.Elem().Set(v.Elem())
+		return
+	}
+	*dst = src
+}
+
+// PermissionError is returned by UpdateAs when a client wrote a field it may not write
+type PermissionError struct {
+	Path     string          // Field path, e.g. "Players[bob].Gold"
+	Required WritePermission // Permission of the field
+	ClientID string          // Client the update was made for
+	OwnerID  string          // Owner of the enclosing struct ("" if none)
+}
+
+func (e *PermissionError) Error() string {
+	switch e.Required {
+	case WriteServer:
+		return fmt.Sprintf("client %q cannot write %s: only the server can", e.ClientID, e.Path)
+	case WriteOwner:
+		return fmt.Sprintf("client %q cannot write %s: only the owner %q can", e.ClientID, e.Path, e.OwnerID)
+	}
+	return fmt.Sprintf("client %q cannot write %s: requires %q", e.ClientID, e.Path, e.Required)
+}
+
+// IsPermissionError reports whether err is (or wraps) a *PermissionError
+func IsPermissionError(err error) bool {
+	var pe *PermissionError
+	return errors.As(err, &pe)
+}
+
+// WriteAudit describes a checked write to a field with a write permission
+type WriteAudit struct {
+	ClientID string
+	Path     string
+	Field    *FieldMeta
+	OwnerID  string // Owner of the enclosing struct ("" if none)
+	Allowed  bool
+}
+
+// CanWrite reports whether a client may write a field of a struct owned by owner
+// (hasOwner is false if the struct has no owner). The server (empty client ID) can write everything.
+func CanWrite(field *FieldMeta, clientID string, owner string, hasOwner bool) bool {
+	if clientID == "" {
+		return true
+	}
+	switch field.Write {
+	case WriteAnyone:
+		return true
+	case WriteOwner:
+		return hasOwner && owner == clientID
+	}
+	return false
+}
+
+// writeRestrictedSchemas caches whether a schema (or a nested schema) has write permissions
+var writeRestrictedSchemas sync.Map // *Schema -> bool
+
+// isWriteRestricted reports whether any field of the schema or its nested schemas has a write permission
+func isWriteRestricted(schema *Schema) bool {
+	if schema == nil {
+		return false
+	}
+	if cached, ok := writeRestrictedSchemas.Load(schema); ok {
+		return cached.(bool)
+	}
+	restricted := hasWritePermissions(schema, make(map[*Schema]bool))
+	writeRestrictedSchemas.Store(schema, restricted)
+	return restricted
+}
+
+func hasWritePermissions(schema *Schema, visited map[*Schema]bool) bool {
+	if schema == nil || visited[schema] {
+		return false
+	}
+	visited[schema] = true
+	for i := range schema.Fields {
+		field := &schema.Fields[i]
+		if field.Write != WriteAnyone || hasWritePermissions(field.ChildSchema, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeSnapshot holds copies of the field values a write check compares, by field index.
+// Only fields with a write permission (all of them below one) and the fields leading to
+// them are copied.
+type writeSnapshot map[uint16]interface{}
+
+// arraySnapshot is a copied array (keys are set for keyed struct arrays)
+type arraySnapshot struct {
+	elems []interface{}
+	keys  []string
+}
+
+// snapshotWrites copies the values of t that a write check needs (nil if t is nil)
+func snapshotWrites(t Trackable, schema *Schema, all bool) writeSnapshot {
+	if isNilValue(t) {
+		return nil
+	}
+	snap := make(writeSnapshot)
+	for i := range schema.Fields {
+		field := &schema.Fields[i]
+		protected := all || field.Write != WriteAnyone
+		if !protected && !isWriteRestricted(field.ChildSchema) && field.Name != schema.OwnerField {
+			continue
+		}
+		snap[field.Index] = snapshotValue(field, field.Type, fieldValue(t, field.Index), protected)
+	}
+	return snap
+}
+
+// snapshotValue copies a field (or element, with typ = field.ElemType) value
+func snapshotValue(field *FieldMeta, typ FieldType, value interface{}, all bool) interface{} {
+	switch typ {
+	case TypeStruct:
+		t, ok := value.(Trackable)
+		if !ok || isNilValue(value) {
+			return nil
+		}
+		return snapshotWrites(t, field.ChildSchema, all)
+	case TypeArray:
+		key := arrayKeyField(field)
+		n := getArrayLength(value)
+		arr := arraySnapshot{elems: make([]interface{}, n)}
+		for i := 0; i < n; i++ {
+			elem := getArrayElement(value, i)
+			arr.elems[i] = snapshotValue(field, field.ElemType, elem, all)
+			if key != nil {
+				arr.keys = append(arr.keys, elementKey(elem, key))
+			}
+		}
+		return arr
+	case TypeMap:
+		keys, values := getMapKeysValues(value)
+		entries := make(map[string]interface{}, len(keys))
+		for i, k := range keys {
+			entries[k] = snapshotValue(field, field.ElemType, values[i], all)
+		}
+		return entries
+	}
+	if b, ok := value.([]byte); ok {
+		return string(b) // Don't alias a buffer the state may reuse
+	}
+	return value
+}
+
+// writeCheck compares snapshots taken before and after an update made for a client
+type writeCheck struct {
+	clientID string
+	audits   []WriteAudit
+	denied   *PermissionError // First denied write
+}
+
+// checkStruct checks the writes to a struct owned by owner (unless its schema has an OwnerField)
+func (c *writeCheck) checkStruct(before, after writeSnapshot, schema *Schema, path string, owner string, hasOwner bool) {
+	if schema.OwnerField != "" {
+		if field := fieldNamed(schema, schema.OwnerField); field != nil {
+			value, ok := before[field.Index]
+			if !ok {
+				value = after[field.Index]
+			}
+			owner, hasOwner = ownerKey(value), true
+		}
+	}
+
+	for i := range schema.Fields {
+		field := &schema.Fields[i]
+		b, inBefore := before[field.Index]
+		a, inAfter := after[field.Index]
+		if !inBefore && !inAfter {
+			continue
+		}
+		fieldPath := field.Name
+		if path != "" {
+			fieldPath = path + "." + field.Name
+		}
+		if field.Write != WriteAnyone && !reflect.DeepEqual(b, a) {
+			c.record(field, fieldPath, owner, hasOwner)
+		}
+		if isWriteRestricted(field.ChildSchema) {
+			c.checkValue(field, field.Type, b, a, fieldPath, owner, hasOwner)
+		}
+	}
+}
+
+// checkValue checks the writes inside a struct, array or map value.
+// Added and removed elements are writes to the container field itself and to
+// each protected field of the element.
+func (c *writeCheck) checkValue(field *FieldMeta, typ FieldType, before, after interface{}, path string, owner string, hasOwner bool) {
+	switch typ {
+	case TypeStruct:
+		b, _ := before.(writeSnapshot)
+		a, _ := after.(writeSnapshot)
+		if b == nil && a == nil {
+			return
+		}
+		// A struct that was added or removed counts as a write to all its protected fields
+		if b == nil {
+			b = writeSnapshot{}
+		}
+		if a == nil {
+			a = writeSnapshot{}
+		}
+		c.checkStruct(b, a, field.ChildSchema, path, owner, hasOwner)
+	case TypeArray:
+		b, _ := before.(arraySnapshot)
+		a, _ := after.(arraySnapshot)
+		if arrayKeyField(field) == nil {
+			for i := 0; i < len(b.elems) || i < len(a.elems); i++ {
+				c.checkValue(field, field.ElemType, elemAt(b.elems, i), elemAt(a.elems, i), path+"["+strconv.Itoa(i)+"]", owner, hasOwner)
+			}
+			return
+		}
+		// Keyed elements are matched and owned by their key
+		index := make(map[string]int, len(b.keys))
+		for i, key := range b.keys {
+			index[key] = i
+		}
+		for i, key := range a.keys {
+			if j, ok := index[key]; ok {
+				c.checkValue(field, field.ElemType, b.elems[j], a.elems[i], path+"["+key+"]", key, true)
+				delete(index, key)
+			} else {
+				c.checkValue(field, field.ElemType, nil, a.elems[i], path+"["+key+"]", key, true)
+			}
+		}
+		for j, key := range b.keys {
+			if _, removed := index[key]; removed {
+				c.checkValue(field, field.ElemType, b.elems[j], nil, path+"["+key+"]", key, true)
+			}
+		}
+	case TypeMap:
+		b, _ := before.(map[string]interface{})
+		a, _ := after.(map[string]interface{})
+		keys := make([]string, 0, len(a)+len(b))
+		for key := range a {
+			keys = append(keys, key)
+		}
+		for key := range b {
+			if _, ok := a[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			c.checkValue(field, field.ElemType, b[key], a[key], path+"["+key+"]", key, true)
+		}
+	}
+}
+
+// elemAt returns elems[i], or nil past the end
+func elemAt(elems []interface{}, i int) interface{} {
+	if i < len(elems) {
+		return elems[i]
+	}
+	return nil
+}
+
+// record audits a changed field with a write permission
+func (c *writeCheck) record(field *FieldMeta, path string, owner string, hasOwner bool) {
+	allowed := CanWrite(field, c.clientID, owner, hasOwner)
+	c.audits = append(c.audits, WriteAudit{
+		ClientID: c.clientID,
+		Path:     path,
+		Field:    field,
+		OwnerID:  owner,
+		Allowed:  allowed,
+	})
+	if !allowed && c.denied == nil {
+		c.denied = &PermissionError{Path: path, Required: field.Write, ClientID: c.clientID, OwnerID: owner}
+	}
+}
+
+// CloneSlice returns a copy of s with each element copied by clone (as is if clone is nil).
+// The generated Clone (see Cloner) uses it for arrays.
+func CloneSlice[E any](s []E, clone func(E) E) []E {
+	if s == nil {
+		return nil
+	}
+	cp := make([]E, len(s))
+	for i, v := range s {
+		if clone != nil {
+			v = clone(v)
+		}
+		cp[i] = v
+	}
+	return cp
+}
+
+// CloneMap returns a copy of m with each value copied by clone (as is if clone is nil).
+// The generated Clone (see Cloner) uses it for maps.
+func CloneMap[K comparable, V any](m map[K]V, clone func(V) V) map[K]V {
+	if m == nil {
+		return nil
+	}
+	cp := make(map[K]V, len(m))
+	for k, v := range m {
+		if clone != nil {
+			v = clone(v)
+		}
+		cp[k] = v
+	}
+	return cp
+}
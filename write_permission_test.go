@@ -0,0 +1,334 @@
+package statesync
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type aclUnit struct {
+	changes *ChangeSet
+	ID      string
+	Name    string // owner only
+	Gold    int64  // server only
+}
+
+var aclUnitSchema = NewSchemaBuilder("AclUnit").WithID(81).
+	String("ID").Write(WriteServer).
+	String("Name").Write(WriteOwner).
+	Int64("Gold").Write(WriteServer).
+	Build()
+
+func (u *aclUnit) Schema() *Schema     { return aclUnitSchema }
+func (u *aclUnit) Changes() *ChangeSet { return u.changes }
+func (u *aclUnit) ClearChanges()       { u.changes.Clear() }
+func (u *aclUnit) MarkAllDirty()       { u.changes.MarkAll(2) }
+func (u *aclUnit) Clone() *aclUnit {
+	if u == nil {
+		return nil
+	}
+	clone := *u
+	clone.changes = u.changes.Clone()
+	return &clone
+}
+func (u *aclUnit) GetFieldValue(index uint8) interface{} {
+	switch index {
+	case 0:
+		return u.ID
+	case 1:
+		return u.Name
+	case 2:
+		return u.Gold
+	}
+	return nil
+}
+
+type aclState struct {
+	changes *ChangeSet
+	Round   int32 // server only
+	Chat    string
+	Units   []*aclUnit
+	Bank    map[string]*aclUnit
+}
+
+var aclStateSchema = NewSchemaBuilder("AclState").WithID(80).
+	Int32("Round").Write(WriteServer).
+	String("Chat").
+	ArrayByKey("Units", TypeStruct, aclUnitSchema, "ID").
+	Map("Bank", TypeStruct, aclUnitSchema).Write(WriteServer).
+	Build()
+
+func (s *aclState) Schema() *Schema     { return aclStateSchema }
+func (s *aclState) Changes() *ChangeSet { return s.changes }
+func (s *aclState) ClearChanges()       { s.changes.Clear() }
+func (s *aclState) MarkAllDirty()       { s.changes.MarkAll(3) }
+func (s *aclState) Clone() *aclState {
+	clone := *s
+	clone.changes = s.changes.Clone()
+	clone.Units = CloneSlice(s.Units, (*aclUnit).Clone)
+	clone.Bank = CloneMap(s.Bank, (*aclUnit).Clone)
+	return &clone
+}
+func (s *aclState) GetFieldValue(index uint8) interface{} {
+	switch index {
+	case 0:
+		return s.Round
+	case 1:
+		return s.Chat
+	case 2:
+		return s.Units
+	case 3:
+		return s.Bank
+	}
+	return nil
+}
+
+func newAclState() *aclState {
+	return &aclState{
+		changes: NewChangeSet(),
+		Round:   1,
+		Units: []*aclUnit{
+			{changes: NewChangeSet(), ID: "alice", Name: "A"},
+			{changes: NewChangeSet(), ID: "bob", Name: "B"},
+		},
+		Bank: map[string]*aclUnit{"alice": {changes: NewChangeSet(), ID: "alice", Gold: 10}},
+	}
+}
+
+func TestCanWrite(t *testing.T) {
+	anyone := &FieldMeta{Name: "Chat"}
+	server := &FieldMeta{Name: "Round", Write: WriteServer}
+	owner := &FieldMeta{Name: "Name", Write: WriteOwner}
+
+	tests := []struct {
+		field  *FieldMeta
+		client string
+		owner  string
+		has    bool
+		want   bool
+	}{
+		{server, "", "", false, true},
+		{anyone, "alice", "", false, true},
+		{server, "alice", "", false, false},
+		{owner, "alice", "alice", true, true},
+		{owner, "alice", "bob", true, false},
+		{owner, "alice", "", false, false},
+		{&FieldMeta{Write: "admin"}, "alice", "", false, false},
+	}
+	for i, tt := range tests {
+		if got := CanWrite(tt.field, tt.client, tt.owner, tt.has); got != tt.want {
+			t.Errorf("%d: CanWrite(%s, %q) = %v, want %v", i, tt.field.Name, tt.client, got, tt.want)
+		}
+	}
+}
+
+func TestTrackedState_UpdateAs(t *testing.T) {
+	tracked := NewTrackedState[*aclState, any](newAclState(), nil)
+	var audits []WriteAudit
+	tracked.SetWriteAudit(func(a WriteAudit) { audits = append(audits, a) })
+
+	tests := []struct {
+		name   string
+		client string
+		fn     func(s *aclState)
+		denied string // Denied path ("" = allowed)
+	}{
+		{"unrestricted field", "alice", func(s *aclState) { s.Chat = "hi" }, ""},
+		{"own element", "alice", func(s *aclState) { s.Units[0].Name = "Al" }, ""},
+		{"other's element", "alice", func(s *aclState) { s.Units[1].Name = "Bobby" }, "Units[bob].Name"},
+		{"server field", "alice", func(s *aclState) { s.Round++ }, "Round"},
+		{"server field in own element", "alice", func(s *aclState) { s.Units[0].Gold = 99 }, "Units[alice].Gold"},
+		{"server container", "alice", func(s *aclState) { s.Bank["alice"].Gold++ }, "Bank"},
+		{"server", "", func(s *aclState) { s.Round++; s.Units[1].Name = "Bob" }, ""},
+		{"remove other's element", "alice", func(s *aclState) { s.Units = s.Units[:1] }, "Units[bob].ID"},
+		{"add element", "alice", func(s *aclState) {
+			s.Units = append(s.Units, &aclUnit{changes: NewChangeSet(), ID: "carol"})
+		}, "Units[carol].ID"},
+		{"element moves", "bob", func(s *aclState) {
+			s.Units[0], s.Units[1] = s.Units[1], s.Units[0]
+			s.Units[0].Name = "Bo"
+		}, ""},
+	}
+	for _, tt := range tests {
+		audits = nil
+		err := tracked.UpdateAs(tt.client, func(s **aclState) { tt.fn(*s) })
+		if tt.denied == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		var pe *PermissionError
+		if !errors.As(err, &pe) || pe.Path != tt.denied || pe.ClientID != tt.client {
+			t.Errorf("%s: expected denied write to %s, got %v", tt.name, tt.denied, err)
+			continue
+		}
+		if len(audits) == 0 || audits[len(audits)-1].Allowed {
+			t.Errorf("%s: expected a denied audit, got %+v", tt.name, audits)
+		}
+	}
+}
+
+func TestTrackedState_UpdateAsIgnoresPendingWrites(t *testing.T) {
+	tracked := NewTrackedState[*aclState, any](newAclState(), nil)
+
+	// A server write pending in the same tick isn't attributed to the client
+	tracked.UpdateInPlace(func(s *aclState) {
+		s.Round = 2
+		s.changes.Mark(0, OpReplace)
+	})
+	var audits []WriteAudit
+	tracked.SetWriteAudit(func(a WriteAudit) { audits = append(audits, a) })
+	err := tracked.UpdateAs("alice", func(s **aclState) {
+		(*s).Units[0].Name = "Al"
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(audits) != 1 || audits[0].Path != "Units[alice].Name" || !audits[0].Allowed || audits[0].OwnerID != "alice" {
+		t.Errorf("expected one allowed audit for Units[alice].Name, got %+v", audits)
+	}
+}
+
+func TestTrackedState_UpdateAsRollsBackDenied(t *testing.T) {
+	state := newAclState()
+	tracked := NewTrackedState[*aclState, any](state, nil)
+	session := NewTrackedSession[*aclState, any, string](tracked)
+	session.Connect("alice", nil)
+	session.Tick()
+
+	err := tracked.UpdateAs("alice", func(s **aclState) {
+		(*s).Chat = "hi"
+		(*s).changes.Mark(1, OpReplace)
+		(*s).Units[1].Name = "Bobby"
+		(*s).Units[1].changes.Mark(1, OpReplace)
+		(*s).Units = (*s).Units[:1]
+		(*s).changes.GetOrCreateArray(2).MarkRemove(1)
+	})
+	if !IsPermissionError(err) {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+	if tracked.GetBase() != state {
+		t.Error("expected the state to be kept")
+	}
+	if state.Chat != "" || len(state.Units) != 2 || state.Units[1].Name != "B" || state.changes.HasChanges() || state.Units[1].changes.HasChanges() {
+		t.Errorf("expected the denied update not to touch the state, got chat %q and %d units", state.Chat, len(state.Units))
+	}
+	if data := session.Tick()["alice"]; len(data) != 0 {
+		t.Errorf("expected nothing to send after a denied update, got % x", data)
+	}
+
+	if err := tracked.UpdateAs("alice", func(s **aclState) {
+		(*s).Units[0].Name = "Al"
+		(*s).Units[0].changes.Mark(1, OpReplace)
+		(*s).changes.GetOrCreateArray(2).MarkReplace(0, (*s).Units[0])
+	}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if state.Units[0].Name != "Al" {
+		t.Error("expected the allowed write to be applied")
+	}
+	if data := session.Tick()["alice"]; len(data) == 0 {
+		t.Error("expected the allowed update to be sent")
+	}
+}
+
+func TestTrackedState_UpdateAsKeepsIdentity(t *testing.T) {
+	tracked := NewTrackedState[*aclState, any](newAclState(), nil)
+	session := NewTrackedSession[*aclState, any, string](tracked)
+	session.Connect("alice", nil)
+	session.Tick()
+
+	root := tracked.GetBase()
+	unit := root.Units[0]
+	if err := tracked.UpdateAs("alice", func(s **aclState) {
+		(*s).Units[0].Name = "Al"
+		(*s).Units[0].changes.Mark(1, OpReplace)
+		(*s).changes.GetOrCreateArray(2).MarkReplace(0, (*s).Units[0])
+	}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if tracked.GetBase() != root || root.Units[0] != unit {
+		t.Fatal("expected an allowed update to keep the root and nested pointers")
+	}
+	if err := tracked.UpdateAs("alice", func(s **aclState) {
+		(*s).Round = 2
+		(*s).changes.Mark(0, OpReplace)
+	}); !IsPermissionError(err) {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+	if tracked.GetBase() != root || root.Round != 1 {
+		t.Fatal("expected a denied update to be rolled back into the same root")
+	}
+	session.Tick()
+
+	// Writes through the held pointer reach the tracked state and clients
+	root.Chat = "server"
+	root.changes.Mark(1, OpReplace)
+	if got := tracked.GetBase().Chat; got != "server" {
+		t.Errorf("Chat = %q, want server", got)
+	}
+	if data := session.Tick()["alice"]; len(data) == 0 {
+		t.Error("expected the write through the held pointer to be sent")
+	}
+}
+
+func TestTrackedState_UpdateAsRequiresCloner(t *testing.T) {
+	type plainState struct{ *aclState }
+	tracked := NewTrackedState[plainState, any](plainState{newAclState()}, nil)
+	err := tracked.UpdateAs("alice", func(s *plainState) { s.Chat = "hi" })
+	if !errors.Is(err, ErrNotCloneable) {
+		t.Fatalf("expected ErrNotCloneable, got %v", err)
+	}
+	if tracked.GetBase().Chat != "" {
+		t.Error("expected fn not to run")
+	}
+}
+
+func TestChangeSet_Clone(t *testing.T) {
+	cs := NewChangeSet()
+	cs.Mark(1, OpReplace)
+	cs.GetOrCreateChild(3).Mark(0, OpReplace)
+	cs.GetOrCreateArray(2).MarkRemove(1)
+	cs.GetOrCreateMap(4).MarkAdd("a", 1)
+
+	clone := cs.Clone()
+	clone.Mark(5, OpReplace)
+	clone.GetOrCreateChild(3).Mark(1, OpReplace)
+	clone.GetOrCreateArray(2).MarkRemove(0)
+	clone.GetOrCreateMap(4).MarkAdd("b", 2)
+	if cs.IsFieldDirty(5) || cs.GetChild(3).IsFieldDirty(1) || len(cs.GetArray(2).changes) != 1 || len(cs.GetMap(4).changes) != 1 {
+		t.Error("expected the clone to be independent of the original")
+	}
+	if !clone.IsFieldDirty(1) || !clone.GetChild(3).IsFieldDirty(0) {
+		t.Error("expected the clone to keep the original's changes")
+	}
+	if (*ChangeSet)(nil).Clone() != nil {
+		t.Error("expected a nil clone of a nil ChangeSet")
+	}
+}
+
+func TestTrackedSession_UpdateAs(t *testing.T) {
+	tracked := NewTrackedState[*aclState, any](newAclState(), nil)
+	session := NewTrackedSession[*aclState, any, string](tracked)
+
+	err := session.UpdateAs("bob", func(s **aclState) { (*s).Round = 5 })
+	if !IsPermissionError(err) {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+	if !IsPermissionError(fmt.Errorf("command: %w", err)) {
+		t.Error("expected wrapped permission errors to be detected")
+	}
+	if err.Error() != `client "bob" cannot write Round: only the server can` {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+}
+
+func TestSchemaBuilder_WriteWithoutFieldPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	NewSchemaBuilder("Empty").Write(WriteServer)
+}